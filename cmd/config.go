@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"backend/common/mailer"
+	"backend/common/storage"
+	"backend/config"
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var configCommand = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configCheckCommand = &cobra.Command{
+	Use:   "check",
+	Short: "Check the configuration and the services it points to",
+	Long: "Load the configuration the way serve does, then check that the database is reachable\n" +
+		"and migrated and that the storage and mail drivers can be set up.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCommand("config check", func() error {
+			db := bootstrap()
+			fmt.Printf("config      ok (%s, %s)\n", config.Config.AppName, config.Config.AppEnv)
+
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err = sqlDB.PingContext(ctx)
+			if err != nil {
+				return fmt.Errorf("database: %w", err)
+			}
+			fmt.Printf("database    ok (%s:%d/%s)\n", config.Config.Database.Host, config.Config.Database.Port, config.Config.Database.Name)
+
+			pending, err := newMigrator(db).Pending(ctx)
+			if err != nil {
+				return fmt.Errorf("migrations: %w", err)
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("migrations: %d pending, starting with %s", len(pending), pending[0])
+			}
+			fmt.Println("migrations  ok")
+
+			_, err = storage.NewStorage(config.Config.Storage)
+			if err != nil {
+				return fmt.Errorf("storage: %w", err)
+			}
+			fmt.Printf("storage     ok (%s)\n", config.Config.Storage.Driver)
+
+			_, err = mailer.NewMailer(config.Config.Mail)
+			if err != nil {
+				return fmt.Errorf("mail: %w", err)
+			}
+			fmt.Printf("mail        ok (%s)\n", config.Config.Mail.Driver)
+
+			return nil
+		})
+	},
+}
+
+func init() {
+	configCommand.AddCommand(configCheckCommand)
+}
@@ -1,20 +1,27 @@
 package cmd
 
 import (
+	"backend/common/keys"
+	"backend/common/mailer"
 	"backend/common/response"
+	"backend/common/storage"
 	"backend/config"
 	"backend/constants"
 	"backend/controllers"
+	"backend/database/audit"
 	"backend/database/seeders"
 	"backend/domain/models"
 	"backend/middlewares"
 	"backend/repositories"
 	"backend/routes"
 	"backend/services"
+	"context"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 	"net/http"
 	"os"
 	"time"
@@ -24,43 +31,30 @@ var command = &cobra.Command{
 	Use:   "serve",
 	Short: "Start the server",
 	Run: func(cmd *cobra.Command, args []string) {
-		if os.Getenv("APP_ENV") != "production" {
-			_ = godotenv.Load()
-		}
-		config.Init()
-		db, err := config.InitDatabase()
-		if err != nil {
-			panic(err)
-		}
-
-		location, err := time.LoadLocation("Asia/Jakarta")
-		if err != nil {
-			panic(err)
-		}
-		time.Local = location
+		db := bootstrap()
+		checkMigrations(cmd, db)
 
-		err = db.AutoMigrate(
-			&models.Role{},
-			&models.User{},
-			&models.Product{},
-		)
-		if err != nil {
-			panic(err)
+		if seed, _ := cmd.Flags().GetBool("seed"); seed {
+			err := seeders.NewSeederRegistry(db).Run()
+			if err != nil {
+				panic(err)
+			}
 		}
-
-		seeders.NewSeederRegistry(db).Run()
-		repository := repositories.NewRepositoryRegistry(db)
-		service := services.NewServiceRegistry(repository)
+		keySet := newKeySet()
+		service := newServiceRegistry(db, keySet)
+		middlewares.Init(service)
 		controller := controllers.NewControllerRegistry(service)
 
 		app := fiber.New(fiber.Config{
 			ErrorHandler: middlewares.HandlePanic(),
+			BodyLimit:    max(fiber.DefaultBodyLimit, config.Config.Storage.MaxImageSize+1024*1024),
 		})
 
 		app.Use(func(c *fiber.Ctx) error {
 			c.Set("Access-Control-Allow-Origin", "*")
 			c.Set("Access-Control-Allow-Methods", "POST, GET, PUT, DELETE, PATCH")
-			c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-api-key, x-request-at")
+			c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-api-key, x-request-at, x-nonce, x-signature, x-terminal-key, x-request-id, If-Match, If-None-Match")
+			c.Set("Access-Control-Expose-Headers", "ETag, Link, Retry-After, X-Request-ID")
 
 			if c.Method() == "OPTIONS" {
 				return c.SendStatus(fiber.StatusNoContent)
@@ -69,6 +63,8 @@ var command = &cobra.Command{
 			return c.Next()
 		})
 
+		app.Use(middlewares.RequestContext())
+
 		app.Use(middlewares.RateLimiter(
 			config.Config.RateLimiterMaxRequest,
 			time.Duration(config.Config.RateLimiterTimeSecond)*time.Second,
@@ -81,6 +77,20 @@ var command = &cobra.Command{
 			})
 		})
 
+		app.Get("/.well-known/jwks.json", controller.GetUserController().GetJwks)
+
+		// Old keys are kept for one access token lifetime after their
+		// successor appears, a minute longer for clock skew.
+		go keySet.Run(
+			context.Background(),
+			time.Duration(config.Config.JwtKeyRotationHours)*time.Hour,
+			time.Duration(config.Config.JwtExpirationTime)*time.Minute+time.Minute,
+		)
+
+		if config.Config.Storage.Driver == "local" {
+			app.Static(config.Config.Storage.BaseURL, config.Config.Storage.LocalPath)
+		}
+
 		group := app.Group("/api/v1")
 		route := routes.NewRouteRegistry(controller, group)
 		route.Serve()
@@ -93,16 +103,146 @@ var command = &cobra.Command{
 		})
 
 		port := fmt.Sprintf(":%d", config.Config.Port)
-		err = app.Listen(port)
+		err := app.Listen(port)
 		if err != nil {
 			return
 		}
 	},
 }
 
+// bootstrap loads the configuration and connects to the database. Every
+// command that needs the database goes through here, the schema is left to
+// the migrate command.
+func bootstrap() *gorm.DB {
+	if os.Getenv("APP_ENV") != "production" {
+		_ = godotenv.Load()
+	}
+	config.Init()
+	db, err := config.InitDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	location, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		panic(err)
+	}
+	time.Local = location
+
+	err = db.Use(audit.New())
+	if err != nil {
+		panic(err)
+	}
+
+	// Role permissions are written through the join model so the audit
+	// plugin sees them.
+	err = db.SetupJoinTable(&models.Role{}, "Permissions", &models.RolePermission{})
+	if err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// checkMigrations refuses to serve a schema the code was not written for.
+// --migrate applies the pending migrations first, --allow-pending-migrations
+// starts anyway.
+func checkMigrations(cmd *cobra.Command, db *gorm.DB) {
+	applyMigrations, _ := cmd.Flags().GetBool("migrate")
+	allowPending, _ := cmd.Flags().GetBool("allow-pending-migrations")
+	migrator := newMigrator(db)
+
+	if applyMigrations {
+		applied, err := migrator.Up(context.Background(), 0)
+		for _, migration := range applied {
+			logrus.Infof("Applied migration %s", migration)
+		}
+		if err != nil {
+			logrus.Fatalf("Failed to apply migrations: %v", err)
+		}
+	}
+
+	pending, err := migrator.Pending(context.Background())
+	if err != nil {
+		logrus.Fatalf("Failed to check migrations: %v", err)
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	if !allowPending {
+		logrus.Fatalf("%d migrations are pending, starting with %s. Run `migrate up` or start with --migrate", len(pending), pending[0])
+	}
+	logrus.Warnf("Starting with %d pending migrations", len(pending))
+}
+
+// runCommand runs the work of an admin command and reports how it ended, on
+// stderr so stdout only carries its output. A failure, panics from
+// bootstrap or a seeder included, exits with status 1.
+func runCommand(name string, fn func() error) {
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		return fn()
+	}()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", name, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "%s succeeded\n", name)
+}
+
+func newKeySet() keys.IKeySet {
+	keySet, err := keys.NewKeySet(config.Config.JwtKeysDir, config.Config.JwtAlgorithm)
+	if err != nil {
+		panic(err)
+	}
+
+	return keySet
+}
+
+// loadKeySet is newKeySet for commands that do not sign tokens, it never
+// writes to the key directory.
+func loadKeySet() keys.IKeySet {
+	keySet, err := keys.LoadKeySet(config.Config.JwtKeysDir, config.Config.JwtAlgorithm)
+	if err != nil {
+		panic(err)
+	}
+
+	return keySet
+}
+
+func newServiceRegistry(db *gorm.DB, keySet keys.IKeySet) services.IServiceRegistry {
+	fileStorage, err := storage.NewStorage(config.Config.Storage)
+	if err != nil {
+		panic(err)
+	}
+
+	mailSender, err := mailer.NewMailer(config.Config.Mail)
+	if err != nil {
+		panic(err)
+	}
+
+	repository := repositories.NewRepositoryRegistry(db)
+	return services.NewServiceRegistry(repository, fileStorage, keySet, mailSender)
+}
+
+func init() {
+	command.Flags().Bool("migrate", false, "apply the pending migrations before starting")
+	command.Flags().Bool("allow-pending-migrations", false, "start even when migrations are pending")
+	command.Flags().Bool("seed", false, "run the seeders before starting")
+}
+
 func Run() {
+	command.AddCommand(migrateCommand, seedCommand, userCommand, productCommand, configCommand)
+	// Cobra already printed the error and the usage.
 	err := command.Execute()
 	if err != nil {
-		panic(err)
+		os.Exit(1)
 	}
 }
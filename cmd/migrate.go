@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"backend/database/migrations"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var migrateCommand = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema",
+}
+
+var migrateUpCommand = &cobra.Command{
+	Use:   "up",
+	Short: "Apply the pending migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		steps, _ := cmd.Flags().GetInt("steps")
+
+		runCommand("migrate up", func() error {
+			applied, err := newMigrator(bootstrap()).Up(context.Background(), steps)
+			for _, migration := range applied {
+				fmt.Printf("applied %s\n", migration)
+			}
+
+			if err == nil && len(applied) == 0 {
+				fmt.Println("no pending migrations")
+			}
+			return err
+		})
+	},
+}
+
+var migrateDownCommand = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the last applied migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		steps, _ := cmd.Flags().GetInt("steps")
+
+		runCommand("migrate down", func() error {
+			if steps < 1 {
+				return errors.New("--steps must be at least 1")
+			}
+
+			reverted, err := newMigrator(bootstrap()).Down(context.Background(), steps)
+			for _, migration := range reverted {
+				fmt.Printf("reverted %s\n", migration)
+			}
+
+			if err == nil && len(reverted) == 0 {
+				fmt.Println("no applied migrations")
+			}
+			return err
+		})
+	},
+}
+
+var migrateStatusCommand = &cobra.Command{
+	Use:   "status",
+	Short: "List the migrations and whether they are applied",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCommand("migrate status", func() error {
+			statuses, err := newMigrator(bootstrap()).Status(context.Background())
+			if err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+			for _, status := range statuses {
+				state, appliedAt := "pending", "-"
+				if status.AppliedAt != nil {
+					state, appliedAt = "applied", status.AppliedAt.Local().Format(time.DateTime)
+				}
+				if status.Missing {
+					state = "applied, file missing"
+				}
+				fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+			}
+			return writer.Flush()
+		})
+	},
+}
+
+var migrateCreateCommand = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an empty up and down migration",
+	Long: "Create an empty up and down migration named after the current time.\n" +
+		"Migrations are embedded in the binary, rebuild it before running them.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+
+		runCommand("migrate create", func() error {
+			paths, err := migrations.Create(dir, args[0], time.Now())
+			for _, path := range paths {
+				fmt.Printf("created %s\n", path)
+			}
+			return err
+		})
+	},
+}
+
+func newMigrator(db *gorm.DB) migrations.IMigrator {
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+
+	migrator, err := migrations.NewMigrator(sqlDB)
+	if err != nil {
+		panic(err)
+	}
+
+	return migrator
+}
+
+func init() {
+	migrateUpCommand.Flags().Int("steps", 0, "number of migrations to apply, all pending when 0")
+	migrateDownCommand.Flags().Int("steps", 1, "number of migrations to revert")
+	migrateCreateCommand.Flags().String("dir", "database/migrations", "directory the migration files are written to")
+
+	migrateCommand.AddCommand(migrateUpCommand, migrateDownCommand, migrateStatusCommand, migrateCreateCommand)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"backend/domain/dto"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var productCommand = &cobra.Command{
+	Use:   "product",
+	Short: "Manage products",
+}
+
+var productImportCommand = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import products from a CSV or XLSX file",
+	Long: "Import products from a CSV or XLSX file. Rows are upserted by code in a single transaction.\n" +
+		"Use --dry-run to only validate the file and print the per-row report.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		mapping, _ := cmd.Flags().GetStringToString("map")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		runCommand("product import", func() error {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			service := newServiceRegistry(bootstrap(), loadKeySet())
+			result, err := service.GetProduct().Import(context.Background(), file, file.Name(), &dto.ProductImportRequest{
+				Format:  format,
+				Mapping: mapping,
+				DryRun:  dryRun,
+			})
+
+			if result != nil {
+				output, _ := json.MarshalIndent(result, "", "  ")
+				fmt.Println(string(output))
+			}
+			return err
+		})
+	},
+}
+
+var productExportCommand = &cobra.Command{
+	Use:   "export",
+	Short: "Export products to a CSV or XLSX file",
+	Long: "Export products to a CSV or XLSX file, the same report GET /products/export returns.\n" +
+		"The file is written to stdout unless --output is given.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		rupiah, _ := cmd.Flags().GetBool("rupiah")
+		search, _ := cmd.Flags().GetString("search")
+		archived, _ := cmd.Flags().GetBool("archived")
+
+		runCommand("product export", func() error {
+			if format == "" && output != "" {
+				format = strings.TrimPrefix(strings.ToLower(filepath.Ext(output)), ".")
+			}
+
+			param := &dto.ProductExportParam{Format: format, Rupiah: rupiah}
+			if search != "" {
+				param.Search = &search
+			}
+			if archived {
+				param.Include = "archived"
+			}
+
+			service := newServiceRegistry(bootstrap(), loadKeySet())
+
+			writer := io.Writer(os.Stdout)
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				writer = file
+			}
+
+			return service.GetProduct().Export(context.Background(), writer, param)
+		})
+	},
+}
+
+func init() {
+	productImportCommand.Flags().String("format", "", "file format (csv or xlsx), detected from the extension when empty")
+	productImportCommand.Flags().StringToString("map", nil, "column mapping, e.g. --map name=\"Nama Barang\",price_sale=Harga")
+	productImportCommand.Flags().Bool("dry-run", false, "validate the file without saving anything")
+
+	productExportCommand.Flags().String("format", "", "file format (csv or xlsx), detected from --output or csv when empty")
+	productExportCommand.Flags().StringP("output", "o", "", "file to write, stdout when empty")
+	productExportCommand.Flags().Bool("rupiah", false, "format the prices as rupiah")
+	productExportCommand.Flags().String("search", "", "only export products whose code or name matches")
+	productExportCommand.Flags().Bool("archived", false, "include archived products")
+
+	productCommand.AddCommand(productImportCommand, productExportCommand)
+}
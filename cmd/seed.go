@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"backend/database/seeders"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var seedCommand = &cobra.Command{
+	Use:   "seed [seeder...]",
+	Short: "Run the database seeders",
+	Long: fmt.Sprintf("Run the database seeders, all of them or only the named ones.\n"+
+		"Seeders: %s. They always run in this order.", strings.Join(seeders.Names(), ", ")),
+	ValidArgs: seeders.Names(),
+	Args:      cobra.OnlyValidArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCommand("seed", func() error {
+			return seeders.NewSeederRegistry(bootstrap()).Run(args...)
+		})
+	},
+}
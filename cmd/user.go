@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"backend/domain/dto"
+	"backend/services"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var userCommand = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+}
+
+var userCreateOwnerCommand = &cobra.Command{
+	Use:   "create-owner",
+	Short: "Create a user with the owner role",
+	Long: "Create a user with the owner role, e.g. to recover a deployment nobody can log in to.\n" +
+		"The password is read from the first line of stdin when --password is not given.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		username, _ := cmd.Flags().GetString("username")
+		email, _ := cmd.Flags().GetString("email")
+		phoneNumber, _ := cmd.Flags().GetString("phone")
+		password, _ := cmd.Flags().GetString("password")
+
+		runCommand("user create-owner", func() error {
+			if password == "" {
+				fmt.Fprint(os.Stderr, "Password: ")
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("read password: %w", err)
+				}
+				password = strings.TrimRight(line, "\r\n")
+			}
+
+			request := &dto.RegisterRequest{
+				Name:            name,
+				Username:        username,
+				Password:        password,
+				ConfirmPassword: password,
+				Email:           email,
+				PhoneNumber:     phoneNumber,
+				Role:            "OWNER",
+			}
+			err := validator.New().Struct(request)
+			if err != nil {
+				return err
+			}
+
+			result, err := newServiceRegistry(bootstrap(), loadKeySet()).GetUser().Register(context.Background(), request)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("created owner %s (%s)\n", result.User.Username, result.User.UUID)
+			return nil
+		})
+	},
+}
+
+var userResetPasswordCommand = &cobra.Command{
+	Use:   "reset-password <username|uuid>",
+	Short: "Replace a user's password with a temporary one",
+	Long: "Replace a user's password with a temporary one and print it. The user has to change it\n" +
+		"after logging in, and every session of the user is revoked.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runCommand("user reset-password", func() error {
+			service := newServiceRegistry(bootstrap(), loadKeySet())
+			user, err := findUser(context.Background(), service, args[0])
+			if err != nil {
+				return err
+			}
+
+			result, err := service.GetUser().ResetPassword(context.Background(), user.UUID.String())
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("temporary password of %s: %s\n", result.User.Username, result.TemporaryPassword)
+			return nil
+		})
+	},
+}
+
+var userListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		param := &dto.UserRequestParam{}
+		param.Page, _ = cmd.Flags().GetInt("page")
+		param.Limit, _ = cmd.Flags().GetInt("limit")
+		param.Status, _ = cmd.Flags().GetString("status")
+		search, _ := cmd.Flags().GetString("search")
+		role, _ := cmd.Flags().GetString("role")
+
+		runCommand("user list", func() error {
+			if search != "" {
+				param.Search = &search
+			}
+			if role != "" {
+				param.Role = &role
+			}
+
+			err := validator.New().Struct(param)
+			if err != nil {
+				return err
+			}
+
+			result, err := newServiceRegistry(bootstrap(), loadKeySet()).GetUser().GetAllWithPagination(context.Background(), param)
+			if err != nil {
+				return err
+			}
+
+			users, _ := result.Data.([]dto.UserResponse)
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "UUID\tUSERNAME\tNAME\tEMAIL\tROLE\tSTATUS")
+			for _, user := range users {
+				status := "active"
+				if !user.IsActive {
+					status = "inactive"
+				}
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", user.UUID, user.Username, user.Name, user.Email, user.Role, status)
+			}
+			if err = writer.Flush(); err != nil {
+				return err
+			}
+
+			fmt.Printf("page %d of %d, %d users\n", result.Page, result.TotalPage, result.TotalData)
+			return nil
+		})
+	},
+}
+
+// findUser looks a user up by UUID or, when the argument is not one, by
+// username.
+func findUser(ctx context.Context, service services.IServiceRegistry, identifier string) (*dto.UserResponse, error) {
+	if _, err := uuid.Parse(identifier); err == nil {
+		return service.GetUser().GetUserByUUID(ctx, identifier)
+	}
+
+	if identifier == "" {
+		return nil, errors.New("username or uuid is required")
+	}
+	return service.GetUser().GetUserByUsername(ctx, identifier)
+}
+
+func init() {
+	userCreateOwnerCommand.Flags().String("name", "Owner", "display name")
+	userCreateOwnerCommand.Flags().String("username", "", "username to log in with")
+	userCreateOwnerCommand.Flags().String("email", "", "email address")
+	userCreateOwnerCommand.Flags().String("phone", "", "phone number")
+	userCreateOwnerCommand.Flags().String("password", "", "password, read from stdin when empty")
+	_ = userCreateOwnerCommand.MarkFlagRequired("username")
+	_ = userCreateOwnerCommand.MarkFlagRequired("email")
+	_ = userCreateOwnerCommand.MarkFlagRequired("phone")
+
+	userListCommand.Flags().Int("page", 1, "page to show")
+	userListCommand.Flags().Int("limit", 50, "users per page")
+	userListCommand.Flags().String("search", "", "only list users whose name, username or email matches")
+	userListCommand.Flags().String("role", "", "only list users with this role code")
+	userListCommand.Flags().String("status", "", "only list active or inactive users")
+
+	userCommand.AddCommand(userCreateOwnerCommand, userResetPasswordCommand, userListCommand)
+}
@@ -14,12 +14,40 @@ type ValidationResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
-var ErrValidator = map[string]string{}
+// ValidationErrors lets code outside the validator report field errors that
+// are rendered the same way as struct validation errors.
+type ValidationErrors []ValidationResponse
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, item := range v {
+		messages = append(messages, item.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+var ErrValidator = map[string]string{
+	"oneof":           "%s must be one of %s",
+	"datetime":        "%s must be a date in %s format",
+	"min":             "%s must be at least %s",
+	"max":             "%s must be at most %s",
+	"required_with":   "%s is required with %s",
+	"required_unless": "%s is required unless %s",
+	"uuid":            "%s must be a valid UUID",
+	"numeric":         "%s must only contain digits",
+	"eqfield":         "%s must match %s",
+}
 
 func ErrValidationResponse(err error) (validationResponse []ValidationResponse) {
 	var fieldErrors validator.ValidationErrors
 	var unmarshalTypeError *json.UnmarshalTypeError
 	var syntaxError *json.SyntaxError
+	var validationErrors ValidationErrors
+
+	if errors.As(err, &validationErrors) {
+		return validationErrors
+	}
 
 	// Handle JSON Syntax Error
 	if errors.As(err, &syntaxError) {
@@ -0,0 +1,356 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	keyExtension = ".pem"
+	kidLayout    = "20060102T150405Z"
+	rsaKeyBits   = 2048
+	pollInterval = time.Minute
+)
+
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// Key is a private key read from the key directory. The file name without
+// extension is its kid, which for generated keys is the time it was created.
+type Key struct {
+	ID        string
+	Algorithm string
+	Signer    crypto.Signer
+	CreatedAt time.Time
+}
+
+func (k *Key) SigningMethod() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+type IKeySet interface {
+	SigningKey() *Key
+	Find(kid string) (*Key, error)
+	JWKS() JWKS
+	Reload() error
+	Rotate() (*Key, error)
+	Prune(retain time.Duration) error
+	Run(ctx context.Context, rotateEvery, retain time.Duration)
+}
+
+// KeySet holds every key of a directory, the newest one signs and the older
+// ones only verify until Prune removes them.
+type KeySet struct {
+	dir       string
+	algorithm string
+
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+// NewKeySet loads the keys of dir and generates a first key of algorithm
+// when there is none yet.
+func NewKeySet(dir, algorithm string) (IKeySet, error) {
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unknown jwt algorithm: %s", algorithm)
+	}
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &KeySet{dir: dir, algorithm: algorithm}
+	err = set.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	if set.SigningKey() == nil {
+		key, err := set.Rotate()
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Generated jwt signing key %s", key.ID)
+	}
+
+	return set, nil
+}
+
+// LoadKeySet reads the keys of dir without creating the directory or a key,
+// for commands that never sign a token. A missing directory is an empty set.
+func LoadKeySet(dir, algorithm string) (IKeySet, error) {
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unknown jwt algorithm: %s", algorithm)
+	}
+
+	set := &KeySet{dir: dir, algorithm: algorithm}
+	err := set.Reload()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+func (s *KeySet) SigningKey() *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.keys) == 0 {
+		return nil
+	}
+	return s.keys[len(s.keys)-1]
+}
+
+func (s *KeySet) Find(kid string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (s *KeySet) JWKS() JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwks.Keys = append(jwks.Keys, newJWK(key))
+	}
+	return jwks
+}
+
+// Reload reads the key directory again, so keys added or removed by an
+// operator or another instance sharing the directory are picked up. A key
+// file that can not be read is logged and skipped, the others stay usable.
+func (s *KeySet) Reload() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*Key, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != keyExtension {
+			continue
+		}
+
+		key, err := readKey(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			logrus.Errorf("Failed to read jwt key %s: %v", entry.Name(), err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// Rotate writes a new key which signs from now on, the previous keys stay
+// available for verification.
+func (s *KeySet) Rotate() (*Key, error) {
+	signer, err := generateSigner(s.algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := time.Now().UTC().Format(kidLayout)
+	target := filepath.Join(s.dir, kid+keyExtension)
+	if _, err = os.Stat(target); err == nil {
+		return nil, fmt.Errorf("key %s already exists", kid)
+	}
+	temp := target + ".tmp"
+
+	err = os.WriteFile(temp, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Rename(temp, target)
+	if err != nil {
+		_ = os.Remove(temp)
+		return nil, err
+	}
+
+	err = s.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Find(kid)
+}
+
+// Prune deletes the keys that were replaced longer than retain ago, no token
+// signed by them can still be valid.
+func (s *KeySet) Prune(retain time.Duration) error {
+	s.mu.RLock()
+	var expired []string
+	for i := 0; i < len(s.keys)-1; i++ {
+		if time.Since(s.keys[i+1].CreatedAt) > retain {
+			expired = append(expired, s.keys[i].ID)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	for _, kid := range expired {
+		err := os.Remove(filepath.Join(s.dir, kid+keyExtension))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		logrus.Infof("Removed retired jwt signing key %s", kid)
+	}
+
+	return s.Reload()
+}
+
+// Run rotates the signing key every rotateEvery and prunes retired keys
+// until ctx is done. A zero rotateEvery only reloads and prunes.
+func (s *KeySet) Run(ctx context.Context, rotateEvery, retain time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.Reload()
+		if err != nil {
+			logrus.Errorf("Failed to reload jwt keys: %v", err)
+			continue
+		}
+
+		key := s.SigningKey()
+		if rotateEvery > 0 && (key == nil || time.Since(key.CreatedAt) >= rotateEvery) {
+			key, err = s.Rotate()
+			if err != nil {
+				logrus.Errorf("Failed to rotate jwt signing key: %v", err)
+				continue
+			}
+			logrus.Infof("Rotated jwt signing key to %s", key.ID)
+		}
+
+		err = s.Prune(retain)
+		if err != nil {
+			logrus.Errorf("Failed to prune jwt keys: %v", err)
+		}
+	}
+}
+
+func generateSigner(algorithm string) (crypto.Signer, error) {
+	if algorithm == AlgorithmEdDSA {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	}
+	return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+}
+
+// readKey accepts PKCS#8 keys and, for RSA, the PKCS#1 format older openssl
+// versions write.
+func readKey(path string) (*Key, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	var private any
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: strings.TrimSuffix(filepath.Base(path), keyExtension)}
+	key.CreatedAt, err = createdAt(path, key.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch signer := private.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm = AlgorithmRS256
+		key.Signer = signer
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgorithmEdDSA
+		key.Signer = signer
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+
+	return key, nil
+}
+
+// createdAt reads the creation time from the kid, so copying or restoring
+// the directory does not change which key signs or when one is pruned. Only
+// keys an operator named differently fall back to the modification time.
+func createdAt(path, kid string) (time.Time, error) {
+	created, err := time.Parse(kidLayout, kid)
+	if err == nil {
+		return created, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
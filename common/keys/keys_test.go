@@ -0,0 +1,339 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKey stores signer in dir as kid.pem, the way an operator would.
+func writeKey(t *testing.T, dir, kid string, signer crypto.Signer) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	path := filepath.Join(dir, kid+keyExtension)
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+}
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	return private
+}
+
+func kidAt(at time.Time) string {
+	return at.UTC().Format(kidLayout)
+}
+
+func TestNewKeySet(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "keys")
+
+			set, err := NewKeySet(dir, algorithm)
+			if err != nil {
+				t.Fatalf("NewKeySet: %v", err)
+			}
+
+			key := set.SigningKey()
+			if key == nil {
+				t.Fatal("no signing key was generated")
+			}
+			if key.Algorithm != algorithm {
+				t.Errorf("algorithm = %s, want %s", key.Algorithm, algorithm)
+			}
+			if _, err = time.Parse(kidLayout, key.ID); err != nil {
+				t.Errorf("kid %q is not the creation time: %v", key.ID, err)
+			}
+
+			info, err := os.Stat(filepath.Join(dir, key.ID+keyExtension))
+			if err != nil {
+				t.Fatalf("stat key file: %v", err)
+			}
+			if info.Mode().Perm() != 0o600 {
+				t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+			}
+
+			// Opening the directory again keeps the key instead of adding one.
+			again, err := NewKeySet(dir, algorithm)
+			if err != nil {
+				t.Fatalf("NewKeySet again: %v", err)
+			}
+			if again.SigningKey().ID != key.ID || len(again.JWKS().Keys) != 1 {
+				t.Errorf("reopening the directory changed the keys: %+v", again.JWKS())
+			}
+		})
+	}
+}
+
+func TestNewKeySetUnknownAlgorithm(t *testing.T) {
+	for _, algorithm := range []string{"HS256", "none", ""} {
+		if _, err := NewKeySet(t.TempDir(), algorithm); err == nil {
+			t.Errorf("NewKeySet accepted algorithm %q", algorithm)
+		}
+		if _, err := LoadKeySet(t.TempDir(), algorithm); err == nil {
+			t.Errorf("LoadKeySet accepted algorithm %q", algorithm)
+		}
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+
+	set, err := LoadKeySet(dir, AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("LoadKeySet of a missing directory: %v", err)
+	}
+	if set.SigningKey() != nil {
+		t.Error("LoadKeySet generated a key")
+	}
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("LoadKeySet created the directory: %v", err)
+	}
+
+	if err = os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("create directory: %v", err)
+	}
+	kid := kidAt(time.Now())
+	writeKey(t, dir, kid, newEd25519(t))
+
+	set, err = LoadKeySet(dir, AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	if key := set.SigningKey(); key == nil || key.ID != kid {
+		t.Errorf("signing key = %+v, want %s", key, kid)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("LoadKeySet changed the directory, it holds %d files", len(entries))
+	}
+}
+
+func TestReloadOrdersByKid(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	older, newer := kidAt(now.Add(-2*time.Hour)), kidAt(now.Add(-time.Hour))
+	writeKey(t, dir, newer, newEd25519(t))
+	writeKey(t, dir, older, newEd25519(t))
+
+	// Copying the directory touches every file, the older key now has the
+	// newer modification time. The kid still decides.
+	if err := os.Chtimes(filepath.Join(dir, older+keyExtension), now, now); err != nil {
+		t.Fatalf("touch key: %v", err)
+	}
+
+	set, err := LoadKeySet(dir, AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	if key := set.SigningKey(); key.ID != newer {
+		t.Errorf("signing key = %s, want the newest kid %s", key.ID, newer)
+	}
+
+	// A key an operator named by hand is dated by its modification time.
+	writeKey(t, dir, "operator", newEd25519(t))
+	if err = set.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if key := set.SigningKey(); key.ID != "operator" {
+		t.Errorf("signing key = %s, want the freshly written operator key", key.ID)
+	}
+}
+
+func TestReloadSkipsUnreadableKeys(t *testing.T) {
+	dir := t.TempDir()
+	kid := kidAt(time.Now())
+	writeKey(t, dir, kid, newEd25519(t))
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	files := map[string][]byte{
+		"garbage.pem":    []byte("not a key"),
+		"public.pem":     pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{1, 2, 3}}),
+		"truncated.pem":  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}}),
+		"legacy-rsa.pem": pkcs1,
+		"notes.txt":      []byte("not a key file"),
+		kid + ".pem.tmp": []byte("half written"),
+	}
+	for name, content := range files {
+		if err = os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	set, err := LoadKeySet(dir, AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	if _, err = set.Find(kid); err != nil {
+		t.Errorf("valid key next to broken ones: %v", err)
+	}
+
+	legacy, err := set.Find("legacy-rsa")
+	if err != nil {
+		t.Fatalf("PKCS#1 RSA key: %v", err)
+	}
+	if legacy.Algorithm != AlgorithmRS256 {
+		t.Errorf("PKCS#1 key algorithm = %s, want %s", legacy.Algorithm, AlgorithmRS256)
+	}
+
+	for _, broken := range []string{"garbage", "public", "truncated"} {
+		if _, err = set.Find(broken); err != ErrKeyNotFound {
+			t.Errorf("Find(%s) = %v, want %v", broken, err, ErrKeyNotFound)
+		}
+	}
+	if got := len(set.JWKS().Keys); got != 2 {
+		t.Errorf("JWKS holds %d keys, want 2", got)
+	}
+}
+
+func TestRotateKeepsOldKeysForVerification(t *testing.T) {
+	dir := t.TempDir()
+	old := kidAt(time.Now().Add(-time.Hour))
+	writeKey(t, dir, old, newEd25519(t))
+
+	set, err := NewKeySet(dir, AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	key, err := set.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if set.SigningKey().ID != key.ID || key.ID == old {
+		t.Errorf("signing key = %s, want the rotated key %s", set.SigningKey().ID, key.ID)
+	}
+	if _, err = set.Find(old); err != nil {
+		t.Errorf("old key is gone after Rotate: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	oldest := kidAt(now.Add(-5 * time.Hour))
+	retired := kidAt(now.Add(-3 * time.Hour))
+	previous := kidAt(now.Add(-2 * time.Hour))
+	current := kidAt(now.Add(-30 * time.Minute))
+	for _, kid := range []string{oldest, retired, previous, current} {
+		writeKey(t, dir, kid, newEd25519(t))
+	}
+
+	set, err := LoadKeySet(dir, AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	// A key is only removed once its successor has signed for longer than
+	// retain, tokens of the key before current may still be alive.
+	if err = set.Prune(time.Hour); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	for kid, want := range map[string]bool{oldest: false, retired: false, previous: true, current: true} {
+		_, err = set.Find(kid)
+		if got := err == nil; got != want {
+			t.Errorf("key %s kept = %v, want %v", kid, got, want)
+		}
+
+		_, err = os.Stat(filepath.Join(dir, kid+keyExtension))
+		if got := err == nil; got != want {
+			t.Errorf("file of key %s kept = %v, want %v", kid, got, want)
+		}
+	}
+
+	// The signing key survives however old it is.
+	if err = set.Prune(time.Nanosecond); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if key := set.SigningKey(); key == nil || key.ID != current {
+		t.Errorf("signing key after pruning everything = %+v, want %s", key, current)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+
+	edKey := newEd25519(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	writeKey(t, dir, "ed", edKey)
+	writeKey(t, dir, "rsa", rsaKey)
+
+	set, err := LoadKeySet(dir, AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	jwks := set.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS holds %d keys, want 2", len(jwks.Keys))
+	}
+
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "sig" {
+			t.Errorf("key %s use = %q, want sig", jwk.Kid, jwk.Use)
+		}
+
+		switch jwk.Kid {
+		case "ed":
+			if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Alg != AlgorithmEdDSA {
+				t.Errorf("ed25519 jwk = %+v", jwk)
+			}
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if err != nil || !edKey.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+				t.Errorf("x does not hold the public key: %v", err)
+			}
+		case "rsa":
+			if jwk.Kty != "RSA" || jwk.Alg != AlgorithmRS256 {
+				t.Errorf("rsa jwk = %+v", jwk)
+			}
+			n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+			if err != nil {
+				t.Fatalf("decode n: %v", err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+			if err != nil {
+				t.Fatalf("decode e: %v", err)
+			}
+			public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			if !rsaKey.PublicKey.Equal(public) {
+				t.Error("n and e do not hold the public key")
+			}
+		default:
+			t.Errorf("unexpected kid %s", jwk.Kid)
+		}
+	}
+}
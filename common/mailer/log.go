@@ -0,0 +1,19 @@
+package mailer
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+// LogMailer writes messages to the log instead of sending them. It is meant
+// for development, the log then holds whatever secret the message carries.
+type LogMailer struct{}
+
+func NewLogMailer() IMailer {
+	return &LogMailer{}
+}
+
+func (l *LogMailer) Send(_ context.Context, message Message) error {
+	logrus.Infof("Mail to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"backend/config"
+	"context"
+	"fmt"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type IMailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+func NewMailer(cfg config.Mail) (IMailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return NewLogMailer(), nil
+	case "smtp":
+		return NewSMTPMailer(cfg)
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", cfg.Driver)
+	}
+}
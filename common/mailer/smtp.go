@@ -0,0 +1,160 @@
+package mailer
+
+import (
+	"backend/config"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dialTimeout = 10 * time.Second
+
+// SMTPMailer delivers through an SMTP server. Without UseTLS the connection
+// is upgraded with STARTTLS when the server offers it, so a local catcher
+// that speaks plain SMTP works as well. Credentials are only sent when a
+// username is configured.
+type SMTPMailer struct {
+	cfg  config.Mail
+	from *mail.Address
+}
+
+func NewSMTPMailer(cfg config.Mail) (IMailer, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("mail host is required")
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mail sender %q: %w", cfg.From, err)
+	}
+
+	return &SMTPMailer{cfg: cfg, from: from}, nil
+}
+
+func (s *SMTPMailer) Send(ctx context.Context, message Message) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("invalid mail recipient %q: %w", message.To, err)
+	}
+
+	content, err := s.compose(to, message)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.cfg.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(s.from.Address); err != nil {
+		return err
+	}
+
+	if err = client.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(content); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (s *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	address := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+	if s.cfg.UseTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if !s.cfg.UseTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(tlsConfig); err != nil {
+				_ = client.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return client, nil
+}
+
+// compose builds the message with its headers, the body is sent quoted
+// printable so any text survives servers that are not 8 bit clean.
+func (s *SMTPMailer) compose(to *mail.Address, message Message) ([]byte, error) {
+	if strings.ContainsAny(message.Subject, "\r\n") {
+		return nil, errors.New("mail subject must be a single line")
+	}
+
+	var buffer bytes.Buffer
+	headers := [][2]string{
+		{"From", s.from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, header := range headers {
+		buffer.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	buffer.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&buffer)
+	if _, err := writer.Write([]byte(strings.ReplaceAll(message.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,333 @@
+package mailer
+
+import (
+	"backend/config"
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// caughtMail is one message received by smtpCatcher.
+type caughtMail struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpCatcher is a plain SMTP server on localhost that keeps every message
+// instead of delivering it, like MailHog or Mailpit. rejectRcpt makes it
+// refuse every recipient.
+type smtpCatcher struct {
+	listener net.Listener
+
+	mu         sync.Mutex
+	rejectRcpt bool
+	caught     []caughtMail
+}
+
+func newSMTPCatcher(t *testing.T) *smtpCatcher {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	catcher := &smtpCatcher{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go catcher.serve(conn)
+		}
+	}()
+
+	return catcher
+}
+
+func (c *smtpCatcher) config() config.Mail {
+	host, port, _ := net.SplitHostPort(c.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return config.Mail{
+		Driver: "smtp",
+		Host:   host,
+		Port:   portNumber,
+		From:   "Backend POS <noreply@example.com>",
+	}
+}
+
+func (c *smtpCatcher) messages() []caughtMail {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]caughtMail(nil), c.caught...)
+}
+
+func (c *smtpCatcher) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = io.WriteString(conn, line+"\r\n")
+	}
+
+	var current caughtMail
+	reply("220 catcher ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO":
+			reply("250-catcher")
+			reply("250 AUTH PLAIN")
+		case "HELO":
+			reply("250 catcher")
+		case "AUTH":
+			parts := strings.Fields(line)
+			if len(parts) == 3 {
+				decoded, _ := base64.StdEncoding.DecodeString(parts[2])
+				current.auth = string(decoded)
+			}
+			reply("235 authenticated")
+		case "MAIL":
+			current.from = strings.Trim(strings.TrimPrefix(line[5:], "FROM:"), "<>")
+			reply("250 ok")
+		case "RCPT":
+			c.mu.Lock()
+			reject := c.rejectRcpt
+			c.mu.Unlock()
+			if reject {
+				reply("550 no such user")
+				continue
+			}
+			current.to = append(current.to, strings.Trim(strings.TrimPrefix(line[5:], "TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			current.data = data.String()
+
+			c.mu.Lock()
+			c.caught = append(c.caught, current)
+			c.mu.Unlock()
+			current = caughtMail{auth: current.auth}
+			reply("250 queued")
+		case "RSET", "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	catcher := newSMTPCatcher(t)
+
+	sender, err := NewMailer(catcher.config())
+	if err != nil {
+		t.Fatalf("NewMailer: %v", err)
+	}
+
+	body := "Hi Budi,\n\nOpen https://app.example.com/reset-password?token=abc&x=1 to pick a new password.\nSelamat berbelanja – terima kasih!"
+	err = sender.Send(context.Background(), Message{
+		To:      "Budi <budi@example.com>",
+		Subject: "Reset your backend-pos password – now",
+		Body:    body,
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	caught := catcher.messages()
+	if len(caught) != 1 {
+		t.Fatalf("caught %d messages, want 1", len(caught))
+	}
+
+	got := caught[0]
+	if got.from != "noreply@example.com" {
+		t.Errorf("envelope sender = %q, want noreply@example.com", got.from)
+	}
+	if len(got.to) != 1 || got.to[0] != "budi@example.com" {
+		t.Errorf("envelope recipients = %q, want [budi@example.com]", got.to)
+	}
+	if got.auth != "" {
+		t.Errorf("authenticated without a username: %q", got.auth)
+	}
+
+	message, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+
+	headers := map[string]string{
+		"From":                      `"Backend POS" <noreply@example.com>`,
+		"To":                        `"Budi" <budi@example.com>`,
+		"Content-Type":              "text/plain; charset=utf-8",
+		"Content-Transfer-Encoding": "quoted-printable",
+		"Mime-Version":              "1.0",
+	}
+	for name, want := range headers {
+		if value := message.Header.Get(name); value != want {
+			t.Errorf("header %s = %q, want %q", name, value, want)
+		}
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decode subject: %v", err)
+	}
+	if subject != "Reset your backend-pos password – now" {
+		t.Errorf("subject = %q", subject)
+	}
+
+	if _, err = message.Header.Date(); err != nil {
+		t.Errorf("date header: %v", err)
+	}
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(message.Body))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	// The SMTP data writer ends the message with a line break.
+	if want := strings.ReplaceAll(body, "\n", "\r\n") + "\r\n"; string(decoded) != want {
+		t.Errorf("body = %q, want %q", decoded, want)
+	}
+}
+
+func TestSMTPMailerAuthenticates(t *testing.T) {
+	catcher := newSMTPCatcher(t)
+	cfg := catcher.config()
+	cfg.Username = "mailer"
+	cfg.Password = "secret"
+
+	sender, err := NewSMTPMailer(cfg)
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+
+	err = sender.Send(context.Background(), Message{To: "budi@example.com", Subject: "Hi", Body: "Hello"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	caught := catcher.messages()
+	if len(caught) != 1 {
+		t.Fatalf("caught %d messages, want 1", len(caught))
+	}
+	if caught[0].auth != "\x00mailer\x00secret" {
+		t.Errorf("auth = %q, want PLAIN credentials of mailer", caught[0].auth)
+	}
+}
+
+func TestSMTPMailerErrors(t *testing.T) {
+	catcher := newSMTPCatcher(t)
+
+	tests := []struct {
+		name       string
+		message    Message
+		rejectRcpt bool
+	}{
+		{name: "invalid recipient", message: Message{To: "not an address", Subject: "Hi", Body: "Hello"}},
+		{name: "header injection in subject", message: Message{To: "budi@example.com", Subject: "Hi\r\nBcc: eve@example.com", Body: "Hello"}},
+		{name: "recipient refused", message: Message{To: "budi@example.com", Subject: "Hi", Body: "Hello"}, rejectRcpt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catcher.mu.Lock()
+			catcher.rejectRcpt = tt.rejectRcpt
+			catcher.mu.Unlock()
+			sender, err := NewSMTPMailer(catcher.config())
+			if err != nil {
+				t.Fatalf("NewSMTPMailer: %v", err)
+			}
+
+			err = sender.Send(context.Background(), tt.message)
+			if err == nil {
+				t.Error("Send succeeded")
+			}
+		})
+	}
+
+	if caught := catcher.messages(); len(caught) != 0 {
+		t.Errorf("caught %d messages, want none", len(caught))
+	}
+}
+
+func TestSMTPMailerUnreachable(t *testing.T) {
+	catcher := newSMTPCatcher(t)
+	cfg := catcher.config()
+	_ = catcher.listener.Close()
+
+	sender, err := NewSMTPMailer(cfg)
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err = sender.Send(ctx, Message{To: "budi@example.com", Subject: "Hi", Body: "Hello"})
+	if err == nil {
+		t.Error("Send to a closed port succeeded")
+	}
+}
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Mail
+		wantErr bool
+	}{
+		{name: "default is log", cfg: config.Mail{}},
+		{name: "log", cfg: config.Mail{Driver: "log"}},
+		{name: "smtp", cfg: config.Mail{Driver: "smtp", Host: "localhost", From: "noreply@example.com"}},
+		{name: "smtp without host", cfg: config.Mail{Driver: "smtp", From: "noreply@example.com"}, wantErr: true},
+		{name: "smtp with invalid sender", cfg: config.Mail{Driver: "smtp", Host: "localhost", From: "nobody"}, wantErr: true},
+		{name: "unknown driver", cfg: config.Mail{Driver: "pigeon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMailer(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewMailer error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
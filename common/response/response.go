@@ -12,6 +12,7 @@ type Response struct {
 	Message any         `json:"message"`
 	Data    interface{} `json:"data"`
 	Token   *string     `json:"token,omitempty"`
+	Refresh *string     `json:"refresh_token,omitempty"`
 }
 
 type ParamHTTPResp struct {
@@ -21,6 +22,7 @@ type ParamHTTPResp struct {
 	Fiber   *fiber.Ctx
 	Data    interface{}
 	Token   *string
+	Refresh *string
 }
 
 func HttpResponse(param ParamHTTPResp) error {
@@ -30,6 +32,7 @@ func HttpResponse(param ParamHTTPResp) error {
 			Message: http.StatusText(http.StatusOK),
 			Data:    param.Data,
 			Token:   param.Token,
+			Refresh: param.Refresh,
 		})
 	}
 
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type LocalStorage struct {
+	basePath string
+	baseURL  string
+}
+
+func NewLocalStorage(basePath, baseURL string) (IStorage, error) {
+	err := os.MkdirAll(basePath, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{
+		basePath: basePath,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+func (l *LocalStorage) path(key string) string {
+	return filepath.Join(l.basePath, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+func (l *LocalStorage) Put(_ context.Context, key string, reader io.Reader, _ int64, _ string) error {
+	target := l.path(key)
+	err := os.MkdirAll(filepath.Dir(target), 0o755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, reader)
+	return err
+}
+
+func (l *LocalStorage) Delete(_ context.Context, key string) error {
+	err := os.Remove(l.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *LocalStorage) URL(key string) string {
+	return l.baseURL + "/" + strings.TrimPrefix(key, "/")
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"backend/config"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoragePutAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStorage(config.Storage{Driver: "local", LocalPath: dir, BaseURL: "http://localhost:8085/uploads/"})
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+
+	ctx := context.Background()
+	err = store.Put(ctx, "products/p1/image.png", strings.NewReader("png"), 3, "image/png")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "products", "p1", "image.png"))
+	if err != nil {
+		t.Fatalf("read stored file: %v", err)
+	}
+	if string(content) != "png" {
+		t.Errorf("stored content = %q, want png", content)
+	}
+
+	if got, want := store.URL("products/p1/image.png"), "http://localhost:8085/uploads/products/p1/image.png"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	err = store.Delete(ctx, "products/p1/image.png")
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "products", "p1", "image.png")); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+
+	err = store.Delete(ctx, "products/p1/image.png")
+	if err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestLocalStorageStaysInsideBasePath(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "uploads")
+	store, err := NewLocalStorage(base, "")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	err = store.Put(context.Background(), "../../escape.txt", strings.NewReader("x"), 1, "text/plain")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(base, "escape.txt")); err != nil {
+		t.Errorf("file was not written inside the base path: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("file escaped the base path: %v", err)
+	}
+}
+
+func TestNewStorageUnknownDriver(t *testing.T) {
+	_, err := NewStorage(config.Storage{Driver: "ftp"})
+	if err == nil {
+		t.Fatal("NewStorage accepted an unknown driver")
+	}
+}
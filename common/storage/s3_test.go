@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"backend/config"
+	"bytes"
+	"context"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/minio/minio-go/v7"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testBucket = "products"
+
+// newTestS3 starts an in-memory S3 server standing in for MinIO and returns
+// the storage under test together with the config it was built from.
+func newTestS3(t *testing.T) (*S3Storage, config.Storage) {
+	t.Helper()
+
+	server := httptest.NewServer(gofakes3.New(s3mem.New()).Server())
+	t.Cleanup(server.Close)
+
+	cfg := config.Storage{
+		Driver:    "s3",
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		AccessKey: "minio",
+		SecretKey: "minio123",
+		Bucket:    testBucket,
+		Region:    "us-east-1",
+	}
+
+	store, err := NewStorage(cfg)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+
+	s3, ok := store.(*S3Storage)
+	if !ok {
+		t.Fatalf("NewStorage returned %T, want *S3Storage", store)
+	}
+
+	err = s3.client.MakeBucket(context.Background(), testBucket, minio.MakeBucketOptions{Region: cfg.Region})
+	if err != nil {
+		t.Fatalf("MakeBucket: %v", err)
+	}
+
+	return s3, cfg
+}
+
+func TestS3StoragePut(t *testing.T) {
+	s3, _ := newTestS3(t)
+	ctx := context.Background()
+	content := []byte("\x89PNG\r\n\x1a\nnot really a png")
+
+	err := s3.Put(ctx, "products/p1/image.png", bytes.NewReader(content), int64(len(content)), "image/png")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	object, err := s3.client.GetObject(ctx, testBucket, "products/p1/image.png", minio.GetObjectOptions{})
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	defer object.Close()
+
+	got, err := io.ReadAll(object)
+	if err != nil {
+		t.Fatalf("read object: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("stored content = %q, want %q", got, content)
+	}
+
+	info, err := object.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.ContentType != "image/png" {
+		t.Errorf("content type = %q, want image/png", info.ContentType)
+	}
+}
+
+func TestS3StoragePutMissingBucket(t *testing.T) {
+	s3, _ := newTestS3(t)
+	s3.bucket = "missing"
+
+	err := s3.Put(context.Background(), "image.png", strings.NewReader("x"), 1, "image/png")
+	if err == nil {
+		t.Fatal("Put into a missing bucket succeeded")
+	}
+}
+
+func TestS3StorageDelete(t *testing.T) {
+	s3, _ := newTestS3(t)
+	ctx := context.Background()
+
+	err := s3.Put(ctx, "products/p1/image.jpg", strings.NewReader("jpeg"), 4, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	err = s3.Delete(ctx, "products/p1/image.jpg")
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err = s3.client.StatObject(ctx, testBucket, "products/p1/image.jpg", minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		t.Errorf("StatObject after Delete = %v, want NoSuchKey", err)
+	}
+
+	// Removing a key that is already gone is not an error, like on S3.
+	err = s3.Delete(ctx, "products/p1/image.jpg")
+	if err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestS3StorageURL(t *testing.T) {
+	_, cfg := newTestS3(t)
+
+	tests := []struct {
+		name    string
+		baseURL string
+		useSSL  bool
+		key     string
+		want    string
+	}{
+		{
+			name: "endpoint and bucket",
+			key:  "products/p1/image.png",
+			want: "http://" + cfg.Endpoint + "/products/products/p1/image.png",
+		},
+		{
+			name:   "https endpoint",
+			useSSL: true,
+			key:    "/products/p1/image.png",
+			want:   "https://" + cfg.Endpoint + "/products/products/p1/image.png",
+		},
+		{
+			name:    "public base url",
+			baseURL: "https://cdn.example.com/",
+			key:     "products/p1/image.png",
+			want:    "https://cdn.example.com/products/p1/image.png",
+		},
+		{
+			name:    "relative base url is ignored",
+			baseURL: "/uploads",
+			key:     "products/p1/image.png",
+			want:    "http://" + cfg.Endpoint + "/products/products/p1/image.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.BaseURL = tt.baseURL
+			cfg.UseSSL = tt.useSSL
+
+			store, err := NewS3Storage(cfg)
+			if err != nil {
+				t.Fatalf("NewS3Storage: %v", err)
+			}
+
+			if got := store.URL(tt.key); got != tt.want {
+				t.Errorf("URL(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
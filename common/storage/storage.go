@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"backend/config"
+	"context"
+	"fmt"
+	"io"
+)
+
+type IStorage interface {
+	Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+func NewStorage(cfg config.Storage) (IStorage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStorage(cfg.LocalPath, cfg.BaseURL)
+	case "s3":
+		return NewS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Driver)
+	}
+}
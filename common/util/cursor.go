@@ -0,0 +1,330 @@
+package util
+
+import (
+	errConstant "backend/constants/error"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CursorParam struct {
+	Cursor    string `form:"cursor"`
+	Limit     int    `form:"limit" validate:"required,min=1,max=100"`
+	WithTotal bool   `form:"withTotal"`
+}
+
+type CursorPage struct {
+	NextCursor     *string
+	PreviousCursor *string
+	TotalData      *int64
+}
+
+type CursorResult struct {
+	NextCursor     *string     `json:"nextCursor"`
+	PreviousCursor *string     `json:"previousCursor"`
+	Limit          int         `json:"limit"`
+	TotalData      *int64      `json:"totalData,omitempty"`
+	Data           interface{} `json:"data"`
+}
+
+const (
+	cursorNext     = "next"
+	cursorPrevious = "prev"
+)
+
+// cursor is encoded as base64 JSON. It is opaque to clients but carries the
+// sort it was created for, so a cursor can not be replayed with another sort.
+type cursor struct {
+	Sort      string        `json:"s"`
+	Direction string        `json:"d"`
+	Values    []cursorValue `json:"v"`
+}
+
+type cursorValue struct {
+	Kind  string `json:"k"`
+	Value string `json:"v"`
+}
+
+func GenerateCursorResult(page *CursorPage, limit int, data interface{}) CursorResult {
+	return CursorResult{
+		NextCursor:     page.NextCursor,
+		PreviousCursor: page.PreviousCursor,
+		Limit:          limit,
+		TotalData:      page.TotalData,
+		Data:           data,
+	}
+}
+
+// CursorPaginate runs a keyset paginated query for any model. db should
+// already carry the filters of the list, the sort is whitelisted through the
+// spec and always ends with the tiebreaker column so every row has a unique
+// position. Sort columns are expected to be NOT NULL.
+func CursorPaginate[T any](db *gorm.DB, spec *ListSpec, sortExpression string, param CursorParam) ([]T, *CursorPage, error) {
+	fields, err := spec.ParseSort(sortExpression)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if spec.Tiebreaker != "" && !hasSortColumn(fields, spec.Tiebreaker) {
+		fields = append(fields, SortField{Field: spec.Tiebreaker, Column: spec.Tiebreaker})
+	}
+
+	sortKey := sortFieldsKey(fields)
+	page := &CursorPage{}
+
+	if param.WithTotal {
+		var total int64
+		err = db.Session(&gorm.Session{}).Model(new(T)).Count(&total).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		page.TotalData = &total
+	}
+
+	query := db.Session(&gorm.Session{})
+	direction := ""
+	if param.Cursor != "" {
+		decoded, err := decodeCursor(param.Cursor)
+		if err != nil || decoded.Sort != sortKey || len(decoded.Values) != len(fields) {
+			return nil, nil, errConstant.ErrInvalidCursor
+		}
+
+		values := make([]interface{}, len(decoded.Values))
+		for i, value := range decoded.Values {
+			values[i], err = value.decode()
+			if err != nil {
+				return nil, nil, errConstant.ErrInvalidCursor
+			}
+		}
+
+		direction = decoded.Direction
+		where, args := keysetCondition(fields, values, direction == cursorPrevious)
+		query = query.Where(where, args...)
+	}
+
+	orderFields := fields
+	if direction == cursorPrevious {
+		orderFields = make([]SortField, len(fields))
+		for i, field := range fields {
+			field.Desc = !field.Desc
+			orderFields[i] = field
+		}
+	}
+
+	var items []T
+	err = query.
+		Order(spec.Order(orderFields)).
+		Limit(param.Limit + 1).
+		Find(&items).
+		Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasMore := len(items) > param.Limit
+	if hasMore {
+		items = items[:param.Limit]
+	}
+
+	if direction == cursorPrevious {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if len(items) == 0 {
+		return items, page, nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err = stmt.Parse(new(T)); err != nil {
+		return nil, nil, err
+	}
+
+	if (direction != cursorPrevious && hasMore) || direction == cursorPrevious {
+		next, err := encodeCursor(stmt, fields, sortKey, cursorNext, &items[len(items)-1])
+		if err != nil {
+			return nil, nil, err
+		}
+		page.NextCursor = &next
+	}
+
+	if (direction == cursorNext) || (direction == cursorPrevious && hasMore) {
+		previous, err := encodeCursor(stmt, fields, sortKey, cursorPrevious, &items[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		page.PreviousCursor = &previous
+	}
+
+	return items, page, nil
+}
+
+// keysetCondition builds (a > ?) OR (a = ? AND b > ?) ... following the
+// direction of every sort column, reversed when paging backwards.
+func keysetCondition(fields []SortField, values []interface{}, backward bool) (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+
+	for i, field := range fields {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = ?", fields[j].Column))
+			args = append(args, values[j])
+		}
+
+		operator := ">"
+		if field.Desc != backward {
+			operator = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", field.Column, operator))
+		args = append(args, values[i])
+
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+func encodeCursor(stmt *gorm.Statement, fields []SortField, sortKey, direction string, item interface{}) (string, error) {
+	value := reflect.ValueOf(item).Elem()
+	payload := cursor{Sort: sortKey, Direction: direction}
+
+	for _, field := range fields {
+		schemaField := stmt.Schema.LookUpField(field.Column)
+		if schemaField == nil {
+			return "", fmt.Errorf("cursor column %s is not found in %s", field.Column, stmt.Schema.Name)
+		}
+
+		fieldValue, _ := schemaField.ValueOf(stmt.Context, value)
+		payload.Values = append(payload.Values, newCursorValue(fieldValue))
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(encoded string) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded cursor
+	err = json.Unmarshal(raw, &decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded.Direction != cursorNext && decoded.Direction != cursorPrevious {
+		return nil, errConstant.ErrInvalidCursor
+	}
+
+	return &decoded, nil
+}
+
+func newCursorValue(value interface{}) cursorValue {
+	reflectValue := reflect.ValueOf(value)
+	for reflectValue.Kind() == reflect.Ptr {
+		if reflectValue.IsNil() {
+			return cursorValue{Kind: "null"}
+		}
+		reflectValue = reflectValue.Elem()
+	}
+
+	if t, ok := reflectValue.Interface().(time.Time); ok {
+		return cursorValue{Kind: "time", Value: t.Format(time.RFC3339Nano)}
+	}
+
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{Kind: "int", Value: strconv.FormatInt(reflectValue.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{Kind: "uint", Value: strconv.FormatUint(reflectValue.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Kind: "float", Value: strconv.FormatFloat(reflectValue.Float(), 'g', -1, 64)}
+	case reflect.Bool:
+		return cursorValue{Kind: "bool", Value: strconv.FormatBool(reflectValue.Bool())}
+	default:
+		return cursorValue{Kind: "string", Value: fmt.Sprint(reflectValue.Interface())}
+	}
+}
+
+func (c cursorValue) decode() (interface{}, error) {
+	switch c.Kind {
+	case "null":
+		return nil, nil
+	case "time":
+		return time.Parse(time.RFC3339Nano, c.Value)
+	case "int":
+		return strconv.ParseInt(c.Value, 10, 64)
+	case "uint":
+		return strconv.ParseUint(c.Value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(c.Value, 64)
+	case "bool":
+		return strconv.ParseBool(c.Value)
+	case "string":
+		return c.Value, nil
+	default:
+		return nil, errConstant.ErrInvalidCursor
+	}
+}
+
+func hasSortColumn(fields []SortField, column string) bool {
+	for _, field := range fields {
+		if field.Column == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortFieldsKey(fields []SortField) string {
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field.Desc {
+			parts = append(parts, "-"+field.Column)
+			continue
+		}
+		parts = append(parts, field.Column)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// CursorLinkHeader builds an RFC 8288 Link header value pointing at the next
+// and previous pages, keeping every other query parameter of the request.
+func CursorLinkHeader(requestURL *url.URL, next, previous *string) string {
+	links := make([]string, 0, 2)
+
+	build := func(cursorValue string, rel string) {
+		link := *requestURL
+		query := link.Query()
+		query.Set("cursor", cursorValue)
+		link.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link.String(), rel))
+	}
+
+	if next != nil {
+		build(*next, "next")
+	}
+	if previous != nil {
+		build(*previous, "prev")
+	}
+
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,380 @@
+package util
+
+import (
+	errConstant "backend/constants/error"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	price := SortField{Field: "price", Column: "price_sale", Desc: true}
+	name := SortField{Field: "name", Column: "name"}
+	id := SortField{Field: "id", Column: "id"}
+
+	tests := []struct {
+		name      string
+		fields    []SortField
+		values    []interface{}
+		backward  bool
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "one ascending column",
+			fields:    []SortField{id},
+			values:    []interface{}{7},
+			wantWhere: "((id > ?))",
+			wantArgs:  []interface{}{7},
+		},
+		{
+			name:      "descending column then tiebreaker",
+			fields:    []SortField{price, id},
+			values:    []interface{}{15000, 7},
+			wantWhere: "((price_sale < ?) OR (price_sale = ? AND id > ?))",
+			wantArgs:  []interface{}{15000, 15000, 7},
+		},
+		{
+			name:      "backward flips every direction",
+			fields:    []SortField{price, id},
+			values:    []interface{}{15000, 7},
+			backward:  true,
+			wantWhere: "((price_sale > ?) OR (price_sale = ? AND id < ?))",
+			wantArgs:  []interface{}{15000, 15000, 7},
+		},
+		{
+			name:      "three columns",
+			fields:    []SortField{name, price, id},
+			values:    []interface{}{"Kopi", 15000, 7},
+			wantWhere: "((name > ?) OR (name = ? AND price_sale < ?) OR (name = ? AND price_sale = ? AND id > ?))",
+			wantArgs:  []interface{}{"Kopi", "Kopi", 15000, "Kopi", 15000, 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := keysetCondition(tt.fields, tt.values, tt.backward)
+			if where != tt.wantWhere {
+				t.Errorf("keysetCondition = %s, want %s", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("keysetCondition args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCursorValue(t *testing.T) {
+	createdAt := time.Date(2026, 10, 19, 9, 30, 0, 123456789, time.UTC)
+	var nilTime *time.Time
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "int", value: -42, want: int64(-42)},
+		{name: "uint", value: uint(42), want: uint64(42)},
+		{name: "float", value: 12.5, want: 12.5},
+		{name: "bool", value: true, want: true},
+		{name: "string", value: "Kopi, \"Susu\"", want: "Kopi, \"Susu\""},
+		{name: "time keeps nanoseconds", value: createdAt, want: createdAt},
+		{name: "pointer to time", value: &createdAt, want: createdAt},
+		{name: "nil pointer", value: nilTime, want: nil},
+		{name: "uuid", value: uuid.MustParse("6f1d6c0e-3c55-4a8b-9a3f-0d5a5f1c2b3d"), want: "6f1d6c0e-3c55-4a8b-9a3f-0d5a5f1c2b3d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newCursorValue(tt.value).decode()
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (cursorValue{Kind: "blob", Value: "x"}).decode(); !errors.Is(err, errConstant.ErrInvalidCursor) {
+		t.Errorf("decode of an unknown kind = %v, want %v", err, errConstant.ErrInvalidCursor)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    *cursor
+		wantErr bool
+	}{
+		{
+			name:   "next",
+			cursor: encode(`{"s":"-price_sale,id","d":"next","v":[{"k":"int","v":"15000"},{"k":"uint","v":"7"}]}`),
+			want: &cursor{Sort: "-price_sale,id", Direction: cursorNext, Values: []cursorValue{
+				{Kind: "int", Value: "15000"},
+				{Kind: "uint", Value: "7"},
+			}},
+		},
+		{name: "not base64", cursor: "not a cursor!", wantErr: true},
+		{name: "not json", cursor: encode("id:7"), wantErr: true},
+		{name: "unknown direction", cursor: encode(`{"s":"id","d":"sideways","v":[]}`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCursor error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCursor = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorLinkHeader(t *testing.T) {
+	requestURL, _ := url.Parse("https://pos.example.com/api/v1/products/cursor?limit=2&sort=-price_sale&cursor=old")
+	next, previous := "bmV4dA", "cHJldg"
+
+	tests := []struct {
+		name     string
+		next     *string
+		previous *string
+		want     string
+	}{
+		{name: "no pages"},
+		{
+			name: "next only",
+			next: &next,
+			want: `<https://pos.example.com/api/v1/products/cursor?cursor=bmV4dA&limit=2&sort=-price_sale>; rel="next"`,
+		},
+		{
+			name:     "both",
+			next:     &next,
+			previous: &previous,
+			want: `<https://pos.example.com/api/v1/products/cursor?cursor=bmV4dA&limit=2&sort=-price_sale>; rel="next", ` +
+				`<https://pos.example.com/api/v1/products/cursor?cursor=cHJldg&limit=2&sort=-price_sale>; rel="prev"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CursorLinkHeader(requestURL, tt.next, tt.previous); got != tt.want {
+				t.Errorf("CursorLinkHeader = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+type cursorItem struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Price     int
+	CreatedAt time.Time
+}
+
+var cursorItemSpec = ListSpec{
+	Sortable: map[string]string{
+		"name":       "name",
+		"price":      "price",
+		"created_at": "created_at",
+	},
+	DefaultSort: "created_at",
+	Tiebreaker:  "id",
+}
+
+func newCursorDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if err = db.AutoMigrate(&cursorItem{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// Prices and times repeat so only the tiebreaker orders some rows.
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	for i := 1; i <= 7; i++ {
+		item := cursorItem{
+			Name:      fmt.Sprintf("item %d", 8-i),
+			Price:     (i % 3) * 1000,
+			CreatedAt: start.Add(time.Duration(i/2) * time.Minute),
+		}
+		if err = db.Create(&item).Error; err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+
+	return db
+}
+
+func TestCursorPaginate(t *testing.T) {
+	db := newCursorDB(t)
+
+	tests := []struct {
+		sort string
+		want []uint
+	}{
+		{sort: "", want: []uint{1, 2, 3, 4, 5, 6, 7}},
+		{sort: "-price", want: []uint{2, 5, 1, 4, 7, 3, 6}},
+		{sort: "price,-created_at", want: []uint{6, 3, 7, 4, 1, 5, 2}},
+		{sort: "name", want: []uint{7, 6, 5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			var (
+				pages  [][]uint
+				cursor string
+			)
+
+			// Forward through every page.
+			for {
+				items, page, err := CursorPaginate[cursorItem](db, &cursorItemSpec, tt.sort, CursorParam{Cursor: cursor, Limit: 3})
+				if err != nil {
+					t.Fatalf("CursorPaginate: %v", err)
+				}
+				if len(pages) == 0 && page.PreviousCursor != nil {
+					t.Error("first page has a previous cursor")
+				}
+
+				pages = append(pages, cursorItemIDs(items))
+				if page.NextCursor == nil {
+					break
+				}
+				if len(pages) > len(tt.want) {
+					t.Fatal("pagination does not end")
+				}
+				cursor = *page.NextCursor
+			}
+
+			var got []uint
+			for _, page := range pages {
+				got = append(got, page...)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("pages %v, want %v in pages of 3", pages, tt.want)
+			}
+
+			// And back again from the last page.
+			_, page, err := CursorPaginate[cursorItem](db, &cursorItemSpec, tt.sort, CursorParam{Cursor: cursor, Limit: 3})
+			if err != nil {
+				t.Fatalf("CursorPaginate: %v", err)
+			}
+			for i := len(pages) - 2; i >= 0; i-- {
+				if page.PreviousCursor == nil {
+					t.Fatalf("page %d has no previous cursor", i+2)
+				}
+
+				var items []cursorItem
+				items, page, err = CursorPaginate[cursorItem](db, &cursorItemSpec, tt.sort, CursorParam{Cursor: *page.PreviousCursor, Limit: 3})
+				if err != nil {
+					t.Fatalf("CursorPaginate: %v", err)
+				}
+				if ids := cursorItemIDs(items); !reflect.DeepEqual(ids, pages[i]) {
+					t.Errorf("page %d going back = %v, want %v", i+1, ids, pages[i])
+				}
+				if page.NextCursor == nil {
+					t.Errorf("page %d going back has no next cursor", i+1)
+				}
+			}
+			if page.PreviousCursor != nil {
+				t.Error("first page going back has a previous cursor")
+			}
+		})
+	}
+}
+
+func TestCursorPaginateWithTotal(t *testing.T) {
+	db := newCursorDB(t)
+
+	items, page, err := CursorPaginate[cursorItem](db.Where("price > ?", 0), &cursorItemSpec, "", CursorParam{Limit: 10, WithTotal: true})
+	if err != nil {
+		t.Fatalf("CursorPaginate: %v", err)
+	}
+	if page.TotalData == nil || *page.TotalData != 5 || len(items) != 5 {
+		t.Errorf("total %v and %d items, want 5 of 5", page.TotalData, len(items))
+	}
+	if page.NextCursor != nil || page.PreviousCursor != nil {
+		t.Errorf("single page has cursors %v and %v", page.NextCursor, page.PreviousCursor)
+	}
+
+	_, page, err = CursorPaginate[cursorItem](db, &cursorItemSpec, "", CursorParam{Limit: 10})
+	if err != nil {
+		t.Fatalf("CursorPaginate: %v", err)
+	}
+	if page.TotalData != nil {
+		t.Errorf("total %d counted without being asked for", *page.TotalData)
+	}
+}
+
+func TestCursorPaginateRejects(t *testing.T) {
+	db := newCursorDB(t)
+
+	_, page, err := CursorPaginate[cursorItem](db, &cursorItemSpec, "-price", CursorParam{Limit: 2})
+	if err != nil {
+		t.Fatalf("CursorPaginate: %v", err)
+	}
+	next := *page.NextCursor
+
+	raw, _ := base64.RawURLEncoding.DecodeString(next)
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(
+		string(raw[:len(raw)-2]) + `,{"k":"int","v":"1"}]}`,
+	))
+
+	tests := []struct {
+		name    string
+		sort    string
+		cursor  string
+		wantErr error
+	}{
+		{name: "cursor of another sort", sort: "price", cursor: next, wantErr: errConstant.ErrInvalidCursor},
+		{name: "garbage", sort: "-price", cursor: "garbage", wantErr: errConstant.ErrInvalidCursor},
+		{name: "extra value", sort: "-price", cursor: tampered, wantErr: errConstant.ErrInvalidCursor},
+		{name: "sort not allowed", sort: "secret", cursor: next},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := CursorPaginate[cursorItem](db, &cursorItemSpec, tt.sort, CursorParam{Cursor: tt.cursor, Limit: 2})
+			if err == nil {
+				t.Fatal("CursorPaginate accepted the cursor")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("CursorPaginate = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func cursorItemIDs(items []cursorItem) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,52 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func ETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ParseIfMatch reads the versions from an If-Match header, a comma
+// separated list of entity tags of which any may match. A wildcard matches
+// any version and is returned as nil. Weak validators are accepted because
+// the version is the only thing compared.
+func ParseIfMatch(header string) ([]uint, bool) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return nil, true
+	}
+
+	var versions []uint
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		version, ok := parseETag(tag)
+		if !ok {
+			return nil, false
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, len(versions) > 0
+}
+
+func parseETag(tag string) (uint, bool) {
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, false
+	}
+
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 0)
+	if err != nil {
+		return 0, false
+	}
+
+	return uint(version), true
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []uint
+		wantOK bool
+	}{
+		{name: "version", header: `"3"`, want: []uint{3}, wantOK: true},
+		{name: "surrounding spaces", header: ` "3" `, want: []uint{3}, wantOK: true},
+		{name: "weak", header: `W/"3"`, want: []uint{3}, wantOK: true},
+		{name: "list", header: `"3", W/"4","5"`, want: []uint{3, 4, 5}, wantOK: true},
+		{name: "list with empty elements", header: `, "3",, "4" ,`, want: []uint{3, 4}, wantOK: true},
+		{name: "wildcard", header: "*", wantOK: true},
+		{name: "empty", header: ""},
+		{name: "empty list", header: " , ,"},
+		{name: "unquoted", header: "3"},
+		{name: "quote only", header: `"`},
+		{name: "empty tag", header: `""`},
+		{name: "not a number", header: `"abc"`},
+		{name: "negative", header: `"-1"`},
+		{name: "lower case weak prefix", header: `w/"3"`},
+		{name: "bad tag in a list", header: `"3", abc`},
+		{name: "wildcard in a list", header: `"3", *`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseIfMatch(tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseIfMatch(%q) ok = %v, want %v", tt.header, ok, tt.wantOK)
+			}
+			if (got == nil) != (tt.want == nil) || !slices.Equal(got, tt.want) {
+				t.Errorf("ParseIfMatch(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETagRoundTrip(t *testing.T) {
+	for _, version := range []uint{0, 1, 42} {
+		got, ok := ParseIfMatch(ETag(version))
+		if !ok || !slices.Equal(got, []uint{version}) {
+			t.Errorf("ParseIfMatch(ETag(%d)) = %v, %v", version, got, ok)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// ErrImageTooManyPixels is returned for images whose header claims more
+// pixels than allowed, before any of them is decoded.
+var ErrImageTooManyPixels = errors.New("image has too many pixels")
+
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// DetectImageType sniffs the content type from the file header instead of
+// trusting the client supplied Content-Type. The returned extension is empty
+// when the content is not an allowed image.
+func DetectImageType(content []byte) (contentType string, extension string) {
+	contentType = http.DetectContentType(content)
+	extension, ok := allowedImageTypes[contentType]
+	if !ok {
+		return contentType, ""
+	}
+
+	return contentType, extension
+}
+
+// GenerateThumbnail scales the image down to fit inside maxWidth x maxHeight
+// keeping the aspect ratio. PNG and GIF sources are encoded as PNG to keep
+// transparency, everything else as JPEG. A small file can claim huge
+// dimensions, so images over maxPixels are refused before they are decoded.
+func GenerateThumbnail(content []byte, maxWidth, maxHeight, maxPixels int) ([]byte, string, error) {
+	header, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if int64(header.Width)*int64(header.Height) > int64(maxPixels) {
+		return nil, "", ErrImageTooManyPixels
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxWidth || height > maxHeight {
+		ratio := min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+		width = max(1, int(float64(width)*ratio))
+		height = max(1, int(float64(height)*ratio))
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buffer bytes.Buffer
+	if format == "png" || format == "gif" {
+		err = png.Encode(&buffer, dst)
+		return buffer.Bytes(), "image/png", err
+	}
+
+	err = jpeg.Encode(&buffer, dst, &jpeg.Options{Quality: 85})
+	return buffer.Bytes(), "image/jpeg", err
+}
@@ -0,0 +1,192 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// webpPixel is a 1x1 lossless WebP, the standard library can not encode one.
+const webpPixel = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeImage(t *testing.T, format string, img image.Image) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buffer, img)
+	case "jpeg":
+		err = jpeg.Encode(&buffer, img, nil)
+	case "gif":
+		err = gif.Encode(&buffer, img, nil)
+	case "webp":
+		var content []byte
+		content, err = base64.StdEncoding.DecodeString(webpPixel)
+		buffer.Write(content)
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestDetectImageType(t *testing.T) {
+	img := testImage(4, 4)
+
+	tests := []struct {
+		name            string
+		content         []byte
+		wantContentType string
+		wantExtension   string
+	}{
+		{"jpeg", encodeImage(t, "jpeg", img), "image/jpeg", ".jpg"},
+		{"png", encodeImage(t, "png", img), "image/png", ".png"},
+		{"gif", encodeImage(t, "gif", img), "image/gif", ".gif"},
+		{"webp", encodeImage(t, "webp", img), "image/webp", ".webp"},
+		{"plain text", []byte("just some text"), "text/plain; charset=utf-8", ""},
+		{"html", []byte("<html><script>alert(1)</script></html>"), "text/html; charset=utf-8", ""},
+		{"svg", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), "text/xml; charset=utf-8", ""},
+		{"pdf", []byte("%PDF-1.7\n"), "application/pdf", ""},
+		{"bmp", []byte("BM\x00\x00\x00\x00\x00\x00\x00\x00"), "image/bmp", ""},
+		{"empty", []byte{}, "text/plain; charset=utf-8", ""},
+		{"script with image extension", []byte("#!/bin/sh\nrm -rf /\n"), "text/plain; charset=utf-8", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, extension := DetectImageType(tt.content)
+			if contentType != tt.wantContentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.wantContentType)
+			}
+			if extension != tt.wantExtension {
+				t.Errorf("extension = %q, want %q", extension, tt.wantExtension)
+			}
+		})
+	}
+}
+
+func TestGenerateThumbnail(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		width      int
+		height     int
+		maxWidth   int
+		maxHeight  int
+		wantType   string
+		wantFormat string
+		wantWidth  int
+		wantHeight int
+	}{
+		{name: "landscape jpeg", format: "jpeg", width: 800, height: 400, maxWidth: 200, maxHeight: 200, wantType: "image/jpeg", wantFormat: "jpeg", wantWidth: 200, wantHeight: 100},
+		{name: "portrait png", format: "png", width: 300, height: 600, maxWidth: 200, maxHeight: 200, wantType: "image/png", wantFormat: "png", wantWidth: 100, wantHeight: 200},
+		{name: "gif becomes png", format: "gif", width: 400, height: 400, maxWidth: 100, maxHeight: 100, wantType: "image/png", wantFormat: "png", wantWidth: 100, wantHeight: 100},
+		{name: "webp becomes jpeg", format: "webp", width: 1, height: 1, maxWidth: 100, maxHeight: 100, wantType: "image/jpeg", wantFormat: "jpeg", wantWidth: 1, wantHeight: 1},
+		{name: "small image is not enlarged", format: "png", width: 50, height: 20, maxWidth: 200, maxHeight: 200, wantType: "image/png", wantFormat: "png", wantWidth: 50, wantHeight: 20},
+		{name: "thin image keeps one pixel", format: "png", width: 1000, height: 2, maxWidth: 100, maxHeight: 100, wantType: "image/png", wantFormat: "png", wantWidth: 100, wantHeight: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := encodeImage(t, tt.format, testImage(tt.width, tt.height))
+
+			thumbnail, contentType, err := GenerateThumbnail(content, tt.maxWidth, tt.maxHeight, 1000*1000)
+			if err != nil {
+				t.Fatalf("GenerateThumbnail: %v", err)
+			}
+			if contentType != tt.wantType {
+				t.Errorf("content type = %q, want %q", contentType, tt.wantType)
+			}
+
+			config, format, err := image.DecodeConfig(bytes.NewReader(thumbnail))
+			if err != nil {
+				t.Fatalf("decode thumbnail: %v", err)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", format, tt.wantFormat)
+			}
+			if config.Width != tt.wantWidth || config.Height != tt.wantHeight {
+				t.Errorf("size = %dx%d, want %dx%d", config.Width, config.Height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestGenerateThumbnailRejectsInvalidImages(t *testing.T) {
+	valid := encodeImage(t, "png", testImage(10, 10))
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"not an image", []byte("just some text")},
+		{"truncated png", valid[:len(valid)/2]},
+		{"png header only", []byte("\x89PNG\r\n\x1a\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := GenerateThumbnail(tt.content, 100, 100, 1000*1000)
+			if err == nil {
+				t.Error("GenerateThumbnail accepted invalid content")
+			}
+		})
+	}
+}
+
+// pngHeader returns the signature and IHDR chunk of a PNG claiming the
+// dimensions, without any pixel data after it.
+func pngHeader(width, height uint32) []byte {
+	data := make([]byte, 13)
+	binary.BigEndian.PutUint32(data[0:], width)
+	binary.BigEndian.PutUint32(data[4:], height)
+	data[8] = 8 // bit depth
+	data[9] = 6 // RGBA
+
+	chunk := append([]byte("IHDR"), data...)
+	content := []byte("\x89PNG\r\n\x1a\n")
+	content = binary.BigEndian.AppendUint32(content, uint32(len(data)))
+	content = append(content, chunk...)
+	return binary.BigEndian.AppendUint32(content, crc32.ChecksumIEEE(chunk))
+}
+
+func TestGenerateThumbnailRejectsTooManyPixels(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		// A few dozen bytes claiming 50000x50000 would need 10 GB decoded.
+		{"dimensions of a decompression bomb", pngHeader(50000, 50000)},
+		{"real image over the limit", encodeImage(t, "png", testImage(1001, 1000))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := GenerateThumbnail(tt.content, 100, 100, 1000*1000)
+			if !errors.Is(err, ErrImageTooManyPixels) {
+				t.Errorf("GenerateThumbnail = %v, want %v", err, ErrImageTooManyPixels)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package util
+
+import (
+	errValidation "backend/common/error"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ListSpec declares which fields of a resource can be sorted and filtered
+// through the query string, and which column each public field maps to.
+// Only columns declared here ever reach the query builder.
+type ListSpec struct {
+	Sortable    map[string]string
+	Filterable  map[string]string
+	DefaultSort string
+	Tiebreaker  string
+}
+
+type SortField struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+type FilterCondition struct {
+	Field  string
+	Column string
+	Value  string
+}
+
+// SortExpression converts the legacy sortColumn and sortOrder parameters to
+// the sort syntax, e.g. ("price_sale", "desc") becomes "-price_sale".
+func SortExpression(sort, sortColumn, sortOrder *string) string {
+	if sort != nil && *sort != "" {
+		return *sort
+	}
+
+	if sortColumn == nil || *sortColumn == "" {
+		return ""
+	}
+
+	if sortOrder != nil && strings.EqualFold(*sortOrder, "desc") {
+		return "-" + *sortColumn
+	}
+
+	return *sortColumn
+}
+
+// ExtractFilters collects filter[field]=value query parameters.
+func ExtractFilters(queries map[string]string) map[string]string {
+	filters := make(map[string]string)
+	for key, value := range queries {
+		if strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]") {
+			filters[key[len("filter["):len(key)-1]] = value
+		}
+	}
+
+	return filters
+}
+
+// ParseSort parses a comma separated list of fields, a leading "-" sorts
+// descending, e.g. "-price_sale,name".
+func (s *ListSpec) ParseSort(expression string) ([]SortField, error) {
+	if strings.TrimSpace(expression) == "" {
+		expression = s.DefaultSort
+	}
+
+	var (
+		fields    []SortField
+		errs      errValidation.ValidationErrors
+		duplicate = make(map[string]bool)
+	)
+
+	for _, part := range strings.Split(expression, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		field := strings.TrimLeft(part, "-+")
+
+		if field == "" {
+			errs = append(errs, errValidation.ValidationResponse{
+				Field:   "sort",
+				Message: "sort contains an empty field",
+			})
+			continue
+		}
+
+		column, ok := s.Sortable[field]
+		if !ok {
+			errs = append(errs, errValidation.ValidationResponse{
+				Field:   "sort",
+				Message: fmt.Sprintf("sort by %s is not allowed, allowed fields: %s", field, allowedFields(s.Sortable)),
+			})
+			continue
+		}
+
+		if duplicate[field] {
+			errs = append(errs, errValidation.ValidationResponse{
+				Field:   "sort",
+				Message: fmt.Sprintf("sort by %s is specified more than once", field),
+			})
+			continue
+		}
+		duplicate[field] = true
+
+		fields = append(fields, SortField{Field: field, Column: column, Desc: desc})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return fields, nil
+}
+
+// Order builds the ORDER BY clause from whitelisted columns only. The
+// tiebreaker keeps the order stable when the sorted values are equal.
+func (s *ListSpec) Order(fields []SortField) string {
+	clauses := make([]string, 0, len(fields)+1)
+	hasTiebreaker := false
+
+	for _, field := range fields {
+		direction := "ASC"
+		if field.Desc {
+			direction = "DESC"
+		}
+		clauses = append(clauses, fmt.Sprintf("%s %s", field.Column, direction))
+
+		if field.Column == s.Tiebreaker {
+			hasTiebreaker = true
+		}
+	}
+
+	if s.Tiebreaker != "" && !hasTiebreaker {
+		clauses = append(clauses, fmt.Sprintf("%s ASC", s.Tiebreaker))
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+// OrderFor parses the expression and falls back to the default sort when it
+// is invalid, for callers that already validated the input.
+func (s *ListSpec) OrderFor(expression string) string {
+	fields, err := s.ParseSort(expression)
+	if err != nil {
+		fields, _ = s.ParseSort(s.DefaultSort)
+	}
+
+	return s.Order(fields)
+}
+
+func (s *ListSpec) ParseFilters(filters map[string]string) ([]FilterCondition, error) {
+	var (
+		conditions []FilterCondition
+		errs       errValidation.ValidationErrors
+	)
+
+	for field, value := range filters {
+		column, ok := s.Filterable[field]
+		if !ok {
+			errs = append(errs, errValidation.ValidationResponse{
+				Field:   fmt.Sprintf("filter[%s]", field),
+				Message: fmt.Sprintf("filter by %s is not allowed, allowed fields: %s", field, allowedFields(s.Filterable)),
+			})
+			continue
+		}
+
+		conditions = append(conditions, FilterCondition{Field: field, Column: column, Value: value})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	sort.Slice(conditions, func(i, j int) bool {
+		return conditions[i].Field < conditions[j].Field
+	})
+
+	return conditions, nil
+}
+
+// Validate checks the sort expression and the filters in one go so every
+// problem is reported in a single response.
+func (s *ListSpec) Validate(expression string, filters map[string]string) error {
+	var errs errValidation.ValidationErrors
+
+	if _, err := s.ParseSort(expression); err != nil {
+		errs = append(errs, err.(errValidation.ValidationErrors)...)
+	}
+
+	if _, err := s.ParseFilters(filters); err != nil {
+		errs = append(errs, err.(errValidation.ValidationErrors)...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func allowedFields(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
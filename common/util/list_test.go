@@ -0,0 +1,235 @@
+package util
+
+import (
+	errValidation "backend/common/error"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testListSpec = ListSpec{
+	Sortable: map[string]string{
+		"name":       "name",
+		"price_sale": "price_sale",
+		"created_at": "created_at",
+		"id":         "id",
+	},
+	Filterable: map[string]string{
+		"code": "code",
+		"unit": "unit_name",
+	},
+	DefaultSort: "-created_at",
+	Tiebreaker:  "id",
+}
+
+func TestSortExpression(t *testing.T) {
+	text := func(value string) *string { return &value }
+
+	tests := []struct {
+		name       string
+		sort       *string
+		sortColumn *string
+		sortOrder  *string
+		want       string
+	}{
+		{name: "nothing"},
+		{name: "sort", sort: text("-name,price_sale"), want: "-name,price_sale"},
+		{name: "sort wins over the legacy parameters", sort: text("name"), sortColumn: text("price_sale"), sortOrder: text("desc"), want: "name"},
+		{name: "empty sort falls back", sort: text(""), sortColumn: text("name"), want: "name"},
+		{name: "legacy ascending", sortColumn: text("name"), sortOrder: text("asc"), want: "name"},
+		{name: "legacy descending", sortColumn: text("name"), sortOrder: text("DESC"), want: "-name"},
+		{name: "legacy without column", sortOrder: text("desc")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SortExpression(tt.sort, tt.sortColumn, tt.sortOrder); got != tt.want {
+				t.Errorf("SortExpression = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractFilters(t *testing.T) {
+	got := ExtractFilters(map[string]string{
+		"filter[code]": "KP-01",
+		"filter[unit]": "",
+		"filter[]":     "empty",
+		"filter[name":  "open",
+		"sort":         "name",
+		"page":         "1",
+	})
+
+	want := map[string]string{"code": "KP-01", "unit": "", "": "empty"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractFilters = %v, want %v", got, want)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       []SortField
+		wantErrors []string
+	}{
+		{
+			name: "default",
+			want: []SortField{{Field: "created_at", Column: "created_at", Desc: true}},
+		},
+		{
+			name:       "several fields",
+			expression: "-price_sale, name",
+			want: []SortField{
+				{Field: "price_sale", Column: "price_sale", Desc: true},
+				{Field: "name", Column: "name"},
+			},
+		},
+		{
+			name:       "explicit ascending",
+			expression: "+name",
+			want:       []SortField{{Field: "name", Column: "name"}},
+		},
+		{
+			name:       "field not whitelisted",
+			expression: "password",
+			wantErrors: []string{"sort by password is not allowed, allowed fields: created_at, id, name, price_sale"},
+		},
+		{
+			name:       "sql in the field",
+			expression: "name;DROP TABLE products",
+			wantErrors: []string{"sort by name;DROP TABLE products is not allowed, allowed fields: created_at, id, name, price_sale"},
+		},
+		{
+			name:       "empty field",
+			expression: "name,,-",
+			wantErrors: []string{"sort contains an empty field", "sort contains an empty field"},
+		},
+		{
+			name:       "same field twice",
+			expression: "name,-name",
+			wantErrors: []string{"sort by name is specified more than once"},
+		},
+		{
+			name:       "every problem reported",
+			expression: "secret,name,name",
+			wantErrors: []string{
+				"sort by secret is not allowed, allowed fields: created_at, id, name, price_sale",
+				"sort by name is specified more than once",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testListSpec.ParseSort(tt.expression)
+			if messages := validationMessages(t, err); !reflect.DeepEqual(messages, tt.wantErrors) {
+				t.Fatalf("ParseSort errors = %q, want %q", messages, tt.wantErrors)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       ListSpec
+		expression string
+		want       string
+	}{
+		{name: "tiebreaker appended", spec: testListSpec, expression: "-price_sale,name", want: "price_sale DESC, name ASC, id ASC"},
+		{name: "tiebreaker already sorted", spec: testListSpec, expression: "-id", want: "id DESC"},
+		{name: "no tiebreaker", spec: ListSpec{Sortable: testListSpec.Sortable}, expression: "name", want: "name ASC"},
+		{name: "invalid falls back to the default", spec: testListSpec, expression: "password", want: "created_at DESC, id ASC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.spec.OrderFor(tt.expression); got != tt.want {
+				t.Errorf("OrderFor = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name       string
+		filters    map[string]string
+		want       []FilterCondition
+		wantErrors []string
+	}{
+		{name: "none"},
+		{
+			name:    "mapped to columns in field order",
+			filters: map[string]string{"unit": "pcs", "code": "KP-01"},
+			want: []FilterCondition{
+				{Field: "code", Column: "code", Value: "KP-01"},
+				{Field: "unit", Column: "unit_name", Value: "pcs"},
+			},
+		},
+		{
+			name:       "field not whitelisted",
+			filters:    map[string]string{"code": "KP-01", "price_buy": "1000"},
+			wantErrors: []string{"filter by price_buy is not allowed, allowed fields: code, unit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testListSpec.ParseFilters(tt.filters)
+			if messages := validationMessages(t, err); !reflect.DeepEqual(messages, tt.wantErrors) {
+				t.Fatalf("ParseFilters errors = %q, want %q", messages, tt.wantErrors)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilters = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	err := testListSpec.Validate("password", map[string]string{"secret": "x"})
+
+	var validationErrors errValidation.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("Validate = %v, want validation errors", err)
+	}
+
+	fields := []string{}
+	for _, item := range validationErrors {
+		fields = append(fields, item.Field)
+	}
+	if !reflect.DeepEqual(fields, []string{"sort", "filter[secret]"}) {
+		t.Errorf("Validate reported %v, want the sort and the filter", fields)
+	}
+
+	if err = testListSpec.Validate("name", map[string]string{"code": "KP-01"}); err != nil {
+		t.Errorf("Validate of a valid list = %v", err)
+	}
+}
+
+// validationMessages returns the messages of err, which must be nil or
+// validation errors.
+func validationMessages(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors errValidation.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("error %v is not a validation error", err)
+	}
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, item := range validationErrors {
+		messages = append(messages, item.Message)
+	}
+
+	return messages
+}
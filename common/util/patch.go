@@ -0,0 +1,116 @@
+package util
+
+import (
+	errValidation "backend/common/error"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MergePatch holds the members of an RFC 7386 merge patch as sent by the
+// client, keyed by JSON field name. It is what tells "set to 0" apart from
+// "not sent" once the patch has been decoded into a struct.
+type MergePatch map[string]json.RawMessage
+
+// Has reports whether the patch sets field, including setting it to null.
+func (m MergePatch) Has(field string) bool {
+	_, ok := m[field]
+	return ok
+}
+
+// IsNull reports whether the patch clears field.
+func (m MergePatch) IsNull(field string) bool {
+	value, ok := m[field]
+	return ok && isJSONNull(value)
+}
+
+// ParseMergePatch decodes a merge patch into dest, a pointer to a struct of
+// pointer fields, and returns the members that were sent. Members dest has no
+// json tag for are rejected, and null is only accepted on fields tagged
+// `patch:"nullable"` because the resources behind it are flat.
+func ParseMergePatch(body []byte, dest interface{}) (MergePatch, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		var syntaxCheck interface{}
+		if err := json.Unmarshal(body, &syntaxCheck); err != nil {
+			return nil, err
+		}
+
+		return nil, errValidation.ValidationErrors{{
+			Field:   "json",
+			Message: "merge patch must be a JSON object",
+		}}
+	}
+
+	patch := MergePatch{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return nil, err
+	}
+
+	nullable := patchFields(dest)
+	var validationErrors errValidation.ValidationErrors
+	for _, field := range sortedKeys(patch) {
+		canBeNull, ok := nullable[field]
+		switch {
+		case !ok:
+			validationErrors = append(validationErrors, errValidation.ValidationResponse{
+				Field:   field,
+				Message: fmt.Sprintf("%s can not be patched", field),
+			})
+		case !canBeNull && isJSONNull(patch[field]):
+			validationErrors = append(validationErrors, errValidation.ValidationResponse{
+				Field:   field,
+				Message: fmt.Sprintf("%s can not be null", field),
+			})
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return nil, validationErrors
+	}
+
+	if err := json.Unmarshal(body, dest); err != nil {
+		return nil, err
+	}
+
+	return patch, nil
+}
+
+// patchFields maps the json names of dest's fields to whether they may be
+// set to null.
+func patchFields(dest interface{}) map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(dest)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		fields[name] = field.Tag.Get("patch") == "nullable"
+	}
+
+	return fields
+}
+
+func isJSONNull(value json.RawMessage) bool {
+	return string(bytes.TrimSpace(value)) == "null"
+}
+
+func sortedKeys(patch MergePatch) []string {
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,139 @@
+package util
+
+import (
+	errValidation "backend/common/error"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testPatch struct {
+	Code  *string `json:"code" patch:"nullable"`
+	Name  *string `json:"name"`
+	Stock *uint   `json:"stock,omitempty"`
+	Note  string  `json:"-"`
+	Ready *bool
+}
+
+func TestParseMergePatch(t *testing.T) {
+	text := func(value string) *string { return &value }
+	number := func(value uint) *uint { return &value }
+
+	tests := []struct {
+		name       string
+		body       string
+		want       testPatch
+		wantFields []string
+		wantErrors []string
+		wantErr    bool
+	}{
+		{
+			name:       "members sent",
+			body:       `{"name":"Kopi Susu","stock":0}`,
+			want:       testPatch{Name: text("Kopi Susu"), Stock: number(0)},
+			wantFields: []string{"name", "stock"},
+		},
+		{
+			name:       "empty patch",
+			body:       ` {} `,
+			wantFields: []string{},
+		},
+		{
+			name:       "null on a nullable member",
+			body:       `{"code":null}`,
+			wantFields: []string{"code"},
+		},
+		{
+			name:       "null on a member that can not be null",
+			body:       `{"name":null,"stock":null}`,
+			wantErrors: []string{"name can not be null", "stock can not be null"},
+		},
+		{
+			name:       "unknown members",
+			body:       `{"password":"secret","Note":"x","Ready":true,"name":"Kopi"}`,
+			wantErrors: []string{"Note can not be patched", "Ready can not be patched", "password can not be patched"},
+		},
+		{
+			name:       "array",
+			body:       `[{"name":"Kopi"}]`,
+			wantErrors: []string{"merge patch must be a JSON object"},
+		},
+		{
+			name:       "null patch",
+			body:       `null`,
+			wantErrors: []string{"merge patch must be a JSON object"},
+		},
+		{
+			name:    "empty body",
+			body:    ``,
+			wantErr: true,
+		},
+		{
+			name:    "broken json",
+			body:    `{"name":`,
+			wantErr: true,
+		},
+		{
+			name:    "wrong type",
+			body:    `{"stock":"ten"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testPatch
+			patch, err := ParseMergePatch([]byte(tt.body), &got)
+
+			if tt.wantErrors != nil {
+				if messages := validationMessages(t, err); !reflect.DeepEqual(messages, tt.wantErrors) {
+					t.Errorf("ParseMergePatch errors = %q, want %q", messages, tt.wantErrors)
+				}
+				return
+			}
+			if tt.wantErr {
+				var validationErrors errValidation.ValidationErrors
+				if err == nil || errors.As(err, &validationErrors) {
+					t.Errorf("ParseMergePatch = %v, want a JSON error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMergePatch: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+			if fields := sortedKeys(patch); !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("fields %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestMergePatchMembers(t *testing.T) {
+	var dest testPatch
+	patch, err := ParseMergePatch([]byte(`{"code":null,"name":"Kopi"}`), &dest)
+	if err != nil {
+		t.Fatalf("ParseMergePatch: %v", err)
+	}
+
+	tests := []struct {
+		field    string
+		wantHas  bool
+		wantNull bool
+	}{
+		{field: "code", wantHas: true, wantNull: true},
+		{field: "name", wantHas: true},
+		{field: "stock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if patch.Has(tt.field) != tt.wantHas || patch.IsNull(tt.field) != tt.wantNull {
+				t.Errorf("Has = %v and IsNull = %v, want %v and %v", patch.Has(tt.field), patch.IsNull(tt.field), tt.wantHas, tt.wantNull)
+			}
+		})
+	}
+}
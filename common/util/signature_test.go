@@ -0,0 +1,69 @@
+package util
+
+import "testing"
+
+func TestSignRequest(t *testing.T) {
+	// Computed independently from the documented payload, so a client
+	// implementing the same scheme in another language gets these values.
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   string
+	}{
+		{
+			name:   "post with query and body",
+			method: "POST",
+			path:   "/api/v1/products?dry_run=true",
+			body:   `{"name":"Kopi"}`,
+			want:   "db114f6fbc516394a9a6139a8d5c41653f1a6a3978e3222ef22ec1bae90f8709",
+		},
+		{
+			name:   "get without body",
+			method: "GET",
+			path:   "/api/v1/products",
+			want:   "c0492e7fdf9b93c6e4f5e1fe77fb882d254c8d60675a517a33adf080a90ea848",
+		},
+		{
+			name:   "method in lower case",
+			method: "get",
+			path:   "/api/v1/products",
+			want:   "c0492e7fdf9b93c6e4f5e1fe77fb882d254c8d60675a517a33adf080a90ea848",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SignRequest("secret", tt.method, tt.path, "1700000000", "0123456789abcdef", []byte(tt.body))
+			if got != tt.want {
+				t.Errorf("SignRequest = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignRequestCoversEveryPart(t *testing.T) {
+	base := SignRequest("secret", "POST", "/api/v1/products", "1700000000", "0123456789abcdef", []byte("{}"))
+
+	tests := []struct {
+		name      string
+		signature string
+	}{
+		{"secret", SignRequest("other", "POST", "/api/v1/products", "1700000000", "0123456789abcdef", []byte("{}"))},
+		{"method", SignRequest("secret", "PUT", "/api/v1/products", "1700000000", "0123456789abcdef", []byte("{}"))},
+		{"path", SignRequest("secret", "POST", "/api/v1/products/1", "1700000000", "0123456789abcdef", []byte("{}"))},
+		{"query", SignRequest("secret", "POST", "/api/v1/products?all=true", "1700000000", "0123456789abcdef", []byte("{}"))},
+		{"request time", SignRequest("secret", "POST", "/api/v1/products", "1700000001", "0123456789abcdef", []byte("{}"))},
+		{"nonce", SignRequest("secret", "POST", "/api/v1/products", "1700000000", "0123456789abcdeg", []byte("{}"))},
+		{"body", SignRequest("secret", "POST", "/api/v1/products", "1700000000", "0123456789abcdef", []byte("[]"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.signature == base {
+				t.Errorf("changing the %s kept the signature", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package util
+
+import (
+	errConstant "backend/constants/error"
+	"encoding/csv"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+
+	// formulaChars start a formula in spreadsheet applications. Some of them
+	// skip a leading tab or carriage return before looking for the others.
+	formulaChars = "=+-@\t\r"
+)
+
+// SpreadsheetFormat resolves the format from an explicit value first and
+// falls back to the file extension.
+func SpreadsheetFormat(format, filename string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(filename), ".")
+	}
+
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatXLSX:
+		return FormatXLSX, nil
+	default:
+		return "", errConstant.ErrUnsupportedFileFormat
+	}
+}
+
+// ReadSpreadsheet returns every row of a CSV file or of the first sheet of an
+// XLSX workbook. The first row is expected to be the header.
+func ReadSpreadsheet(reader io.Reader, format string) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		csvReader := csv.NewReader(reader)
+		csvReader.FieldsPerRecord = -1
+		csvReader.TrimLeadingSpace = true
+		return csvReader.ReadAll()
+	case FormatXLSX:
+		file, err := excelize.OpenReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		sheets := file.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, nil
+		}
+
+		return file.GetRows(sheets[0])
+	default:
+		return nil, errConstant.ErrUnsupportedFileFormat
+	}
+}
+
+type ISpreadsheetWriter interface {
+	WriteRow(values ...interface{}) error
+	Close() error
+}
+
+// NewSpreadsheetWriter returns a row writer that streams to w. CSV rows are
+// flushed as they are written, XLSX rows go through the excelize stream
+// writer which spills to a temporary file instead of keeping every cell in
+// memory.
+func NewSpreadsheetWriter(w io.Writer, format string) (ISpreadsheetWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		file := excelize.NewFile()
+		stream, err := file.NewStreamWriter(file.GetSheetName(0))
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+
+		return &xlsxWriter{output: w, file: file, stream: stream}, nil
+	default:
+		return nil, errConstant.ErrUnsupportedFileFormat
+	}
+}
+
+func SpreadsheetContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	rows   int
+}
+
+func (c *csvWriter) WriteRow(values ...interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		if value != nil {
+			record[i] = fmt.Sprint(escapeFormula(value))
+		}
+	}
+
+	err := c.writer.Write(record)
+	if err != nil {
+		return err
+	}
+
+	c.rows++
+	if c.rows%500 == 0 {
+		c.writer.Flush()
+		return c.writer.Error()
+	}
+
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type xlsxWriter struct {
+	output io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	rows   int
+}
+
+func (x *xlsxWriter) WriteRow(values ...interface{}) error {
+	x.rows++
+	cell, err := excelize.CoordinatesToCellName(1, x.rows)
+	if err != nil {
+		return err
+	}
+
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		row[i] = escapeFormula(value)
+	}
+
+	return x.stream.SetRow(cell, row)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+
+	err := x.stream.Flush()
+	if err != nil {
+		return err
+	}
+
+	return x.file.Write(x.output)
+}
+
+// UnescapeFormula drops the quote escapeFormula added, so an exported file
+// imports with the values it was exported from.
+func UnescapeFormula(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.ContainsRune(formulaChars, rune(text[1])) {
+		return text[1:]
+	}
+
+	return text
+}
+
+// escapeFormula prefixes text starting with a formula character with a quote,
+// so a product name like =HYPERLINK(...) is shown as text instead of being
+// run by the spreadsheet application that opens the export.
+func escapeFormula(value interface{}) interface{} {
+	text, ok := value.(string)
+	if ok && text != "" && strings.ContainsRune(formulaChars, rune(text[0])) {
+		return "'" + text
+	}
+
+	return value
+}
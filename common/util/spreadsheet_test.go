@@ -0,0 +1,211 @@
+package util
+
+import (
+	errConstant "backend/constants/error"
+	"bytes"
+	"errors"
+	"github.com/xuri/excelize/v2"
+	"reflect"
+	"testing"
+)
+
+func TestSpreadsheetFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		filename string
+		want     string
+		wantErr  error
+	}{
+		{name: "csv extension", filename: "products.csv", want: FormatCSV},
+		{name: "xlsx extension", filename: "products.xlsx", want: FormatXLSX},
+		{name: "extension in upper case", filename: "PRODUCTS.XLSX", want: FormatXLSX},
+		{name: "explicit format wins", format: "csv", filename: "products.xlsx", want: FormatCSV},
+		{name: "explicit format in upper case", format: "XLSX", want: FormatXLSX},
+		{name: "unknown extension", filename: "products.xls", wantErr: errConstant.ErrUnsupportedFileFormat},
+		{name: "no extension", filename: "products", wantErr: errConstant.ErrUnsupportedFileFormat},
+		{name: "unknown explicit format", format: "json", filename: "products.csv", wantErr: errConstant.ErrUnsupportedFileFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SpreadsheetFormat(tt.format, tt.filename)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SpreadsheetFormat error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SpreadsheetFormat = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSpreadsheet(t *testing.T) {
+	want := [][]string{
+		{"code", "name", "price_sale"},
+		{"KP-01", "Kopi, Susu", "18000"},
+		{"KP-02", "Teh"},
+	}
+
+	var workbook bytes.Buffer
+	file := excelize.NewFile()
+	for i, row := range want {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		values := make([]interface{}, len(row))
+		for j, value := range row {
+			values[j] = value
+		}
+		if err := file.SetSheetRow(file.GetSheetName(0), cell, &values); err != nil {
+			t.Fatalf("SetSheetRow: %v", err)
+		}
+	}
+	if err := file.Write(&workbook); err != nil {
+		t.Fatalf("write workbook: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		format  string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			// Rows may be shorter than the header and spaces after a comma
+			// are dropped.
+			name:    "csv",
+			content: []byte("code,name,price_sale\nKP-01, \"Kopi, Susu\",18000\nKP-02,Teh\n"),
+			format:  FormatCSV,
+			want:    want,
+		},
+		{
+			name:    "xlsx",
+			content: workbook.Bytes(),
+			format:  FormatXLSX,
+			want:    want,
+		},
+		{
+			name:    "broken csv",
+			content: []byte("code,name\n\"KP-01,Kopi\n"),
+			format:  FormatCSV,
+			wantErr: true,
+		},
+		{
+			name:    "csv read as xlsx",
+			content: []byte("code,name\nKP-01,Kopi\n"),
+			format:  FormatXLSX,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			content: []byte("code,name\n"),
+			format:  "json",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSpreadsheet(bytes.NewReader(tt.content), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadSpreadsheet error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadSpreadsheet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpreadsheetWriter(t *testing.T) {
+	rows := [][]interface{}{
+		{"Code", "Name", "Price", "Stock", "Created At"},
+		{"KP-01", "Kopi, \"Susu\"", 18000.0, uint(20), nil},
+		{"KP-02", "Teh", "Rp 5.000", uint(0), "2026-10-19 09:00:00"},
+	}
+	want := [][]string{
+		{"Code", "Name", "Price", "Stock", "Created At"},
+		{"KP-01", "Kopi, \"Susu\"", "18000", "20"},
+		{"KP-02", "Teh", "Rp 5.000", "0", "2026-10-19 09:00:00"},
+	}
+
+	for _, format := range []string{FormatCSV, FormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			var output bytes.Buffer
+			writer, err := NewSpreadsheetWriter(&output, format)
+			if err != nil {
+				t.Fatalf("NewSpreadsheetWriter: %v", err)
+			}
+
+			for _, row := range rows {
+				if err = writer.WriteRow(row...); err != nil {
+					t.Fatalf("WriteRow: %v", err)
+				}
+			}
+			if err = writer.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			got, err := ReadSpreadsheet(&output, format)
+			if err != nil {
+				t.Fatalf("ReadSpreadsheet: %v", err)
+			}
+
+			// CSV keeps the empty trailing cell, XLSX drops it.
+			if format == FormatCSV {
+				got[1] = got[1][:len(got[1])-1]
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("rows read back = %q, want %q", got, want)
+			}
+		})
+	}
+
+	if _, err := NewSpreadsheetWriter(&bytes.Buffer{}, "json"); !errors.Is(err, errConstant.ErrUnsupportedFileFormat) {
+		t.Errorf("NewSpreadsheetWriter of an unknown format = %v, want %v", err, errConstant.ErrUnsupportedFileFormat)
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "formula", value: `=HYPERLINK("http://example.com")`, want: `'=HYPERLINK("http://example.com")`},
+		{name: "plus", value: "+6281234567", want: "'+6281234567"},
+		{name: "minus", value: "-5", want: "'-5"},
+		{name: "at", value: "@SUM(A1)", want: "'@SUM(A1)"},
+		{name: "tab", value: "\t=1+2", want: "'\t=1+2"},
+		{name: "carriage return", value: "\r=1+2", want: "'\r=1+2"},
+		{name: "tab in the middle", value: "Kopi\tSusu", want: "Kopi\tSusu"},
+		{name: "plain text", value: "Kopi = enak", want: "Kopi = enak"},
+		{name: "empty", value: "", want: ""},
+		{name: "number", value: -5000.0, want: -5000.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := escapeFormula(tt.value)
+			if got != tt.want {
+				t.Fatalf("escapeFormula(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+
+			// Importing the exported text gives the original back.
+			if text, ok := tt.value.(string); ok {
+				if back := UnescapeFormula(got.(string)); back != text {
+					t.Errorf("UnescapeFormula(%q) = %q, want %q", got, back, text)
+				}
+			}
+		})
+	}
+
+	for _, text := range []string{"'", "'quoted", "''=x", "' \t"} {
+		if got := UnescapeFormula(text); got != text {
+			t.Errorf("UnescapeFormula(%q) = %q, want it unchanged", text, got)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package util
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret "12345678901234567890" of the RFC 6238 test
+// vectors, base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTotpCode(t *testing.T) {
+	// RFC 6238 appendix B, cut down to the six digits used here.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TotpCode(rfcSecret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("TotpCode at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TotpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTotpCodeInvalidSecret(t *testing.T) {
+	if _, err := TotpCode("not base32!", 1); err == nil {
+		t.Error("TotpCode accepted a secret that is not base32")
+	}
+}
+
+func TestValidateTotp(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	code := func(step int64) string {
+		value, err := TotpCode(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("TotpCode: %v", err)
+		}
+		return value
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", secret: rfcSecret, code: code(current), wantStep: current, wantOK: true},
+		{name: "previous step", secret: rfcSecret, code: code(current - 1), wantStep: current - 1, wantOK: true},
+		{name: "next step", secret: rfcSecret, code: code(current + 1), wantStep: current + 1, wantOK: true},
+		{name: "lower case secret", secret: strings.ToLower(rfcSecret), code: code(current), wantStep: current, wantOK: true},
+		{name: "two steps old", secret: rfcSecret, code: code(current - 2)},
+		{name: "two steps ahead", secret: rfcSecret, code: code(current + 2)},
+		{name: "too short", secret: rfcSecret, code: code(current)[:5]},
+		{name: "too long", secret: rfcSecret, code: code(current) + "0"},
+		{name: "empty", secret: rfcSecret, code: ""},
+		{name: "other secret", secret: "JBSWY3DPEHPK3PXP", code: code(current)},
+		{name: "invalid secret", secret: "not base32!", code: code(current)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTotp(tt.secret, tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ValidateTotp ok = %v, want %v", ok, tt.wantOK)
+			}
+			if step != tt.wantStep {
+				t.Errorf("ValidateTotp step = %d, want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestGenerateTotpSecret(t *testing.T) {
+	first, err := GenerateTotpSecret()
+	if err != nil {
+		t.Fatalf("GenerateTotpSecret: %v", err)
+	}
+	second, err := GenerateTotpSecret()
+	if err != nil {
+		t.Fatalf("GenerateTotpSecret: %v", err)
+	}
+
+	if first == second {
+		t.Error("two secrets are the same")
+	}
+	if len(first) != 32 {
+		t.Errorf("secret %q has %d characters, want 32 for 160 bits", first, len(first))
+	}
+	if _, err = TotpCode(first, 1); err != nil {
+		t.Errorf("generated secret does not decode: %v", err)
+	}
+}
+
+func TestTotpProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TotpProvisioningURI("Backend POS", "budi", rfcSecret))
+	if err != nil {
+		t.Fatalf("parse URI: %v", err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" {
+		t.Errorf("URI %s is not otpauth://totp", uri)
+	}
+	if uri.Path != "/Backend POS:budi" {
+		t.Errorf("label = %q, want issuer:account", uri.Path)
+	}
+
+	query := uri.Query()
+	want := map[string]string{
+		"secret":    rfcSecret,
+		"issuer":    "Backend POS",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for name, value := range want {
+		if query.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, query.Get(name), value)
+		}
+	}
+}
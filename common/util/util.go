@@ -48,6 +48,10 @@ func GeneratePagination(params PaginationParam) PaginationResult {
 		nextPage = params.Page - 1
 	}
 
+	if params.Page > 1 {
+		previousPage = min(params.Page-1, max(totalPage, 1))
+	}
+
 	result := PaginationResult{
 		TotalPage:    totalPage,
 		TotalData:    params.Count,
@@ -169,3 +173,9 @@ func BindFromConsul(dest any, endPoint, path string) error {
 
 	return nil
 }
+
+// EscapeLike escapes the LIKE wildcards in value so user input is matched
+// literally.
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
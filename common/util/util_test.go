@@ -0,0 +1,31 @@
+package util
+
+import "testing"
+
+func TestGeneratePagination(t *testing.T) {
+	tests := []struct {
+		name         string
+		count        int64
+		page         int
+		wantTotal    int
+		wantNext     int
+		wantPrevious int
+	}{
+		{name: "first of three", count: 25, page: 1, wantTotal: 3, wantNext: 2},
+		{name: "middle", count: 25, page: 2, wantTotal: 3, wantNext: 3, wantPrevious: 1},
+		{name: "last", count: 25, page: 3, wantTotal: 3, wantPrevious: 2},
+		{name: "past the end", count: 25, page: 5, wantTotal: 3, wantNext: 4, wantPrevious: 3},
+		{name: "empty", count: 0, page: 1},
+		{name: "past the end of nothing", count: 0, page: 3, wantNext: 2, wantPrevious: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GeneratePagination(PaginationParam{Count: tt.count, Page: tt.page, Limit: 10})
+			if result.TotalPage != tt.wantTotal || *result.NextPage != tt.wantNext || *result.PreviousPage != tt.wantPrevious {
+				t.Errorf("total %d, next %d and previous %d, want %d, %d and %d",
+					result.TotalPage, *result.NextPage, *result.PreviousPage, tt.wantTotal, tt.wantNext, tt.wantPrevious)
+			}
+		})
+	}
+}
@@ -18,8 +18,28 @@ type AppConfig struct {
 	Database              Database
 	RateLimiterMaxRequest int
 	RateLimiterTimeSecond int
-	JwtSecretKey          string
+	JwtAlgorithm          string
+	JwtKeysDir            string
+	JwtKeyRotationHours   int
 	JwtExpirationTime     int
+	RefreshTokenExpiry    int
+	LoginMaxAttempts      int
+	LoginIPMaxAttempts    int
+	LoginLockoutMinutes   int
+	LoginDelaySeconds     int
+	PasswordMinLength     int
+	PasswordMinClasses    int
+	PasswordHistory       int
+	PinMaxAttempts        int
+	TerminalMaxAttempts   int
+	TotpIssuer            string
+	TotpChallengeMinutes  int
+	RequestMaxAgeSeconds  int
+	ApiKeyOverlapMinutes  int
+	PasswordResetMinutes  int
+	PasswordResetURL      string
+	Storage               Storage
+	Mail                  Mail
 }
 
 type Database struct {
@@ -34,6 +54,32 @@ type Database struct {
 	MaxIdleTime           int
 }
 
+type Storage struct {
+	Driver          string
+	LocalPath       string
+	BaseURL         string
+	Endpoint        string
+	AccessKey       string
+	SecretKey       string
+	Bucket          string
+	Region          string
+	UseSSL          bool
+	MaxImageSize    int
+	MaxImagePixels  int
+	ThumbnailWidth  int
+	ThumbnailHeight int
+}
+
+type Mail struct {
+	Driver   string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	UseTLS   bool
+}
+
 func Init() {
 	// 1️⃣ Default dari ENV (SOURCE OF TRUTH)
 	loadFromEnv()
@@ -93,8 +139,50 @@ func loadFromEnv() {
 		},
 		RateLimiterMaxRequest: getEnvInt("RATE_LIMITER_MAX_REQUEST", 1000),
 		RateLimiterTimeSecond: getEnvInt("RATE_LIMITER_TIME_SECOND", 60),
-		JwtSecretKey:          getEnv("JWT_SECRET_KEY", ""),
-		JwtExpirationTime:     getEnvInt("JWT_EXPIRATION_TIME", 1440),
+		JwtAlgorithm:          getEnv("JWT_ALGORITHM", "RS256"),
+		JwtKeysDir:            getEnv("JWT_KEYS_DIR", "keys"),
+		JwtKeyRotationHours:   getEnvInt("JWT_KEY_ROTATION_HOURS", 720),
+		JwtExpirationTime:     getEnvInt("JWT_EXPIRATION_TIME", 15),
+		RefreshTokenExpiry:    getEnvInt("REFRESH_TOKEN_EXPIRATION_TIME", 43200),
+		LoginMaxAttempts:      getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginIPMaxAttempts:    getEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+		LoginLockoutMinutes:   getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+		LoginDelaySeconds:     getEnvInt("LOGIN_DELAY_SECONDS", 1),
+		PasswordMinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMinClasses:    getEnvInt("PASSWORD_MIN_CLASSES", 2),
+		PasswordHistory:       getEnvInt("PASSWORD_HISTORY", 5),
+		PinMaxAttempts:        getEnvInt("PIN_MAX_ATTEMPTS", 5),
+		TerminalMaxAttempts:   getEnvInt("TERMINAL_MAX_ATTEMPTS", 20),
+		TotpIssuer:            getEnv("TOTP_ISSUER", "backend-pos"),
+		TotpChallengeMinutes:  getEnvInt("TOTP_CHALLENGE_MINUTES", 5),
+		RequestMaxAgeSeconds:  getEnvInt("REQUEST_MAX_AGE_SECONDS", 300),
+		ApiKeyOverlapMinutes:  getEnvInt("API_KEY_OVERLAP_MINUTES", 1440),
+		PasswordResetMinutes:  getEnvInt("PASSWORD_RESET_MINUTES", 30),
+		PasswordResetURL:      getEnv("PASSWORD_RESET_URL", ""),
+		Storage: Storage{
+			Driver:          getEnv("STORAGE_DRIVER", "local"),
+			LocalPath:       getEnv("STORAGE_LOCAL_PATH", "uploads"),
+			BaseURL:         getEnv("STORAGE_BASE_URL", "/uploads"),
+			Endpoint:        getEnv("STORAGE_ENDPOINT", ""),
+			AccessKey:       getEnv("STORAGE_ACCESS_KEY", ""),
+			SecretKey:       getEnv("STORAGE_SECRET_KEY", ""),
+			Bucket:          getEnv("STORAGE_BUCKET", ""),
+			Region:          getEnv("STORAGE_REGION", ""),
+			UseSSL:          getEnvBool("STORAGE_USE_SSL", false),
+			MaxImageSize:    getEnvInt("STORAGE_MAX_IMAGE_SIZE", 2*1024*1024),
+			MaxImagePixels:  getEnvInt("STORAGE_MAX_IMAGE_PIXELS", 4096*4096),
+			ThumbnailWidth:  getEnvInt("STORAGE_THUMBNAIL_WIDTH", 300),
+			ThumbnailHeight: getEnvInt("STORAGE_THUMBNAIL_HEIGHT", 300),
+		},
+		Mail: Mail{
+			Driver:   getEnv("MAIL_DRIVER", "log"),
+			Host:     getEnv("MAIL_HOST", ""),
+			Port:     getEnvInt("MAIL_PORT", 587),
+			Username: getEnv("MAIL_USERNAME", ""),
+			Password: getEnv("MAIL_PASSWORD", ""),
+			From:     getEnv("MAIL_FROM", "backend-pos <no-reply@localhost>"),
+			UseTLS:   getEnvBool("MAIL_USE_TLS", false),
+		},
 	}
 }
 
@@ -126,18 +214,132 @@ func overrideFromEnv() {
 	if v := os.Getenv("DB_PASSWORD"); v != "" {
 		Config.Database.Password = v
 	}
-	if v := os.Getenv("JWT_SECRET_KEY"); v != "" {
-		Config.JwtSecretKey = v
+	if v := os.Getenv("JWT_ALGORITHM"); v != "" {
+		Config.JwtAlgorithm = v
+	}
+	if v := os.Getenv("JWT_KEYS_DIR"); v != "" {
+		Config.JwtKeysDir = v
+	}
+	if v := os.Getenv("JWT_KEY_ROTATION_HOURS"); v != "" {
+		Config.JwtKeyRotationHours, _ = strconv.Atoi(v)
 	}
 	if v := os.Getenv("JWT_EXPIRATION_TIME"); v != "" {
 		Config.JwtExpirationTime, _ = strconv.Atoi(v)
 	}
+	if v := os.Getenv("REFRESH_TOKEN_EXPIRATION_TIME"); v != "" {
+		Config.RefreshTokenExpiry, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("LOGIN_MAX_ATTEMPTS"); v != "" {
+		Config.LoginMaxAttempts, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("LOGIN_IP_MAX_ATTEMPTS"); v != "" {
+		Config.LoginIPMaxAttempts, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("LOGIN_LOCKOUT_MINUTES"); v != "" {
+		Config.LoginLockoutMinutes, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("LOGIN_DELAY_SECONDS"); v != "" {
+		Config.LoginDelaySeconds, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		Config.PasswordMinLength, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("PASSWORD_MIN_CLASSES"); v != "" {
+		Config.PasswordMinClasses, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("PASSWORD_HISTORY"); v != "" {
+		Config.PasswordHistory, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("PIN_MAX_ATTEMPTS"); v != "" {
+		Config.PinMaxAttempts, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("TERMINAL_MAX_ATTEMPTS"); v != "" {
+		Config.TerminalMaxAttempts, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("TOTP_ISSUER"); v != "" {
+		Config.TotpIssuer = v
+	}
+	if v := os.Getenv("TOTP_CHALLENGE_MINUTES"); v != "" {
+		Config.TotpChallengeMinutes, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("REQUEST_MAX_AGE_SECONDS"); v != "" {
+		Config.RequestMaxAgeSeconds, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("API_KEY_OVERLAP_MINUTES"); v != "" {
+		Config.ApiKeyOverlapMinutes, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("PASSWORD_RESET_MINUTES"); v != "" {
+		Config.PasswordResetMinutes, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("PASSWORD_RESET_URL"); v != "" {
+		Config.PasswordResetURL = v
+	}
 	if v := os.Getenv("RATE_LIMITER_MAX_REQUEST"); v != "" {
 		Config.RateLimiterMaxRequest, _ = strconv.Atoi(v)
 	}
 	if v := os.Getenv("RATE_LIMITER_TIME_SECOND"); v != "" {
 		Config.RateLimiterTimeSecond, _ = strconv.Atoi(v)
 	}
+	if v := os.Getenv("STORAGE_DRIVER"); v != "" {
+		Config.Storage.Driver = v
+	}
+	if v := os.Getenv("STORAGE_LOCAL_PATH"); v != "" {
+		Config.Storage.LocalPath = v
+	}
+	if v := os.Getenv("STORAGE_BASE_URL"); v != "" {
+		Config.Storage.BaseURL = v
+	}
+	if v := os.Getenv("STORAGE_ENDPOINT"); v != "" {
+		Config.Storage.Endpoint = v
+	}
+	if v := os.Getenv("STORAGE_ACCESS_KEY"); v != "" {
+		Config.Storage.AccessKey = v
+	}
+	if v := os.Getenv("STORAGE_SECRET_KEY"); v != "" {
+		Config.Storage.SecretKey = v
+	}
+	if v := os.Getenv("STORAGE_BUCKET"); v != "" {
+		Config.Storage.Bucket = v
+	}
+	if v := os.Getenv("STORAGE_REGION"); v != "" {
+		Config.Storage.Region = v
+	}
+	if v := os.Getenv("STORAGE_USE_SSL"); v != "" {
+		Config.Storage.UseSSL, _ = strconv.ParseBool(v)
+	}
+	if v := os.Getenv("STORAGE_MAX_IMAGE_SIZE"); v != "" {
+		Config.Storage.MaxImageSize, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("STORAGE_MAX_IMAGE_PIXELS"); v != "" {
+		Config.Storage.MaxImagePixels, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("STORAGE_THUMBNAIL_WIDTH"); v != "" {
+		Config.Storage.ThumbnailWidth, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("STORAGE_THUMBNAIL_HEIGHT"); v != "" {
+		Config.Storage.ThumbnailHeight, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("MAIL_DRIVER"); v != "" {
+		Config.Mail.Driver = v
+	}
+	if v := os.Getenv("MAIL_HOST"); v != "" {
+		Config.Mail.Host = v
+	}
+	if v := os.Getenv("MAIL_PORT"); v != "" {
+		Config.Mail.Port, _ = strconv.Atoi(v)
+	}
+	if v := os.Getenv("MAIL_USERNAME"); v != "" {
+		Config.Mail.Username = v
+	}
+	if v := os.Getenv("MAIL_PASSWORD"); v != "" {
+		Config.Mail.Password = v
+	}
+	if v := os.Getenv("MAIL_FROM"); v != "" {
+		Config.Mail.From = v
+	}
+	if v := os.Getenv("MAIL_USE_TLS"); v != "" {
+		Config.Mail.UseTLS, _ = strconv.ParseBool(v)
+	}
 }
 
 func validate() {
@@ -150,8 +352,14 @@ func validate() {
 	if Config.Database.Username == "" {
 		logrus.Fatal("DB_USER is required")
 	}
-	if Config.JwtSecretKey == "" {
-		logrus.Fatal("JWT_SECRET_KEY is required")
+	if Config.JwtAlgorithm != "RS256" && Config.JwtAlgorithm != "EdDSA" {
+		logrus.Fatal("JWT_ALGORITHM must be RS256 or EdDSA")
+	}
+	if Config.Storage.Driver == "s3" && Config.Storage.Bucket == "" {
+		logrus.Fatal("STORAGE_BUCKET is required when STORAGE_DRIVER is s3")
+	}
+	if Config.Mail.Driver == "smtp" && Config.Mail.Host == "" {
+		logrus.Fatal("MAIL_HOST is required when MAIL_DRIVER is smtp")
 	}
 }
 
@@ -170,3 +378,12 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -19,7 +19,7 @@ func InitDatabase() (*gorm.DB, error) {
 		config.Database.Name,
 	)
 
-	db, err := gorm.Open(postgres.Open(uri), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(uri), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, err
 	}
@@ -3,4 +3,10 @@ package constants
 const (
 	UserLogin = "user_login"
 	Token     = "token"
+	IfMatch   = "if_match"
+	SessionID = "session_id"
+	Terminal  = "terminal"
+	ApiClient = "api_client"
+	RequestID = "request_id"
+	ClientIP  = "client_ip"
 )
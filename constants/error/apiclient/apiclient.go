@@ -0,0 +1,21 @@
+package error
+
+import "errors"
+
+var (
+	ErrApiClientNotFound = errors.New("api client not found")
+	ErrApiKeyInvalid     = errors.New("api key is invalid or has been revoked")
+	ErrSignatureInvalid  = errors.New("request signature is invalid")
+	ErrRequestExpired    = errors.New("x-request-at is missing or outside the allowed window")
+	ErrNonceInvalid      = errors.New("x-nonce must be 16 to 64 characters")
+	ErrNonceReused       = errors.New("request nonce has already been used")
+)
+
+var ApiClientErrors = []error{
+	ErrApiClientNotFound,
+	ErrApiKeyInvalid,
+	ErrSignatureInvalid,
+	ErrRequestExpired,
+	ErrNonceInvalid,
+	ErrNonceReused,
+}
@@ -1,13 +1,19 @@
 package error
 
 import (
+	errApiClient "backend/constants/error/apiclient"
 	errProduct "backend/constants/error/product"
+	errRole "backend/constants/error/role"
+	errTerminal "backend/constants/error/terminal"
 	errUser "backend/constants/error/user"
 )
 
 func ErrMapping(err error) bool {
 	allErrors := make([]error, 0)
 	allErrors = append(append(GeneralErrors[:], errUser.UserErrors[:]...), errProduct.ProductErrors[:]...)
+	allErrors = append(allErrors, errRole.RoleErrors...)
+	allErrors = append(allErrors, errTerminal.TerminalErrors...)
+	allErrors = append(allErrors, errApiClient.ApiClientErrors...)
 
 	for _, item := range allErrors {
 		if err.Error() == item.Error() {
@@ -3,12 +3,18 @@ package error
 import "errors"
 
 var (
-	ErrInternalServerError = errors.New("internal server error")
-	ErrSQLError            = errors.New("database server failed to execute query")
-	ErrTooManyRequests     = errors.New("too many requests")
-	ErrUnauthorized        = errors.New("unauthorized")
-	ErrInvalidToken        = errors.New("invalid token")
-	ErrForbidden           = errors.New("forbidden")
+	ErrInternalServerError   = errors.New("internal server error")
+	ErrSQLError              = errors.New("database server failed to execute query")
+	ErrTooManyRequests       = errors.New("too many requests")
+	ErrUnauthorized          = errors.New("unauthorized")
+	ErrInvalidToken          = errors.New("invalid token")
+	ErrForbidden             = errors.New("forbidden")
+	ErrFileRequired          = errors.New("file is required")
+	ErrUnsupportedFileFormat = errors.New("unsupported file format")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrPreconditionRequired  = errors.New("If-Match header is required")
+	ErrPreconditionFailed    = errors.New("resource has been modified, reload and try again")
+	ErrUnsupportedMediaType  = errors.New("content type must be application/merge-patch+json")
 )
 
 var GeneralErrors = []error{
@@ -18,4 +24,10 @@ var GeneralErrors = []error{
 	ErrUnauthorized,
 	ErrInvalidToken,
 	ErrForbidden,
+	ErrFileRequired,
+	ErrUnsupportedFileFormat,
+	ErrInvalidCursor,
+	ErrPreconditionRequired,
+	ErrPreconditionFailed,
+	ErrUnsupportedMediaType,
 }
@@ -3,11 +3,43 @@ package error
 import "errors"
 
 var (
-	ErrProductNotFound = errors.New("product not found")
-	ErrProductIsExist  = errors.New("product already exist")
+	ErrProductNotFound    = errors.New("product not found")
+	ErrProductIsExist     = errors.New("product code already exist")
+	ErrProductNotArchived = errors.New("product is not archived")
+
+	ErrProductImageRequired = errors.New("product image is required")
+	ErrProductImageInvalid  = errors.New("product image must be jpeg, png, gif or webp")
+	ErrProductImageTooLarge = errors.New("product image is too large")
+
+	ErrProductImportEmpty          = errors.New("import file has no data rows")
+	ErrProductImportColumnNotFound = errors.New("import file is missing required columns")
+	ErrProductImportInvalidRows    = errors.New("import file contains invalid rows")
+
+	ErrProductBatchInvalid = errors.New("batch contains invalid operations")
+	ErrProductBatchFailed  = errors.New("batch operation failed, no changes were saved")
+
+	ErrProductAdjustmentNoScope   = errors.New("price adjustment needs uuids or at least one filter")
+	ErrProductAdjustmentEmpty     = errors.New("no products match the price adjustment")
+	ErrProductAdjustmentBelowCost = errors.New("price adjustment puts price sale below price buy, confirm to continue")
+	ErrProductAdjustmentPercent   = errors.New("percent price adjustment must be between -100 and 1000")
+	ErrProductAdjustmentTooHigh   = errors.New("price adjustment puts price sale above the highest price")
 )
 
 var ProductErrors = []error{
 	ErrProductNotFound,
 	ErrProductIsExist,
+	ErrProductNotArchived,
+	ErrProductImageRequired,
+	ErrProductImageInvalid,
+	ErrProductImageTooLarge,
+	ErrProductImportEmpty,
+	ErrProductImportColumnNotFound,
+	ErrProductImportInvalidRows,
+	ErrProductBatchInvalid,
+	ErrProductBatchFailed,
+	ErrProductAdjustmentNoScope,
+	ErrProductAdjustmentEmpty,
+	ErrProductAdjustmentBelowCost,
+	ErrProductAdjustmentPercent,
+	ErrProductAdjustmentTooHigh,
 }
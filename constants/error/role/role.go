@@ -0,0 +1,25 @@
+package error
+
+import "errors"
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleExist          = errors.New("role already exist")
+	ErrRoleProtected      = errors.New("built-in role can not be changed")
+	ErrRoleInUse          = errors.New("role is still assigned to users")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrPermissionDenied   = errors.New("you do not have permission to perform this action")
+	ErrPermissionNotHeld  = errors.New("you can not grant or take away a permission your role lacks")
+	ErrOwnRole            = errors.New("you can not change your own role")
+)
+
+var RoleErrors = []error{
+	ErrRoleNotFound,
+	ErrRoleExist,
+	ErrRoleProtected,
+	ErrRoleInUse,
+	ErrPermissionNotFound,
+	ErrPermissionDenied,
+	ErrPermissionNotHeld,
+	ErrOwnRole,
+}
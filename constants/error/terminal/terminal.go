@@ -0,0 +1,17 @@
+package error
+
+import "errors"
+
+var (
+	ErrTerminalNotFound     = errors.New("terminal not found")
+	ErrTerminalUnauthorized = errors.New("terminal key is invalid or has been revoked")
+	ErrPinIncorrect         = errors.New("user or PIN is incorrect")
+	ErrPinNotEnrolled       = errors.New("no PIN enrolled on this terminal")
+)
+
+var TerminalErrors = []error{
+	ErrTerminalNotFound,
+	ErrTerminalUnauthorized,
+	ErrPinIncorrect,
+	ErrPinNotEnrolled,
+}
@@ -1,6 +1,9 @@
 package error
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrUserNotFound         = errors.New("user not found")
@@ -8,6 +11,30 @@ var (
 	ErrUsernameExist        = errors.New("username exist")
 	ErrEmailExist           = errors.New("email exist")
 	ErrPasswordDoesNotMatch = errors.New("password does not match")
+
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, please log in again")
+	ErrSessionRevoked      = errors.New("session has been revoked, please log in again")
+	ErrSessionNotFound     = errors.New("session not found")
+
+	ErrUserInactive           = errors.New("user is inactive")
+	ErrPasswordChangeRequired = errors.New("password must be changed before continuing")
+	ErrCannotManageSelf       = errors.New("you can not change the status, role or password of your own account here")
+	ErrRoleOutranksYours      = errors.New("you can not change a user whose role has permissions yours lacks")
+
+	ErrInvalidCredentials = errors.New("username or password is incorrect")
+	ErrLoginLocked        = errors.New("too many failed login attempts, try again later")
+	ErrPasswordReused     = errors.New("password has been used recently, choose a different one")
+
+	ErrTwoFactorSetupRequired  = errors.New("two-factor authentication must be set up before continuing")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for your role")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp       = errors.New("two-factor authentication has not been set up")
+	ErrTwoFactorCodeIncorrect  = errors.New("two-factor code is incorrect")
+	ErrChallengeInvalid        = errors.New("login challenge is invalid or has expired, please log in again")
+
+	ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or has expired")
 )
 
 var UserErrors = []error{
@@ -16,4 +43,37 @@ var UserErrors = []error{
 	ErrUsernameExist,
 	ErrEmailExist,
 	ErrPasswordDoesNotMatch,
+	ErrRefreshTokenInvalid,
+	ErrRefreshTokenReused,
+	ErrSessionRevoked,
+	ErrSessionNotFound,
+	ErrUserInactive,
+	ErrPasswordChangeRequired,
+	ErrCannotManageSelf,
+	ErrRoleOutranksYours,
+	ErrInvalidCredentials,
+	ErrLoginLocked,
+	ErrPasswordReused,
+	ErrTwoFactorSetupRequired,
+	ErrTwoFactorRequired,
+	ErrTwoFactorNotEnabled,
+	ErrTwoFactorAlreadyEnabled,
+	ErrTwoFactorNotSetUp,
+	ErrTwoFactorCodeIncorrect,
+	ErrChallengeInvalid,
+	ErrPasswordResetTokenInvalid,
+}
+
+// LoginLockedError is ErrLoginLocked together with how long the client has
+// to wait before the next attempt.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrLoginLocked
 }
@@ -6,4 +6,11 @@ var (
 	XApiKey       = textproto.CanonicalMIMEHeaderKey("x-api-key")
 	XRequestAt    = textproto.CanonicalMIMEHeaderKey("x-request-at")
 	Authorization = textproto.CanonicalMIMEHeaderKey("authorization")
+	XIfMatch      = textproto.CanonicalMIMEHeaderKey("if-match")
+	XTerminalKey  = textproto.CanonicalMIMEHeaderKey("x-terminal-key")
+	XNonce        = textproto.CanonicalMIMEHeaderKey("x-nonce")
+	XSignature    = textproto.CanonicalMIMEHeaderKey("x-signature")
+	XRequestID    = textproto.CanonicalMIMEHeaderKey("x-request-id")
 )
+
+const MergePatchJSON = "application/merge-patch+json"
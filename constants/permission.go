@@ -0,0 +1,54 @@
+package constants
+
+const (
+	PermissionProductRead     = "product:read"
+	PermissionProductCreate   = "product:create"
+	PermissionProductUpdate   = "product:update"
+	PermissionProductDelete   = "product:delete"
+	PermissionProductPurge    = "product:purge"
+	PermissionProductImport   = "product:import"
+	PermissionProductExport   = "product:export"
+	PermissionProductPrice    = "product:price"
+	PermissionUserRead        = "user:read"
+	PermissionUserRegister    = "user:register"
+	PermissionUserUpdate      = "user:update"
+	PermissionUserManage      = "user:manage"
+	PermissionRoleManage      = "role:manage"
+	PermissionTerminalManage  = "terminal:manage"
+	PermissionApiClientManage = "api-client:manage"
+	PermissionAuditRead       = "audit:read"
+)
+
+// Permissions is the catalog seeded into the permissions table, roles can
+// only be granted codes from this list.
+var Permissions = map[string]string{
+	PermissionProductRead:     "View products",
+	PermissionProductCreate:   "Create products",
+	PermissionProductUpdate:   "Update products and their images",
+	PermissionProductDelete:   "Archive and restore products",
+	PermissionProductPurge:    "Permanently delete archived products",
+	PermissionProductImport:   "Import products and run batch operations",
+	PermissionProductExport:   "Export products",
+	PermissionProductPrice:    "Adjust sale prices in bulk",
+	PermissionUserRead:        "View users",
+	PermissionUserRegister:    "Register users",
+	PermissionUserUpdate:      "Update users",
+	PermissionUserManage:      "List users, (de)activate them, reset passwords and assign roles",
+	PermissionRoleManage:      "Manage roles and permissions",
+	PermissionTerminalManage:  "Register and revoke PIN login terminals",
+	PermissionApiClientManage: "Create, rotate and revoke API client keys",
+	PermissionAuditRead:       "View the audit log",
+}
+
+// AdminPermissions is what the admin role gets when it has no permissions
+// yet, it matches what admins could do before roles were manageable.
+var AdminPermissions = []string{
+	PermissionProductRead,
+	PermissionProductCreate,
+	PermissionProductUpdate,
+	PermissionProductDelete,
+	PermissionProductImport,
+	PermissionProductExport,
+	PermissionUserRead,
+	PermissionUserUpdate,
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	errValidation "backend/common/error"
+	"backend/common/response"
+	errApiClient "backend/constants/error/apiclient"
+	"backend/domain/dto"
+	"backend/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+type ApiClientController struct {
+	service services.IServiceRegistry
+}
+
+type IApiClientController interface {
+	GetAll(*fiber.Ctx) error
+	Create(*fiber.Ctx) error
+	Rotate(*fiber.Ctx) error
+	Revoke(*fiber.Ctx) error
+}
+
+func NewApiClientController(service services.IServiceRegistry) IApiClientController {
+	return &ApiClientController{service: service}
+}
+
+func (a *ApiClientController) GetAll(ctx *fiber.Ctx) error {
+	result, err := a.service.GetApiClient().GetAll(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+// Create answers with the client secret, it is only shown this once.
+func (a *ApiClientController) Create(ctx *fiber.Ctx) error {
+	request := &dto.ApiClientRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	result, err := a.service.GetApiClient().Create(ctx.Context(), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusCreated,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (a *ApiClientController) Rotate(ctx *fiber.Ctx) error {
+	result, err := a.service.GetApiClient().Rotate(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (a *ApiClientController) Revoke(ctx *fiber.Ctx) error {
+	err := a.service.GetApiClient().Revoke(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+// parseBody decodes and validates the request body. When it reports false
+// the error response has already been written and the handler must return.
+func parseBody(ctx *fiber.Ctx, request interface{}) (bool, error) {
+	err := ctx.BodyParser(request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errValidation.ErrValidationResponse(err)
+
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err = validate.Struct(request); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	return true, nil
+}
+
+func statusCode(err error) int {
+	if errors.Is(err, errApiClient.ErrApiClientNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	errValidation "backend/common/error"
+	"backend/common/response"
+	"backend/common/util"
+	errConstant "backend/constants/error"
+	"backend/domain/dto"
+	"backend/services"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"net/url"
+)
+
+type AuditLogController struct {
+	service services.IServiceRegistry
+}
+
+type IAuditLogController interface {
+	GetAllWithPagination(*fiber.Ctx) error
+	GetAllWithCursor(*fiber.Ctx) error
+}
+
+func NewAuditLogController(service services.IServiceRegistry) IAuditLogController {
+	return &AuditLogController{service: service}
+}
+
+func (a *AuditLogController) GetAllWithPagination(ctx *fiber.Ctx) error {
+	params := &dto.AuditLogRequestParam{}
+	if ok, err := parseQuery(ctx, params); !ok {
+		return err
+	}
+
+	result, err := a.service.GetAuditLog().GetAllWithPagination(ctx.Context(), params)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusInternalServerError,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (a *AuditLogController) GetAllWithCursor(ctx *fiber.Ctx) error {
+	params := &dto.AuditLogCursorParam{}
+	if ok, err := parseQuery(ctx, params); !ok {
+		return err
+	}
+
+	result, err := a.service.GetAuditLog().GetAllWithCursor(ctx.Context(), params)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, errConstant.ErrInvalidCursor) {
+			statusCode = http.StatusUnprocessableEntity
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	requestURL, err := url.Parse(ctx.OriginalURL())
+	if err == nil {
+		if link := util.CursorLinkHeader(requestURL, result.NextCursor, result.PreviousCursor); link != "" {
+			ctx.Set(fiber.HeaderLink, link)
+		}
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+// parseQuery decodes and validates the query string. When it reports false
+// the error response has already been written and the handler must return.
+func parseQuery(ctx *fiber.Ctx, params interface{}) (bool, error) {
+	if err := ctx.QueryParser(params); err != nil {
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(params); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errValidation.ErrValidationResponse(err)
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	return true, nil
+}
@@ -3,14 +3,24 @@ package controllers
 import (
 	errValidation "backend/common/error"
 	"backend/common/response"
+	"backend/common/util"
+	"backend/constants"
+	errConstant "backend/constants/error"
 	errProduct "backend/constants/error/product"
+	errRole "backend/constants/error/role"
 	"backend/domain/dto"
 	productService "backend/services"
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 type ProductController struct {
@@ -20,11 +30,21 @@ type ProductController struct {
 type IProductController interface {
 	GetAllWithPagination(*fiber.Ctx) error
 	GetAllWithoutPagination(*fiber.Ctx) error
+	GetAllWithCursor(*fiber.Ctx) error
 	GetByUUID(*fiber.Ctx) error
 	GetByCode(*fiber.Ctx) error
 	Create(*fiber.Ctx) error
 	Update(*fiber.Ctx) error
+	Patch(*fiber.Ctx) error
 	Delete(*fiber.Ctx) error
+	Restore(*fiber.Ctx) error
+	Purge(*fiber.Ctx) error
+	UploadImage(*fiber.Ctx) error
+	DeleteImage(*fiber.Ctx) error
+	Import(*fiber.Ctx) error
+	Batch(*fiber.Ctx) error
+	AdjustPrice(*fiber.Ctx) error
+	Export(*fiber.Ctx) error
 }
 
 func NewProductController(service productService.IServiceRegistry) IProductController {
@@ -55,6 +75,20 @@ func (p *ProductController) GetAllWithPagination(ctx *fiber.Ctx) error {
 		})
 	}
 
+	params.Filters = util.ExtractFilters(ctx.Queries())
+	if err := dto.ProductListSpec.Validate(params.SortExpression(), params.Filters); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
 	result, err := p.service.GetProduct().GetAllWithPagination(ctx.Context(), &params)
 	if err != nil {
 		return response.HttpResponse(response.ParamHTTPResp{
@@ -71,8 +105,111 @@ func (p *ProductController) GetAllWithPagination(ctx *fiber.Ctx) error {
 	})
 }
 
+func (p *ProductController) GetAllWithCursor(ctx *fiber.Ctx) error {
+	var params dto.ProductCursorParam
+	if err := ctx.QueryParser(&params); err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(params); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	params.Filters = util.ExtractFilters(ctx.Queries())
+	if err := dto.ProductListSpec.Validate(params.SortExpression(), params.Filters); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	result, err := p.service.GetProduct().GetAllWithCursor(ctx.Context(), &params)
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, errConstant.ErrInvalidCursor) {
+			statusCode = http.StatusUnprocessableEntity
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	requestURL, err := url.Parse(ctx.OriginalURL())
+	if err == nil {
+		if link := util.CursorLinkHeader(requestURL, result.NextCursor, result.PreviousCursor); link != "" {
+			ctx.Set(fiber.HeaderLink, link)
+		}
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
 func (p *ProductController) GetAllWithoutPagination(ctx *fiber.Ctx) error {
-	result, err := p.service.GetProduct().GetAllWithoutPagination(ctx.Context())
+	var params dto.ProductFilterParam
+	if err := ctx.QueryParser(&params); err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(params); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	params.Filters = util.ExtractFilters(ctx.Queries())
+	if err := dto.ProductListSpec.Validate(params.SortExpression(), params.Filters); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	result, err := p.service.GetProduct().GetAllWithoutPagination(ctx.Context(), &params)
 	if err != nil {
 		return response.HttpResponse(response.ParamHTTPResp{
 			Code:  http.StatusBadRequest,
@@ -106,6 +243,12 @@ func (p *ProductController) GetByUUID(ctx *fiber.Ctx) error {
 		})
 	}
 
+	etag := util.ETag(result.Version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if ctx.Get(fiber.HeaderIfNoneMatch) == etag {
+		return ctx.SendStatus(http.StatusNotModified)
+	}
+
 	return response.HttpResponse(response.ParamHTTPResp{
 		Code:  http.StatusOK,
 		Data:  result,
@@ -131,6 +274,12 @@ func (p *ProductController) GetByCode(ctx *fiber.Ctx) error {
 		})
 	}
 
+	etag := util.ETag(result.Version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if ctx.Get(fiber.HeaderIfNoneMatch) == etag {
+		return ctx.SendStatus(http.StatusNotModified)
+	}
+
 	return response.HttpResponse(response.ParamHTTPResp{
 		Code:  http.StatusOK,
 		Data:  result,
@@ -178,8 +327,13 @@ func (p *ProductController) Create(ctx *fiber.Ctx) error {
 
 	result, err := p.service.GetProduct().Create(ctx.Context(), request)
 	if err != nil {
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, errProduct.ErrProductIsExist) {
+			statusCode = http.StatusConflict
+		}
+
 		return response.HttpResponse(response.ParamHTTPResp{
-			Code:  http.StatusBadRequest,
+			Code:  statusCode,
 			Err:   err,
 			Fiber: ctx,
 		})
@@ -230,12 +384,18 @@ func (p *ProductController) Update(ctx *fiber.Ctx) error {
 		})
 	}
 
+	versions, _ := ctx.Locals(constants.IfMatch).([]uint)
 	result, err := p.service.GetProduct().Update(
 		ctx.Context(),
 		ctx.Params("uuid"),
 		request,
+		versions,
 	)
 	if err != nil {
+		if errors.Is(err, errConstant.ErrPreconditionFailed) {
+			return p.preconditionFailed(ctx, err)
+		}
+
 		if errors.Is(err, errProduct.ErrProductNotFound) {
 			return response.HttpResponse(response.ParamHTTPResp{
 				Code:  http.StatusNotFound,
@@ -244,13 +404,102 @@ func (p *ProductController) Update(ctx *fiber.Ctx) error {
 			})
 		}
 
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, errProduct.ErrProductIsExist) {
+			statusCode = http.StatusConflict
+		}
+
 		return response.HttpResponse(response.ParamHTTPResp{
-			Code:  http.StatusBadRequest,
+			Code:  statusCode,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	ctx.Set(fiber.HeaderETag, util.ETag(result.Version))
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+// Patch applies an RFC 7386 merge patch, only the members in the body are
+// changed.
+func (p *ProductController) Patch(ctx *fiber.Ctx) error {
+	request := &dto.PatchProductRequest{}
+
+	fields, err := util.ParseMergePatch(ctx.Body(), request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+	request.Fields = fields
+
+	validate := validator.New()
+	if err = validate.Struct(request); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	versions, _ := ctx.Locals(constants.IfMatch).([]uint)
+	result, err := p.service.GetProduct().Patch(
+		ctx.Context(),
+		ctx.Params("uuid"),
+		request,
+		versions,
+	)
+	if err != nil {
+		if errors.Is(err, errConstant.ErrPreconditionFailed) {
+			return p.preconditionFailed(ctx, err)
+		}
+
+		if errors.Is(err, errProduct.ErrProductNotFound) {
+			return response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusNotFound,
+				Err:   err,
+				Fiber: ctx,
+			})
+		}
+
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, errProduct.ErrProductIsExist) {
+			statusCode = http.StatusConflict
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
 			Err:   err,
 			Fiber: ctx,
 		})
 	}
 
+	ctx.Set(fiber.HeaderETag, util.ETag(result.Version))
+
 	return response.HttpResponse(response.ParamHTTPResp{
 		Code:  http.StatusOK,
 		Data:  result,
@@ -259,7 +508,121 @@ func (p *ProductController) Update(ctx *fiber.Ctx) error {
 }
 
 func (p *ProductController) Delete(ctx *fiber.Ctx) error {
-	err := p.service.GetProduct().Delete(ctx.Context(), ctx.Params("uuid"))
+	versions, _ := ctx.Locals(constants.IfMatch).([]uint)
+	err := p.service.GetProduct().Delete(ctx.Context(), ctx.Params("uuid"), versions)
+	if err != nil {
+		if errors.Is(err, errConstant.ErrPreconditionFailed) {
+			return p.preconditionFailed(ctx, err)
+		}
+
+		if errors.Is(err, errProduct.ErrProductNotFound) {
+			return response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusNotFound,
+				Err:   err,
+				Fiber: ctx,
+			})
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+func (p *ProductController) Restore(ctx *fiber.Ctx) error {
+	result, err := p.service.GetProduct().Restore(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errProduct.ErrProductNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, errProduct.ErrProductNotArchived):
+			statusCode = http.StatusConflict
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (p *ProductController) Purge(ctx *fiber.Ctx) error {
+	err := p.service.GetProduct().Purge(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		if errors.Is(err, errProduct.ErrProductNotFound) {
+			return response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusNotFound,
+				Err:   err,
+				Fiber: ctx,
+			})
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+func (p *ProductController) UploadImage(ctx *fiber.Ctx) error {
+	file, err := ctx.FormFile("image")
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusUnprocessableEntity,
+			Err:   errProduct.ErrProductImageRequired,
+			Fiber: ctx,
+		})
+	}
+
+	result, err := p.service.GetProduct().UploadImage(ctx.Context(), ctx.Params("uuid"), file)
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errProduct.ErrProductNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, errProduct.ErrProductImageTooLarge):
+			statusCode = http.StatusRequestEntityTooLarge
+		case errors.Is(err, errProduct.ErrProductImageInvalid):
+			statusCode = http.StatusUnsupportedMediaType
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (p *ProductController) DeleteImage(ctx *fiber.Ctx) error {
+	result, err := p.service.GetProduct().DeleteImage(ctx.Context(), ctx.Params("uuid"))
 	if err != nil {
 		if errors.Is(err, errProduct.ErrProductNotFound) {
 			return response.HttpResponse(response.ParamHTTPResp{
@@ -278,6 +641,343 @@ func (p *ProductController) Delete(ctx *fiber.Ctx) error {
 
 	return response.HttpResponse(response.ParamHTTPResp{
 		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (p *ProductController) Import(ctx *fiber.Ctx) error {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusUnprocessableEntity,
+			Err:   errConstant.ErrFileRequired,
+			Fiber: ctx,
+		})
+	}
+
+	request := &dto.ProductImportRequest{
+		Format: ctx.FormValue("format"),
+		DryRun: ctx.QueryBool("dry_run"),
+	}
+
+	if mapping := ctx.FormValue("mapping"); mapping != "" {
+		err = json.Unmarshal([]byte(mapping), &request.Mapping)
+		if err != nil {
+			errMessage := http.StatusText(http.StatusUnprocessableEntity)
+			errResponse := errValidation.ErrValidationResponse(err)
+
+			return response.HttpResponse(response.ParamHTTPResp{
+				Code:    http.StatusUnprocessableEntity,
+				Message: &errMessage,
+				Data:    errResponse,
+				Err:     err,
+				Fiber:   ctx,
+			})
+		}
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+	defer src.Close()
+
+	result, err := p.service.GetProduct().Import(ctx.Context(), src, file.Filename, request)
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errConstant.ErrUnsupportedFileFormat):
+			statusCode = http.StatusUnsupportedMediaType
+		case errors.Is(err, errProduct.ErrProductImportEmpty),
+			errors.Is(err, errProduct.ErrProductImportColumnNotFound),
+			errors.Is(err, errProduct.ErrProductImportInvalidRows):
+			statusCode = http.StatusUnprocessableEntity
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Data:  result,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+// Batch only checks the envelope here, each operation is validated by the
+// service so invalid ones come back as per-item results.
+func (p *ProductController) Batch(ctx *fiber.Ctx) error {
+	request := &dto.ProductBatchRequest{}
+
+	err := ctx.BodyParser(request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err = validate.Struct(request); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	result, err := p.service.GetProduct().Batch(ctx.Context(), request)
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errProduct.ErrProductBatchInvalid), errors.Is(err, errProduct.ErrProductBatchFailed):
+			statusCode = http.StatusUnprocessableEntity
+		case errors.Is(err, errRole.ErrPermissionDenied):
+			statusCode = http.StatusForbidden
+		case errors.Is(err, errConstant.ErrUnauthorized):
+			statusCode = http.StatusUnauthorized
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Data:  result,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+// AdjustPrice takes the price rule from the body and the products it
+// applies to from the same query filters as the list endpoints.
+func (p *ProductController) AdjustPrice(ctx *fiber.Ctx) error {
+	var params dto.ProductFilterParam
+	if err := ctx.QueryParser(&params); err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(params); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	params.Filters = util.ExtractFilters(ctx.Queries())
+	if err := dto.ProductListSpec.Validate(params.SortExpression(), params.Filters); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	request := &dto.ProductPriceAdjustmentRequest{}
+	err := ctx.BodyParser(request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	if err = validate.Struct(request); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	result, err := p.service.GetProduct().AdjustPrice(ctx.Context(), &params, request)
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errProduct.ErrProductAdjustmentNoScope),
+			errors.Is(err, errProduct.ErrProductAdjustmentPercent),
+			errors.Is(err, errProduct.ErrProductAdjustmentTooHigh):
+			statusCode = http.StatusUnprocessableEntity
+		case errors.Is(err, errProduct.ErrProductAdjustmentEmpty):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, errProduct.ErrProductAdjustmentBelowCost):
+			statusCode = http.StatusConflict
+		case errors.Is(err, errConstant.ErrPreconditionFailed):
+			statusCode = http.StatusPreconditionFailed
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Data:  result,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (p *ProductController) Export(ctx *fiber.Ctx) error {
+	var params dto.ProductExportParam
+	if err := ctx.QueryParser(&params); err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(params); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	params.Filters = util.ExtractFilters(ctx.Queries())
+	if err := dto.ProductListSpec.Validate(params.SortExpression(), params.Filters); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	if params.Format == "" {
+		params.Format = util.FormatCSV
+	}
+
+	filename := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102-150405"), params.Format)
+	ctx.Set(fiber.HeaderContentType, util.SpreadsheetContentType(params.Format))
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// The body is written after the handler returns, so the request context
+	// can not be used inside the stream writer. The export gets its own
+	// context instead, cancelled once a write fails because the client went
+	// away, which stops the query.
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		exportCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		err := p.service.GetProduct().Export(exportCtx, &cancelOnErrorWriter{writer: w, cancel: cancel}, &params)
+		if err != nil {
+			logrus.Errorf("failed to export products: %v", err)
+		}
+		_ = w.Flush()
+	})
+
+	return nil
+}
+
+// cancelOnErrorWriter cancels the export context on the first failed write.
+type cancelOnErrorWriter struct {
+	writer *bufio.Writer
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnErrorWriter) Write(data []byte) (int, error) {
+	n, err := c.writer.Write(data)
+	if err != nil {
+		c.cancel()
+	}
+
+	return n, err
+}
+
+// preconditionFailed answers a version mismatch with the current product so
+// the client can merge its change without another request.
+func (p *ProductController) preconditionFailed(ctx *fiber.Ctx, err error) error {
+	current, findErr := p.service.GetProduct().GetByUUID(ctx.Context(), ctx.Params("uuid"))
+	if findErr == nil {
+		ctx.Set(fiber.HeaderETag, util.ETag(current.Version))
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusPreconditionFailed,
+		Err:   err,
+		Data:  current,
 		Fiber: ctx,
 	})
 }
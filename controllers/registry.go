@@ -1,7 +1,11 @@
 package controllers
 
 import (
+	apiClientController "backend/controllers/apiclient"
+	auditController "backend/controllers/audit"
 	productController "backend/controllers/product"
+	roleController "backend/controllers/role"
+	terminalController "backend/controllers/terminal"
 	userControllers "backend/controllers/user"
 	"backend/services"
 )
@@ -13,6 +17,10 @@ type Registry struct {
 type IControllerRegistry interface {
 	GetUserController() userControllers.IUserController
 	GetProductController() productController.IProductController
+	GetRoleController() roleController.IRoleController
+	GetTerminalController() terminalController.ITerminalController
+	GetApiClientController() apiClientController.IApiClientController
+	GetAuditLogController() auditController.IAuditLogController
 }
 
 func NewControllerRegistry(service services.IServiceRegistry) IControllerRegistry {
@@ -28,3 +36,19 @@ func (r *Registry) GetUserController() userControllers.IUserController {
 func (r *Registry) GetProductController() productController.IProductController {
 	return productController.NewProductController(r.service)
 }
+
+func (r *Registry) GetRoleController() roleController.IRoleController {
+	return roleController.NewRoleController(r.service)
+}
+
+func (r *Registry) GetTerminalController() terminalController.ITerminalController {
+	return terminalController.NewTerminalController(r.service)
+}
+
+func (r *Registry) GetApiClientController() apiClientController.IApiClientController {
+	return apiClientController.NewApiClientController(r.service)
+}
+
+func (r *Registry) GetAuditLogController() auditController.IAuditLogController {
+	return auditController.NewAuditLogController(r.service)
+}
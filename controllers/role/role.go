@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	errValidation "backend/common/error"
+	"backend/common/response"
+	errRole "backend/constants/error/role"
+	"backend/domain/dto"
+	"backend/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+type RoleController struct {
+	service services.IServiceRegistry
+}
+
+type IRoleController interface {
+	GetAll(*fiber.Ctx) error
+	GetPermissions(*fiber.Ctx) error
+	Create(*fiber.Ctx) error
+	Update(*fiber.Ctx) error
+	SetTwoFactor(*fiber.Ctx) error
+	Delete(*fiber.Ctx) error
+}
+
+func NewRoleController(service services.IServiceRegistry) IRoleController {
+	return &RoleController{service: service}
+}
+
+func (r *RoleController) GetAll(ctx *fiber.Ctx) error {
+	result, err := r.service.GetRole().GetAll(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (r *RoleController) GetPermissions(ctx *fiber.Ctx) error {
+	result, err := r.service.GetRole().GetPermissions(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (r *RoleController) Create(ctx *fiber.Ctx) error {
+	request := &dto.RoleRequest{}
+
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	result, err := r.service.GetRole().Create(ctx.Context(), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusCreated,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (r *RoleController) Update(ctx *fiber.Ctx) error {
+	request := &dto.UpdateRoleRequest{}
+
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	result, err := r.service.GetRole().Update(ctx.Context(), ctx.Params("code"), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (r *RoleController) SetTwoFactor(ctx *fiber.Ctx) error {
+	request := &dto.RoleTwoFactorRequest{}
+
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	result, err := r.service.GetRole().SetTwoFactor(ctx.Context(), ctx.Params("code"), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (r *RoleController) Delete(ctx *fiber.Ctx) error {
+	err := r.service.GetRole().Delete(ctx.Context(), ctx.Params("code"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+// parseBody decodes and validates the request body. When it reports false
+// the error response has already been written and the handler must return.
+func parseBody(ctx *fiber.Ctx, request interface{}) (bool, error) {
+	err := ctx.BodyParser(request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errValidation.ErrValidationResponse(err)
+
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err = validate.Struct(request); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	return true, nil
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, errRole.ErrRoleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errRole.ErrRoleProtected),
+		errors.Is(err, errRole.ErrPermissionNotHeld),
+		errors.Is(err, errRole.ErrOwnRole):
+		return http.StatusForbidden
+	case errors.Is(err, errRole.ErrRoleExist), errors.Is(err, errRole.ErrRoleInUse):
+		return http.StatusConflict
+	case errors.Is(err, errRole.ErrPermissionNotFound):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
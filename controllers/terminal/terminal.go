@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	errValidation "backend/common/error"
+	"backend/common/response"
+	errConstant "backend/constants/error"
+	errTerminal "backend/constants/error/terminal"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"backend/services"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type TerminalController struct {
+	service services.IServiceRegistry
+}
+
+type ITerminalController interface {
+	GetAll(*fiber.Ctx) error
+	Create(*fiber.Ctx) error
+	Revoke(*fiber.Ctx) error
+	GetUsers(*fiber.Ctx) error
+	PinLogin(*fiber.Ctx) error
+	EnrollPin(*fiber.Ctx) error
+	RemovePin(*fiber.Ctx) error
+}
+
+func NewTerminalController(service services.IServiceRegistry) ITerminalController {
+	return &TerminalController{service: service}
+}
+
+func (t *TerminalController) GetAll(ctx *fiber.Ctx) error {
+	result, err := t.service.GetTerminal().GetAll(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+// Create answers with the terminal key, it is only shown this once.
+func (t *TerminalController) Create(ctx *fiber.Ctx) error {
+	request := &dto.TerminalRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	result, err := t.service.GetTerminal().Create(ctx.Context(), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusCreated,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (t *TerminalController) Revoke(ctx *fiber.Ctx) error {
+	err := t.service.GetTerminal().Revoke(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+func (t *TerminalController) GetUsers(ctx *fiber.Ctx) error {
+	result, err := t.service.GetTerminal().GetUsers(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+// PinLogin answers like the password login, with the tokens of the user who
+// now has the terminal.
+func (t *TerminalController) PinLogin(ctx *fiber.Ctx) error {
+	request := &dto.PinLoginRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	request.ClientIP = ctx.IP()
+	request.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+	user, err := t.service.GetUser().PinLogin(ctx.Context(), request)
+	if err != nil {
+		var lockedError *errUser.LoginLockedError
+		if errors.As(err, &lockedError) {
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(lockedError.RetryAfter.Seconds()))))
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:    http.StatusOK,
+		Data:    user.User,
+		Token:   &user.Token,
+		Refresh: &user.RefreshToken,
+		Fiber:   ctx,
+	})
+}
+
+func (t *TerminalController) EnrollPin(ctx *fiber.Ctx) error {
+	request := &dto.EnrollPinRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	err := t.service.GetUser().EnrollPin(ctx.Context(), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+func (t *TerminalController) RemovePin(ctx *fiber.Ctx) error {
+	err := t.service.GetUser().RemovePin(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+// parseBody decodes and validates the request body. When it reports false
+// the error response has already been written and the handler must return.
+func parseBody(ctx *fiber.Ctx, request interface{}) (bool, error) {
+	err := ctx.BodyParser(request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errValidation.ErrValidationResponse(err)
+
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err = validate.Struct(request); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errorResponse := errValidation.ErrValidationResponse(err)
+
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Err:     err,
+			Message: &errMessage,
+			Data:    errorResponse,
+			Fiber:   ctx,
+		})
+	}
+
+	return true, nil
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, errTerminal.ErrTerminalNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errTerminal.ErrTerminalUnauthorized),
+		errors.Is(err, errTerminal.ErrPinIncorrect),
+		errors.Is(err, errConstant.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, errUser.ErrUserInactive):
+		return http.StatusForbidden
+	case errors.Is(err, errUser.ErrLoginLocked):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errUser.ErrPasswordIncorrect):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -3,13 +3,21 @@ package controllers
 import (
 	errWrap "backend/common/error"
 	"backend/common/response"
+	"backend/common/util"
+	"backend/constants"
+	errConstant "backend/constants/error"
+	errRole "backend/constants/error/role"
+	errUser "backend/constants/error/user"
 	"backend/domain/dto"
 	"backend/services"
 	"encoding/json"
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 type UserController struct {
@@ -18,10 +26,38 @@ type UserController struct {
 
 type IUserController interface {
 	Login(*fiber.Ctx) error
+	Refresh(*fiber.Ctx) error
+	Logout(*fiber.Ctx) error
 	Register(*fiber.Ctx) error
 	Update(*fiber.Ctx) error
+	Patch(*fiber.Ctx) error
 	GetUserLogin(*fiber.Ctx) error
 	GetUserByUUID(*fiber.Ctx) error
+	GetAllWithPagination(*fiber.Ctx) error
+	Activate(*fiber.Ctx) error
+	Deactivate(*fiber.Ctx) error
+	ResetPassword(*fiber.Ctx) error
+	AssignRole(*fiber.Ctx) error
+	Delete(*fiber.Ctx) error
+	ChangePassword(*fiber.Ctx) error
+	ForgotPassword(*fiber.Ctx) error
+	ResetForgottenPassword(*fiber.Ctx) error
+	Unlock(*fiber.Ctx) error
+	VerifyTwoFactor(*fiber.Ctx) error
+	GetTwoFactor(*fiber.Ctx) error
+	SetupTwoFactor(*fiber.Ctx) error
+	EnableTwoFactor(*fiber.Ctx) error
+	DisableTwoFactor(*fiber.Ctx) error
+	RegenerateRecoveryCodes(*fiber.Ctx) error
+	GetSessions(*fiber.Ctx) error
+	RevokeSession(*fiber.Ctx) error
+	GetLoginHistory(*fiber.Ctx) error
+	GetUserSessions(*fiber.Ctx) error
+	RevokeUserSession(*fiber.Ctx) error
+	GetAllLoginHistory(*fiber.Ctx) error
+	GetLoginHistoryWithCursor(*fiber.Ctx) error
+	GetAllLoginHistoryWithCursor(*fiber.Ctx) error
+	GetJwks(*fiber.Ctx) error
 }
 
 func NewUserController(service services.IServiceRegistry) IUserController {
@@ -65,10 +101,114 @@ func (u *UserController) Login(ctx *fiber.Ctx) error {
 		})
 	}
 
+	request.ClientIP = ctx.IP()
+	request.UserAgent = ctx.Get(fiber.HeaderUserAgent)
 	user, err := u.service.GetUser().Login(ctx.Context(), request)
 	if err != nil {
+		statusCode := http.StatusInternalServerError
+
+		switch {
+		case setRetryAfter(ctx, err):
+			statusCode = http.StatusTooManyRequests
+		case errors.Is(err, errUser.ErrInvalidCredentials):
+			statusCode = http.StatusUnauthorized
+		}
+
 		return response.HttpResponse(response.ParamHTTPResp{
-			Code:  http.StatusUnprocessableEntity,
+			Code:  statusCode,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	// The password was right but a two-factor code is still needed.
+	if user.Challenge != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusAccepted,
+			Data:  user.Challenge,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:    http.StatusOK,
+		Data:    user.User,
+		Token:   &user.Token,
+		Refresh: &user.RefreshToken,
+		Fiber:   ctx,
+	})
+}
+
+// Refresh rotates the refresh token. Any refresh token error is a 401 so the
+// client knows to send the user back to the login screen.
+func (u *UserController) Refresh(ctx *fiber.Ctx) error {
+	request := &dto.RefreshTokenRequest{}
+
+	err := ctx.BodyParser(request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errWrap.ErrValidationResponse(err)
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	user, err := u.service.GetUser().Refresh(ctx.Context(), request)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, errUser.ErrRefreshTokenInvalid) ||
+			errors.Is(err, errUser.ErrRefreshTokenReused) ||
+			errors.Is(err, errUser.ErrSessionRevoked) ||
+			errors.Is(err, errUser.ErrUserInactive) {
+			statusCode = http.StatusUnauthorized
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:    http.StatusOK,
+		Data:    user.User,
+		Token:   &user.Token,
+		Refresh: &user.RefreshToken,
+		Fiber:   ctx,
+	})
+}
+
+func (u *UserController) Logout(ctx *fiber.Ctx) error {
+	err := u.service.GetUser().Logout(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusInternalServerError,
 			Err:   err,
 			Fiber: ctx,
 		})
@@ -76,8 +216,6 @@ func (u *UserController) Login(ctx *fiber.Ctx) error {
 
 	return response.HttpResponse(response.ParamHTTPResp{
 		Code:  http.StatusOK,
-		Data:  user.User,
-		Token: &user.Token,
 		Fiber: ctx,
 	})
 }
@@ -121,6 +259,10 @@ func (u *UserController) Register(ctx *fiber.Ctx) error {
 
 	user, err := u.service.GetUser().Register(ctx.Context(), request)
 	if err != nil {
+		if isValidationError(err) {
+			return validationFailed(ctx, err)
+		}
+
 		return response.HttpResponse(response.ParamHTTPResp{
 			Code:  http.StatusUnprocessableEntity,
 			Err:   err,
@@ -173,8 +315,117 @@ func (u *UserController) Update(ctx *fiber.Ctx) error {
 		})
 	}
 
-	user, err := u.service.GetUser().Update(ctx.Context(), request, uuid)
+	versions, _ := ctx.Locals(constants.IfMatch).([]uint)
+	user, err := u.service.GetUser().Update(ctx.Context(), request, uuid, versions)
+	if err != nil {
+		if errors.Is(err, errConstant.ErrPreconditionFailed) {
+			return u.preconditionFailed(ctx, err)
+		}
+
+		if isValidationError(err) {
+			return validationFailed(ctx, err)
+		}
+
+		if errors.Is(err, errUser.ErrUserNotFound) {
+			return response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusNotFound,
+				Err:   err,
+				Fiber: ctx,
+			})
+		}
+
+		if errors.Is(err, errRole.ErrPermissionDenied) || errors.Is(err, errUser.ErrRoleOutranksYours) {
+			return response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusForbidden,
+				Err:   err,
+				Fiber: ctx,
+			})
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusUnprocessableEntity,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	ctx.Set(fiber.HeaderETag, util.ETag(user.Version))
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  user,
+		Fiber: ctx,
+	})
+}
+
+// Patch applies an RFC 7386 merge patch, only the members in the body are
+// changed.
+func (u *UserController) Patch(ctx *fiber.Ctx) error {
+	request := &dto.PatchUserRequest{}
+	uuid := ctx.Params("uuid")
+
+	fields, err := util.ParseMergePatch(ctx.Body(), request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errWrap.ErrValidationResponse(err)
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+	request.Fields = fields
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	versions, _ := ctx.Locals(constants.IfMatch).([]uint)
+	user, err := u.service.GetUser().Patch(ctx.Context(), request, uuid, versions)
 	if err != nil {
+		if errors.Is(err, errConstant.ErrPreconditionFailed) {
+			return u.preconditionFailed(ctx, err)
+		}
+
+		if isValidationError(err) {
+			return validationFailed(ctx, err)
+		}
+
+		if errors.Is(err, errUser.ErrUserNotFound) {
+			return response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusNotFound,
+				Err:   err,
+				Fiber: ctx,
+			})
+		}
+
+		if errors.Is(err, errRole.ErrPermissionDenied) || errors.Is(err, errUser.ErrRoleOutranksYours) {
+			return response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusForbidden,
+				Err:   err,
+				Fiber: ctx,
+			})
+		}
+
 		return response.HttpResponse(response.ParamHTTPResp{
 			Code:  http.StatusUnprocessableEntity,
 			Err:   err,
@@ -182,6 +433,8 @@ func (u *UserController) Update(ctx *fiber.Ctx) error {
 		})
 	}
 
+	ctx.Set(fiber.HeaderETag, util.ETag(user.Version))
+
 	return response.HttpResponse(response.ParamHTTPResp{
 		Code:  http.StatusOK,
 		Data:  user,
@@ -206,6 +459,13 @@ func (u *UserController) GetUserLogin(ctx *fiber.Ctx) error {
 	})
 }
 
+// GetJwks serves the public signing keys as a bare JWK set, verifiers
+// expect the standard document rather than the response envelope.
+func (u *UserController) GetJwks(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.Status(http.StatusOK).JSON(u.service.GetUser().GetJwks())
+}
+
 func (u *UserController) GetUserByUUID(ctx *fiber.Ctx) error {
 	user, err := u.service.GetUser().GetUserByUUID(ctx.Context(), ctx.Params("uuid"))
 	if err != nil {
@@ -216,9 +476,684 @@ func (u *UserController) GetUserByUUID(ctx *fiber.Ctx) error {
 		})
 	}
 
+	etag := util.ETag(user.Version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if ctx.Get(fiber.HeaderIfNoneMatch) == etag {
+		return ctx.SendStatus(http.StatusNotModified)
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  user,
+		Fiber: ctx,
+	})
+}
+
+// preconditionFailed answers a stale If-Match with the current user so the
+// client can merge and retry.
+func (u *UserController) preconditionFailed(ctx *fiber.Ctx, err error) error {
+	current, findErr := u.service.GetUser().GetUserByUUID(ctx.Context(), ctx.Params("uuid"))
+	if findErr == nil {
+		ctx.Set(fiber.HeaderETag, util.ETag(current.Version))
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusPreconditionFailed,
+		Err:   err,
+		Data:  current,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) GetAllWithPagination(ctx *fiber.Ctx) error {
+	var params dto.UserRequestParam
+	if err := ctx.QueryParser(&params); err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(params); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	result, err := u.service.GetUser().GetAllWithPagination(ctx.Context(), &params)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) Activate(ctx *fiber.Ctx) error {
+	return u.setActive(ctx, true)
+}
+
+// Deactivate blocks the user from logging in and ends their sessions.
+func (u *UserController) Deactivate(ctx *fiber.Ctx) error {
+	return u.setActive(ctx, false)
+}
+
+func (u *UserController) setActive(ctx *fiber.Ctx, active bool) error {
+	user, err := u.service.GetUser().SetActive(ctx.Context(), ctx.Params("uuid"), active)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  user,
+		Fiber: ctx,
+	})
+}
+
+// ResetPassword answers with a temporary password the user has to replace
+// after logging in with it.
+func (u *UserController) ResetPassword(ctx *fiber.Ctx) error {
+	result, err := u.service.GetUser().ResetPassword(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) AssignRole(ctx *fiber.Ctx) error {
+	request := &dto.AssignRoleRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	user, err := u.service.GetUser().AssignRole(ctx.Context(), ctx.Params("uuid"), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  user,
+		Fiber: ctx,
+	})
+}
+
+// Unlock lifts a login lockout on the user before it expires.
+func (u *UserController) Unlock(ctx *fiber.Ctx) error {
+	user, err := u.service.GetUser().Unlock(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
 	return response.HttpResponse(response.ParamHTTPResp{
 		Code:  http.StatusOK,
 		Data:  user,
 		Fiber: ctx,
 	})
 }
+
+func (u *UserController) ChangePassword(ctx *fiber.Ctx) error {
+	request := &dto.ChangePasswordRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	err := u.service.GetUser().ChangePassword(ctx.Context(), request)
+	if err != nil {
+		if isValidationError(err) {
+			return validationFailed(ctx, err)
+		}
+
+		setRetryAfter(ctx, err)
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+// ForgotPassword answers the same whether or not the email is registered.
+func (u *UserController) ForgotPassword(ctx *fiber.Ctx) error {
+	request := &dto.ForgotPasswordRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	err := u.service.GetUser().ForgotPassword(ctx.Context(), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusAccepted,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) ResetForgottenPassword(ctx *fiber.Ctx) error {
+	request := &dto.PasswordResetRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	err := u.service.GetUser().ResetForgottenPassword(ctx.Context(), request)
+	if err != nil {
+		if isValidationError(err) {
+			return validationFailed(ctx, err)
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+// VerifyTwoFactor trades a login challenge and a code for the tokens.
+func (u *UserController) VerifyTwoFactor(ctx *fiber.Ctx) error {
+	request := &dto.TwoFactorVerifyRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	request.ClientIP = ctx.IP()
+	request.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+	user, err := u.service.GetUser().VerifyTwoFactor(ctx.Context(), request)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+
+		switch {
+		case setRetryAfter(ctx, err):
+			statusCode = http.StatusTooManyRequests
+		case errors.Is(err, errUser.ErrChallengeInvalid),
+			errors.Is(err, errUser.ErrTwoFactorCodeIncorrect):
+			statusCode = http.StatusUnauthorized
+		}
+
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:    http.StatusOK,
+		Data:    user.User,
+		Token:   &user.Token,
+		Refresh: &user.RefreshToken,
+		Fiber:   ctx,
+	})
+}
+
+func (u *UserController) GetTwoFactor(ctx *fiber.Ctx) error {
+	result, err := u.service.GetUser().GetTwoFactor(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) SetupTwoFactor(ctx *fiber.Ctx) error {
+	request := &dto.TwoFactorSetupRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	result, err := u.service.GetUser().SetupTwoFactor(ctx.Context(), request)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) EnableTwoFactor(ctx *fiber.Ctx) error {
+	request := &dto.TwoFactorCodeRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	result, err := u.service.GetUser().EnableTwoFactor(ctx.Context(), request)
+	if err != nil {
+		setRetryAfter(ctx, err)
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) DisableTwoFactor(ctx *fiber.Ctx) error {
+	request := &dto.TwoFactorDisableRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	err := u.service.GetUser().DisableTwoFactor(ctx.Context(), request)
+	if err != nil {
+		setRetryAfter(ctx, err)
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	request := &dto.TwoFactorCodeRequest{}
+	if ok, err := parseBody(ctx, request); !ok {
+		return err
+	}
+
+	result, err := u.service.GetUser().RegenerateRecoveryCodes(ctx.Context(), request)
+	if err != nil {
+		setRetryAfter(ctx, err)
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) GetSessions(ctx *fiber.Ctx) error {
+	sessions, err := u.service.GetUser().GetSessions(ctx.Context())
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  sessions,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) RevokeSession(ctx *fiber.Ctx) error {
+	err := u.service.GetUser().RevokeSession(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) GetLoginHistory(ctx *fiber.Ctx) error {
+	params := &dto.LoginHistoryRequestParam{}
+	if ok, err := parseQuery(ctx, params); !ok {
+		return err
+	}
+
+	result, err := u.service.GetUser().GetLoginHistory(ctx.Context(), params)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) GetUserSessions(ctx *fiber.Ctx) error {
+	sessions, err := u.service.GetUser().GetUserSessions(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  sessions,
+		Fiber: ctx,
+	})
+}
+
+// Delete removes the user and ends their sessions.
+func (u *UserController) Delete(ctx *fiber.Ctx) error {
+	err := u.service.GetUser().Delete(ctx.Context(), ctx.Params("uuid"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) RevokeUserSession(ctx *fiber.Ctx) error {
+	err := u.service.GetUser().RevokeUserSession(ctx.Context(), ctx.Params("uuid"), ctx.Params("session"))
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) GetAllLoginHistory(ctx *fiber.Ctx) error {
+	params := &dto.LoginHistoryRequestParam{}
+	if ok, err := parseQuery(ctx, params); !ok {
+		return err
+	}
+
+	result, err := u.service.GetUser().GetAllLoginHistory(ctx.Context(), params)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) GetLoginHistoryWithCursor(ctx *fiber.Ctx) error {
+	params := &dto.LoginHistoryCursorParam{}
+	if ok, err := parseQuery(ctx, params); !ok {
+		return err
+	}
+
+	result, err := u.service.GetUser().GetLoginHistoryWithCursor(ctx.Context(), params)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	setCursorLink(ctx, result)
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+func (u *UserController) GetAllLoginHistoryWithCursor(ctx *fiber.Ctx) error {
+	params := &dto.LoginHistoryCursorParam{}
+	if ok, err := parseQuery(ctx, params); !ok {
+		return err
+	}
+
+	result, err := u.service.GetUser().GetAllLoginHistoryWithCursor(ctx.Context(), params)
+	if err != nil {
+		return response.HttpResponse(response.ParamHTTPResp{
+			Code:  statusCode(err),
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	setCursorLink(ctx, result)
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Fiber: ctx,
+	})
+}
+
+// setCursorLink points the Link header at the next and previous pages.
+func setCursorLink(ctx *fiber.Ctx, result *util.CursorResult) {
+	requestURL, err := url.Parse(ctx.OriginalURL())
+	if err != nil {
+		return
+	}
+
+	if link := util.CursorLinkHeader(requestURL, result.NextCursor, result.PreviousCursor); link != "" {
+		ctx.Set(fiber.HeaderLink, link)
+	}
+}
+
+// setRetryAfter sets the Retry-After header when err is a login lockout and
+// reports whether it was one.
+func setRetryAfter(ctx *fiber.Ctx, err error) bool {
+	var lockedError *errUser.LoginLockedError
+	if !errors.As(err, &lockedError) {
+		return false
+	}
+
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(lockedError.RetryAfter.Seconds()))))
+	return true
+}
+
+// parseBody decodes and validates the request body. When it reports false
+// the error response has already been written and the handler must return.
+func parseBody(ctx *fiber.Ctx, request interface{}) (bool, error) {
+	err := ctx.BodyParser(request)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		statusCode := http.StatusUnprocessableEntity
+
+		if errors.As(err, &syntaxError) {
+			statusCode = http.StatusBadRequest
+		}
+
+		errMessage := http.StatusText(statusCode)
+		errResponse := errWrap.ErrValidationResponse(err)
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    statusCode,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err = validate.Struct(request); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	return true, nil
+}
+
+// parseQuery is parseBody for query parameters.
+func parseQuery(ctx *fiber.Ctx, params interface{}) (bool, error) {
+	if err := ctx.QueryParser(params); err != nil {
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Err:   err,
+			Fiber: ctx,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(params); err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		return false, response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Err:     err,
+			Fiber:   ctx,
+		})
+	}
+
+	return true, nil
+}
+
+// isValidationError reports whether the service rejected a field, like a
+// password that does not meet the policy.
+func isValidationError(err error) bool {
+	var validationErrors errWrap.ValidationErrors
+	return errors.As(err, &validationErrors)
+}
+
+func validationFailed(ctx *fiber.Ctx, err error) error {
+	errMessage := http.StatusText(http.StatusUnprocessableEntity)
+	return response.HttpResponse(response.ParamHTTPResp{
+		Code:    http.StatusUnprocessableEntity,
+		Message: &errMessage,
+		Data:    errWrap.ErrValidationResponse(err),
+		Err:     err,
+		Fiber:   ctx,
+	})
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, errUser.ErrUserNotFound),
+		errors.Is(err, errUser.ErrSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errUser.ErrCannotManageSelf),
+		errors.Is(err, errUser.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, errUser.ErrTwoFactorNotEnabled),
+		errors.Is(err, errUser.ErrTwoFactorNotSetUp),
+		errors.Is(err, errUser.ErrTwoFactorRequired):
+		return http.StatusConflict
+	case errors.Is(err, errUser.ErrRoleOutranksYours),
+		errors.Is(err, errRole.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, errUser.ErrLoginLocked):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errUser.ErrPasswordIncorrect),
+		errors.Is(err, errUser.ErrPasswordDoesNotMatch),
+		errors.Is(err, errUser.ErrPasswordReused),
+		errors.Is(err, errUser.ErrTwoFactorCodeIncorrect),
+		errors.Is(err, errUser.ErrPasswordResetTokenInvalid),
+		errors.Is(err, errRole.ErrRoleNotFound),
+		errors.Is(err, errConstant.ErrInvalidCursor):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errConstant.ErrUnauthorized):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,395 @@
+package audit
+
+import (
+	"backend/constants"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+
+	snapshotKey = "audit:snapshot"
+	redacted    = "[redacted]"
+)
+
+// Plugin writes an audit log entry for every row of an Auditable model that
+// a create, update or delete changed. Rows are read before and after the
+// statement inside its transaction, so bulk updates are covered and a
+// rolled back change leaves no entry behind.
+type Plugin struct{}
+
+func New() gorm.Plugin {
+	return &Plugin{}
+}
+
+func (p *Plugin) Name() string {
+	return "audit"
+}
+
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	create := db.Callback().Create()
+	err := create.Before("gorm:create").Register("audit:before_create", before(ActionCreate))
+	if err != nil {
+		return err
+	}
+
+	err = create.After("gorm:create").Before("gorm:commit_or_rollback_transaction").Register("audit:after_create", after(ActionCreate))
+	if err != nil {
+		return err
+	}
+
+	update := db.Callback().Update()
+	err = update.Before("gorm:update").Register("audit:before_update", before(ActionUpdate))
+	if err != nil {
+		return err
+	}
+
+	err = update.After("gorm:update").Before("gorm:commit_or_rollback_transaction").Register("audit:after_update", after(ActionUpdate))
+	if err != nil {
+		return err
+	}
+
+	remove := db.Callback().Delete()
+	err = remove.Before("gorm:delete").Register("audit:before_delete", before(ActionDelete))
+	if err != nil {
+		return err
+	}
+
+	return remove.After("gorm:delete").Before("gorm:commit_or_rollback_transaction").Register("audit:after_delete", after(ActionDelete))
+}
+
+// row is the audited state of one record, values only holds the columns
+// that are audited.
+type row struct {
+	primaryKey []interface{}
+	entityID   string
+	values     map[string]interface{}
+}
+
+type change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// before remembers the rows the statement is about to change. A create only
+// looks for existing rows when its primary key is given, like the join rows
+// of a many2many association.
+func before(action string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || !auditable(db.Statement) {
+			return
+		}
+
+		if action == ActionUpdate && onlyIgnoredColumns(db.Statement) {
+			return
+		}
+
+		conditions := primaryKeyConditions(db.Statement, db.Statement.ReflectValue)
+		if action != ActionCreate {
+			conditions = append(whereConditions(db.Statement), conditions...)
+		}
+
+		rows := []row{}
+		if action != ActionCreate || len(conditions) > 0 {
+			var err error
+			rows, err = load(db, conditions)
+			if err != nil {
+				_ = db.AddError(err)
+				return
+			}
+		}
+
+		db.InstanceSet(snapshotKey, rows)
+	}
+}
+
+// after reads the remembered rows, and the created ones, again and logs
+// every row whose audited columns differ.
+func after(action string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || !auditable(db.Statement) {
+			return
+		}
+
+		value, ok := db.InstanceGet(snapshotKey)
+		if !ok {
+			return
+		}
+
+		stmt := db.Statement
+		beforeRows := map[string]row{}
+		var keys []string
+		var primaryKeys [][]interface{}
+		for _, r := range value.([]row) {
+			key := keyOf(r.primaryKey)
+			beforeRows[key] = r
+			keys = append(keys, key)
+			primaryKeys = append(primaryKeys, r.primaryKey)
+		}
+
+		if action == ActionCreate {
+			_, created := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+			for _, primaryKey := range created {
+				key := keyOf(primaryKey)
+				if _, ok := beforeRows[key]; !ok {
+					keys = append(keys, key)
+					primaryKeys = append(primaryKeys, primaryKey)
+				}
+			}
+		}
+
+		if len(keys) == 0 {
+			return
+		}
+
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, primaryKeys)
+		rows, err := load(db, []clause.Expression{clause.IN{Column: column, Values: values}})
+		if err != nil {
+			_ = db.AddError(err)
+			return
+		}
+
+		afterRows := map[string]row{}
+		for _, r := range rows {
+			afterRows[keyOf(r.primaryKey)] = r
+		}
+
+		entity := stmt.Schema.ModelType.Name()
+		if model, ok := reflect.New(stmt.Schema.ModelType).Interface().(models.Auditable); ok {
+			entity = model.AuditEntity()
+		}
+
+		var logs []models.AuditLog
+		for _, key := range keys {
+			log, ok := entry(stmt, action, beforeRows[key], afterRows[key])
+			if !ok {
+				continue
+			}
+
+			log.Entity = entity
+			logs = append(logs, log)
+		}
+
+		if len(logs) == 0 {
+			return
+		}
+
+		err = db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&logs).Error
+		if err != nil {
+			_ = db.AddError(err)
+		}
+	}
+}
+
+// entry builds the log of one row, ok is false when nothing audited changed.
+func entry(stmt *gorm.Statement, action string, before, after row) (models.AuditLog, bool) {
+	switch {
+	case before.values == nil && after.values == nil:
+		return models.AuditLog{}, false
+	case before.values == nil:
+		action = ActionCreate
+	case after.values == nil:
+		action = ActionDelete
+	case action == ActionCreate:
+		// An upsert that hit an existing row.
+		action = ActionUpdate
+	}
+
+	changes := diff(stmt.Schema, before.values, after.values, action == ActionCreate)
+	if len(changes) == 0 {
+		return models.AuditLog{}, false
+	}
+
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return models.AuditLog{}, false
+	}
+
+	log := models.AuditLog{
+		Action:    action,
+		EntityID:  after.entityID,
+		Changes:   encoded,
+		IPAddress: contextString(stmt, constants.ClientIP),
+		RequestID: contextString(stmt, constants.RequestID),
+	}
+	if log.EntityID == "" {
+		log.EntityID = before.entityID
+	}
+
+	if user, ok := stmt.Context.Value(constants.UserLogin).(*dto.UserResponse); ok && user != nil {
+		actor := user.UUID
+		log.ActorUUID = &actor
+		log.ActorUsername = user.Username
+	}
+
+	if client, ok := stmt.Context.Value(constants.ApiClient).(*models.ApiClient); ok && client != nil {
+		log.ApiClient = client.KeyID
+	}
+
+	return log, true
+}
+
+// diff compares the audited columns. The update time is left out of updates
+// since it changes with every one of them.
+func diff(s *schema.Schema, before, after map[string]interface{}, created bool) map[string]change {
+	changes := map[string]change{}
+	for _, field := range s.Fields {
+		if !audited(field) || (!created && field.AutoUpdateTime > 0) {
+			continue
+		}
+
+		oldValue, newValue := before[field.DBName], after[field.DBName]
+		oldJSON, _ := json.Marshal(oldValue)
+		newJSON, _ := json.Marshal(newValue)
+		if bytes.Equal(oldJSON, newJSON) {
+			continue
+		}
+
+		if field.Tag.Get("audit") == "redact" {
+			oldValue, newValue = redact(oldValue), redact(newValue)
+		}
+
+		changes[field.DBName] = change{Before: oldValue, After: newValue}
+	}
+
+	return changes
+}
+
+// load reads the rows matching conditions, archived ones included.
+func load(db *gorm.DB, conditions []clause.Expression) ([]row, error) {
+	stmt := db.Statement
+	records := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+
+	query := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+		Unscoped().
+		Table(stmt.Table)
+	if len(conditions) > 0 {
+		query = query.Clauses(clause.Where{Exprs: conditions})
+	}
+
+	err := query.Find(records.Interface()).Error
+	if err != nil {
+		return nil, err
+	}
+
+	uuidField := stmt.Schema.LookUpField("uuid")
+	rows := make([]row, 0, records.Elem().Len())
+	for i := 0; i < records.Elem().Len(); i++ {
+		record := records.Elem().Index(i)
+		r := row{values: map[string]interface{}{}}
+
+		for _, field := range stmt.Schema.PrimaryFields {
+			value, _ := field.ValueOf(stmt.Context, record)
+			r.primaryKey = append(r.primaryKey, value)
+		}
+
+		r.entityID = keyOf(r.primaryKey)
+		if uuidField != nil {
+			value, _ := uuidField.ValueOf(stmt.Context, record)
+			r.entityID = fmt.Sprint(value)
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if audited(field) {
+				r.values[field.DBName], _ = field.ValueOf(stmt.Context, record)
+			}
+		}
+
+		rows = append(rows, r)
+	}
+
+	return rows, nil
+}
+
+func auditable(stmt *gorm.Statement) bool {
+	if stmt.Schema == nil || len(stmt.Schema.PrimaryFields) == 0 {
+		return false
+	}
+
+	_, ok := reflect.New(stmt.Schema.ModelType).Interface().(models.Auditable)
+	return ok
+}
+
+func audited(field *schema.Field) bool {
+	return field.DBName != "" && field.Tag.Get("audit") != "-"
+}
+
+// onlyIgnoredColumns reports whether an update only sets columns that are not
+// audited, like the last seen time, so it can skip reading the rows.
+func onlyIgnoredColumns(stmt *gorm.Statement) bool {
+	values, ok := stmt.Dest.(map[string]interface{})
+	if !ok || len(values) == 0 {
+		return false
+	}
+
+	for column := range values {
+		field := stmt.Schema.LookUpField(column)
+		if field == nil || (audited(field) && field.AutoUpdateTime == 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func whereConditions(stmt *gorm.Statement) []clause.Expression {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+
+	return append([]clause.Expression(nil), where.Exprs...)
+}
+
+// primaryKeyConditions matches the records in value by primary key, the way
+// gorm narrows an update or delete of a loaded model.
+func primaryKeyConditions(stmt *gorm.Statement, value reflect.Value) []clause.Expression {
+	if !value.IsValid() {
+		return nil
+	}
+
+	_, primaryKeys := schema.GetIdentityFieldValuesMap(stmt.Context, value, stmt.Schema.PrimaryFields)
+	if len(primaryKeys) == 0 {
+		return nil
+	}
+
+	column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, primaryKeys)
+	return []clause.Expression{clause.IN{Column: column, Values: values}}
+}
+
+func keyOf(primaryKey []interface{}) string {
+	parts := make([]string, 0, len(primaryKey))
+	for _, value := range primaryKey {
+		parts = append(parts, fmt.Sprint(value))
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func redact(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+
+	return redacted
+}
+
+func contextString(stmt *gorm.Statement, key string) string {
+	value, _ := stmt.Context.Value(key).(string)
+	return value
+}
@@ -0,0 +1,267 @@
+package audit
+
+import (
+	"backend/constants"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestDB returns a fresh SQLite database with the audit plugin in use.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Terminal{}, &models.Product{}, &models.AuditLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if err = db.Use(New()); err != nil {
+		t.Fatalf("use audit plugin: %v", err)
+	}
+
+	return db
+}
+
+// auditLogs returns the logged entries in order with their changes decoded.
+func auditLogs(t *testing.T, db *gorm.DB) ([]models.AuditLog, []map[string]change) {
+	t.Helper()
+
+	var logs []models.AuditLog
+	if err := db.Order("id").Find(&logs).Error; err != nil {
+		t.Fatalf("find audit logs: %v", err)
+	}
+
+	changes := make([]map[string]change, len(logs))
+	for i, log := range logs {
+		if err := json.Unmarshal(log.Changes, &changes[i]); err != nil {
+			t.Fatalf("decode changes of entry %d: %v", i, err)
+		}
+	}
+
+	return logs, changes
+}
+
+func TestDiff(t *testing.T) {
+	s, err := schema.Parse(&models.Terminal{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("parse schema: %v", err)
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	later := now.Add(time.Hour)
+	terminal := map[string]interface{}{"id": uint(1), "name": "Kasir 1", "key_hash": "old", "updated_at": &now}
+
+	with := func(column string, value interface{}) map[string]interface{} {
+		values := map[string]interface{}{}
+		for key, v := range terminal {
+			values[key] = v
+		}
+		values[column] = value
+		return values
+	}
+
+	tests := []struct {
+		name    string
+		before  map[string]interface{}
+		after   map[string]interface{}
+		created bool
+		want    map[string]change
+	}{
+		{name: "nothing changed", before: terminal, after: terminal, want: map[string]change{}},
+		{name: "renamed", before: terminal, after: with("name", "Kasir 2"), want: map[string]change{"name": {Before: "Kasir 1", After: "Kasir 2"}}},
+		{name: "redacted column", before: terminal, after: with("key_hash", "new"), want: map[string]change{"key_hash": {Before: redacted, After: redacted}}},
+		{name: "update time left out of updates", before: terminal, after: with("updated_at", &later), want: map[string]change{}},
+		{name: "ignored column", before: terminal, after: with("last_seen_at", &later), want: map[string]change{}},
+		{
+			name:    "created",
+			after:   terminal,
+			created: true,
+			want: map[string]change{
+				"id":         {After: uint(1)},
+				"name":       {After: "Kasir 1"},
+				"key_hash":   {After: redacted},
+				"updated_at": {After: &now},
+			},
+		},
+		{
+			name:   "deleted",
+			before: terminal,
+			want: map[string]change{
+				"id":       {Before: uint(1)},
+				"name":     {Before: "Kasir 1"},
+				"key_hash": {Before: redacted},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diff(s, tt.before, tt.after, tt.created)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	var none *string
+	secret := "secret"
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "value", value: "secret", want: redacted},
+		{name: "pointer", value: &secret, want: redacted},
+		{name: "nil", value: nil, want: nil},
+		{name: "nil pointer", value: none, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redact(tt.value); got != tt.want {
+				t.Errorf("redact(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	db := newTestDB(t)
+	actor := &dto.UserResponse{UUID: uuid.New(), Username: "budi"}
+	ctx := context.WithValue(context.Background(), constants.UserLogin, actor)
+	ctx = context.WithValue(ctx, constants.RequestID, "request-1")
+	ctx = context.WithValue(ctx, constants.ClientIP, "10.0.0.1")
+	tx := db.WithContext(ctx)
+
+	till := &models.Terminal{UUID: uuid.New(), Name: "Kasir 1", KeyHash: "hash-1"}
+	other := &models.Terminal{UUID: uuid.New(), Name: "Kasir 2", KeyHash: "hash-2"}
+	if err := tx.Create(&[]*models.Terminal{till, other}).Error; err != nil {
+		t.Fatalf("create terminals: %v", err)
+	}
+
+	now := time.Now()
+	if err := tx.Model(till).Update("last_seen_at", &now).Error; err != nil {
+		t.Fatalf("update last seen: %v", err)
+	}
+	if err := tx.Model(till).Update("name", "Kasir Depan").Error; err != nil {
+		t.Fatalf("rename terminal: %v", err)
+	}
+	if err := tx.Model(&models.Terminal{}).Where("name LIKE ?", "Kasir%").Update("revoked_at", &now).Error; err != nil {
+		t.Fatalf("revoke terminals: %v", err)
+	}
+
+	rollback := errors.New("roll back")
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(other).Update("name", "Kasir Belakang").Error; err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Transaction = %v, want %v", err, rollback)
+	}
+
+	if err = tx.Delete(other).Error; err != nil {
+		t.Fatalf("delete terminal: %v", err)
+	}
+
+	logs, changes := auditLogs(t, db)
+
+	type logged struct {
+		action   string
+		entityID string
+		columns  []string
+	}
+	want := []logged{
+		{action: ActionCreate, entityID: till.UUID.String(), columns: []string{"created_at", "id", "key_hash", "name", "updated_at", "uuid"}},
+		{action: ActionCreate, entityID: other.UUID.String(), columns: []string{"created_at", "id", "key_hash", "name", "updated_at", "uuid"}},
+		{action: ActionUpdate, entityID: till.UUID.String(), columns: []string{"name"}},
+		{action: ActionUpdate, entityID: till.UUID.String(), columns: []string{"revoked_at"}},
+		{action: ActionUpdate, entityID: other.UUID.String(), columns: []string{"revoked_at"}},
+		{action: ActionDelete, entityID: other.UUID.String(), columns: []string{"created_at", "id", "key_hash", "name", "revoked_at", "uuid"}},
+	}
+
+	var got []logged
+	for i, log := range logs {
+		var columns []string
+		for _, field := range []string{"created_at", "id", "key_hash", "name", "revoked_at", "updated_at", "uuid", "last_seen_at"} {
+			if _, ok := changes[i][field]; ok {
+				columns = append(columns, field)
+			}
+		}
+		got = append(got, logged{action: log.Action, entityID: log.EntityID, columns: columns})
+
+		if log.Entity != "terminal" || log.ActorUsername != "budi" || log.ActorUUID == nil || *log.ActorUUID != actor.UUID {
+			t.Errorf("entry %d = %+v, want the terminal changed by budi", i, log)
+		}
+		if log.RequestID != "request-1" || log.IPAddress != "10.0.0.1" {
+			t.Errorf("entry %d request = %q from %q", i, log.RequestID, log.IPAddress)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("audit logs = %+v, want %+v", got, want)
+	}
+
+	if key := changes[0]["key_hash"]; key.Before != nil || key.After != redacted {
+		t.Errorf("created key hash = %+v, want it redacted", key)
+	}
+	if name := changes[2]["name"]; name.Before != "Kasir 1" || name.After != "Kasir Depan" {
+		t.Errorf("renamed = %+v", name)
+	}
+}
+
+func TestPluginSoftDelete(t *testing.T) {
+	db := newTestDB(t)
+
+	product := &models.Product{UUID: uuid.New(), Code: "P-1", Name: "Teh", PriceBuy: 3000, PriceSale: 5000, Stock: 10, Unit: "pcs"}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if err := db.Delete(product).Error; err != nil {
+		t.Fatalf("delete product: %v", err)
+	}
+
+	logs, changes := auditLogs(t, db)
+	if len(logs) != 2 {
+		t.Fatalf("logged %d entries, want 2", len(logs))
+	}
+
+	// An archived row is still there, only its deletion time is set.
+	deleted, ok := changes[1]["deleted_at"]
+	if logs[1].Action != ActionDelete || !ok || deleted.Before != nil || deleted.After == nil || len(changes[1]) != 1 {
+		t.Errorf("soft delete logged %s with %+v", logs[1].Action, changes[1])
+	}
+	if logs[1].ActorUUID != nil || logs[1].ActorUsername != "" {
+		t.Errorf("change without a user logged actor %v %q", logs[1].ActorUUID, logs[1].ActorUsername)
+	}
+}
@@ -0,0 +1,41 @@
+-- The schema as the first release created it with AutoMigrate. Databases
+-- from that release already have these tables and skip them, every later
+-- change comes in its own migration.
+
+CREATE TABLE IF NOT EXISTS "roles" (
+    "id" bigserial,
+    "code" text NOT NULL,
+    "name" text NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "uuid" uuid NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "username" varchar(20) NOT NULL,
+    "password" varchar(255) NOT NULL,
+    "phone_number" varchar(15) NOT NULL,
+    "email" varchar(100) NOT NULL,
+    "role_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_role" FOREIGN KEY ("role_id") REFERENCES "roles" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "products" (
+    "id" bigserial,
+    "uuid" uuid NOT NULL,
+    "code" varchar(100),
+    "name" varchar(255) NOT NULL,
+    "price_buy" bigint NOT NULL,
+    "price_sale" bigint NOT NULL,
+    "stock" bigint NOT NULL,
+    "unit" varchar(100) NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
@@ -0,0 +1,2 @@
+ALTER TABLE "products" ADD COLUMN IF NOT EXISTS "image" varchar(255);
+ALTER TABLE "products" ADD COLUMN IF NOT EXISTS "thumbnail" varchar(255);
@@ -0,0 +1,3 @@
+-- Existing rows start at version 1 through the default.
+ALTER TABLE "products" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
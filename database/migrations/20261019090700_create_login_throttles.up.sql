@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "login_throttles" (
+    "id" bigserial,
+    "scope" varchar(10) NOT NULL,
+    "subject" varchar(255) NOT NULL,
+    "failures" bigint NOT NULL DEFAULT 0,
+    "last_failed_at" timestamptz NOT NULL,
+    "locked_until" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_login_throttle_subject" ON "login_throttles" ("scope", "subject");
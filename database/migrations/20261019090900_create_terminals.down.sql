@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "idx_sessions_terminal_id";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "terminal_id";
+DROP TABLE IF EXISTS "terminal_pins";
+DROP TABLE IF EXISTS "terminals";
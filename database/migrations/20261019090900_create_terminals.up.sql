@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS "terminals" (
+    "id" bigserial,
+    "uuid" uuid NOT NULL,
+    "name" varchar(50) NOT NULL,
+    "key_hash" varchar(64) NOT NULL,
+    "last_seen_at" timestamptz,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_terminals_uuid" ON "terminals" ("uuid");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_terminals_key_hash" ON "terminals" ("key_hash");
+
+CREATE TABLE IF NOT EXISTS "terminal_pins" (
+    "id" bigserial,
+    "terminal_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "pin_hash" varchar(255) NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_terminal_pins_terminal" FOREIGN KEY ("terminal_id") REFERENCES "terminals" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_terminal_pins_user" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_terminal_pin_user" ON "terminal_pins" ("terminal_id", "user_id");
+
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "terminal_id" bigint;
+CREATE INDEX IF NOT EXISTS "idx_sessions_terminal_id" ON "sessions" ("terminal_id");
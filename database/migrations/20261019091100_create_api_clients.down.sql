@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "api_nonces";
+DROP TABLE IF EXISTS "api_client_secrets";
+DROP TABLE IF EXISTS "api_clients";
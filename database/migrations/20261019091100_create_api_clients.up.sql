@@ -0,0 +1,38 @@
+-- The default client is seeded from SIGNATURE_KEY.
+
+CREATE TABLE IF NOT EXISTS "api_clients" (
+    "id" bigserial,
+    "uuid" uuid NOT NULL,
+    "name" varchar(50) NOT NULL,
+    "key_id" varchar(64) NOT NULL,
+    "last_used_at" timestamptz,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_clients_uuid" ON "api_clients" ("uuid");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_clients_key_id" ON "api_clients" ("key_id");
+
+CREATE TABLE IF NOT EXISTS "api_client_secrets" (
+    "id" bigserial,
+    "api_client_id" bigint NOT NULL,
+    "secret" varchar(255) NOT NULL,
+    "expires_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_api_clients_secrets" FOREIGN KEY ("api_client_id") REFERENCES "api_clients" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_api_client_secrets_api_client_id" ON "api_client_secrets" ("api_client_id");
+
+CREATE TABLE IF NOT EXISTS "api_nonces" (
+    "id" bigserial,
+    "api_client_id" bigint NOT NULL,
+    "nonce" varchar(64) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_api_nonces_api_client" FOREIGN KEY ("api_client_id") REFERENCES "api_clients" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_nonce_client" ON "api_nonces" ("api_client_id", "nonce");
+CREATE INDEX IF NOT EXISTS "idx_api_nonces_expires_at" ON "api_nonces" ("expires_at");
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "login_histories";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "user_agent";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "ip_address";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "device_name";
@@ -0,0 +1,24 @@
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "device_name" varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "ip_address" varchar(45) NOT NULL DEFAULT '';
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "user_agent" varchar(255) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS "login_histories" (
+    "id" bigserial,
+    "user_id" bigint,
+    "session_id" bigint,
+    "username" varchar(50) NOT NULL,
+    "method" varchar(20) NOT NULL,
+    "success" boolean NOT NULL,
+    "reason" varchar(100) NOT NULL DEFAULT '',
+    "device_name" varchar(100) NOT NULL DEFAULT '',
+    "ip_address" varchar(45) NOT NULL DEFAULT '',
+    "user_agent" varchar(255) NOT NULL DEFAULT '',
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_login_histories_user" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE SET NULL ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_login_histories_user_id" ON "login_histories" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_login_histories_session_id" ON "login_histories" ("session_id");
+CREATE INDEX IF NOT EXISTS "idx_login_histories_username" ON "login_histories" ("username");
+CREATE INDEX IF NOT EXISTS "idx_login_histories_ip_address" ON "login_histories" ("ip_address");
+CREATE INDEX IF NOT EXISTS "idx_login_histories_created_at" ON "login_histories" ("created_at");
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" bigserial,
+    "action" varchar(10) NOT NULL,
+    "entity" varchar(50) NOT NULL,
+    "entity_id" varchar(64) NOT NULL,
+    "actor_uuid" uuid,
+    "actor_username" varchar(20) NOT NULL DEFAULT '',
+    "api_client" varchar(64) NOT NULL DEFAULT '',
+    "ip_address" varchar(45) NOT NULL DEFAULT '',
+    "request_id" varchar(64) NOT NULL DEFAULT '',
+    "changes" jsonb NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_log_entity" ON "audit_logs" ("entity", "entity_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_actor_uuid" ON "audit_logs" ("actor_uuid");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_request_id" ON "audit_logs" ("request_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_created_at" ON "audit_logs" ("created_at");
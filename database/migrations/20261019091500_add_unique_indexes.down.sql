@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "idx_products_code";
+DROP INDEX IF EXISTS "idx_products_uuid";
+DROP INDEX IF EXISTS "idx_users_email";
+DROP INDEX IF EXISTS "idx_users_username";
+DROP INDEX IF EXISTS "idx_users_uuid";
+DROP INDEX IF EXISTS "idx_roles_code";
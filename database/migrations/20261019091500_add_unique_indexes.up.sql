@@ -0,0 +1,13 @@
+-- AutoMigrate never enforced these, the services only checked before
+-- inserting. Creating an index fails when duplicates slipped in, they have
+-- to be cleaned up by hand first. Products without a code store an empty
+-- one, so only real codes have to be unique, archived products included.
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_roles_code" ON "roles" ("code");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_uuid" ON "users" ("uuid");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_products_uuid" ON "products" ("uuid");
+
+UPDATE "products" SET "code" = '' WHERE "code" IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_products_code" ON "products" ("code") WHERE "code" <> '';
@@ -0,0 +1,332 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// versionTable records the applied migrations, one row per version.
+const versionTable = "schema_migrations"
+
+//go:embed *.sql
+var files embed.FS
+
+var (
+	fileName      = regexp.MustCompile(`^(\d{14})_([a-z0-9_]+)\.(up|down)\.sql$`)
+	migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+	ErrIrreversible = errors.New("migration has no down file")
+)
+
+// Migration is a pair of <version>_<name>.up.sql and .down.sql files. The
+// version is the time the migration was created, so they sort in order.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Status is a migration together with the time it was applied. Missing is
+// set for versions the database has but this build does not know about.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	Missing   bool
+}
+
+type IMigrator interface {
+	Up(ctx context.Context, steps int) ([]Migration, error)
+	Down(ctx context.Context, steps int) ([]Migration, error)
+	Pending(ctx context.Context) ([]Migration, error)
+	Status(ctx context.Context) ([]Status, error)
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator reads the migrations embedded in the binary.
+func NewMigrator(db *sql.DB) (IMigrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies the pending migrations oldest first, all of them when steps is
+// zero. Every migration runs in its own transaction together with its
+// version row, so a failed one leaves nothing behind and a concurrent run
+// fails on the version's primary key. The applied migrations are returned
+// even when a later one failed.
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+
+	for i, migration := range pending {
+		err = m.run(ctx, migration.Up,
+			`INSERT INTO `+versionTable+` (version, name, applied_at) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, time.Now(),
+		)
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %s: %w", migration, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// Down reverts the last applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Status
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if statuses[i].AppliedAt != nil {
+			applied = append(applied, statuses[i])
+		}
+	}
+
+	if steps > 0 && steps < len(applied) {
+		applied = applied[:steps]
+	}
+
+	reverted := make([]Migration, 0, len(applied))
+	for _, status := range applied {
+		if status.Missing {
+			return reverted, fmt.Errorf("migration %s: not part of this build", status.Migration)
+		}
+
+		if strings.TrimSpace(status.Down) == "" {
+			return reverted, fmt.Errorf("migration %s: %w", status.Migration, ErrIrreversible)
+		}
+
+		err = m.run(ctx, status.Down, `DELETE FROM `+versionTable+` WHERE version = $1`, status.Version)
+		if err != nil {
+			return reverted, fmt.Errorf("migration %s: %w", status.Migration, err)
+		}
+
+		reverted = append(reverted, status.Migration)
+	}
+
+	return reverted, nil
+}
+
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Status lists every known and every applied migration by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, row := range applied {
+		statuses = append(statuses, row)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]Status, error) {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+versionTable+` (
+		version bigint PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, name, applied_at FROM `+versionTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]Status{}
+	for rows.Next() {
+		var status Status
+		var appliedAt time.Time
+		err = rows.Scan(&status.Version, &status.Name, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		status.AppliedAt = &appliedAt
+		status.Missing = true
+		applied[status.Version] = status
+	}
+
+	return applied, rows.Err()
+}
+
+// run executes a migration script and the statement that records it in one
+// transaction.
+func (m *Migrator) run(ctx context.Context, script, record string, args ...interface{}) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(script) != "" {
+		_, err = tx.ExecContext(ctx, script)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+
+		var version int64
+		_, _ = fmt.Sscan(match[1], &version)
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", entry.Name(), version, migration)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %s: up file is missing or empty", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Create writes an empty up and down file for a new migration into dir and
+// returns their paths. They are only embedded after the binary is rebuilt.
+func Create(dir, name string, now time.Time) ([]string, error) {
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "-", "_"))
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q, use letters, digits and underscores", name)
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	version := now.UTC().Format("20060102150405")
+	matches, err := filepath.Glob(filepath.Join(dir, version+"_*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) > 0 {
+		return nil, fmt.Errorf("a migration with version %s already exists", version)
+	}
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		err = os.WriteFile(path, []byte("-- "+direction+" migration for "+name+"\n"), 0o644)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
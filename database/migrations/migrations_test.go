@@ -0,0 +1,202 @@
+package migrations
+
+import (
+	"context"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := load(files)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations are embedded")
+	}
+
+	for _, migration := range migrations {
+		if strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %s has no down file", migration)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"20260102000000_add_stock.up.sql":       file("ALTER 2"),
+				"20260102000000_add_stock.down.sql":     file("REVERT 2"),
+				"20260101000000_create_schema.up.sql":   file("CREATE 1"),
+				"20260101000000_create_schema.down.sql": file("DROP 1"),
+				"20260103000000_backfill.up.sql":        file("UPDATE 3"),
+				"README.md":                             file("not a migration"),
+				"archive/20251201000000_old.up.sql":     file("OLD"),
+			},
+			want: []Migration{
+				{Version: 20260101000000, Name: "create_schema", Up: "CREATE 1", Down: "DROP 1"},
+				{Version: 20260102000000, Name: "add_stock", Up: "ALTER 2", Down: "REVERT 2"},
+				{Version: 20260103000000, Name: "backfill", Up: "UPDATE 3"},
+			},
+		},
+		{
+			name:    "badly named file",
+			fsys:    fstest.MapFS{"create_schema.up.sql": file("CREATE")},
+			wantErr: "expected <version>_<name>.up.sql or .down.sql",
+		},
+		{
+			name: "version used twice",
+			fsys: fstest.MapFS{
+				"20260101000000_create_schema.up.sql": file("CREATE"),
+				"20260101000000_add_stock.up.sql":     file("ALTER"),
+			},
+			wantErr: "is already used by",
+		},
+		{
+			name:    "down file only",
+			fsys:    fstest.MapFS{"20260101000000_create_schema.down.sql": file("DROP")},
+			wantErr: "up file is missing or empty",
+		},
+		{
+			name:    "empty up file",
+			fsys:    fstest.MapFS{"20260101000000_create_schema.up.sql": file(" \n")},
+			wantErr: "up file is missing or empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := load(tt.fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("load = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	now := time.Date(2026, 10, 19, 9, 30, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+	paths, err := Create(dir, " Add-Stock ", now)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// The version is the UTC time, so it does not depend on the zone.
+	want := []string{
+		filepath.Join(dir, "20261019023000_add_stock.up.sql"),
+		filepath.Join(dir, "20261019023000_add_stock.down.sql"),
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Create = %q, want %q", paths, want)
+	}
+
+	migrations, err := load(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("load the created files: %v", err)
+	}
+	if len(migrations) != 1 || migrations[0].Name != "add_stock" || migrations[0].Down == "" {
+		t.Errorf("load the created files = %+v", migrations)
+	}
+
+	if _, err = Create(dir, "other", now); err == nil {
+		t.Error("Create with a version in use succeeded")
+	}
+
+	for _, name := range []string{"", "add stock", "tambah_stok!"} {
+		if _, err = Create(dir, name, now.Add(time.Minute)); err == nil {
+			t.Errorf("Create(%q) succeeded", name)
+		}
+	}
+}
+
+// TestUpDown applies and reverts every migration on the empty PostgreSQL
+// database at TEST_DATABASE_URL, and is skipped when it is not set.
+func TestUpDown(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	migrator, err := NewMigrator(sqlDB)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+
+	ctx := context.Background()
+	all, err := migrator.Pending(ctx)
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if len(all) == 0 {
+		t.Fatal("the database already has every migration applied, use an empty one")
+	}
+
+	// Up and down twice, so every down file leaves a schema its up file
+	// applies to again.
+	for round := 1; round <= 2; round++ {
+		applied, err := migrator.Up(ctx, 0)
+		if err != nil {
+			t.Fatalf("round %d: Up: %v", round, err)
+		}
+		if len(applied) != len(all) {
+			t.Errorf("round %d: Up applied %d migrations, want %d", round, len(applied), len(all))
+		}
+
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			t.Fatalf("round %d: Pending: %v", round, err)
+		}
+		if len(pending) != 0 {
+			t.Errorf("round %d: pending after Up = %v", round, pending)
+		}
+
+		reverted, err := migrator.Down(ctx, 0)
+		if err != nil {
+			t.Fatalf("round %d: Down: %v", round, err)
+		}
+		if len(reverted) != len(all) {
+			t.Errorf("round %d: Down reverted %d migrations, want %d", round, len(reverted), len(all))
+		}
+	}
+}
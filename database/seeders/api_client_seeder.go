@@ -0,0 +1,44 @@
+package seeders
+
+import (
+	"backend/config"
+	"backend/domain/models"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// defaultApiClientKeyID names the client seeded from SIGNATURE_KEY.
+const defaultApiClientKeyID = "default"
+
+// RunApiClientSeeder turns SIGNATURE_KEY into the secret of a "default" API
+// client so existing deployments have a client to sign with. It is only
+// created once, after that its secret is rotated through the API.
+func RunApiClientSeeder(db *gorm.DB) {
+	if config.Config.SignatureKey == "" {
+		return
+	}
+
+	var count int64
+	err := db.Model(&models.ApiClient{}).Where("key_id = ?", defaultApiClientKeyID).Count(&count).Error
+	if err != nil {
+		logrus.Errorf("failed to find default api client: %v", err)
+		panic(err)
+	}
+
+	if count > 0 {
+		return
+	}
+
+	client := models.ApiClient{
+		UUID:    uuid.New(),
+		Name:    "Default",
+		KeyID:   defaultApiClientKeyID,
+		Secrets: []models.ApiClientSecret{{Secret: config.Config.SignatureKey}},
+	}
+	if err = db.Create(&client).Error; err != nil {
+		logrus.Errorf("failed to seed default api client: %v", err)
+		panic(err)
+	}
+	logrus.Infof("api client %s successfully seeded", client.KeyID)
+}
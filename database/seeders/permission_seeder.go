@@ -0,0 +1,65 @@
+package seeders
+
+import (
+	"backend/constants"
+	"backend/domain/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RunPermissionSeeder keeps the permissions table in line with the catalog.
+// The owner always gets every permission, the admin only gets its defaults
+// while it has none so changes made by the owner are not undone.
+func RunPermissionSeeder(db *gorm.DB) {
+	permissions := make([]models.Permission, 0, len(constants.Permissions))
+	for code, description := range constants.Permissions {
+		permission := models.Permission{
+			Code:        code,
+			Description: description,
+		}
+
+		err := db.
+			Where(models.Permission{Code: code}).
+			Assign(models.Permission{Description: description}).
+			FirstOrCreate(&permission).
+			Error
+		if err != nil {
+			logrus.Errorf("failed to seed permission: %v", err)
+			panic(err)
+		}
+		permissions = append(permissions, permission)
+	}
+	logrus.Infof("%d permissions successfully seeded", len(permissions))
+
+	var owner models.Role
+	if err := db.Where("code = ?", "OWNER").First(&owner).Error; err != nil {
+		logrus.Errorf("failed to find owner role: %v", err)
+		panic(err)
+	}
+
+	if err := db.Model(&owner).Association("Permissions").Append(permissions); err != nil {
+		logrus.Errorf("failed to grant owner permissions: %v", err)
+		panic(err)
+	}
+
+	var admin models.Role
+	if err := db.Where("code = ?", "ADMIN").First(&admin).Error; err != nil {
+		logrus.Errorf("failed to find admin role: %v", err)
+		panic(err)
+	}
+
+	if db.Model(&admin).Association("Permissions").Count() > 0 {
+		return
+	}
+
+	var adminPermissions []models.Permission
+	err := db.Where("code IN ?", constants.AdminPermissions).Find(&adminPermissions).Error
+	if err == nil {
+		err = db.Model(&admin).Association("Permissions").Append(adminPermissions)
+	}
+	if err != nil {
+		logrus.Errorf("failed to grant admin permissions: %v", err)
+		panic(err)
+	}
+	logrus.Info("admin permissions successfully seeded")
+}
@@ -1,20 +1,69 @@
 package seeders
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+)
 
 type Registry struct {
 	db *gorm.DB
 }
 
 type ISeederRegistry interface {
-	Run()
+	Run(names ...string) error
+}
+
+type seeder struct {
+	name string
+	run  func(*gorm.DB)
+}
+
+// seeders run in this order, later ones rely on the roles and permissions
+// seeded before them.
+var seeders = []seeder{
+	{name: "role", run: RunRoleSeeder},
+	{name: "permission", run: RunPermissionSeeder},
+	{name: "user", run: RunUserSeeder},
+	{name: "api-client", run: RunApiClientSeeder},
 }
 
 func NewSeederRegistry(db *gorm.DB) ISeederRegistry {
 	return &Registry{db: db}
 }
 
-func (s *Registry) Run() {
-	RunRoleSeeder(s.db)
-	RunUserSeeder(s.db)
+// Names lists the seeders in the order they run.
+func Names() []string {
+	names := make([]string, 0, len(seeders))
+	for _, s := range seeders {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// Run runs the named seeders, or all of them when no name is given. They
+// always run in their own order, whatever order the names come in.
+func (s *Registry) Run(names ...string) error {
+	selected := map[string]bool{}
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	for _, name := range names {
+		known := false
+		for _, seeder := range seeders {
+			known = known || seeder.name == name
+		}
+		if !known {
+			return fmt.Errorf("unknown seeder %q, expected one of %s", name, strings.Join(Names(), ", "))
+		}
+	}
+
+	for _, seeder := range seeders {
+		if len(selected) == 0 || selected[seeder.name] {
+			seeder.run(s.db)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,83 @@
+package seeders
+
+import (
+	"backend/domain/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestDB opens a fresh SQLite database with the role tables.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.Permission{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	return db
+}
+
+func TestNames(t *testing.T) {
+	want := []string{"role", "permission", "user", "api-client"}
+	if got := Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Names = %q, want %q", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		seeders         []string
+		wantErr         string
+		wantRoles       int64
+		wantPermissions bool
+	}{
+		{name: "one seeder", seeders: []string{"role"}, wantRoles: 2},
+		// The permission seeder needs the roles, so it only works when
+		// the role seeder runs first whatever the order of the names.
+		{name: "own order", seeders: []string{"permission", "role"}, wantRoles: 2, wantPermissions: true},
+		{name: "unknown seeder", seeders: []string{"role", "product"}, wantErr: `unknown seeder "product"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+
+			err := NewSeederRegistry(db).Run(tt.seeders...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run = %v, want an error containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			var roles, permissions int64
+			db.Model(&models.Role{}).Count(&roles)
+			db.Model(&models.Permission{}).Count(&permissions)
+			if roles != tt.wantRoles || (permissions > 0) != tt.wantPermissions {
+				t.Errorf("seeded %d roles and %d permissions", roles, permissions)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ApiClientRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type ApiClientSecretResponse struct {
+	CreatedAt *time.Time `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ApiClientResponse struct {
+	UUID       uuid.UUID                 `json:"uuid"`
+	Name       string                    `json:"name"`
+	KeyID      string                    `json:"key_id"`
+	Secrets    []ApiClientSecretResponse `json:"secrets"`
+	LastUsedAt *time.Time                `json:"last_used_at"`
+	RevokedAt  *time.Time                `json:"revoked_at,omitempty"`
+	CreatedAt  *time.Time                `json:"created_at"`
+}
+
+// ApiClientSecretCreatedResponse carries a new secret once, after that it
+// can only be rotated.
+type ApiClientSecretCreatedResponse struct {
+	Client ApiClientResponse `json:"client"`
+	Secret string            `json:"secret"`
+}
+
+// SignedRequest is what the middleware hands over to verify a request.
+type SignedRequest struct {
+	KeyID     string
+	RequestAt string
+	Nonce     string
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+}
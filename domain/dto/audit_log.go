@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"backend/common/util"
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// AuditLogListSpec keeps the audit log newest first, the cursor of the
+// keyset pagination needs the id to tell changes of the same instant apart.
+var AuditLogListSpec = util.ListSpec{
+	Sortable:    map[string]string{"created_at": "created_at"},
+	DefaultSort: "-created_at",
+	Tiebreaker:  "id",
+}
+
+// AuditLogFilterParam filters the audit log. Actor takes the UUID of the
+// user who made the change, From and To limit the day it was made.
+type AuditLogFilterParam struct {
+	Entity    *string `form:"entity"`
+	EntityID  *string `form:"entityId"`
+	Action    *string `form:"action" validate:"omitempty,oneof=create update delete"`
+	Actor     *string `form:"actor" validate:"omitempty,uuid"`
+	RequestID *string `form:"requestId"`
+	From      *string `form:"from" validate:"omitempty,datetime=2006-01-02"`
+	To        *string `form:"to" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type AuditLogRequestParam struct {
+	Page  int `form:"page" validate:"required"`
+	Limit int `form:"limit" validate:"required"`
+	AuditLogFilterParam
+}
+
+type AuditLogCursorParam struct {
+	util.CursorParam
+	AuditLogFilterParam
+}
+
+type AuditLogResponse struct {
+	Action        string          `json:"action"`
+	Entity        string          `json:"entity"`
+	EntityID      string          `json:"entity_id"`
+	ActorUUID     *uuid.UUID      `json:"actor_uuid"`
+	ActorUsername string          `json:"actor_username"`
+	ApiClient     string          `json:"api_client"`
+	IPAddress     string          `json:"ip_address"`
+	RequestID     string          `json:"request_id"`
+	Changes       json.RawMessage `json:"changes"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
@@ -1,7 +1,11 @@
 package dto
 
 import (
+	errValidation "backend/common/error"
+	"backend/common/util"
+	"encoding/json"
 	"github.com/google/uuid"
+	"strings"
 	"time"
 )
 
@@ -23,16 +27,33 @@ type UpdateProductRequest struct {
 	Unit      string `json:"unit"`
 }
 
+// PatchProductRequest is decoded from a JSON merge patch. Only the members
+// in Fields are written, so 0 and "" are applied as sent and a null code
+// clears it.
+type PatchProductRequest struct {
+	Code      *string         `json:"code" patch:"nullable"`
+	Name      *string         `json:"name" validate:"omitnil,min=1"`
+	PriceBuy  *uint           `json:"price_buy"`
+	PriceSale *uint           `json:"price_sale"`
+	Stock     *uint           `json:"stock"`
+	Unit      *string         `json:"unit" validate:"omitnil,min=1"`
+	Fields    util.MergePatch `json:"-"`
+}
+
 type ProductResponse struct {
-	UUID      uuid.UUID  `json:"uuid"`
-	Code      string     `json:"code"`
-	Name      string     `json:"name"`
-	PriceBuy  uint       `json:"price_buy"`
-	PriceSale uint       `json:"price_sale"`
-	Stock     uint       `json:"stock"`
-	Unit      string     `json:"unit"`
-	CreatedAt *time.Time `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	UUID         uuid.UUID  `json:"uuid"`
+	Code         string     `json:"code"`
+	Name         string     `json:"name"`
+	PriceBuy     uint       `json:"price_buy"`
+	PriceSale    uint       `json:"price_sale"`
+	Stock        uint       `json:"stock"`
+	Unit         string     `json:"unit"`
+	ImageURL     *string    `json:"image_url"`
+	ThumbnailURL *string    `json:"thumbnail_url"`
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	Version      uint       `json:"version"`
 }
 
 type ProductDetailResponse struct {
@@ -46,9 +67,191 @@ type ProductDetailResponse struct {
 	UpdatedAt *time.Time `json:"updated_at"`
 }
 
+var ProductListSpec = util.ListSpec{
+	Sortable: map[string]string{
+		"code":       "code",
+		"name":       "name",
+		"price_buy":  "price_buy",
+		"price_sale": "price_sale",
+		"stock":      "stock",
+		"unit":       "unit",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Filterable: map[string]string{
+		"code": "code",
+		"name": "name",
+		"unit": "unit",
+	},
+	DefaultSort: "-created_at",
+	Tiebreaker:  "id",
+}
+
+// ProductFilterParam holds the filter and sort shared by the paginated list
+// and the export so both always return the same products. Sort and Filters
+// are checked against ProductListSpec, sortColumn and sortOrder are kept for
+// older clients. UUIDs narrows the result to picked products.
+type ProductFilterParam struct {
+	Sort        *string           `form:"sort"`
+	SortColumn  *string           `form:"sortColumn"`
+	SortOrder   *string           `form:"sortOrder" validate:"omitempty,oneof=asc desc ASC DESC"`
+	Filters     map[string]string `form:"-"`
+	UUIDs       []string          `form:"-"`
+	Include     string            `form:"include" validate:"omitempty,oneof=archived"`
+	Search      *string           `form:"search"`
+	MinPrice    *uint             `form:"minPrice"`
+	MaxPrice    *uint             `form:"maxPrice"`
+	MinStock    *uint             `form:"minStock"`
+	MaxStock    *uint             `form:"maxStock"`
+	OutOfStock  bool              `form:"outOfStock"`
+	Unit        *string           `form:"unit"`
+	CreatedFrom *string           `form:"createdFrom" validate:"omitempty,datetime=2006-01-02"`
+	CreatedTo   *string           `form:"createdTo" validate:"omitempty,datetime=2006-01-02"`
+	UpdatedFrom *string           `form:"updatedFrom" validate:"omitempty,datetime=2006-01-02"`
+	UpdatedTo   *string           `form:"updatedTo" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// HasScope tells whether any uuid or filter narrows the products down.
+func (p *ProductFilterParam) HasScope() bool {
+	for _, value := range p.Filters {
+		if strings.TrimSpace(value) != "" {
+			return true
+		}
+	}
+
+	for _, value := range []*string{p.Search, p.Unit, p.CreatedFrom, p.CreatedTo, p.UpdatedFrom, p.UpdatedTo} {
+		if value != nil && strings.TrimSpace(*value) != "" {
+			return true
+		}
+	}
+
+	return len(p.UUIDs) > 0 || p.OutOfStock ||
+		p.MinPrice != nil || p.MaxPrice != nil || p.MinStock != nil || p.MaxStock != nil
+}
+
+func (p *ProductFilterParam) SortExpression() string {
+	return util.SortExpression(p.Sort, p.SortColumn, p.SortOrder)
+}
+
 type ProductRequestParam struct {
-	Page       int     `form:"page" validate:"required"`
-	Limit      int     `form:"limit" validate:"required"`
-	SortColumn *string `form:"sortColumn"`
-	SortOrder  *string `form:"sortOrder"`
+	Page  int `form:"page" validate:"required"`
+	Limit int `form:"limit" validate:"required"`
+	ProductFilterParam
+}
+
+type ProductCursorParam struct {
+	util.CursorParam
+	ProductFilterParam
+}
+
+type ProductExportParam struct {
+	Format string `form:"format" validate:"omitempty,oneof=csv xlsx"`
+	Rupiah bool   `form:"rupiah"`
+	ProductFilterParam
+}
+
+type ProductImportRequest struct {
+	Format  string            `json:"format"`
+	Mapping map[string]string `json:"mapping"`
+	DryRun  bool              `json:"dry_run"`
+}
+
+type ProductImportRowResult struct {
+	Row    int                                `json:"row"`
+	Code   string                             `json:"code"`
+	Name   string                             `json:"name"`
+	Action string                             `json:"action,omitempty"`
+	Errors []errValidation.ValidationResponse `json:"errors,omitempty"`
+}
+
+type ProductImportResponse struct {
+	DryRun      bool                               `json:"dry_run"`
+	TotalRows   int                                `json:"total_rows"`
+	ValidRows   int                                `json:"valid_rows"`
+	InvalidRows int                                `json:"invalid_rows"`
+	Created     int                                `json:"created"`
+	Updated     int                                `json:"updated"`
+	Restored    int                                `json:"restored"`
+	Columns     []errValidation.ValidationResponse `json:"columns,omitempty"`
+	Rows        []ProductImportRowResult           `json:"rows"`
+}
+
+const (
+	ProductBatchAtomic     = "atomic"
+	ProductBatchBestEffort = "best_effort"
+
+	ProductBatchCreate = "create"
+	ProductBatchUpdate = "update"
+	ProductBatchDelete = "delete"
+)
+
+// ProductBatchOperation is one item of a batch. Data is a ProductRequest for
+// create and a merge patch for update. Version works like If-Match and is
+// only checked when sent.
+type ProductBatchOperation struct {
+	Op      string          `json:"op" validate:"required,oneof=create update delete"`
+	UUID    string          `json:"uuid" validate:"required_unless=Op create,omitempty,uuid"`
+	Version *uint           `json:"version"`
+	Data    json.RawMessage `json:"data" validate:"required_unless=Op delete"`
+}
+
+type ProductBatchRequest struct {
+	Mode       string                  `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Operations []ProductBatchOperation `json:"operations" validate:"required,min=1,max=500"`
+}
+
+type ProductBatchItemResult struct {
+	Index  int                                `json:"index"`
+	Op     string                             `json:"op"`
+	UUID   string                             `json:"uuid,omitempty"`
+	Status string                             `json:"status"`
+	Error  string                             `json:"error,omitempty"`
+	Errors []errValidation.ValidationResponse `json:"errors,omitempty"`
+	Data   *ProductResponse                   `json:"data,omitempty"`
+}
+
+type ProductBatchResponse struct {
+	Mode      string                   `json:"mode"`
+	Succeeded int                      `json:"succeeded"`
+	Failed    int                      `json:"failed"`
+	Results   []ProductBatchItemResult `json:"results"`
+}
+
+const (
+	ProductPriceAdjustmentPercent = "percent"
+	ProductPriceAdjustmentFixed   = "fixed"
+)
+
+// ProductPriceAdjustmentRequest raises or lowers PriceSale by a percentage or
+// a fixed amount, negative values lower it. A fixed amount is at most a
+// billion either way, a percentage is also kept between -100 and 1000 by
+// the service. Products are picked by UUIDs and by the list filters in the
+// query string. Confirm must be set to save changes that put PriceSale
+// below PriceBuy.
+type ProductPriceAdjustmentRequest struct {
+	Type     string   `json:"type" validate:"required,oneof=percent fixed"`
+	Value    float64  `json:"value" validate:"required,min=-1000000000,max=1000000000"`
+	Rounding uint     `json:"rounding" validate:"omitempty,oneof=100 500"`
+	UUIDs    []string `json:"uuids" validate:"omitempty,max=1000,dive,uuid"`
+	DryRun   bool     `json:"dry_run"`
+	Confirm  bool     `json:"confirm"`
+}
+
+type ProductPriceAdjustmentItem struct {
+	UUID            uuid.UUID `json:"uuid"`
+	Code            string    `json:"code"`
+	Name            string    `json:"name"`
+	PriceBuy        uint      `json:"price_buy"`
+	PriceSaleBefore uint      `json:"price_sale_before"`
+	PriceSaleAfter  uint      `json:"price_sale_after"`
+	BelowCost       bool      `json:"below_cost"`
+}
+
+type ProductPriceAdjustmentResponse struct {
+	DryRun    bool                         `json:"dry_run"`
+	Applied   bool                         `json:"applied"`
+	Total     int                          `json:"total"`
+	Changed   int                          `json:"changed"`
+	BelowCost int                          `json:"below_cost"`
+	Items     []ProductPriceAdjustmentItem `json:"items"`
 }
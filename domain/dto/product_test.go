@@ -0,0 +1,33 @@
+package dto
+
+import "testing"
+
+func TestProductFilterParamHasScope(t *testing.T) {
+	text := func(value string) *string { return &value }
+	zero := uint(0)
+
+	tests := []struct {
+		name  string
+		param ProductFilterParam
+		want  bool
+	}{
+		{name: "nothing", param: ProductFilterParam{}},
+		{name: "sort and archived do not narrow", param: ProductFilterParam{Sort: text("name"), Include: "archived"}},
+		{name: "blank search", param: ProductFilterParam{Search: text("  ")}},
+		{name: "blank filter", param: ProductFilterParam{Filters: map[string]string{"unit": ""}}},
+		{name: "uuids", param: ProductFilterParam{UUIDs: []string{"6f1d6c0e-3c55-4a8b-9a3f-0d5a5f1c2b3d"}}, want: true},
+		{name: "search", param: ProductFilterParam{Search: text("kopi")}, want: true},
+		{name: "filter", param: ProductFilterParam{Filters: map[string]string{"unit": "pcs"}}, want: true},
+		{name: "zero minimum price", param: ProductFilterParam{MinPrice: &zero}, want: true},
+		{name: "out of stock", param: ProductFilterParam{OutOfStock: true}, want: true},
+		{name: "updated since", param: ProductFilterParam{UpdatedFrom: text("2026-10-01")}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.param.HasScope(); got != tt.want {
+				t.Errorf("HasScope = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
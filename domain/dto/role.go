@@ -0,0 +1,28 @@
+package dto
+
+type RoleRequest struct {
+	Code        string   `json:"code" validate:"required,alphanum,max=15"`
+	Name        string   `json:"name" validate:"required,max=20"`
+	Permissions []string `json:"permissions" validate:"required,dive,required"`
+}
+
+type UpdateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,max=20"`
+	Permissions []string `json:"permissions" validate:"required,dive,required"`
+}
+
+type RoleTwoFactorRequest struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+type PermissionResponse struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+type RoleResponse struct {
+	Code             string   `json:"code"`
+	Name             string   `json:"name"`
+	RequireTwoFactor bool     `json:"require_two_factor"`
+	Permissions      []string `json:"permissions"`
+}
@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"backend/common/util"
+	"github.com/google/uuid"
+	"time"
+)
+
+// SessionDevice describes where a login came from, it is stored on the
+// session and in the login history.
+type SessionDevice struct {
+	DeviceName string
+	IPAddress  string
+	UserAgent  string
+}
+
+// SessionResponse is one place the account is logged in. Current marks the
+// session of the access token the list was requested with.
+type SessionResponse struct {
+	UUID       uuid.UUID  `json:"uuid"`
+	DeviceName string     `json:"device_name"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	Terminal   bool       `json:"terminal"`
+	Current    bool       `json:"current"`
+	LoginAt    *time.Time `json:"login_at"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+}
+
+// LoginHistoryListSpec keeps the login history newest first, the cursor of
+// the keyset pagination needs the id to tell attempts of the same instant
+// apart.
+var LoginHistoryListSpec = util.ListSpec{
+	Sortable:    map[string]string{"created_at": "created_at"},
+	DefaultSort: "-created_at",
+	Tiebreaker:  "id",
+}
+
+// LoginHistoryFilterParam filters the login history. User takes a user UUID
+// and only applies to the admin listing, users always see their own.
+type LoginHistoryFilterParam struct {
+	User     *string `form:"user" validate:"omitempty,uuid"`
+	Username *string `form:"username"`
+	Method   *string `form:"method" validate:"omitempty,oneof=password pin 2fa"`
+	Success  *bool   `form:"success"`
+	IP       *string `form:"ip"`
+	UserID   *uint   `form:"-"`
+}
+
+type LoginHistoryRequestParam struct {
+	Page  int `form:"page" validate:"required"`
+	Limit int `form:"limit" validate:"required"`
+	LoginHistoryFilterParam
+}
+
+type LoginHistoryCursorParam struct {
+	util.CursorParam
+	LoginHistoryFilterParam
+}
+
+type LoginHistoryResponse struct {
+	UserUUID   *uuid.UUID `json:"user_uuid"`
+	Username   string     `json:"username"`
+	Method     string     `json:"method"`
+	Success    bool       `json:"success"`
+	Reason     string     `json:"reason,omitempty"`
+	DeviceName string     `json:"device_name"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type TerminalRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type TerminalResponse struct {
+	UUID       uuid.UUID  `json:"uuid"`
+	Name       string     `json:"name"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  *time.Time `json:"created_at"`
+}
+
+// TerminalCreatedResponse carries the terminal key once, only its hash is
+// stored.
+type TerminalCreatedResponse struct {
+	Terminal TerminalResponse `json:"terminal"`
+	Key      string           `json:"key"`
+}
+
+// TerminalUserResponse is what a terminal shows on its user picker.
+type TerminalUserResponse struct {
+	UUID     uuid.UUID `json:"uuid"`
+	Name     string    `json:"name"`
+	Username string    `json:"username"`
+}
+
+// EnrollPinRequest sets the PIN of the logged in user on the terminal. The
+// password is asked again since a PIN is a second way into the account.
+type EnrollPinRequest struct {
+	Password   string `json:"password" validate:"required"`
+	Pin        string `json:"pin" validate:"required,numeric,min=4,max=6"`
+	ConfirmPin string `json:"confirm_pin" validate:"required,eqfield=Pin"`
+}
+
+type PinLoginRequest struct {
+	UserUUID  string `json:"user_uuid" validate:"required,uuid"`
+	Pin       string `json:"pin" validate:"required,numeric,min=4,max=6"`
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
+}
@@ -0,0 +1,50 @@
+package dto
+
+import "time"
+
+type TwoFactorChallengeResponse struct {
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// TwoFactorVerifyRequest answers a login challenge. Code is either the
+// current TOTP code or one of the recovery codes.
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+	DeviceName     string `json:"device_name" validate:"max=100"`
+	ClientIP       string `json:"-"`
+	UserAgent      string `json:"-"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+type TwoFactorSetupRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// TwoFactorSetupResponse carries the secret for manual entry and the
+// otpauth:// URI to show as a QR code.
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// RecoveryCodesResponse carries the recovery codes once, only their hashes
+// are stored.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
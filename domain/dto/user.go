@@ -1,24 +1,42 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"backend/common/util"
+	"github.com/google/uuid"
+)
 
 type LoginRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Username   string `json:"username" validate:"required"`
+	Password   string `json:"password" validate:"required"`
+	DeviceName string `json:"device_name" validate:"max=100"`
+	ClientIP   string `json:"-"`
+	UserAgent  string `json:"-"`
 }
 
 type UserResponse struct {
-	UUID        uuid.UUID `json:"uuid"`
-	Name        string    `json:"name"`
-	Username    string    `json:"username"`
-	Email       string    `json:"email"`
-	Role        string    `json:"role,omitempty"`
-	PhoneNumber string    `json:"phone_number"`
+	UUID               uuid.UUID `json:"uuid"`
+	Name               string    `json:"name"`
+	Username           string    `json:"username"`
+	Email              string    `json:"email"`
+	Role               string    `json:"role,omitempty"`
+	PhoneNumber        string    `json:"phone_number"`
+	IsActive           bool      `json:"is_active"`
+	MustChangePassword bool      `json:"must_change_password"`
+	TwoFactorEnabled   bool      `json:"two_factor_enabled"`
+	Version            uint      `json:"version,omitempty"`
 }
 
+// LoginResponse carries either the tokens or, for users with two-factor
+// authentication, only the Challenge to answer with a code.
 type LoginResponse struct {
-	User  UserResponse `json:"user"`
-	Token string       `json:"token"`
+	User         UserResponse                `json:"user"`
+	Token        string                      `json:"token"`
+	RefreshToken string                      `json:"refresh_token"`
+	Challenge    *TwoFactorChallengeResponse `json:"-"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 type RegisterRequest struct {
@@ -28,7 +46,8 @@ type RegisterRequest struct {
 	ConfirmPassword string `json:"confirm_password" validate:"required"`
 	Email           string `json:"email" validate:"required,email"`
 	PhoneNumber     string `json:"phone_number" validate:"required,number"`
-	RoleID          uint
+	Role            string `json:"role" validate:"required"`
+	RoleID          uint   `json:"-"`
 }
 
 type RegisterResponse struct {
@@ -36,11 +55,58 @@ type RegisterResponse struct {
 }
 
 type UpdateRequest struct {
-	Name            string  `json:"name" validate:"required"`
-	Username        string  `json:"username" validate:"required"`
-	Password        *string `json:"password,omitempty"`
-	ConfirmPassword *string `json:"confirm_password,omitempty"`
-	Email           string  `json:"email" validate:"required,email"`
-	PhoneNumber     string  `json:"phone_number" validate:"required,number"`
-	RoleID          uint
+	Name        string `json:"name" validate:"required"`
+	Username    string `json:"username" validate:"required"`
+	Email       string `json:"email" validate:"required,email"`
+	PhoneNumber string `json:"phone_number" validate:"required,number"`
+	RoleID      uint
+}
+
+// UserRequestParam filters the user list. Search matches name, username and
+// email, Role is a role code.
+type UserRequestParam struct {
+	Page   int     `form:"page" validate:"required"`
+	Limit  int     `form:"limit" validate:"required"`
+	Search *string `form:"search"`
+	Role   *string `form:"role"`
+	Status string  `form:"status" validate:"omitempty,oneof=active inactive"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// ResetPasswordResponse carries the temporary password once, it is not
+// stored anywhere but as a hash.
+type ResetPasswordResponse struct {
+	User              UserResponse `json:"user"`
+	TemporaryPassword string       `json:"temporary_password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+}
+
+// ForgotPasswordRequest asks for a password reset link to be mailed.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// PasswordResetRequest sets a new password with the token from the mail.
+type PasswordResetRequest struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+}
+
+// PatchUserRequest is decoded from a JSON merge patch. The password can not
+// be patched, it has its own endpoints.
+type PatchUserRequest struct {
+	Name        *string         `json:"name" validate:"omitnil,min=1"`
+	Username    *string         `json:"username" validate:"omitnil,min=1"`
+	Email       *string         `json:"email" validate:"omitnil,email"`
+	PhoneNumber *string         `json:"phone_number" validate:"omitnil,number"`
+	Fields      util.MergePatch `json:"-"`
 }
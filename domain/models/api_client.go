@@ -0,0 +1,49 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ApiClient is an application allowed to call the API. It names itself by
+// KeyID and signs every request with one of its secrets.
+type ApiClient struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement"`
+	UUID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex"`
+	Name       string     `gorm:"type:varchar(50);not null"`
+	KeyID      string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	LastUsedAt *time.Time `audit:"-"`
+	RevokedAt  *time.Time
+	CreatedAt  *time.Time
+	UpdatedAt  *time.Time
+	Secrets    []ApiClientSecret `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (ApiClient) AuditEntity() string {
+	return "api_client"
+}
+
+// ApiClientSecret is kept as is since the signature has to be recomputed
+// with it. A rotated secret keeps working until ExpiresAt.
+type ApiClientSecret struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	ApiClientID uint   `gorm:"not null;index"`
+	Secret      string `gorm:"type:varchar(255);not null" audit:"redact"`
+	ExpiresAt   *time.Time
+	CreatedAt   *time.Time
+}
+
+func (ApiClientSecret) AuditEntity() string {
+	return "api_client_secret"
+}
+
+// ApiNonce remembers the nonces a client used while their requests are
+// still fresh, so a captured request can not be sent again.
+type ApiNonce struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	ApiClientID uint      `gorm:"not null;uniqueIndex:idx_api_nonce_client"`
+	Nonce       string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_api_nonce_client"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   *time.Time
+	ApiClient   ApiClient `gorm:"foreignKey:api_client_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
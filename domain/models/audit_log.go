@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Auditable models get every create, update and delete written to the audit
+// log under the entity name they return. Fields tagged audit:"-" are left
+// out, fields tagged audit:"redact" only show that they changed.
+type Auditable interface {
+	AuditEntity() string
+}
+
+// AuditLog is one changed row. Changes maps each changed column to its value
+// before and after, the API never writes to this table.
+type AuditLog struct {
+	ID            uint            `gorm:"primaryKey;autoIncrement"`
+	Action        string          `gorm:"type:varchar(10);not null"`
+	Entity        string          `gorm:"type:varchar(50);not null;index:idx_audit_log_entity"`
+	EntityID      string          `gorm:"type:varchar(64);not null;index:idx_audit_log_entity"`
+	ActorUUID     *uuid.UUID      `gorm:"type:uuid;index"`
+	ActorUsername string          `gorm:"type:varchar(20);not null;default:''"`
+	ApiClient     string          `gorm:"type:varchar(64);not null;default:''"`
+	IPAddress     string          `gorm:"type:varchar(45);not null;default:''"`
+	RequestID     string          `gorm:"type:varchar(64);not null;default:'';index"`
+	Changes       json.RawMessage `gorm:"type:jsonb;not null"`
+	CreatedAt     time.Time       `gorm:"index"`
+}
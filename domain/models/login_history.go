@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// LoginHistory records every login attempt, failed ones included, for
+// security review. UserID is empty when the username did not exist.
+type LoginHistory struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	UserID     *uint     `gorm:"index"`
+	SessionID  *uint     `gorm:"index"`
+	Username   string    `gorm:"type:varchar(50);not null;index"`
+	Method     string    `gorm:"type:varchar(20);not null"`
+	Success    bool      `gorm:"not null"`
+	Reason     string    `gorm:"type:varchar(100);not null;default:''"`
+	DeviceName string    `gorm:"type:varchar(100);not null;default:''"`
+	IPAddress  string    `gorm:"type:varchar(45);not null;default:'';index"`
+	UserAgent  string    `gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt  time.Time `gorm:"index"`
+	User       *User     `gorm:"foreignKey:user_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginThrottle counts recent failed logins for one username or one client
+// IP. Until LockedUntil has passed every login for that subject is refused.
+type LoginThrottle struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement"`
+	Scope        string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_login_throttle_subject"`
+	Subject      string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_login_throttle_subject"`
+	Failures     int       `gorm:"not null;default:0"`
+	LastFailedAt time.Time `gorm:"not null"`
+	LockedUntil  *time.Time
+	CreatedAt    *time.Time
+	UpdatedAt    *time.Time
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PasswordResetToken is the secret mailed to a user who forgot their
+// password. Only its SHA-256 hash is stored, UsedAt is set when the password
+// was reset with it so it works only once.
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt *time.Time
+	User      User `gorm:"foreignKey:user_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
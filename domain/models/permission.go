@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type Permission struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Code        string `gorm:"type:varchar(50);not null;uniqueIndex"`
+	Description string `gorm:"type:varchar(255)"`
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+}
+
+// RolePermission is the join table of Role.Permissions. It is set up as the
+// join model so granting and revoking permissions is audited.
+type RolePermission struct {
+	RoleID       uint `gorm:"primaryKey"`
+	PermissionID uint `gorm:"primaryKey"`
+}
+
+func (RolePermission) AuditEntity() string {
+	return "role_permission"
+}
@@ -2,18 +2,27 @@ package models
 
 import (
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 	"time"
 )
 
 type Product struct {
 	ID        uint      `gorm:"primaryKey;autoIncrement"`
-	UUID      uuid.UUID `gorm:"type:uuid;not null"`
-	Code      string    `gorm:"type:varchar(100)"`
+	UUID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	Code      string    `gorm:"type:varchar(100);uniqueIndex:idx_products_code,where:code <> ''"`
 	Name      string    `gorm:"type:varchar(255);not null"`
 	PriceBuy  uint      `gorm:"type:uint;not null"`
 	PriceSale uint      `gorm:"type:uint;not null"`
 	Stock     uint      `gorm:"type:uint;not null"`
 	Unit      string    `gorm:"type:varchar(100);not null"`
+	Image     *string   `gorm:"type:varchar(255)"`
+	Thumbnail *string   `gorm:"type:varchar(255)"`
+	Version   uint      `gorm:"not null;default:1"`
 	CreatedAt *time.Time
 	UpdatedAt *time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (Product) AuditEntity() string {
+	return "product"
 }
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Session is one refresh token family. Every refresh rotates the token
+// inside the same session, so revoking the session ends every token it
+// ever issued. TerminalID is set for sessions started by PIN at a terminal.
+// The device fields describe where the login came from, LastUsedAt is the
+// last time the session was seen.
+type Session struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	UUID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	UserID     uint      `gorm:"not null;index"`
+	TerminalID *uint     `gorm:"index"`
+	DeviceName string    `gorm:"type:varchar(100);not null;default:''"`
+	IPAddress  string    `gorm:"type:varchar(45);not null;default:''"`
+	UserAgent  string    `gorm:"type:varchar(255);not null;default:''"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  *time.Time
+	UpdatedAt  *time.Time
+	User       User `gorm:"foreignKey:user_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// RefreshToken only keeps a SHA-256 hash of the token handed to the client.
+// UsedAt is set when the token is rotated, presenting it again is reuse.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	SessionID uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt *time.Time
+	Session   Session `gorm:"foreignKey:session_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
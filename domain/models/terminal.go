@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Terminal is a trusted till. It authenticates with its own key, only the
+// SHA-256 hash of which is stored, and lets enrolled staff log in by PIN.
+type Terminal struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement"`
+	UUID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex"`
+	Name       string     `gorm:"type:varchar(50);not null"`
+	KeyHash    string     `gorm:"type:varchar(64);not null;uniqueIndex" audit:"redact"`
+	LastSeenAt *time.Time `audit:"-"`
+	RevokedAt  *time.Time
+	CreatedAt  *time.Time
+	UpdatedAt  *time.Time
+}
+
+func (Terminal) AuditEntity() string {
+	return "terminal"
+}
+
+// TerminalPin is the bcrypt hashed PIN a user enrolled on one terminal.
+type TerminalPin struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	TerminalID uint   `gorm:"not null;uniqueIndex:idx_terminal_pin_user"`
+	UserID     uint   `gorm:"not null;uniqueIndex:idx_terminal_pin_user"`
+	PinHash    string `gorm:"type:varchar(255);not null"`
+	CreatedAt  *time.Time
+	UpdatedAt  *time.Time
+	Terminal   Terminal `gorm:"foreignKey:terminal_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	User       User     `gorm:"foreignKey:user_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
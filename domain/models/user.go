@@ -6,15 +6,35 @@ import (
 )
 
 type User struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement"`
-	UUID        uuid.UUID `gorm:"type:uuid;not null"`
-	Name        string    `gorm:"type:varchar(100);not null"`
-	Username    string    `gorm:"type:varchar(20);not null"`
-	Password    string    `gorm:"type:varchar(255);not null"`
-	PhoneNumber string    `gorm:"type:varchar(15);not null"`
-	Email       string    `gorm:"type:varchar(100);not null"`
-	RoleID      uint      `gorm:"type:uint;not null"`
-	CreatedAt   *time.Time
-	UpdatedAt   *time.Time
-	Role        Role `gorm:"foreignKey:role_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ID                 uint      `gorm:"primaryKey;autoIncrement"`
+	UUID               uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	Name               string    `gorm:"type:varchar(100);not null"`
+	Username           string    `gorm:"type:varchar(20);not null;uniqueIndex"`
+	Password           string    `gorm:"type:varchar(255);not null" audit:"redact"`
+	PhoneNumber        string    `gorm:"type:varchar(15);not null"`
+	Email              string    `gorm:"type:varchar(100);not null;uniqueIndex"`
+	RoleID             uint      `gorm:"type:uint;not null"`
+	Version            uint      `gorm:"not null;default:1"`
+	IsActive           bool      `gorm:"not null;default:true"`
+	MustChangePassword bool      `gorm:"not null;default:false"`
+	TotpSecret         *string   `gorm:"type:varchar(32)" audit:"redact"`
+	TotpLastStep       int64     `gorm:"not null;default:0" audit:"-"`
+	TotpEnabledAt      *time.Time
+	CreatedAt          *time.Time
+	UpdatedAt          *time.Time
+	Role               Role `gorm:"foreignKey:role_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (User) AuditEntity() string {
+	return "user"
+}
+
+// PasswordHistory keeps the hashes of passwords a user had before, so a
+// password change can refuse recently used ones.
+type PasswordHistory struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement"`
+	UserID       uint   `gorm:"not null;index"`
+	PasswordHash string `gorm:"type:varchar(255);not null"`
+	CreatedAt    *time.Time
+	User         User `gorm:"foreignKey:user_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
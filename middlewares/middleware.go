@@ -2,16 +2,17 @@ package middlewares
 
 import (
 	"backend/common/response"
-	"backend/config"
+	"backend/common/util"
 	"backend/constants"
 	errConstant "backend/constants/error"
-	services "backend/services/user"
-	"crypto/sha256"
-	"encoding/hex"
-	"fmt"
+	errRole "backend/constants/error/role"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	serviceRegistry "backend/services"
+	"errors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"runtime/debug"
@@ -19,6 +20,16 @@ import (
 	"time"
 )
 
+const maxRequestIDLength = 64
+
+// service gives the middlewares access to the services, Init sets it once
+// at startup before any route is served.
+var service serviceRegistry.IServiceRegistry
+
+func Init(registry serviceRegistry.IServiceRegistry) {
+	service = registry
+}
+
 func HandlePanic() fiber.ErrorHandler {
 	return func(c *fiber.Ctx, err error) error {
 		code := fiber.StatusInternalServerError
@@ -55,6 +66,23 @@ func RateLimiter(max int, duration time.Duration) fiber.Handler {
 	return limiter.New(configLimiter)
 }
 
+// RequestContext tags every request with an ID, the one the caller sent in
+// X-Request-ID or a new one, and keeps it with the client IP in Locals for
+// the audit log. The ID is echoed back so callers can quote it.
+func RequestContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := strings.TrimSpace(c.Get(constants.XRequestID))
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(constants.XRequestID, requestID)
+		c.Locals(constants.RequestID, requestID)
+		c.Locals(constants.ClientIP, c.IP())
+		return c.Next()
+	}
+}
+
 func extractBearerToken(token string) string {
 	arrayToken := strings.Split(token, " ")
 	if len(arrayToken) == 2 {
@@ -70,19 +98,34 @@ func responseUnauthorized(c *fiber.Ctx, message string) error {
 	})
 }
 
-func validateAPIKey(c *fiber.Ctx) error {
-	apiKey := c.Get(constants.XApiKey)
-	requestAt := c.Get(constants.XRequestAt)
-	signatureKey := config.Config.SignatureKey
-
-	validateKey := fmt.Sprintf("%s:%s", signatureKey, requestAt)
-	hash := sha256.New()
-	hash.Write([]byte(validateKey))
-	resultHash := hex.EncodeToString(hash.Sum(nil))
+func responseForbidden(c *fiber.Ctx, message string) error {
+	return c.Status(http.StatusForbidden).JSON(response.Response{
+		Status:  constants.Error,
+		Message: message,
+	})
+}
 
-	if apiKey != resultHash {
+// validateAPIKey checks the request signature of the API client named by
+// x-api-key and stores the client in Locals.
+func validateAPIKey(c *fiber.Ctx) error {
+	if service == nil {
 		return errConstant.ErrUnauthorized
 	}
+
+	client, err := service.GetApiClient().Verify(c.Context(), &dto.SignedRequest{
+		KeyID:     c.Get(constants.XApiKey),
+		RequestAt: c.Get(constants.XRequestAt),
+		Nonce:     c.Get(constants.XNonce),
+		Signature: c.Get(constants.XSignature),
+		Method:    c.Method(),
+		Path:      c.OriginalURL(),
+		Body:      c.Body(),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Locals(constants.ApiClient, client)
 	return nil
 }
 
@@ -96,115 +139,102 @@ func validateBearerToken(c *fiber.Ctx, token string) error {
 		return errConstant.ErrUnauthorized
 	}
 
-	claims := &services.Claims{}
-	tokenJwt, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, errConstant.ErrInvalidToken
-		}
+	if service == nil {
+		return errConstant.ErrUnauthorized
+	}
 
-		jwtSecret := []byte(config.Config.JwtSecretKey)
-		return jwtSecret, nil
-	})
+	claims, err := service.GetUser().ParseToken(tokenString)
+	if err != nil {
+		return err
+	}
 
-	if err != nil || !tokenJwt.Valid {
-		return errConstant.ErrUnauthorized
+	err = validateSession(c, claims.SessionID)
+	if err != nil && !isAccountSetupPending(err) {
+		return err
 	}
 
 	c.Locals(constants.UserLogin, claims.User)
+	c.Locals(constants.SessionID, claims.SessionID)
 	c.Set(constants.Token, token)
-	return nil
+	return err
 }
 
-func CheckRole(allowedRoles []string) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		// Ambil token dari header Authorization
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Missing authorization header",
-			})
-		}
+// validateSession rejects access tokens whose session was logged out or
+// revoked, even when the token itself has not expired yet. A pending
+// password change or two-factor setup is passed up so the caller decides
+// whether the route is still allowed.
+func validateSession(c *fiber.Ctx, sessionID string) error {
+	if service == nil {
+		return errConstant.ErrUnauthorized
+	}
 
-		// Bersihkan prefix "Bearer "
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid authorization format. Use: Bearer <token>",
-			})
-		}
+	return service.GetUser().ValidateSession(c.Context(), sessionID)
+}
 
-		// Parse dan validasi JWT token
-		claims := jwt.MapClaims{}
-		parsedToken, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// CRITICAL: Validasi algoritma signing untuk mencegah algorithm confusion attack
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			// Ambil JWT secret dari config
-			return []byte(config.Config.JwtSecretKey), nil
-		})
+// isAccountSetupPending reports whether the user still has to change their
+// password or set up two-factor authentication before using the API.
+func isAccountSetupPending(err error) bool {
+	return errors.Is(err, errUser.ErrPasswordChangeRequired) || errors.Is(err, errUser.ErrTwoFactorSetupRequired)
+}
 
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or expired token",
-			})
-		}
+// RequirePermission lets the request through when the role in the access
+// token has been granted permission. It reuses the claims Authenticate
+// already parsed and validates the bearer token itself when it runs alone.
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals(constants.UserLogin).(*dto.UserResponse)
+		if !ok {
+			token := c.Get(constants.Authorization)
+			if token == "" {
+				return responseUnauthorized(c, errConstant.ErrUnauthorized.Error())
+			}
 
-		if !parsedToken.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Token is not valid",
-			})
-		}
+			if err := validateBearerToken(c, token); err != nil {
+				if isAccountSetupPending(err) {
+					return responseForbidden(c, err.Error())
+				}
 
-		if exp, ok := claims["exp"].(float64); ok {
-			if time.Now().Unix() > int64(exp) {
-				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-					"error": "Token has expired",
-				})
+				return responseUnauthorized(c, err.Error())
 			}
-		}
 
-		userData, ok := claims["User"].(map[string]interface{})
-		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "User data not found in token",
-			})
+			user, _ = c.Locals(constants.UserLogin).(*dto.UserResponse)
 		}
 
-		userRole, ok := userData["role"].(string)
-		if !ok || userRole == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Role not found in token",
-			})
-		}
-
-		userRole = strings.ToLower(strings.TrimSpace(userRole))
-
-		hasPermission := false
-		for _, allowedRole := range allowedRoles {
-			if strings.ToLower(allowedRole) == userRole {
-				hasPermission = true
-				break
-			}
+		if user == nil || user.Role == "" {
+			return responseUnauthorized(c, errConstant.ErrUnauthorized.Error())
 		}
 
-		if !hasPermission {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error":   "Forbidden: insufficient permissions",
-				"message": fmt.Sprintf("Required role: %v, your role: %s", allowedRoles, userRole),
+		allowed, err := service.GetRole().HasPermission(c.Context(), user.Role, permission)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(response.Response{
+				Status:  constants.Error,
+				Message: err.Error(),
 			})
 		}
 
-		c.Locals("uuid", userData["uuid"].(string))
-		c.Locals("username", userData["username"].(string))
-		c.Locals("role", userRole)
+		if !allowed {
+			return responseForbidden(c, errRole.ErrPermissionDenied.Error())
+		}
 
 		return c.Next()
 	}
 }
 
+// Authenticate requires a valid access token. Users who have to change
+// their password or set up two-factor authentication are turned away with
+// 403 until they did.
 func Authenticate() fiber.Handler {
+	return authenticate(false)
+}
+
+// AuthenticateAllowingAccountSetup is Authenticate for the few routes a
+// user with a pending password change or two-factor setup still needs, like
+// doing it.
+func AuthenticateAllowingAccountSetup() fiber.Handler {
+	return authenticate(true)
+}
+
+func authenticate(allowAccountSetup bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var err error
 		token := c.Get(constants.Authorization)
@@ -213,7 +243,11 @@ func Authenticate() fiber.Handler {
 		}
 
 		err = validateBearerToken(c, token)
-		if err != nil {
+		if isAccountSetupPending(err) {
+			if !allowAccountSetup {
+				return responseForbidden(c, err.Error())
+			}
+		} else if err != nil {
 			return responseUnauthorized(c, err.Error())
 		}
 
@@ -225,3 +259,59 @@ func Authenticate() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// RequireTerminal only lets requests from a registered terminal through and
+// stores the terminal in Locals.
+func RequireTerminal() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		terminal, err := service.GetTerminal().Authenticate(c.Context(), c.Get(constants.XTerminalKey))
+		if err != nil {
+			return responseUnauthorized(c, err.Error())
+		}
+
+		c.Locals(constants.Terminal, terminal)
+		return c.Next()
+	}
+}
+
+// RequireIfMatch enforces optimistic concurrency on writes. The parsed
+// versions are stored in Locals, nil means the client sent a wildcard.
+func RequireIfMatch() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(constants.XIfMatch)
+		if header == "" {
+			return c.Status(http.StatusPreconditionRequired).JSON(response.Response{
+				Status:  constants.Error,
+				Message: errConstant.ErrPreconditionRequired.Error(),
+			})
+		}
+
+		versions, ok := util.ParseIfMatch(header)
+		if !ok {
+			return c.Status(http.StatusPreconditionFailed).JSON(response.Response{
+				Status:  constants.Error,
+				Message: errConstant.ErrPreconditionFailed.Error(),
+			})
+		}
+
+		c.Locals(constants.IfMatch, versions)
+		return c.Next()
+	}
+}
+
+// RequireMergePatch only lets JSON merge patches through. Plain JSON is
+// accepted as well since most clients send that by default.
+func RequireMergePatch() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contentType, _, _ := strings.Cut(strings.ToLower(c.Get(fiber.HeaderContentType)), ";")
+		contentType = strings.TrimSpace(contentType)
+		if contentType != constants.MergePatchJSON && contentType != fiber.MIMEApplicationJSON {
+			return c.Status(http.StatusUnsupportedMediaType).JSON(response.Response{
+				Status:  constants.Error,
+				Message: errConstant.ErrUnsupportedMediaType.Error(),
+			})
+		}
+
+		return c.Next()
+	}
+}
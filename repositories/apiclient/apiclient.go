@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	errConstant "backend/constants/error"
+	errApiClient "backend/constants/error/apiclient"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type ApiClientRepository struct {
+	db *gorm.DB
+}
+
+type IApiClientRepository interface {
+	Create(context.Context, *models.ApiClient) error
+	FindAll(context.Context) ([]models.ApiClient, error)
+	FindByUUID(context.Context, string) (*models.ApiClient, error)
+	FindByKeyID(context.Context, string) (*models.ApiClient, error)
+	Rotate(context.Context, uint, string, time.Time) error
+	Revoke(context.Context, uint) error
+	Touch(context.Context, uint) error
+	UseNonce(context.Context, uint, string, time.Time) error
+}
+
+func NewApiClientRepository(db *gorm.DB) IApiClientRepository {
+	return &ApiClientRepository{
+		db: db,
+	}
+}
+
+// activeSecrets preloads only the secrets that still verify signatures.
+func activeSecrets(db *gorm.DB) *gorm.DB {
+	return db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Order("id DESC")
+}
+
+// Create stores the client together with its first secret.
+func (a *ApiClientRepository) Create(ctx context.Context, client *models.ApiClient) error {
+	err := a.db.WithContext(ctx).Create(client).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+func (a *ApiClientRepository) FindAll(ctx context.Context) ([]models.ApiClient, error) {
+	var clients []models.ApiClient
+
+	err := a.db.WithContext(ctx).Preload("Secrets", activeSecrets).Order("id").Find(&clients).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return clients, nil
+}
+
+func (a *ApiClientRepository) FindByUUID(ctx context.Context, uuid string) (*models.ApiClient, error) {
+	var client models.ApiClient
+
+	err := a.db.WithContext(ctx).Preload("Secrets", activeSecrets).Where("uuid = ?", uuid).First(&client).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errApiClient.ErrApiClientNotFound
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &client, nil
+}
+
+// FindByKeyID only finds clients that have not been revoked, with the
+// secrets that have not expired yet.
+func (a *ApiClientRepository) FindByKeyID(ctx context.Context, keyID string) (*models.ApiClient, error) {
+	var client models.ApiClient
+
+	err := a.db.WithContext(ctx).
+		Preload("Secrets", activeSecrets).
+		Where("key_id = ? AND revoked_at IS NULL", keyID).
+		First(&client).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errApiClient.ErrApiKeyInvalid
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &client, nil
+}
+
+// Rotate adds a new secret and lets the current ones expire at overlapUntil,
+// secrets that already expire sooner keep their time.
+func (a *ApiClientRepository) Rotate(ctx context.Context, clientID uint, secret string, overlapUntil time.Time) error {
+	err := a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ApiClientSecret{}).
+			Where("api_client_id = ? AND (expires_at IS NULL OR expires_at > ?)", clientID, overlapUntil).
+			Update("expires_at", overlapUntil).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.ApiClientSecret{ApiClientID: clientID, Secret: secret}).Error
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+func (a *ApiClientRepository) Revoke(ctx context.Context, id uint) error {
+	err := a.db.WithContext(ctx).
+		Model(&models.ApiClient{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+func (a *ApiClientRepository) Touch(ctx context.Context, id uint) error {
+	err := a.db.WithContext(ctx).
+		Model(&models.ApiClient{}).
+		Where("id = ?", id).
+		Update("last_used_at", time.Now()).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// UseNonce records the nonce until expiresAt and returns ErrNonceReused when
+// the client already used it. Expired nonces of the client are dropped on
+// the way since their requests would be refused as too old anyway.
+func (a *ApiClientRepository) UseNonce(ctx context.Context, clientID uint, nonce string, expiresAt time.Time) error {
+	var inserted int64
+	err := a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("api_client_id = ? AND expires_at <= ?", clientID, time.Now()).
+			Delete(&models.ApiNonce{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ApiNonce{
+			ApiClientID: clientID,
+			Nonce:       nonce,
+			ExpiresAt:   expiresAt,
+		})
+		inserted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if inserted == 0 {
+		return errApiClient.ErrNonceReused
+	}
+
+	return nil
+}
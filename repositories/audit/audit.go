@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	"backend/common/util"
+	errConstant "backend/constants/error"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// AuditLogRepository only reads, the entries are written by the audit
+// plugin of the database.
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+type IAuditLogRepository interface {
+	FindAllWithPagination(context.Context, *dto.AuditLogRequestParam) ([]models.AuditLog, int64, error)
+	FindAllWithCursor(context.Context, *dto.AuditLogCursorParam) ([]models.AuditLog, *util.CursorPage, error)
+}
+
+func NewAuditLogRepository(db *gorm.DB) IAuditLogRepository {
+	return &AuditLogRepository{
+		db: db,
+	}
+}
+
+// filter applies the optional filters of param, FindAllWithPagination uses
+// it for both the page and the total count.
+func (a *AuditLogRepository) filter(db *gorm.DB, param *dto.AuditLogFilterParam) *gorm.DB {
+	if param.Entity != nil && *param.Entity != "" {
+		db = db.Where("entity = ?", strings.TrimSpace(*param.Entity))
+	}
+
+	if param.EntityID != nil && *param.EntityID != "" {
+		db = db.Where("entity_id = ?", strings.TrimSpace(*param.EntityID))
+	}
+
+	if param.Action != nil && *param.Action != "" {
+		db = db.Where("action = ?", *param.Action)
+	}
+
+	if param.Actor != nil && *param.Actor != "" {
+		db = db.Where("actor_uuid = ?", *param.Actor)
+	}
+
+	if param.RequestID != nil && *param.RequestID != "" {
+		db = db.Where("request_id = ?", strings.TrimSpace(*param.RequestID))
+	}
+
+	if param.From != nil && *param.From != "" {
+		if date, err := time.ParseInLocation(time.DateOnly, *param.From, time.Local); err == nil {
+			db = db.Where("created_at >= ?", date)
+		}
+	}
+
+	if param.To != nil && *param.To != "" {
+		if date, err := time.ParseInLocation(time.DateOnly, *param.To, time.Local); err == nil {
+			db = db.Where("created_at < ?", date.AddDate(0, 0, 1))
+		}
+	}
+
+	return db
+}
+
+// FindAllWithPagination returns the newest changes first.
+func (a *AuditLogRepository) FindAllWithPagination(ctx context.Context, param *dto.AuditLogRequestParam) ([]models.AuditLog, int64, error) {
+	var (
+		logs  []models.AuditLog
+		total int64
+	)
+
+	limit := param.Limit
+	offset := (param.Page - 1) * limit
+	err := a.filter(a.db.WithContext(ctx), &param.AuditLogFilterParam).
+		Limit(limit).
+		Offset(offset).
+		Order("created_at DESC, id DESC").
+		Find(&logs).
+		Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	err = a.filter(a.db.WithContext(ctx), &param.AuditLogFilterParam).
+		Model(&models.AuditLog{}).
+		Count(&total).
+		Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return logs, total, nil
+}
+
+// FindAllWithCursor returns the newest changes first, page by page through
+// keyset pagination so deep pages stay as fast as the first.
+func (a *AuditLogRepository) FindAllWithCursor(ctx context.Context, param *dto.AuditLogCursorParam) ([]models.AuditLog, *util.CursorPage, error) {
+	db := a.filter(a.db.WithContext(ctx), &param.AuditLogFilterParam)
+	logs, page, err := util.CursorPaginate[models.AuditLog](db, &dto.AuditLogListSpec, "", param.CursorParam)
+	if err != nil {
+		if errors.Is(err, errConstant.ErrInvalidCursor) {
+			return nil, nil, errWrap.WrapError(errConstant.ErrInvalidCursor)
+		}
+
+		return nil, nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return logs, page, nil
+}
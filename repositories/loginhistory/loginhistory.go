@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	"backend/common/util"
+	errConstant "backend/constants/error"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type LoginHistoryRepository struct {
+	db *gorm.DB
+}
+
+type ILoginHistoryRepository interface {
+	Create(context.Context, *models.LoginHistory) error
+	FindAllWithPagination(context.Context, *dto.LoginHistoryRequestParam) ([]models.LoginHistory, int64, error)
+	FindAllWithCursor(context.Context, *dto.LoginHistoryCursorParam) ([]models.LoginHistory, *util.CursorPage, error)
+}
+
+func NewLoginHistoryRepository(db *gorm.DB) ILoginHistoryRepository {
+	return &LoginHistoryRepository{
+		db: db,
+	}
+}
+
+func (l *LoginHistoryRepository) Create(ctx context.Context, history *models.LoginHistory) error {
+	err := l.db.WithContext(ctx).Create(history).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// filter applies the optional filters of param, FindAllWithPagination uses
+// it for both the page and the total count.
+func (l *LoginHistoryRepository) filter(db *gorm.DB, param *dto.LoginHistoryFilterParam) *gorm.DB {
+	if param.UserID != nil {
+		db = db.Where("user_id = ?", *param.UserID)
+	}
+
+	if param.Username != nil && *param.Username != "" {
+		db = db.Where("username = ?", strings.TrimSpace(*param.Username))
+	}
+
+	if param.Method != nil && *param.Method != "" {
+		db = db.Where("method = ?", *param.Method)
+	}
+
+	if param.Success != nil {
+		db = db.Where("success = ?", *param.Success)
+	}
+
+	if param.IP != nil && *param.IP != "" {
+		db = db.Where("ip_address = ?", strings.TrimSpace(*param.IP))
+	}
+
+	return db
+}
+
+// FindAllWithPagination returns the newest attempts first.
+func (l *LoginHistoryRepository) FindAllWithPagination(ctx context.Context, param *dto.LoginHistoryRequestParam) ([]models.LoginHistory, int64, error) {
+	var (
+		histories []models.LoginHistory
+		total     int64
+	)
+
+	limit := param.Limit
+	offset := (param.Page - 1) * limit
+	err := l.filter(l.db.WithContext(ctx), &param.LoginHistoryFilterParam).
+		Preload("User").
+		Limit(limit).
+		Offset(offset).
+		Order("created_at DESC, id DESC").
+		Find(&histories).
+		Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	err = l.filter(l.db.WithContext(ctx), &param.LoginHistoryFilterParam).
+		Model(&models.LoginHistory{}).
+		Count(&total).
+		Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return histories, total, nil
+}
+
+// FindAllWithCursor returns the newest attempts first, page by page through
+// keyset pagination so deep pages stay as fast as the first.
+func (l *LoginHistoryRepository) FindAllWithCursor(ctx context.Context, param *dto.LoginHistoryCursorParam) ([]models.LoginHistory, *util.CursorPage, error) {
+	db := l.filter(l.db.WithContext(ctx), &param.LoginHistoryFilterParam).Preload("User")
+	histories, page, err := util.CursorPaginate[models.LoginHistory](db, &dto.LoginHistoryListSpec, "", param.CursorParam)
+	if err != nil {
+		if errors.Is(err, errConstant.ErrInvalidCursor) {
+			return nil, nil, errWrap.WrapError(errConstant.ErrInvalidCursor)
+		}
+
+		return nil, nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return histories, page, nil
+}
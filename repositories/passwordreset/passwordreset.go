@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	errConstant "backend/constants/error"
+	errUser "backend/constants/error/user"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+type IPasswordResetRepository interface {
+	Create(context.Context, *models.PasswordResetToken) error
+	FindValid(context.Context, string) (*models.PasswordResetToken, error)
+	Use(context.Context, uint) error
+	CountSince(context.Context, uint, time.Time) (int64, error)
+}
+
+func NewPasswordResetRepository(db *gorm.DB) IPasswordResetRepository {
+	return &PasswordResetRepository{
+		db: db,
+	}
+}
+
+// Create drops the other tokens of the user first, only the link mailed last
+// resets the password.
+func (p *PasswordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", token.UserID).
+			Delete(&models.PasswordResetToken{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(token).Error
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// FindValid returns the unused, unexpired token with its user, or
+// ErrPasswordResetTokenInvalid.
+func (p *PasswordResetRepository) FindValid(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+
+	err := p.db.WithContext(ctx).
+		Preload("User").
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+		First(&token).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errUser.ErrPasswordResetTokenInvalid
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &token, nil
+}
+
+// Use marks the token as used. Of two requests with the same token only the
+// one that marked it gets through.
+func (p *PasswordResetRepository) Use(ctx context.Context, id uint) error {
+	result := p.db.WithContext(ctx).
+		Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if result.RowsAffected == 0 {
+		return errUser.ErrPasswordResetTokenInvalid
+	}
+
+	return nil
+}
+
+// CountSince counts the tokens the user was sent since the given time.
+func (p *PasswordResetRepository) CountSince(ctx context.Context, userID uint, since time.Time) (int64, error) {
+	var total int64
+
+	err := p.db.WithContext(ctx).
+		Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Count(&total).
+		Error
+	if err != nil {
+		return 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return total, nil
+}
@@ -2,15 +2,17 @@ package repositories
 
 import (
 	errWrap "backend/common/error"
+	"backend/common/util"
 	errConstant "backend/constants/error"
 	errProduct "backend/constants/error/product"
 	"backend/domain/dto"
 	"backend/domain/models"
 	"context"
 	"errors"
-	"fmt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
 type ProductRepository struct {
@@ -19,47 +21,118 @@ type ProductRepository struct {
 
 type IProductRepository interface {
 	FindAllWithPagination(context.Context, *dto.ProductRequestParam) ([]models.Product, int64, error)
-	FindAllWithoutPagination(context.Context) ([]models.Product, error)
+	FindAllWithoutPagination(context.Context, *dto.ProductFilterParam) ([]models.Product, error)
+	FindAllWithCursor(context.Context, *dto.ProductCursorParam) ([]models.Product, *util.CursorPage, error)
 	FindByUUID(context.Context, string) (*models.Product, error)
+	FindByUUIDWithArchived(context.Context, string) (*models.Product, error)
 	FindByCode(context.Context, string) (*models.Product, error)
 	Create(context.Context, *dto.ProductRequest) (*models.Product, error)
-	Update(context.Context, string, *dto.UpdateProductRequest) (*models.Product, error)
-	Delete(context.Context, string) error
+	Update(context.Context, string, *dto.UpdateProductRequest, []uint) (*models.Product, error)
+	Patch(context.Context, string, map[string]interface{}, []uint) error
+	Delete(context.Context, string, []uint) error
+	Restore(context.Context, string) error
+	Purge(context.Context, string) error
+	UpdateImage(context.Context, string, *string, *string) error
+	FindByCodes(context.Context, []string) ([]models.Product, error)
+	Import(context.Context, []dto.ProductRequest) (int, int, int, error)
+	Stream(context.Context, *dto.ProductFilterParam, func(*models.Product) error) error
 }
 
 func NewProductRepository(db *gorm.DB) IProductRepository {
 	return &ProductRepository{db: db}
 }
 
+// filter applies the shared list filters. Archived products are hidden
+// unless they are explicitly included. Every value is passed as a bind
+// parameter, never formatted into the query.
+func (p *ProductRepository) filter(db *gorm.DB, param *dto.ProductFilterParam) *gorm.DB {
+	if param.Include == "archived" {
+		db = db.Unscoped()
+	}
+
+	if len(param.UUIDs) > 0 {
+		db = db.Where("uuid IN ?", param.UUIDs)
+	}
+
+	if param.Search != nil && strings.TrimSpace(*param.Search) != "" {
+		search := "%" + util.EscapeLike(strings.TrimSpace(*param.Search)) + "%"
+		db = db.Where("(name ILIKE ? OR code ILIKE ?)", search, search)
+	}
+
+	if param.MinPrice != nil {
+		db = db.Where("price_sale >= ?", *param.MinPrice)
+	}
+	if param.MaxPrice != nil {
+		db = db.Where("price_sale <= ?", *param.MaxPrice)
+	}
+
+	if param.OutOfStock {
+		db = db.Where("stock = 0")
+	} else {
+		if param.MinStock != nil {
+			db = db.Where("stock >= ?", *param.MinStock)
+		}
+		if param.MaxStock != nil {
+			db = db.Where("stock <= ?", *param.MaxStock)
+		}
+	}
+
+	conditions, _ := dto.ProductListSpec.ParseFilters(param.Filters)
+	for _, condition := range conditions {
+		db = db.Where(condition.Column+" = ?", condition.Value)
+	}
+
+	if param.Unit != nil && *param.Unit != "" {
+		db = db.Where("LOWER(unit) = LOWER(?)", *param.Unit)
+	}
+
+	db = filterDateRange(db, "created_at", param.CreatedFrom, param.CreatedTo)
+	db = filterDateRange(db, "updated_at", param.UpdatedFrom, param.UpdatedTo)
+
+	return db
+}
+
+// filterDateRange treats both dates as inclusive days in the local timezone.
+func filterDateRange(db *gorm.DB, column string, from, to *string) *gorm.DB {
+	if from != nil && *from != "" {
+		if date, err := time.ParseInLocation(time.DateOnly, *from, time.Local); err == nil {
+			db = db.Where(column+" >= ?", date)
+		}
+	}
+
+	if to != nil && *to != "" {
+		if date, err := time.ParseInLocation(time.DateOnly, *to, time.Local); err == nil {
+			db = db.Where(column+" < ?", date.AddDate(0, 0, 1))
+		}
+	}
+
+	return db
+}
+
+func (p *ProductRepository) sort(param *dto.ProductFilterParam) string {
+	return dto.ProductListSpec.OrderFor(param.SortExpression())
+}
+
 func (p *ProductRepository) FindAllWithPagination(ctx context.Context, param *dto.ProductRequestParam) ([]models.Product, int64, error) {
 	var (
 		products []models.Product
-		sort     string
 		total    int64
 	)
 
-	if param.SortColumn != nil {
-		sort = fmt.Sprintf("%s %s", *param.SortColumn, *param.SortOrder)
-	} else {
-		sort = "created_at desc"
-	}
-
 	limit := param.Limit
 	offset := (param.Page - 1) * limit
-	err := p.db.
-		WithContext(ctx).
+	err := p.filter(p.db.WithContext(ctx), &param.ProductFilterParam).
 		Limit(limit).
 		Offset(offset).
-		Order(sort).
+		Order(p.sort(&param.ProductFilterParam)).
 		Find(&products).
 		Error
 	if err != nil {
 		return nil, 0, errWrap.WrapError(errConstant.ErrSQLError)
 	}
 
-	err = p.db.
-		WithContext(ctx).
-		Model(&products).
+	err = p.filter(p.db.WithContext(ctx), &param.ProductFilterParam).
+		Model(&models.Product{}).
 		Count(&total).
 		Error
 	if err != nil {
@@ -69,10 +142,24 @@ func (p *ProductRepository) FindAllWithPagination(ctx context.Context, param *dt
 	return products, total, nil
 }
 
-func (p *ProductRepository) FindAllWithoutPagination(ctx context.Context) ([]models.Product, error) {
+func (p *ProductRepository) FindAllWithCursor(ctx context.Context, param *dto.ProductCursorParam) ([]models.Product, *util.CursorPage, error) {
+	db := p.filter(p.db.WithContext(ctx), &param.ProductFilterParam)
+	products, page, err := util.CursorPaginate[models.Product](db, &dto.ProductListSpec, param.SortExpression(), param.CursorParam)
+	if err != nil {
+		if errors.Is(err, errConstant.ErrInvalidCursor) {
+			return nil, nil, errWrap.WrapError(errConstant.ErrInvalidCursor)
+		}
+
+		return nil, nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return products, page, nil
+}
+
+func (p *ProductRepository) FindAllWithoutPagination(ctx context.Context, param *dto.ProductFilterParam) ([]models.Product, error) {
 	var products []models.Product
-	err := p.db.
-		WithContext(ctx).
+	err := p.filter(p.db.WithContext(ctx), param).
+		Order(p.sort(param)).
 		Find(&products).
 		Error
 	if err != nil {
@@ -101,6 +188,26 @@ func (p *ProductRepository) FindByUUID(ctx context.Context, uuid string) (*model
 	return &product, nil
 }
 
+func (p *ProductRepository) FindByUUIDWithArchived(ctx context.Context, uuid string) (*models.Product, error) {
+	var product models.Product
+	err := p.db.
+		WithContext(ctx).
+		Unscoped().
+		Where("uuid = ?", uuid).
+		First(&product).
+		Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errProduct.ErrProductNotFound)
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &product, nil
+}
+
 func (p *ProductRepository) FindByCode(ctx context.Context, code string) (*models.Product, error) {
 	var product models.Product
 	err := p.db.
@@ -133,13 +240,16 @@ func (p *ProductRepository) Create(ctx context.Context, req *dto.ProductRequest)
 
 	err := p.db.WithContext(ctx).Create(&product).Error
 	if err != nil {
-		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+		return nil, writeError(err)
 	}
 
 	return &product, nil
 }
 
-func (p *ProductRepository) Update(ctx context.Context, uuid string, req *dto.UpdateProductRequest) (*models.Product, error) {
+// Update only changes non-zero fields. When versions are set the row is only
+// written if it still has one of them, otherwise ErrPreconditionFailed is
+// returned so concurrent edits never overwrite each other silently.
+func (p *ProductRepository) Update(ctx context.Context, uuid string, req *dto.UpdateProductRequest, versions []uint) (*models.Product, error) {
 	product := models.Product{
 		Code:      req.Code,
 		Name:      req.Name,
@@ -149,21 +259,266 @@ func (p *ProductRepository) Update(ctx context.Context, uuid string, req *dto.Up
 		Unit:      req.Unit,
 	}
 
-	err := p.db.WithContext(ctx).Where("uuid = ?", uuid).Updates(&product).Error
+	values := map[string]interface{}{}
+	if req.Code != "" {
+		values["code"] = req.Code
+	}
+	if req.Name != "" {
+		values["name"] = req.Name
+	}
+	if req.PriceBuy != 0 {
+		values["price_buy"] = req.PriceBuy
+	}
+	if req.PriceSale != 0 {
+		values["price_sale"] = req.PriceSale
+	}
+	if req.Stock != 0 {
+		values["stock"] = req.Stock
+	}
+	if req.Unit != "" {
+		values["unit"] = req.Unit
+	}
+
+	err := p.Patch(ctx, uuid, values, versions)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errWrap.WrapError(errProduct.ErrProductNotFound)
-		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// Patch writes values as given, zero values included, and bumps the version.
+func (p *ProductRepository) Patch(ctx context.Context, uuid string, values map[string]interface{}, versions []uint) error {
+	columns := map[string]interface{}{
+		"version": gorm.Expr("version + 1"),
+	}
+	for column, value := range values {
+		columns[column] = value
+	}
+
+	result := p.versioned(p.db.WithContext(ctx), uuid, versions).
+		Model(&models.Product{}).
+		Updates(columns)
+	if result.Error != nil {
+		return writeError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return p.missingOrModified(ctx, uuid)
+	}
+
+	return nil
+}
+
+// writeError maps a failed write, a code another product already has comes
+// back as ErrProductIsExist.
+func writeError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return errWrap.WrapError(errProduct.ErrProductIsExist)
+	}
+
+	return errWrap.WrapError(errConstant.ErrSQLError)
+}
+
+func (p *ProductRepository) Delete(ctx context.Context, uuid string, versions []uint) error {
+	result := p.versioned(p.db.WithContext(ctx), uuid, versions).Delete(&models.Product{})
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if result.RowsAffected == 0 {
+		return p.missingOrModified(ctx, uuid)
+	}
+
+	return nil
+}
+
+func (p *ProductRepository) versioned(db *gorm.DB, uuid string, versions []uint) *gorm.DB {
+	db = db.Where("uuid = ?", uuid)
+	if versions != nil {
+		db = db.Where("version IN ?", versions)
+	}
+
+	return db
+}
+
+// missingOrModified tells apart why a versioned write touched no row.
+func (p *ProductRepository) missingOrModified(ctx context.Context, uuid string) error {
+	var count int64
+	err := p.db.WithContext(ctx).Model(&models.Product{}).Where("uuid = ?", uuid).Count(&count).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if count == 0 {
+		return errWrap.WrapError(errProduct.ErrProductNotFound)
+	}
+
+	return errWrap.WrapError(errConstant.ErrPreconditionFailed)
+}
+
+func (p *ProductRepository) Restore(ctx context.Context, uuid string) error {
+	err := p.db.
+		WithContext(ctx).
+		Unscoped().
+		Model(&models.Product{}).
+		Where("uuid = ?", uuid).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+func (p *ProductRepository) Purge(ctx context.Context, uuid string) error {
+	err := p.db.WithContext(ctx).Unscoped().Where("uuid = ?", uuid).Delete(&models.Product{}).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
 
+func (p *ProductRepository) UpdateImage(ctx context.Context, uuid string, image, thumbnail *string) error {
+	err := p.db.
+		WithContext(ctx).
+		Model(&models.Product{}).
+		Where("uuid = ?", uuid).
+		Updates(map[string]interface{}{
+			"image":     image,
+			"thumbnail": thumbnail,
+			"version":   gorm.Expr("version + 1"),
+		}).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// FindByCodes includes archived products, their code is still taken.
+func (p *ProductRepository) FindByCodes(ctx context.Context, codes []string) ([]models.Product, error) {
+	var products []models.Product
+	if len(codes) == 0 {
+		return products, nil
+	}
+
+	err := p.db.
+		WithContext(ctx).
+		Unscoped().
+		Where("code IN ?", codes).
+		Find(&products).
+		Error
+	if err != nil {
 		return nil, errWrap.WrapError(errConstant.ErrSQLError)
 	}
 
-	return &product, nil
+	return products, nil
 }
 
-func (p *ProductRepository) Delete(ctx context.Context, uuid string) error {
-	err := p.db.WithContext(ctx).Where("uuid = ?", uuid).Delete(&models.Product{}).Error
+// Import upserts the products by code in a single transaction, rows without a
+// code are always created. An archived product with the same code is
+// restored and updated. Either every row is written or none of them.
+func (p *ProductRepository) Import(ctx context.Context, requests []dto.ProductRequest) (int, int, int, error) {
+	var created, updated, restored int
+
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, req := range requests {
+			var existing models.Product
+			if req.Code != "" {
+				err := tx.Unscoped().Where("code = ?", req.Code).Limit(1).Find(&existing).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			if existing.ID != 0 {
+				archived := existing.DeletedAt.Valid
+				values := map[string]interface{}{
+					"name":       req.Name,
+					"price_buy":  req.PriceBuy,
+					"price_sale": req.PriceSale,
+					"stock":      req.Stock,
+					"unit":       req.Unit,
+					"version":    gorm.Expr("version + 1"),
+				}
+				if archived {
+					values["deleted_at"] = nil
+				}
+
+				err := tx.Unscoped().Model(&existing).Updates(values).Error
+				if err != nil {
+					return err
+				}
+
+				if archived {
+					restored++
+				} else {
+					updated++
+				}
+				continue
+			}
+
+			product := models.Product{
+				UUID:      uuid.New(),
+				Code:      req.Code,
+				Name:      req.Name,
+				PriceBuy:  req.PriceBuy,
+				PriceSale: req.PriceSale,
+				Stock:     req.Stock,
+				Unit:      req.Unit,
+			}
+
+			err := tx.Create(&product).Error
+			if err != nil {
+				return err
+			}
+
+			created++
+		}
+
+		return nil
+	})
 	if err != nil {
+		return 0, 0, 0, writeError(err)
+	}
+
+	return created, updated, restored, nil
+}
+
+// Stream walks every product matching the filter one row at a time so large
+// exports never hold the whole catalog in memory.
+func (p *ProductRepository) Stream(ctx context.Context, param *dto.ProductFilterParam, fn func(*models.Product) error) error {
+	db := p.db.WithContext(ctx)
+	rows, err := p.filter(db, param).
+		Model(&models.Product{}).
+		Order(p.sort(param)).
+		Rows()
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product models.Product
+		err = db.ScanRows(rows, &product)
+		if err != nil {
+			return errWrap.WrapError(errConstant.ErrSQLError)
+		}
+
+		err = fn(&product)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
 		return errWrap.WrapError(errConstant.ErrSQLError)
 	}
 
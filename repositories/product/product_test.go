@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"backend/domain/dto"
+	"backend/domain/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// filterSQL returns the WHERE clause and its bind values the list filter
+// builds for param, without running it.
+func filterSQL(t *testing.T, param *dto.ProductFilterParam) (string, []interface{}) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		DryRun: true,
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	repository := &ProductRepository{db: db}
+	stmt := repository.filter(db.Model(&models.Product{}), param).Find(&[]models.Product{}).Statement
+
+	sql := stmt.SQL.String()
+	if _, where, ok := strings.Cut(sql, " WHERE "); ok {
+		return where, stmt.Vars
+	}
+
+	return "", stmt.Vars
+}
+
+func TestFilter(t *testing.T) {
+	text := func(value string) *string { return &value }
+	number := func(value uint) *uint { return &value }
+
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		param     dto.ProductFilterParam
+		wantWhere string
+		wantVars  []interface{}
+	}{
+		{
+			name:      "archived are hidden",
+			wantWhere: "`products`.`deleted_at` IS NULL",
+		},
+		{
+			name:  "archived included",
+			param: dto.ProductFilterParam{Include: "archived"},
+		},
+		{
+			// Wildcards typed by the user are matched literally.
+			name:      "search",
+			param:     dto.ProductFilterParam{Search: text(" 50%_off\\ ")},
+			wantWhere: "((name ILIKE ? OR code ILIKE ?)) AND `products`.`deleted_at` IS NULL",
+			wantVars:  []interface{}{`%50\%\_off\\%`, `%50\%\_off\\%`},
+		},
+		{
+			name:      "blank search",
+			param:     dto.ProductFilterParam{Search: text("  ")},
+			wantWhere: "`products`.`deleted_at` IS NULL",
+		},
+		{
+			name:      "price and stock ranges",
+			param:     dto.ProductFilterParam{MinPrice: number(1000), MaxPrice: number(5000), MinStock: number(1), MaxStock: number(10)},
+			wantWhere: "price_sale >= ? AND price_sale <= ? AND stock >= ? AND stock <= ? AND `products`.`deleted_at` IS NULL",
+			wantVars:  []interface{}{uint(1000), uint(5000), uint(1), uint(10)},
+		},
+		{
+			name:      "out of stock overrides the stock range",
+			param:     dto.ProductFilterParam{OutOfStock: true, MinStock: number(1)},
+			wantWhere: "stock = 0 AND `products`.`deleted_at` IS NULL",
+		},
+		{
+			name:      "whitelisted filters",
+			param:     dto.ProductFilterParam{Filters: map[string]string{"name": "Kopi", "code": "KP-01"}},
+			wantWhere: "code = ? AND name = ? AND `products`.`deleted_at` IS NULL",
+			wantVars:  []interface{}{"KP-01", "Kopi"},
+		},
+		{
+			name:      "unit ignores case",
+			param:     dto.ProductFilterParam{Unit: text("PCS")},
+			wantWhere: "LOWER(unit) = LOWER(?) AND `products`.`deleted_at` IS NULL",
+			wantVars:  []interface{}{"PCS"},
+		},
+		{
+			// The end date is a whole day, so the range ends at the start of
+			// the day after it.
+			name:      "created dates",
+			param:     dto.ProductFilterParam{CreatedFrom: text("2026-10-01"), CreatedTo: text("2026-10-19")},
+			wantWhere: "created_at >= ? AND created_at < ? AND `products`.`deleted_at` IS NULL",
+			wantVars:  []interface{}{from, to},
+		},
+		{
+			name:      "updated from only",
+			param:     dto.ProductFilterParam{UpdatedFrom: text("2026-10-01")},
+			wantWhere: "updated_at >= ? AND `products`.`deleted_at` IS NULL",
+			wantVars:  []interface{}{from},
+		},
+		{
+			name:      "invalid dates are ignored",
+			param:     dto.ProductFilterParam{CreatedFrom: text("01-10-2026"), UpdatedTo: text("")},
+			wantWhere: "`products`.`deleted_at` IS NULL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, vars := filterSQL(t, &tt.param)
+			if where != tt.wantWhere {
+				t.Errorf("WHERE %s, want %s", where, tt.wantWhere)
+			}
+			if len(vars) != 0 || len(tt.wantVars) != 0 {
+				if !reflect.DeepEqual(vars, tt.wantVars) {
+					t.Errorf("bind values %#v, want %#v", vars, tt.wantVars)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,18 @@
 package repositories
 
 import (
+	apiClientRepositories "backend/repositories/apiclient"
+	auditRepositories "backend/repositories/audit"
+	loginHistoryRepositories "backend/repositories/loginhistory"
+	passwordResetRepositories "backend/repositories/passwordreset"
 	productRepositories "backend/repositories/product"
+	roleRepositories "backend/repositories/role"
+	sessionRepositories "backend/repositories/session"
+	terminalRepositories "backend/repositories/terminal"
+	throttleRepositories "backend/repositories/throttle"
+	twoFactorRepositories "backend/repositories/twofactor"
 	userRepositories "backend/repositories/user"
+	"context"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +23,16 @@ type Registry struct {
 type IRepositoryRegistry interface {
 	GetUser() userRepositories.IUserRepository
 	GetProduct() productRepositories.IProductRepository
+	GetSession() sessionRepositories.ISessionRepository
+	GetRole() roleRepositories.IRoleRepository
+	GetLoginThrottle() throttleRepositories.ILoginThrottleRepository
+	GetTerminal() terminalRepositories.ITerminalRepository
+	GetTwoFactor() twoFactorRepositories.ITwoFactorRepository
+	GetApiClient() apiClientRepositories.IApiClientRepository
+	GetLoginHistory() loginHistoryRepositories.ILoginHistoryRepository
+	GetAuditLog() auditRepositories.IAuditLogRepository
+	GetPasswordReset() passwordResetRepositories.IPasswordResetRepository
+	Transaction(context.Context, func(IRepositoryRegistry) error) error
 }
 
 func NewRepositoryRegistry(db *gorm.DB) IRepositoryRegistry {
@@ -26,3 +46,47 @@ func (r *Registry) GetUser() userRepositories.IUserRepository {
 func (r *Registry) GetProduct() productRepositories.IProductRepository {
 	return productRepositories.NewProductRepository(r.db)
 }
+
+func (r *Registry) GetSession() sessionRepositories.ISessionRepository {
+	return sessionRepositories.NewSessionRepository(r.db)
+}
+
+func (r *Registry) GetRole() roleRepositories.IRoleRepository {
+	return roleRepositories.NewRoleRepository(r.db)
+}
+
+func (r *Registry) GetLoginThrottle() throttleRepositories.ILoginThrottleRepository {
+	return throttleRepositories.NewLoginThrottleRepository(r.db)
+}
+
+func (r *Registry) GetTerminal() terminalRepositories.ITerminalRepository {
+	return terminalRepositories.NewTerminalRepository(r.db)
+}
+
+func (r *Registry) GetTwoFactor() twoFactorRepositories.ITwoFactorRepository {
+	return twoFactorRepositories.NewTwoFactorRepository(r.db)
+}
+
+func (r *Registry) GetApiClient() apiClientRepositories.IApiClientRepository {
+	return apiClientRepositories.NewApiClientRepository(r.db)
+}
+
+func (r *Registry) GetLoginHistory() loginHistoryRepositories.ILoginHistoryRepository {
+	return loginHistoryRepositories.NewLoginHistoryRepository(r.db)
+}
+
+func (r *Registry) GetAuditLog() auditRepositories.IAuditLogRepository {
+	return auditRepositories.NewAuditLogRepository(r.db)
+}
+
+func (r *Registry) GetPasswordReset() passwordResetRepositories.IPasswordResetRepository {
+	return passwordResetRepositories.NewPasswordResetRepository(r.db)
+}
+
+// Transaction runs fn with a registry whose repositories share one database
+// transaction. Everything fn wrote is rolled back when it returns an error.
+func (r *Registry) Transaction(ctx context.Context, fn func(IRepositoryRegistry) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Registry{db: tx})
+	})
+}
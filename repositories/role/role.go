@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	errConstant "backend/constants/error"
+	errRole "backend/constants/error/role"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+type IRoleRepository interface {
+	FindAll(context.Context) ([]models.Role, error)
+	FindByCode(context.Context, string) (*models.Role, error)
+	Create(context.Context, *models.Role) error
+	Update(context.Context, *models.Role, []models.Permission) error
+	SetRequireTwoFactor(context.Context, *models.Role, bool) error
+	Delete(context.Context, *models.Role) error
+	CountUsers(context.Context, uint) (int64, error)
+	FindPermissions(context.Context) ([]models.Permission, error)
+	FindPermissionsByCodes(context.Context, []string) ([]models.Permission, error)
+	HasPermission(context.Context, string, string) (bool, error)
+}
+
+func NewRoleRepository(db *gorm.DB) IRoleRepository {
+	return &RoleRepository{
+		db: db,
+	}
+}
+
+func (r *RoleRepository) FindAll(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+
+	err := r.db.WithContext(ctx).Preload("Permissions").Order("id").Find(&roles).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return roles, nil
+}
+
+// FindByCode ignores case, role codes are stored upper case but the token
+// carries them lower case.
+func (r *RoleRepository) FindByCode(ctx context.Context, code string) (*models.Role, error) {
+	var role models.Role
+
+	err := r.db.WithContext(ctx).
+		Preload("Permissions").
+		Where("code = ?", strings.ToUpper(code)).
+		First(&role).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errRole.ErrRoleNotFound
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &role, nil
+}
+
+// Create stores the role together with the permissions set on it.
+func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
+	role.Code = strings.ToUpper(role.Code)
+
+	err := r.db.WithContext(ctx).Create(role).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// Update renames the role and replaces its permissions in one transaction.
+func (r *RoleRepository) Update(ctx context.Context, role *models.Role, permissions []models.Permission) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(role).Update("name", role.Name).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(role).Association("Permissions").Replace(permissions)
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	role.Permissions = permissions
+	return nil
+}
+
+func (r *RoleRepository) SetRequireTwoFactor(ctx context.Context, role *models.Role, required bool) error {
+	err := r.db.WithContext(ctx).Model(role).Update("require_two_factor", required).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) Delete(ctx context.Context, role *models.Role) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(role).Association("Permissions").Clear()
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(role).Error
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) CountUsers(ctx context.Context, roleID uint) (int64, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("role_id = ?", roleID).Count(&count).Error
+	if err != nil {
+		return 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return count, nil
+}
+
+func (r *RoleRepository) FindPermissions(ctx context.Context) ([]models.Permission, error) {
+	var permissions []models.Permission
+
+	err := r.db.WithContext(ctx).Order("code").Find(&permissions).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return permissions, nil
+}
+
+func (r *RoleRepository) FindPermissionsByCodes(ctx context.Context, codes []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+
+	err := r.db.WithContext(ctx).Where("code IN ?", codes).Find(&permissions).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return permissions, nil
+}
+
+func (r *RoleRepository) HasPermission(ctx context.Context, roleCode, permission string) (bool, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).
+		Table("role_permissions").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("roles.code = ? AND permissions.code = ?", strings.ToUpper(roleCode), permission).
+		Count(&count).
+		Error
+	if err != nil {
+		return false, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	errConstant "backend/constants/error"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+type ISessionRepository interface {
+	Create(context.Context, uint, *uint, dto.SessionDevice, string, time.Time) (*models.Session, error)
+	FindRefreshToken(context.Context, string) (*models.RefreshToken, error)
+	Rotate(context.Context, *models.RefreshToken, string, time.Time) error
+	Revoke(context.Context, string) error
+	RevokeByUser(context.Context, uint) error
+	RevokeOthers(context.Context, uint, string) error
+	RevokeByTerminal(context.Context, uint) error
+	FindActive(context.Context, string) (*models.Session, error)
+	FindActiveByUser(context.Context, uint) ([]models.Session, error)
+	RevokeOwned(context.Context, uint, string) error
+	Touch(context.Context, uint) error
+}
+
+// touchInterval limits how often a session in use writes its last seen time.
+const touchInterval = time.Minute
+
+func NewSessionRepository(db *gorm.DB) ISessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+// Create starts a session for the user together with its first refresh
+// token. terminalID is set when the user logged in by PIN at a terminal.
+func (s *SessionRepository) Create(ctx context.Context, userID uint, terminalID *uint, device dto.SessionDevice, tokenHash string, expiresAt time.Time) (*models.Session, error) {
+	now := time.Now()
+	session := models.Session{
+		UUID:       uuid.New(),
+		UserID:     userID,
+		TerminalID: terminalID,
+		DeviceName: device.DeviceName,
+		IPAddress:  device.IPAddress,
+		UserAgent:  device.UserAgent,
+		LastUsedAt: &now,
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.RefreshToken{
+			SessionID: session.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &session, nil
+}
+
+func (s *SessionRepository) FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+
+	err := s.db.WithContext(ctx).
+		Preload("Session.User.Role").
+		Where("token_hash = ?", tokenHash).
+		First(&token).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errUser.ErrRefreshTokenInvalid
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &token, nil
+}
+
+// Rotate marks token as used and stores its successor in the same session.
+// The used check is part of the update so two requests racing with the same
+// token can not both rotate it, the loser gets ErrRefreshTokenReused.
+func (s *SessionRepository) Rotate(ctx context.Context, token *models.RefreshToken, tokenHash string, expiresAt time.Time) error {
+	now := time.Now()
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errUser.ErrRefreshTokenReused
+		}
+
+		err := tx.Model(&models.Session{}).
+			Where("id = ?", token.SessionID).
+			Update("last_used_at", now).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.RefreshToken{
+			SessionID: token.SessionID,
+			TokenHash: tokenHash,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, errUser.ErrRefreshTokenReused) {
+			return err
+		}
+
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// Revoke ends the session with the given UUID. Revoking an already revoked
+// session keeps the first revocation time.
+func (s *SessionRepository) Revoke(ctx context.Context, uuid string) error {
+	err := s.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("uuid = ? AND revoked_at IS NULL", uuid).
+		Update("revoked_at", time.Now()).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// RevokeByUser ends every open session of the user, used when an admin
+// deactivates them, resets their password or changes their role.
+func (s *SessionRepository) RevokeByUser(ctx context.Context, userID uint) error {
+	err := s.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// RevokeOthers ends every open session of the user except the one with the
+// given UUID, the one the request was made with.
+func (s *SessionRepository) RevokeOthers(ctx context.Context, userID uint, uuid string) error {
+	err := s.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("user_id = ? AND uuid <> ? AND revoked_at IS NULL", userID, uuid).
+		Update("revoked_at", time.Now()).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// RevokeByTerminal ends the sessions started at the terminal, when another
+// user takes over the till or the terminal is revoked.
+func (s *SessionRepository) RevokeByTerminal(ctx context.Context, terminalID uint) error {
+	err := s.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("terminal_id = ? AND revoked_at IS NULL", terminalID).
+		Update("revoked_at", time.Now()).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// FindActive returns the session with its user and role, or ErrSessionRevoked
+// when it does not exist or was revoked.
+func (s *SessionRepository) FindActive(ctx context.Context, uuid string) (*models.Session, error) {
+	var session models.Session
+
+	err := s.db.WithContext(ctx).
+		Preload("User.Role").
+		Where("uuid = ? AND revoked_at IS NULL", uuid).
+		First(&session).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errUser.ErrSessionRevoked
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &session, nil
+}
+
+// FindActiveByUser returns the open sessions of the user that still hold a
+// usable refresh token, most recently seen first.
+func (s *SessionRepository) FindActiveByUser(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Where("EXISTS (?)", s.db.Model(&models.RefreshToken{}).
+			Select("1").
+			Where("refresh_tokens.session_id = sessions.id AND used_at IS NULL AND expires_at > ?", time.Now())).
+		Order("last_used_at DESC, id DESC").
+		Find(&sessions).
+		Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return sessions, nil
+}
+
+// RevokeOwned ends the session with the given UUID only when it belongs to
+// the user, otherwise it reports ErrSessionNotFound.
+func (s *SessionRepository) RevokeOwned(ctx context.Context, userID uint, uuid string) error {
+	result := s.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("uuid = ? AND user_id = ? AND revoked_at IS NULL", uuid, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if result.RowsAffected == 0 {
+		return errUser.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// Touch records that the session was just used. It writes at most once per
+// touchInterval so busy sessions do not update their row on every request.
+func (s *SessionRepository) Touch(ctx context.Context, id uint) error {
+	now := time.Now()
+	err := s.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-touchInterval)).
+		Update("last_used_at", now).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	errConstant "backend/constants/error"
+	errTerminal "backend/constants/error/terminal"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type TerminalRepository struct {
+	db *gorm.DB
+}
+
+type ITerminalRepository interface {
+	Create(context.Context, *models.Terminal) error
+	FindAll(context.Context) ([]models.Terminal, error)
+	FindByUUID(context.Context, string) (*models.Terminal, error)
+	FindByKeyHash(context.Context, string) (*models.Terminal, error)
+	Touch(context.Context, uint) error
+	Revoke(context.Context, uint) error
+	SavePin(context.Context, uint, uint, string) error
+	FindPin(context.Context, uint, uint) (*models.TerminalPin, error)
+	DeletePin(context.Context, uint, uint) error
+	FindUsers(context.Context, uint) ([]models.User, error)
+}
+
+func NewTerminalRepository(db *gorm.DB) ITerminalRepository {
+	return &TerminalRepository{
+		db: db,
+	}
+}
+
+func (t *TerminalRepository) Create(ctx context.Context, terminal *models.Terminal) error {
+	err := t.db.WithContext(ctx).Create(terminal).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+func (t *TerminalRepository) FindAll(ctx context.Context) ([]models.Terminal, error) {
+	var terminals []models.Terminal
+
+	err := t.db.WithContext(ctx).Order("id").Find(&terminals).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return terminals, nil
+}
+
+func (t *TerminalRepository) FindByUUID(ctx context.Context, uuid string) (*models.Terminal, error) {
+	var terminal models.Terminal
+
+	err := t.db.WithContext(ctx).Where("uuid = ?", uuid).First(&terminal).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errTerminal.ErrTerminalNotFound
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &terminal, nil
+}
+
+// FindByKeyHash only finds terminals that have not been revoked.
+func (t *TerminalRepository) FindByKeyHash(ctx context.Context, keyHash string) (*models.Terminal, error) {
+	var terminal models.Terminal
+
+	err := t.db.WithContext(ctx).
+		Where("key_hash = ? AND revoked_at IS NULL", keyHash).
+		First(&terminal).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errTerminal.ErrTerminalUnauthorized
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &terminal, nil
+}
+
+func (t *TerminalRepository) Touch(ctx context.Context, id uint) error {
+	err := t.db.WithContext(ctx).
+		Model(&models.Terminal{}).
+		Where("id = ?", id).
+		Update("last_seen_at", time.Now()).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// Revoke stops the terminal key from working. The PINs enrolled on it go
+// with it.
+func (t *TerminalRepository) Revoke(ctx context.Context, id uint) error {
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Terminal{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("terminal_id = ?", id).Delete(&models.TerminalPin{}).Error
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// SavePin enrolls the PIN of the user on the terminal, replacing the one
+// they had there.
+func (t *TerminalRepository) SavePin(ctx context.Context, terminalID, userID uint, pinHash string) error {
+	now := time.Now()
+	err := t.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "terminal_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"pin_hash":   pinHash,
+			"updated_at": now,
+		}),
+	}).Create(&models.TerminalPin{
+		TerminalID: terminalID,
+		UserID:     userID,
+		PinHash:    pinHash,
+	}).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+func (t *TerminalRepository) FindPin(ctx context.Context, terminalID, userID uint) (*models.TerminalPin, error) {
+	var pin models.TerminalPin
+
+	err := t.db.WithContext(ctx).
+		Where("terminal_id = ? AND user_id = ?", terminalID, userID).
+		First(&pin).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errTerminal.ErrPinNotEnrolled
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &pin, nil
+}
+
+func (t *TerminalRepository) DeletePin(ctx context.Context, terminalID, userID uint) error {
+	err := t.db.WithContext(ctx).
+		Where("terminal_id = ? AND user_id = ?", terminalID, userID).
+		Delete(&models.TerminalPin{}).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// FindUsers returns the active users with a PIN on the terminal.
+func (t *TerminalRepository) FindUsers(ctx context.Context, terminalID uint) ([]models.User, error) {
+	var users []models.User
+
+	err := t.db.WithContext(ctx).
+		Joins("JOIN terminal_pins ON terminal_pins.user_id = users.id").
+		Where("terminal_pins.terminal_id = ? AND users.is_active = ?", terminalID, true).
+		Order("users.name").
+		Find(&users).
+		Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return users, nil
+}
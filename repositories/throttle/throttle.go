@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	errConstant "backend/constants/error"
+	"backend/domain/models"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type LoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+type ILoginThrottleRepository interface {
+	Find(context.Context, string, string) (*models.LoginThrottle, error)
+	RecordFailure(context.Context, string, string, time.Duration) (*models.LoginThrottle, error)
+	Lock(context.Context, uint, time.Time) error
+	Reset(context.Context, string, string) error
+	ResetPrefix(context.Context, string, string) error
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) ILoginThrottleRepository {
+	return &LoginThrottleRepository{
+		db: db,
+	}
+}
+
+// Find returns the throttle of the subject, a subject without failures gets
+// an empty one.
+func (l *LoginThrottleRepository) Find(ctx context.Context, scope, subject string) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+
+	result := l.db.WithContext(ctx).Where("scope = ? AND subject = ?", scope, subject).Limit(1).Find(&throttle)
+	if result.Error != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if result.RowsAffected == 0 {
+		return &models.LoginThrottle{Scope: scope, Subject: subject}, nil
+	}
+
+	return &throttle, nil
+}
+
+// RecordFailure counts a failed login in a single upsert so parallel
+// attempts can not lose increments. Failures older than window are
+// forgotten and counting starts over.
+func (l *LoginThrottleRepository) RecordFailure(ctx context.Context, scope, subject string, window time.Duration) (*models.LoginThrottle, error) {
+	now := time.Now()
+	throttle := models.LoginThrottle{
+		Scope:        scope,
+		Subject:      subject,
+		Failures:     1,
+		LastFailedAt: now,
+	}
+
+	err := l.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "scope"}, {Name: "subject"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures": gorm.Expr(
+				"CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failures + 1 END",
+				now.Add(-window),
+			),
+			"last_failed_at": now,
+			"updated_at":     now,
+		}),
+	}).Create(&throttle).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return l.Find(ctx, scope, subject)
+}
+
+func (l *LoginThrottleRepository) Lock(ctx context.Context, id uint, until time.Time) error {
+	err := l.db.WithContext(ctx).
+		Model(&models.LoginThrottle{}).
+		Where("id = ?", id).
+		Update("locked_until", until).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// Reset forgets the failures of the subject, after a successful login or
+// when an owner unlocks the account.
+func (l *LoginThrottleRepository) Reset(ctx context.Context, scope, subject string) error {
+	err := l.db.WithContext(ctx).
+		Where("scope = ? AND subject = ?", scope, subject).
+		Delete(&models.LoginThrottle{}).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// ResetPrefix forgets the failures of every subject of the scope starting
+// with prefix, like the PIN logins of one user on all terminals.
+func (l *LoginThrottleRepository) ResetPrefix(ctx context.Context, scope, prefix string) error {
+	err := l.db.WithContext(ctx).
+		Where("scope = ? AND subject LIKE ?", scope, prefix+"%").
+		Delete(&models.LoginThrottle{}).
+		Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	errWrap "backend/common/error"
+	errConstant "backend/constants/error"
+	errUser "backend/constants/error/user"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+type ITwoFactorRepository interface {
+	CreateChallenge(context.Context, *models.LoginChallenge) error
+	FindChallenge(context.Context, string) (*models.LoginChallenge, error)
+	DeleteChallenge(context.Context, uint) error
+	ReplaceRecoveryCodes(context.Context, uint, []string) error
+	UseRecoveryCode(context.Context, uint, string) error
+	CountRecoveryCodes(context.Context, uint) (int64, error)
+	DeleteRecoveryCodes(context.Context, uint) error
+}
+
+func NewTwoFactorRepository(db *gorm.DB) ITwoFactorRepository {
+	return &TwoFactorRepository{
+		db: db,
+	}
+}
+
+// CreateChallenge also clears the expired challenges of the user so the
+// ones that were never answered do not pile up.
+func (t *TwoFactorRepository) CreateChallenge(ctx context.Context, challenge *models.LoginChallenge) error {
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND expires_at <= ?", challenge.UserID, time.Now()).
+			Delete(&models.LoginChallenge{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(challenge).Error
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// FindChallenge returns the unexpired challenge with its user and role, or
+// ErrChallengeInvalid.
+func (t *TwoFactorRepository) FindChallenge(ctx context.Context, tokenHash string) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+
+	err := t.db.WithContext(ctx).
+		Preload("User.Role").
+		Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).
+		First(&challenge).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errUser.ErrChallengeInvalid
+		}
+
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return &challenge, nil
+}
+
+// DeleteChallenge uses the challenge up. Of two requests answering the same
+// challenge only the one that deleted it gets through.
+func (t *TwoFactorRepository) DeleteChallenge(ctx context.Context, id uint) error {
+	result := t.db.WithContext(ctx).Where("id = ?", id).Delete(&models.LoginChallenge{})
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if result.RowsAffected == 0 {
+		return errUser.ErrChallengeInvalid
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes drops every recovery code of the user, used or not,
+// and stores the new hashes.
+func (t *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, hashes []string) error {
+	codes := make([]models.RecoveryCode, 0, len(hashes))
+	for _, hash := range hashes {
+		codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&codes).Error
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused code of the user as used, or returns
+// ErrTwoFactorCodeIncorrect when there is none with that hash.
+func (t *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uint, hash string) error {
+	result := t.db.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if result.RowsAffected == 0 {
+		return errUser.ErrTwoFactorCodeIncorrect
+	}
+
+	return nil
+}
+
+// CountRecoveryCodes counts the codes of the user that are still unused.
+func (t *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+
+	err := t.db.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).
+		Error
+	if err != nil {
+		return 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return count, nil
+}
+
+func (t *TwoFactorRepository) DeleteRecoveryCodes(ctx context.Context, userID uint) error {
+	err := t.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
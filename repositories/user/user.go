@@ -2,6 +2,7 @@ package repositories
 
 import (
 	errWrap "backend/common/error"
+	"backend/common/util"
 	errConstant "backend/constants/error"
 	errUser "backend/constants/error/user"
 	"backend/domain/dto"
@@ -10,6 +11,7 @@ import (
 	"errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"strings"
 )
 
 type UserRepository struct {
@@ -18,10 +20,16 @@ type UserRepository struct {
 
 type IUserRepository interface {
 	Register(context.Context, *dto.RegisterRequest) (*models.User, error)
-	Update(context.Context, *dto.UpdateRequest, string) (*models.User, error)
+	Update(context.Context, *dto.UpdateRequest, string, []uint) (*models.User, error)
+	Patch(context.Context, string, map[string]interface{}, []uint) error
 	FindByUsername(context.Context, string) (*models.User, error)
 	FindByEmail(context.Context, string) (*models.User, error)
 	FindByUUID(context.Context, string) (*models.User, error)
+	FindAllWithPagination(context.Context, *dto.UserRequestParam) ([]models.User, int64, error)
+	FindPasswordHistory(context.Context, uint, int) ([]string, error)
+	AddPasswordHistory(context.Context, uint, string, int) error
+	UseTotpStep(context.Context, uint, int64) error
+	Delete(context.Context, *models.User) error
 }
 
 func NewUserRepository(db *gorm.DB) IUserRepository {
@@ -39,6 +47,7 @@ func (u *UserRepository) Register(ctx context.Context, req *dto.RegisterRequest)
 		PhoneNumber: req.PhoneNumber,
 		Email:       req.Email,
 		RoleID:      req.RoleID,
+		IsActive:    true,
 	}
 
 	err := u.db.WithContext(ctx).Create(&user).Error
@@ -49,23 +58,75 @@ func (u *UserRepository) Register(ctx context.Context, req *dto.RegisterRequest)
 	return &user, nil
 }
 
-func (u *UserRepository) Update(ctx context.Context, req *dto.UpdateRequest, uuid string) (*models.User, error) {
+// Update only changes non-empty fields. When versions are set the row is only
+// written if it still has one of them.
+func (u *UserRepository) Update(ctx context.Context, req *dto.UpdateRequest, uuid string, versions []uint) (*models.User, error) {
 	user := models.User{
 		Name:        req.Name,
 		Username:    req.Username,
-		Password:    *req.Password,
 		PhoneNumber: req.PhoneNumber,
 		Email:       req.Email,
 	}
 
-	err := u.db.WithContext(ctx).Model(&user).Where("uuid = ?", uuid).Updates(&user).Error
+	values := map[string]interface{}{}
+	if user.Name != "" {
+		values["name"] = user.Name
+	}
+	if user.Username != "" {
+		values["username"] = user.Username
+	}
+	if user.PhoneNumber != "" {
+		values["phone_number"] = user.PhoneNumber
+	}
+	if user.Email != "" {
+		values["email"] = user.Email
+	}
+
+	err := u.Patch(ctx, uuid, values, versions)
 	if err != nil {
-		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+		return nil, err
 	}
 
 	return &user, nil
 }
 
+// Patch writes values as given, zero values included, and bumps the version.
+// When versions are set the row is only written if it still has one of them.
+func (u *UserRepository) Patch(ctx context.Context, uuid string, values map[string]interface{}, versions []uint) error {
+	columns := map[string]interface{}{
+		"version": gorm.Expr("version + 1"),
+	}
+	for column, value := range values {
+		columns[column] = value
+	}
+
+	db := u.db.WithContext(ctx).Model(&models.User{}).Where("uuid = ?", uuid)
+	if versions != nil {
+		db = db.Where("version IN ?", versions)
+	}
+
+	result := db.Updates(columns)
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		err := u.db.WithContext(ctx).Model(&models.User{}).Where("uuid = ?", uuid).Count(&count).Error
+		if err != nil {
+			return errWrap.WrapError(errConstant.ErrSQLError)
+		}
+
+		if count == 0 {
+			return errUser.ErrUserNotFound
+		}
+
+		return errWrap.WrapError(errConstant.ErrPreconditionFailed)
+	}
+
+	return nil
+}
+
 func (u *UserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
 
@@ -110,3 +171,129 @@ func (u *UserRepository) FindByUUID(ctx context.Context, uuid string) (*models.U
 
 	return &user, nil
 }
+
+func (u *UserRepository) filter(db *gorm.DB, param *dto.UserRequestParam) *gorm.DB {
+	if param.Search != nil && strings.TrimSpace(*param.Search) != "" {
+		search := "%" + util.EscapeLike(strings.TrimSpace(*param.Search)) + "%"
+		db = db.Where("(name ILIKE ? OR username ILIKE ? OR email ILIKE ?)", search, search, search)
+	}
+
+	if param.Role != nil && *param.Role != "" {
+		roles := u.db.Model(&models.Role{}).Select("id").Where("code = ?", strings.ToUpper(*param.Role))
+		db = db.Where("role_id IN (?)", roles)
+	}
+
+	switch param.Status {
+	case "active":
+		db = db.Where("is_active = ?", true)
+	case "inactive":
+		db = db.Where("is_active = ?", false)
+	}
+
+	return db
+}
+
+func (u *UserRepository) FindAllWithPagination(ctx context.Context, param *dto.UserRequestParam) ([]models.User, int64, error) {
+	var (
+		users []models.User
+		total int64
+	)
+
+	limit := param.Limit
+	offset := (param.Page - 1) * limit
+	err := u.filter(u.db.WithContext(ctx), param).
+		Preload("Role").
+		Limit(limit).
+		Offset(offset).
+		Order("name, id").
+		Find(&users).
+		Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	err = u.filter(u.db.WithContext(ctx), param).
+		Model(&models.User{}).
+		Count(&total).
+		Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return users, total, nil
+}
+
+// FindPasswordHistory returns the latest limit previous password hashes of
+// the user, newest first.
+func (u *UserRepository) FindPasswordHistory(ctx context.Context, userID uint, limit int) ([]string, error) {
+	var hashes []string
+
+	err := u.db.WithContext(ctx).
+		Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).
+		Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return hashes, nil
+}
+
+// AddPasswordHistory stores a replaced password hash and only keeps the keep
+// newest ones of the user.
+func (u *UserRepository) AddPasswordHistory(ctx context.Context, userID uint, hash string, keep int) error {
+	err := u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&models.PasswordHistory{UserID: userID, PasswordHash: hash}).Error
+		if err != nil {
+			return err
+		}
+
+		newest := tx.Model(&models.PasswordHistory{}).
+			Select("id").
+			Where("user_id = ?", userID).
+			Order("id DESC").
+			Limit(keep)
+
+		return tx.Where("user_id = ? AND id NOT IN (?)", userID, newest).
+			Delete(&models.PasswordHistory{}).
+			Error
+	})
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
+
+// UseTotpStep records the time step of an accepted TOTP code. A code from
+// the same or an earlier step than the last one used is a replay and gets
+// ErrTwoFactorCodeIncorrect.
+func (u *UserRepository) UseTotpStep(ctx context.Context, userID uint, step int64) error {
+	result := u.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	if result.RowsAffected == 0 {
+		return errUser.ErrTwoFactorCodeIncorrect
+	}
+
+	return nil
+}
+
+// Delete removes the user. Their sessions, PINs, recovery codes and password
+// history go with them, their login history is kept without the link.
+func (u *UserRepository) Delete(ctx context.Context, user *models.User) error {
+	err := u.db.WithContext(ctx).Delete(user).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSQLError)
+	}
+
+	return nil
+}
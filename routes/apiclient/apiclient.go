@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"backend/constants"
+	"backend/controllers"
+	"backend/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ApiClientRoute struct {
+	controller controllers.IControllerRegistry
+	group      fiber.Router
+}
+
+type IApiClientRoute interface {
+	Run()
+}
+
+func NewApiClientRoute(controller controllers.IControllerRegistry, group fiber.Router) IApiClientRoute {
+	return &ApiClientRoute{
+		controller: controller,
+		group:      group,
+	}
+}
+
+func (r *ApiClientRoute) Run() {
+	group := r.group.Group("/api-clients", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionApiClientManage))
+	controller := r.controller.GetApiClientController()
+	group.Get("", controller.GetAll)
+	group.Post("", controller.Create)
+	group.Post("/:uuid/rotate", controller.Rotate)
+	group.Delete("/:uuid", controller.Revoke)
+}
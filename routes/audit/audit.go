@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"backend/constants"
+	"backend/controllers"
+	"backend/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditLogRoute struct {
+	controller controllers.IControllerRegistry
+	group      fiber.Router
+}
+
+type IAuditLogRoute interface {
+	Run()
+}
+
+func NewAuditLogRoute(controller controllers.IControllerRegistry, group fiber.Router) IAuditLogRoute {
+	return &AuditLogRoute{
+		controller: controller,
+		group:      group,
+	}
+}
+
+// Run only registers reads, the audit log can not be changed through the API.
+func (r *AuditLogRoute) Run() {
+	group := r.group.Group("/audit-logs", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionAuditRead))
+	group.Get("", r.controller.GetAuditLogController().GetAllWithPagination)
+	group.Get("/cursor", r.controller.GetAuditLogController().GetAllWithCursor)
+}
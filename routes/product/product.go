@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"backend/constants"
 	"backend/controllers"
 	"backend/middlewares"
 	"github.com/gofiber/fiber/v2"
@@ -24,12 +25,37 @@ func NewProductRoute(controller controllers.IControllerRegistry, group fiber.Rou
 
 func (r *ProductRoute) Run() {
 	group := r.group.Group("/products")
-	group.Get("", middlewares.Authenticate(), r.controller.GetProductController().GetAllWithoutPagination)
-	group.Get("/pagination", middlewares.Authenticate(), r.controller.GetProductController().GetAllWithPagination)
-	group.Get("/:uuid", middlewares.Authenticate(), r.controller.GetProductController().GetByUUID)
-	group.Get("/code/:code", middlewares.Authenticate(), r.controller.GetProductController().GetByCode)
+	controller := r.controller.GetProductController()
+	group.Get("", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductRead), controller.GetAllWithoutPagination)
+	group.Get("/pagination", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductRead), controller.GetAllWithPagination)
+	group.Get("/cursor", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductRead), controller.GetAllWithCursor)
+	group.Get("/export", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductExport), controller.Export)
+	group.Get("/:uuid", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductRead), controller.GetByUUID)
+	group.Get("/code/:code", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductRead), controller.GetByCode)
 
-	group.Post("", middlewares.Authenticate(), r.controller.GetProductController().Create)
-	group.Put("/:uuid", middlewares.Authenticate(), r.controller.GetProductController().Update)
-	group.Delete("/:uuid", middlewares.Authenticate(), r.controller.GetProductController().Delete)
+	group.Post("", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductCreate), controller.Create)
+	group.Post("/import", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductImport), controller.Import)
+	group.Post("/batch", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductImport), controller.Batch)
+	group.Post("/price-adjustment", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductPrice), controller.AdjustPrice)
+	group.Put("/:uuid",
+		middlewares.Authenticate(),
+		middlewares.RequirePermission(constants.PermissionProductUpdate),
+		middlewares.RequireIfMatch(),
+		controller.Update)
+	group.Patch("/:uuid",
+		middlewares.Authenticate(),
+		middlewares.RequirePermission(constants.PermissionProductUpdate),
+		middlewares.RequireMergePatch(),
+		middlewares.RequireIfMatch(),
+		controller.Patch)
+	group.Delete("/:uuid",
+		middlewares.Authenticate(),
+		middlewares.RequirePermission(constants.PermissionProductDelete),
+		middlewares.RequireIfMatch(),
+		controller.Delete)
+	group.Post("/:uuid/restore", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductDelete), controller.Restore)
+	group.Delete("/:uuid/purge", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductPurge), controller.Purge)
+
+	group.Post("/:uuid/image", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductUpdate), controller.UploadImage)
+	group.Delete("/:uuid/image", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionProductUpdate), controller.DeleteImage)
 }
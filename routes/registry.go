@@ -2,7 +2,11 @@ package routes
 
 import (
 	"backend/controllers"
+	apiClientRoutes "backend/routes/apiclient"
+	auditRoutes "backend/routes/audit"
 	productRoutes "backend/routes/product"
+	roleRoutes "backend/routes/role"
+	terminalRoutes "backend/routes/terminal"
 	userRoutes "backend/routes/user"
 	"github.com/gofiber/fiber/v2"
 )
@@ -26,6 +30,10 @@ func NewRouteRegistry(controller controllers.IControllerRegistry, group fiber.Ro
 func (r *Registry) Serve() {
 	r.userRoute().Run()
 	r.productRoute().Run()
+	r.roleRoute().Run()
+	r.terminalRoute().Run()
+	r.apiClientRoute().Run()
+	r.auditLogRoute().Run()
 }
 
 func (r *Registry) userRoute() userRoutes.IUserRoute {
@@ -35,3 +43,19 @@ func (r *Registry) userRoute() userRoutes.IUserRoute {
 func (r *Registry) productRoute() productRoutes.IProductRoute {
 	return productRoutes.NewProductRoute(r.controller, r.group)
 }
+
+func (r *Registry) roleRoute() roleRoutes.IRoleRoute {
+	return roleRoutes.NewRoleRoute(r.controller, r.group)
+}
+
+func (r *Registry) terminalRoute() terminalRoutes.ITerminalRoute {
+	return terminalRoutes.NewTerminalRoute(r.controller, r.group)
+}
+
+func (r *Registry) apiClientRoute() apiClientRoutes.IApiClientRoute {
+	return apiClientRoutes.NewApiClientRoute(r.controller, r.group)
+}
+
+func (r *Registry) auditLogRoute() auditRoutes.IAuditLogRoute {
+	return auditRoutes.NewAuditLogRoute(r.controller, r.group)
+}
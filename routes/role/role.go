@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"backend/constants"
+	"backend/controllers"
+	"backend/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RoleRoute struct {
+	controller controllers.IControllerRegistry
+	group      fiber.Router
+}
+
+type IRoleRoute interface {
+	Run()
+}
+
+func NewRoleRoute(controller controllers.IControllerRegistry, group fiber.Router) IRoleRoute {
+	return &RoleRoute{
+		controller: controller,
+		group:      group,
+	}
+}
+
+func (r *RoleRoute) Run() {
+	group := r.group.Group("/roles", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionRoleManage))
+	controller := r.controller.GetRoleController()
+	group.Get("", controller.GetAll)
+	group.Get("/permissions", controller.GetPermissions)
+	group.Post("", controller.Create)
+	group.Put("/:code", controller.Update)
+	group.Put("/:code/two-factor", controller.SetTwoFactor)
+	group.Delete("/:code", controller.Delete)
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"backend/constants"
+	"backend/controllers"
+	"backend/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type TerminalRoute struct {
+	controller controllers.IControllerRegistry
+	group      fiber.Router
+}
+
+type ITerminalRoute interface {
+	Run()
+}
+
+func NewTerminalRoute(controller controllers.IControllerRegistry, group fiber.Router) ITerminalRoute {
+	return &TerminalRoute{
+		controller: controller,
+		group:      group,
+	}
+}
+
+// Run registers the terminal administration under /terminals and what a
+// terminal itself calls, identified by its key, under /terminal.
+func (r *TerminalRoute) Run() {
+	controller := r.controller.GetTerminalController()
+
+	terminals := r.group.Group("/terminals", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionTerminalManage))
+	terminals.Get("", controller.GetAll)
+	terminals.Post("", controller.Create)
+	terminals.Delete("/:uuid", controller.Revoke)
+
+	terminal := r.group.Group("/terminal", middlewares.RequireTerminal())
+	terminal.Get("/users", controller.GetUsers)
+	terminal.Post("/login", controller.PinLogin)
+	terminal.Put("/pin", middlewares.Authenticate(), controller.EnrollPin)
+	terminal.Delete("/pin", middlewares.Authenticate(), controller.RemovePin)
+}
@@ -25,13 +25,48 @@ func NewUserRoute(controller controllers.IControllerRegistry, group fiber.Router
 
 func (r *UserRoute) Run() {
 	group := r.group.Group("/auth")
-	group.Get("/user", middlewares.Authenticate(), r.controller.GetUserController().GetUserLogin)
-	group.Get("/:uuid", middlewares.Authenticate(), r.controller.GetUserController().GetUserByUUID)
-	group.Post("/register",
-		middlewares.CheckRole(
-			[]string{constants.OwnerString},
-		),
-		r.controller.GetUserController().Register)
-	group.Post("/login", r.controller.GetUserController().Login)
-	group.Put("/:uuid", middlewares.Authenticate(), r.controller.GetUserController().Update)
+	controller := r.controller.GetUserController()
+	group.Get("/user", middlewares.AuthenticateAllowingAccountSetup(), controller.GetUserLogin)
+	group.Post("/2fa/verify", controller.VerifyTwoFactor)
+	group.Get("/2fa", middlewares.AuthenticateAllowingAccountSetup(), controller.GetTwoFactor)
+	group.Post("/2fa/setup", middlewares.AuthenticateAllowingAccountSetup(), controller.SetupTwoFactor)
+	group.Post("/2fa/enable", middlewares.AuthenticateAllowingAccountSetup(), controller.EnableTwoFactor)
+	group.Post("/2fa/disable", middlewares.Authenticate(), controller.DisableTwoFactor)
+	group.Post("/2fa/recovery-codes", middlewares.Authenticate(), controller.RegenerateRecoveryCodes)
+	group.Get("/sessions", middlewares.Authenticate(), controller.GetSessions)
+	group.Delete("/sessions/:uuid", middlewares.Authenticate(), controller.RevokeSession)
+	group.Get("/login-history", middlewares.Authenticate(), controller.GetLoginHistory)
+	group.Get("/login-history/cursor", middlewares.Authenticate(), controller.GetLoginHistoryWithCursor)
+	group.Get("/:uuid", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionUserRead), controller.GetUserByUUID)
+	group.Post("/register", middlewares.RequirePermission(constants.PermissionUserRegister), controller.Register)
+	group.Post("/login", controller.Login)
+	group.Post("/refresh", controller.Refresh)
+	group.Post("/logout", middlewares.AuthenticateAllowingAccountSetup(), controller.Logout)
+	group.Post("/password", middlewares.AuthenticateAllowingAccountSetup(), controller.ChangePassword)
+	group.Post("/forgot-password", controller.ForgotPassword)
+	group.Post("/reset-password", controller.ResetForgottenPassword)
+	group.Put("/:uuid",
+		middlewares.Authenticate(),
+		middlewares.RequirePermission(constants.PermissionUserUpdate),
+		middlewares.RequireIfMatch(),
+		controller.Update)
+	group.Patch("/:uuid",
+		middlewares.Authenticate(),
+		middlewares.RequirePermission(constants.PermissionUserUpdate),
+		middlewares.RequireMergePatch(),
+		middlewares.RequireIfMatch(),
+		controller.Patch)
+
+	users := r.group.Group("/users", middlewares.Authenticate(), middlewares.RequirePermission(constants.PermissionUserManage))
+	users.Get("", controller.GetAllWithPagination)
+	users.Get("/login-history", controller.GetAllLoginHistory)
+	users.Get("/login-history/cursor", controller.GetAllLoginHistoryWithCursor)
+	users.Get("/:uuid/sessions", controller.GetUserSessions)
+	users.Delete("/:uuid/sessions/:session", controller.RevokeUserSession)
+	users.Post("/:uuid/activate", controller.Activate)
+	users.Post("/:uuid/deactivate", controller.Deactivate)
+	users.Post("/:uuid/reset-password", controller.ResetPassword)
+	users.Put("/:uuid/role", controller.AssignRole)
+	users.Delete("/:uuid", controller.Delete)
+	users.Post("/:uuid/unlock", controller.Unlock)
 }
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"backend/common/util"
+	"backend/config"
+	errApiClient "backend/constants/error/apiclient"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"backend/repositories"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"strconv"
+	"time"
+)
+
+const (
+	minNonceLength = 16
+	maxNonceLength = 64
+)
+
+type ApiClientService struct {
+	repository repositories.IRepositoryRegistry
+}
+
+type IApiClientService interface {
+	GetAll(context.Context) ([]dto.ApiClientResponse, error)
+	Create(context.Context, *dto.ApiClientRequest) (*dto.ApiClientSecretCreatedResponse, error)
+	Rotate(context.Context, string) (*dto.ApiClientSecretCreatedResponse, error)
+	Revoke(context.Context, string) error
+	Verify(context.Context, *dto.SignedRequest) (*models.ApiClient, error)
+}
+
+func NewApiClientService(repository repositories.IRepositoryRegistry) IApiClientService {
+	return &ApiClientService{repository: repository}
+}
+
+func apiClientResponse(client *models.ApiClient) dto.ApiClientResponse {
+	secrets := make([]dto.ApiClientSecretResponse, 0, len(client.Secrets))
+	for _, secret := range client.Secrets {
+		secrets = append(secrets, dto.ApiClientSecretResponse{
+			CreatedAt: secret.CreatedAt,
+			ExpiresAt: secret.ExpiresAt,
+		})
+	}
+
+	return dto.ApiClientResponse{
+		UUID:       client.UUID,
+		Name:       client.Name,
+		KeyID:      client.KeyID,
+		Secrets:    secrets,
+		LastUsedAt: client.LastUsedAt,
+		RevokedAt:  client.RevokedAt,
+		CreatedAt:  client.CreatedAt,
+	}
+}
+
+func (a *ApiClientService) GetAll(ctx context.Context) ([]dto.ApiClientResponse, error) {
+	clients, err := a.repository.GetApiClient().FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.ApiClientResponse, 0, len(clients))
+	for i := range clients {
+		result = append(result, apiClientResponse(&clients[i]))
+	}
+
+	return result, nil
+}
+
+// Create registers a client and returns its secret, the only time it is
+// shown.
+func (a *ApiClientService) Create(ctx context.Context, request *dto.ApiClientRequest) (*dto.ApiClientSecretCreatedResponse, error) {
+	keyID, err := generateKeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, _, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &models.ApiClient{
+		UUID:    uuid.New(),
+		Name:    request.Name,
+		KeyID:   keyID,
+		Secrets: []models.ApiClientSecret{{Secret: secret}},
+	}
+	if err = a.repository.GetApiClient().Create(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return &dto.ApiClientSecretCreatedResponse{
+		Client: apiClientResponse(client),
+		Secret: secret,
+	}, nil
+}
+
+// Rotate issues a new secret. The old ones keep working for
+// ApiKeyOverlapMinutes so the client can be redeployed in the meantime.
+func (a *ApiClientService) Rotate(ctx context.Context, uuid string) (*dto.ApiClientSecretCreatedResponse, error) {
+	client, err := a.repository.GetApiClient().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.RevokedAt != nil {
+		return nil, errApiClient.ErrApiClientNotFound
+	}
+
+	secret, _, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	overlapUntil := time.Now().Add(time.Duration(config.Config.ApiKeyOverlapMinutes) * time.Minute)
+	err = a.repository.GetApiClient().Rotate(ctx, client.ID, secret, overlapUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err = a.repository.GetApiClient().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ApiClientSecretCreatedResponse{
+		Client: apiClientResponse(client),
+		Secret: secret,
+	}, nil
+}
+
+func (a *ApiClientService) Revoke(ctx context.Context, uuid string) error {
+	client, err := a.repository.GetApiClient().FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	return a.repository.GetApiClient().Revoke(ctx, client.ID)
+}
+
+// Verify checks a signed request: the timestamp has to be within
+// RequestMaxAgeSeconds, the signature has to match one of the live secrets
+// of the client and the nonce must not have been used before. The nonce is
+// only recorded once the signature checked out.
+func (a *ApiClientService) Verify(ctx context.Context, request *dto.SignedRequest) (*models.ApiClient, error) {
+	requestAt, err := strconv.ParseInt(request.RequestAt, 10, 64)
+	if err != nil {
+		return nil, errApiClient.ErrRequestExpired
+	}
+
+	maxAge := time.Duration(config.Config.RequestMaxAgeSeconds) * time.Second
+	age := time.Since(time.Unix(requestAt, 0))
+	if age > maxAge || age < -maxAge {
+		return nil, errApiClient.ErrRequestExpired
+	}
+
+	if len(request.Nonce) < minNonceLength || len(request.Nonce) > maxNonceLength {
+		return nil, errApiClient.ErrNonceInvalid
+	}
+
+	if request.KeyID == "" {
+		return nil, errApiClient.ErrApiKeyInvalid
+	}
+
+	client, err := a.repository.GetApiClient().FindByKeyID(ctx, request.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := hex.DecodeString(request.Signature)
+	if err != nil {
+		return nil, errApiClient.ErrSignatureInvalid
+	}
+
+	valid := false
+	for _, secret := range client.Secrets {
+		expected, _ := hex.DecodeString(util.SignRequest(
+			secret.Secret,
+			request.Method,
+			request.Path,
+			request.RequestAt,
+			request.Nonce,
+			request.Body,
+		))
+		if hmac.Equal(expected, signature) {
+			valid = true
+		}
+	}
+
+	if !valid {
+		return nil, errApiClient.ErrSignatureInvalid
+	}
+
+	// The request is refused once it is older than maxAge, the nonce only
+	// has to be remembered until then.
+	err = a.repository.GetApiClient().UseNonce(ctx, client.ID, request.Nonce, time.Unix(requestAt, 0).Add(maxAge))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.repository.GetApiClient().Touch(ctx, client.ID); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// generateKeyID returns the public identifier a client sends as x-api-key.
+func generateKeyID() (string, error) {
+	buffer := make([]byte, 12)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	return "ak_" + hex.EncodeToString(buffer), nil
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"backend/common/util"
+	"backend/config"
+	errApiClient "backend/constants/error/apiclient"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"backend/repositories"
+	"context"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestService(t *testing.T) (*ApiClientService, *gorm.DB) {
+	t.Helper()
+
+	previous := config.Config
+	t.Cleanup(func() { config.Config = previous })
+	config.Config.RequestMaxAgeSeconds = 300
+	config.Config.ApiKeyOverlapMinutes = 60
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	err = db.AutoMigrate(&models.ApiClient{}, &models.ApiClientSecret{}, &models.ApiNonce{})
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return NewApiClientService(repositories.NewRepositoryRegistry(db)).(*ApiClientService), db
+}
+
+// newTestClient registers a client and returns its uuid, key id and secret.
+func newTestClient(t *testing.T, service *ApiClientService, name string) (string, string, string) {
+	t.Helper()
+
+	created, err := service.Create(context.Background(), &dto.ApiClientRequest{Name: name})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	return created.Client.UUID.String(), created.Client.KeyID, created.Secret
+}
+
+// signedRequest builds the request a client with keyID and secret sends at
+// requestAt.
+func signedRequest(keyID, secret, nonce string, requestAt time.Time) *dto.SignedRequest {
+	at := strconv.FormatInt(requestAt.Unix(), 10)
+	body := []byte(`{"name":"Kopi Susu","price":18000}`)
+
+	return &dto.SignedRequest{
+		KeyID:     keyID,
+		RequestAt: at,
+		Nonce:     nonce,
+		Signature: util.SignRequest(secret, "POST", "/api/v1/products", at, nonce, body),
+		Method:    "POST",
+		Path:      "/api/v1/products",
+		Body:      body,
+	}
+}
+
+func TestVerify(t *testing.T) {
+	service, _ := newTestService(t)
+	_, keyID, secret := newTestClient(t, service, "Online store")
+
+	client, err := service.Verify(context.Background(), signedRequest(keyID, secret, "nonce-0000000001", time.Now()))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if client.KeyID != keyID {
+		t.Errorf("Verify returned client %s, want %s", client.KeyID, keyID)
+	}
+
+	clients, err := service.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(clients) != 1 || clients[0].LastUsedAt == nil {
+		t.Errorf("last use of the client was not recorded: %+v", clients)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	maxAge := 300 * time.Second
+
+	tests := []struct {
+		name    string
+		tamper  func(request *dto.SignedRequest, secret string)
+		wantErr error
+	}{
+		{
+			name: "too old",
+			tamper: func(request *dto.SignedRequest, secret string) {
+				*request = *signedRequest(request.KeyID, secret, request.Nonce, time.Now().Add(-maxAge-time.Minute))
+			},
+			wantErr: errApiClient.ErrRequestExpired,
+		},
+		{
+			name: "too far in the future",
+			tamper: func(request *dto.SignedRequest, secret string) {
+				*request = *signedRequest(request.KeyID, secret, request.Nonce, time.Now().Add(maxAge+time.Minute))
+			},
+			wantErr: errApiClient.ErrRequestExpired,
+		},
+		{
+			name:    "missing request time",
+			tamper:  func(request *dto.SignedRequest, secret string) { request.RequestAt = "" },
+			wantErr: errApiClient.ErrRequestExpired,
+		},
+		{
+			name:    "request time not a unix timestamp",
+			tamper:  func(request *dto.SignedRequest, secret string) { request.RequestAt = time.Now().Format(time.RFC3339) },
+			wantErr: errApiClient.ErrRequestExpired,
+		},
+		{
+			name:    "short nonce",
+			tamper:  func(request *dto.SignedRequest, secret string) { request.Nonce = "short" },
+			wantErr: errApiClient.ErrNonceInvalid,
+		},
+		{
+			name:    "unknown key",
+			tamper:  func(request *dto.SignedRequest, secret string) { request.KeyID = "ak_unknown" },
+			wantErr: errApiClient.ErrApiKeyInvalid,
+		},
+		{
+			name:    "missing key",
+			tamper:  func(request *dto.SignedRequest, secret string) { request.KeyID = "" },
+			wantErr: errApiClient.ErrApiKeyInvalid,
+		},
+		{
+			name:    "signature not hex",
+			tamper:  func(request *dto.SignedRequest, secret string) { request.Signature = "not hex" },
+			wantErr: errApiClient.ErrSignatureInvalid,
+		},
+		{
+			name: "wrong secret",
+			tamper: func(request *dto.SignedRequest, secret string) {
+				*request = *signedRequest(request.KeyID, "wrong secret", request.Nonce, time.Now())
+			},
+			wantErr: errApiClient.ErrSignatureInvalid,
+		},
+		{
+			name: "body changed",
+			tamper: func(request *dto.SignedRequest, secret string) {
+				request.Body = []byte(`{"name":"Kopi Susu","price":1}`)
+			},
+			wantErr: errApiClient.ErrSignatureInvalid,
+		},
+		{
+			name:    "path changed",
+			tamper:  func(request *dto.SignedRequest, secret string) { request.Path = "/api/v1/products/batch" },
+			wantErr: errApiClient.ErrSignatureInvalid,
+		},
+		{
+			name:    "method changed",
+			tamper:  func(request *dto.SignedRequest, secret string) { request.Method = "PUT" },
+			wantErr: errApiClient.ErrSignatureInvalid,
+		},
+		{
+			name: "request time changed",
+			tamper: func(request *dto.SignedRequest, secret string) {
+				request.RequestAt = strconv.FormatInt(time.Now().Unix()-1, 10)
+			},
+			wantErr: errApiClient.ErrSignatureInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			_, keyID, secret := newTestClient(t, service, "Online store")
+
+			request := signedRequest(keyID, secret, "nonce-0000000001", time.Now().Add(-time.Minute))
+			tt.tamper(request, secret)
+
+			_, err := service.Verify(context.Background(), request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsReplayedNonce(t *testing.T) {
+	service, _ := newTestService(t)
+	_, keyID, secret := newTestClient(t, service, "Online store")
+	_, otherKeyID, otherSecret := newTestClient(t, service, "Marketplace")
+	ctx := context.Background()
+
+	request := signedRequest(keyID, secret, "nonce-0000000001", time.Now())
+	if _, err := service.Verify(ctx, request); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	_, err := service.Verify(ctx, request)
+	if !errors.Is(err, errApiClient.ErrNonceReused) {
+		t.Errorf("Verify of a replayed request = %v, want %v", err, errApiClient.ErrNonceReused)
+	}
+
+	// The nonce is remembered per client.
+	if _, err = service.Verify(ctx, signedRequest(otherKeyID, otherSecret, "nonce-0000000001", time.Now())); err != nil {
+		t.Errorf("Verify of the same nonce by another client: %v", err)
+	}
+
+	if _, err = service.Verify(ctx, signedRequest(keyID, secret, "nonce-0000000002", time.Now())); err != nil {
+		t.Errorf("Verify with a new nonce: %v", err)
+	}
+}
+
+func TestVerifyKeepsNonceOfForgedRequest(t *testing.T) {
+	service, _ := newTestService(t)
+	_, keyID, secret := newTestClient(t, service, "Online store")
+	ctx := context.Background()
+
+	// A forged request must not use up the nonce of the real one.
+	forged := signedRequest(keyID, "wrong secret", "nonce-0000000001", time.Now())
+	if _, err := service.Verify(ctx, forged); !errors.Is(err, errApiClient.ErrSignatureInvalid) {
+		t.Fatalf("Verify of a forged request = %v, want %v", err, errApiClient.ErrSignatureInvalid)
+	}
+
+	if _, err := service.Verify(ctx, signedRequest(keyID, secret, "nonce-0000000001", time.Now())); err != nil {
+		t.Errorf("Verify after a forged request with the same nonce: %v", err)
+	}
+}
+
+func TestVerifyConcurrentReplay(t *testing.T) {
+	service, _ := newTestService(t)
+	_, keyID, secret := newTestClient(t, service, "Online store")
+	request := signedRequest(keyID, secret, "nonce-0000000001", time.Now())
+
+	const replays = 8
+	errs := make([]error, replays)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < replays; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = service.Verify(context.Background(), request)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	accepted := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			accepted++
+		case !errors.Is(err, errApiClient.ErrNonceReused):
+			t.Errorf("replay %d = %v, want %v", i, err, errApiClient.ErrNonceReused)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("%d of %d identical requests were accepted, want 1", accepted, replays)
+	}
+}
+
+func TestVerifyAfterRotation(t *testing.T) {
+	service, db := newTestService(t)
+	clientUUID, keyID, oldSecret := newTestClient(t, service, "Online store")
+	ctx := context.Background()
+
+	rotated, err := service.Rotate(ctx, clientUUID)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	// Both secrets work while the client is redeployed.
+	if _, err = service.Verify(ctx, signedRequest(keyID, oldSecret, "nonce-0000000001", time.Now())); err != nil {
+		t.Errorf("Verify with the old secret during the overlap: %v", err)
+	}
+	if _, err = service.Verify(ctx, signedRequest(keyID, rotated.Secret, "nonce-0000000002", time.Now())); err != nil {
+		t.Errorf("Verify with the new secret: %v", err)
+	}
+
+	err = db.Model(&models.ApiClientSecret{}).
+		Where("secret = ?", oldSecret).
+		Update("expires_at", time.Now().Add(-time.Second)).
+		Error
+	if err != nil {
+		t.Fatalf("expire old secret: %v", err)
+	}
+
+	_, err = service.Verify(ctx, signedRequest(keyID, oldSecret, "nonce-0000000003", time.Now()))
+	if !errors.Is(err, errApiClient.ErrSignatureInvalid) {
+		t.Errorf("Verify with the expired secret = %v, want %v", err, errApiClient.ErrSignatureInvalid)
+	}
+
+	if err = service.Revoke(ctx, clientUUID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	_, err = service.Verify(ctx, signedRequest(keyID, rotated.Secret, "nonce-0000000004", time.Now()))
+	if !errors.Is(err, errApiClient.ErrApiKeyInvalid) {
+		t.Errorf("Verify of a revoked client = %v, want %v", err, errApiClient.ErrApiKeyInvalid)
+	}
+}
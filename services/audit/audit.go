@@ -0,0 +1,69 @@
+package services
+
+import (
+	"backend/common/util"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"backend/repositories"
+	"context"
+)
+
+type AuditLogService struct {
+	repository repositories.IRepositoryRegistry
+}
+
+type IAuditLogService interface {
+	GetAllWithPagination(context.Context, *dto.AuditLogRequestParam) (*util.PaginationResult, error)
+	GetAllWithCursor(context.Context, *dto.AuditLogCursorParam) (*util.CursorResult, error)
+}
+
+func NewAuditLogService(repository repositories.IRepositoryRegistry) IAuditLogService {
+	return &AuditLogService{repository: repository}
+}
+
+func (a *AuditLogService) GetAllWithPagination(ctx context.Context, param *dto.AuditLogRequestParam) (*util.PaginationResult, error) {
+	logs, total, err := a.repository.GetAuditLog().FindAllWithPagination(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination := &util.PaginationParam{
+		Count: total,
+		Page:  param.Page,
+		Limit: param.Limit,
+		Data:  auditLogResponses(logs),
+	}
+
+	response := util.GeneratePagination(*pagination)
+	return &response, nil
+}
+
+func (a *AuditLogService) GetAllWithCursor(ctx context.Context, param *dto.AuditLogCursorParam) (*util.CursorResult, error) {
+	logs, page, err := a.repository.GetAuditLog().FindAllWithCursor(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	response := util.GenerateCursorResult(page, param.Limit, auditLogResponses(logs))
+	return &response, nil
+}
+
+func auditLogResponses(logs []models.AuditLog) []dto.AuditLogResponse {
+	result := make([]dto.AuditLogResponse, 0, len(logs))
+	for _, log := range logs {
+		result = append(result, dto.AuditLogResponse{
+			Action:        log.Action,
+			Entity:        log.Entity,
+			EntityID:      log.EntityID,
+			ActorUUID:     log.ActorUUID,
+			ActorUsername: log.ActorUsername,
+			ApiClient:     log.ApiClient,
+			IPAddress:     log.IPAddress,
+			RequestID:     log.RequestID,
+			Changes:       log.Changes,
+			CreatedAt:     log.CreatedAt,
+		})
+	}
+
+	return result
+}
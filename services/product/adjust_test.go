@@ -0,0 +1,156 @@
+package services
+
+import (
+	errProduct "backend/constants/error/product"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAdjustPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		price    uint
+		request  dto.ProductPriceAdjustmentRequest
+		wantSale uint
+		wantErr  error
+	}{
+		{name: "percent up", price: 15000, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 10}, wantSale: 16500},
+		{name: "percent down", price: 15000, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: -20}, wantSale: 12000},
+		{name: "percent rounds to the nearest rupiah", price: 999, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 5}, wantSale: 1049},
+		{name: "fixed", price: 15000, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: 2500}, wantSale: 17500},
+		{name: "rounded to 100", price: 15000, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 3, Rounding: 100}, wantSale: 15500},
+		{name: "rounded to 500", price: 15000, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: 1240, Rounding: 500}, wantSale: 16000},
+		{name: "half rounds up", price: 1250, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: 0, Rounding: 500}, wantSale: 1500},
+		{name: "fixed below zero", price: 1000, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: -5000}, wantSale: 0},
+		{name: "percent below zero", price: 1000, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: -150}, wantSale: 0},
+		{name: "percent down to zero", price: 1000, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: -100}, wantSale: 0},
+		{name: "rounded down to zero", price: 200, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: -1, Rounding: 500}, wantSale: 0},
+		{name: "highest price kept", price: 1 << 62, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 50}, wantSale: 3 << 61},
+		{name: "percent past the highest price", price: 1 << 62, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 1000}, wantErr: errProduct.ErrProductAdjustmentTooHigh},
+		{name: "fixed past the highest price", price: math.MaxInt64 - 10, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: 1000000000}, wantErr: errProduct.ErrProductAdjustmentTooHigh},
+		{name: "rounded past the highest price", price: math.MaxInt64 - 10, request: dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: 1, Rounding: 500}, wantErr: errProduct.ErrProductAdjustmentTooHigh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adjustPrice(tt.price, &tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("adjustPrice(%d) = %v, want %v", tt.price, err, tt.wantErr)
+			}
+			if got != tt.wantSale {
+				t.Errorf("adjustPrice(%d) = %d, want %d", tt.price, got, tt.wantSale)
+			}
+		})
+	}
+}
+
+func TestAdjustPriceService(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     dto.ProductPriceAdjustmentRequest
+		uuids       func(kopi, teh string) []string
+		unscoped    bool
+		wantErr     error
+		wantApplied bool
+		wantChanged int
+		wantBelow   int
+		wantSale    uint
+	}{
+		{
+			name:        "dry run saves nothing",
+			request:     dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 10, DryRun: true},
+			wantChanged: 2,
+			wantSale:    15000,
+		},
+		{
+			name:        "applied",
+			request:     dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 10},
+			wantApplied: true,
+			wantChanged: 2,
+			wantSale:    16500,
+		},
+		{
+			name:        "picked products only",
+			request:     dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: 1000},
+			uuids:       func(kopi, teh string) []string { return []string{kopi} },
+			wantApplied: true,
+			wantChanged: 1,
+			wantSale:    16000,
+		},
+		{
+			name:        "below cost needs confirmation",
+			request:     dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: -50},
+			wantErr:     errProduct.ErrProductAdjustmentBelowCost,
+			wantChanged: 2,
+			wantBelow:   2,
+			wantSale:    15000,
+		},
+		{
+			name:        "below cost confirmed",
+			request:     dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: -50, Confirm: true},
+			wantApplied: true,
+			wantChanged: 2,
+			wantBelow:   2,
+			wantSale:    7500,
+		},
+		{
+			name:     "no uuids or filter",
+			request:  dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 10},
+			unscoped: true,
+			wantErr:  errProduct.ErrProductAdjustmentNoScope,
+			wantSale: 15000,
+		},
+		{
+			name:     "percent out of range",
+			request:  dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentPercent, Value: 1500},
+			wantErr:  errProduct.ErrProductAdjustmentPercent,
+			wantSale: 15000,
+		},
+		{
+			name:     "nothing matched",
+			request:  dto.ProductPriceAdjustmentRequest{Type: dto.ProductPriceAdjustmentFixed, Value: 1000},
+			uuids:    func(kopi, teh string) []string { return []string{unknownUUID} },
+			wantErr:  errProduct.ErrProductAdjustmentEmpty,
+			wantSale: 15000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			kopi := newTestProduct(t, db, "KP-01", "Kopi")
+			teh := newTestProduct(t, db, "KP-02", "Es Teh")
+			if tt.uuids != nil {
+				tt.request.UUIDs = tt.uuids(kopi.UUID.String(), teh.UUID.String())
+			}
+
+			// Every product is a pcs, the unit filter matches them all.
+			param := &dto.ProductFilterParam{}
+			if !tt.unscoped {
+				unit := "pcs"
+				param.Unit = &unit
+			}
+
+			result, err := service.AdjustPrice(context.Background(), param, &tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AdjustPrice = %v, want %v", err, tt.wantErr)
+			}
+			if result != nil {
+				if result.Applied != tt.wantApplied || result.Changed != tt.wantChanged || result.BelowCost != tt.wantBelow {
+					t.Errorf("AdjustPrice applied %v, changed %d, below cost %d, want %v, %d, %d",
+						result.Applied, result.Changed, result.BelowCost, tt.wantApplied, tt.wantChanged, tt.wantBelow)
+				}
+			}
+
+			var stored models.Product
+			db.First(&stored, kopi.ID)
+			if stored.PriceSale != tt.wantSale {
+				t.Errorf("stored price sale = %d, want %d", stored.PriceSale, tt.wantSale)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"backend/common/storage"
+	errProduct "backend/constants/error/product"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeleteArchives(t *testing.T) {
+	service, db := newTestService(t)
+	ctx := context.Background()
+	archived := newTestProduct(t, db, "KP-01", "Kopi")
+	newTestProduct(t, db, "KP-02", "Es Teh")
+
+	if err := service.Delete(ctx, archived.UUID.String(), nil); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := service.GetByUUID(ctx, archived.UUID.String()); !errors.Is(err, errProduct.ErrProductNotFound) {
+		t.Errorf("GetByUUID of an archived product = %v, want %v", err, errProduct.ErrProductNotFound)
+	}
+
+	// The row is kept, only hidden from the list unless asked for.
+	tests := []struct {
+		name    string
+		include string
+		want    []string
+	}{
+		{name: "active only", want: []string{"KP-02"}},
+		{name: "archived included", include: "archived", want: []string{"KP-01", "KP-02"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, asc := "code", "asc"
+			products, err := service.GetAllWithoutPagination(ctx, &dto.ProductFilterParam{
+				SortColumn: &code,
+				SortOrder:  &asc,
+				Include:    tt.include,
+			})
+			if err != nil {
+				t.Fatalf("GetAllWithoutPagination: %v", err)
+			}
+
+			var codes []string
+			for _, product := range products {
+				codes = append(codes, product.Code)
+				if (product.Code == "KP-01") != (product.DeletedAt != nil) {
+					t.Errorf("product %s has archive time %v", product.Code, product.DeletedAt)
+				}
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("listed %v, want %v", codes, tt.want)
+			}
+		})
+	}
+
+	if err := service.Delete(ctx, archived.UUID.String(), nil); !errors.Is(err, errProduct.ErrProductNotFound) {
+		t.Errorf("Delete of an archived product = %v, want %v", err, errProduct.ErrProductNotFound)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	service, db := newTestService(t)
+	ctx := context.Background()
+	archived := newTestProduct(t, db, "KP-01", "Kopi")
+	active := newTestProduct(t, db, "KP-02", "Es Teh")
+
+	if err := service.Delete(ctx, archived.UUID.String(), nil); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		uuid    string
+		wantErr error
+	}{
+		{name: "archived", uuid: archived.UUID.String()},
+		{name: "restored twice", uuid: archived.UUID.String(), wantErr: errProduct.ErrProductNotArchived},
+		{name: "never archived", uuid: active.UUID.String(), wantErr: errProduct.ErrProductNotArchived},
+		{name: "unknown", uuid: "6f1d6c0e-3c55-4a8b-9a3f-0d5a5f1c2b3d", wantErr: errProduct.ErrProductNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, err := service.Restore(ctx, tt.uuid)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Restore = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (product.Code != "KP-01" || product.DeletedAt != nil) {
+				t.Errorf("Restore returned %+v", product)
+			}
+		})
+	}
+
+	if _, err := service.GetByUUID(ctx, archived.UUID.String()); err != nil {
+		t.Errorf("GetByUUID after restore: %v", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	service, db := newTestService(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	files, err := storage.NewLocalStorage(dir, "http://localhost/uploads")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	service.storage = files
+
+	tests := []struct {
+		name    string
+		archive bool
+	}{
+		{name: "active"},
+		{name: "archived", archive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := newTestProduct(t, db, "KP-"+tt.name, "Kopi")
+			image, thumbnail := "products/"+tt.name+".png", "products/"+tt.name+"_thumb.png"
+			for _, key := range []string{image, thumbnail} {
+				if err := files.Put(ctx, key, strings.NewReader("image"), 5, "image/png"); err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+			}
+			db.Model(product).Updates(map[string]interface{}{"image": image, "thumbnail": thumbnail})
+
+			if tt.archive {
+				if err := service.Delete(ctx, product.UUID.String(), nil); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+			}
+
+			if err := service.Purge(ctx, product.UUID.String()); err != nil {
+				t.Fatalf("Purge: %v", err)
+			}
+
+			var count int64
+			db.Unscoped().Model(&models.Product{}).Where("uuid = ?", product.UUID).Count(&count)
+			if count != 0 {
+				t.Errorf("purged product is still stored")
+			}
+
+			for _, key := range []string{image, thumbnail} {
+				if _, err := os.Stat(filepath.Join(dir, key)); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("file %s of a purged product: %v", key, err)
+				}
+			}
+
+			if err := service.Purge(ctx, product.UUID.String()); !errors.Is(err, errProduct.ErrProductNotFound) {
+				t.Errorf("Purge of a purged product = %v, want %v", err, errProduct.ErrProductNotFound)
+			}
+		})
+	}
+}
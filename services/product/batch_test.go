@@ -0,0 +1,195 @@
+package services
+
+import (
+	"backend/constants"
+	errConstant "backend/constants/error"
+	errProduct "backend/constants/error/product"
+	errRole "backend/constants/error/role"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const unknownUUID = "6f1d6c0e-3c55-4a8b-9a3f-0d5a5f1c2b3d"
+
+func TestBatch(t *testing.T) {
+	stale := uint(0)
+	create := json.RawMessage(`{"code":"KP-03","name":"Roti","price_buy":8000,"price_sale":12000,"stock":5,"unit":"pcs"}`)
+
+	tests := []struct {
+		name          string
+		mode          string
+		role          string
+		operations    func(kopi, teh string) []dto.ProductBatchOperation
+		wantErr       error
+		wantStatuses  []string
+		wantSucceeded int
+		wantFailed    int
+		wantCodes     []string
+	}{
+		{
+			name: "atomic",
+			operations: func(kopi, teh string) []dto.ProductBatchOperation {
+				return []dto.ProductBatchOperation{
+					{Op: dto.ProductBatchCreate, Data: create},
+					{Op: dto.ProductBatchUpdate, UUID: kopi, Data: json.RawMessage(`{"code":"KP-10"}`)},
+					{Op: dto.ProductBatchDelete, UUID: teh},
+				}
+			},
+			wantStatuses:  []string{"ok", "ok", "ok"},
+			wantSucceeded: 3,
+			wantCodes:     []string{"KP-03", "KP-10"},
+		},
+		{
+			name: "atomic with an invalid operation runs nothing",
+			operations: func(kopi, teh string) []dto.ProductBatchOperation {
+				return []dto.ProductBatchOperation{
+					{Op: dto.ProductBatchCreate, Data: create},
+					{Op: dto.ProductBatchUpdate, UUID: kopi, Data: json.RawMessage(`{"name":null}`)},
+					{Op: dto.ProductBatchDelete},
+				}
+			},
+			wantErr:      errProduct.ErrProductBatchInvalid,
+			wantStatuses: []string{"skipped", "failed", "failed"},
+			wantFailed:   2,
+			wantCodes:    []string{"KP-01", "KP-02"},
+		},
+		{
+			name: "atomic rolls back on a failed operation",
+			operations: func(kopi, teh string) []dto.ProductBatchOperation {
+				return []dto.ProductBatchOperation{
+					{Op: dto.ProductBatchCreate, Data: create},
+					{Op: dto.ProductBatchDelete, UUID: teh},
+					{Op: dto.ProductBatchUpdate, UUID: kopi, Version: &stale, Data: json.RawMessage(`{"stock":0}`)},
+					{Op: dto.ProductBatchDelete, UUID: kopi},
+				}
+			},
+			wantErr:      errProduct.ErrProductBatchFailed,
+			wantStatuses: []string{"rolled_back", "rolled_back", "failed", "skipped"},
+			wantFailed:   1,
+			wantCodes:    []string{"KP-01", "KP-02"},
+		},
+		{
+			name: "best effort keeps what went through",
+			mode: dto.ProductBatchBestEffort,
+			operations: func(kopi, teh string) []dto.ProductBatchOperation {
+				return []dto.ProductBatchOperation{
+					{Op: dto.ProductBatchCreate, Data: create},
+					{Op: dto.ProductBatchDelete, UUID: unknownUUID},
+					{Op: dto.ProductBatchUpdate, UUID: kopi, Data: json.RawMessage(`{"price":1}`)},
+					{Op: dto.ProductBatchDelete, UUID: teh},
+				}
+			},
+			wantStatuses:  []string{"ok", "failed", "failed", "ok"},
+			wantSucceeded: 2,
+			wantFailed:    2,
+			wantCodes:     []string{"KP-01", "KP-03"},
+		},
+		{
+			name: "atomic with an operation the role may not run runs nothing",
+			role: "cashier",
+			operations: func(kopi, teh string) []dto.ProductBatchOperation {
+				return []dto.ProductBatchOperation{
+					{Op: dto.ProductBatchUpdate, UUID: kopi, Data: json.RawMessage(`{"stock":0}`)},
+					{Op: dto.ProductBatchDelete, UUID: teh},
+				}
+			},
+			wantErr:      errRole.ErrPermissionDenied,
+			wantStatuses: []string{"skipped", "failed"},
+			wantFailed:   1,
+			wantCodes:    []string{"KP-01", "KP-02"},
+		},
+		{
+			name: "best effort only fails the operations the role may not run",
+			mode: dto.ProductBatchBestEffort,
+			role: "cashier",
+			operations: func(kopi, teh string) []dto.ProductBatchOperation {
+				return []dto.ProductBatchOperation{
+					{Op: dto.ProductBatchCreate, Data: create},
+					{Op: dto.ProductBatchUpdate, UUID: kopi, Data: json.RawMessage(`{"code":"KP-10"}`)},
+					{Op: dto.ProductBatchDelete, UUID: teh},
+				}
+			},
+			wantStatuses:  []string{"failed", "ok", "failed"},
+			wantSucceeded: 1,
+			wantFailed:    2,
+			wantCodes:     []string{"KP-02", "KP-10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			kopi := newTestProduct(t, db, "KP-01", "Kopi")
+			teh := newTestProduct(t, db, "KP-02", "Es Teh")
+
+			// A cashier may only update products.
+			cashier := models.Role{Code: "CASHIER", Name: "Cashier"}
+			var update models.Permission
+			db.Create(&cashier)
+			db.Where("code = ?", constants.PermissionProductUpdate).First(&update)
+			if err := db.Model(&cashier).Association("Permissions").Append(&update); err != nil {
+				t.Fatalf("grant %s: %v", constants.PermissionProductUpdate, err)
+			}
+
+			role := tt.role
+			if role == "" {
+				role = "owner"
+			}
+
+			result, err := service.Batch(roleContext(role), &dto.ProductBatchRequest{
+				Mode:       tt.mode,
+				Operations: tt.operations(kopi.UUID.String(), teh.UUID.String()),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Batch = %v, want %v", err, tt.wantErr)
+			}
+
+			var statuses []string
+			for _, item := range result.Results {
+				statuses = append(statuses, item.Status)
+				if item.Status == "failed" && item.Error == "" && len(item.Errors) == 0 {
+					t.Errorf("operation %d failed without a reason", item.Index)
+				}
+				if item.Status == "rolled_back" && item.Data != nil {
+					t.Errorf("operation %d was rolled back but returns data", item.Index)
+				}
+			}
+			if !reflect.DeepEqual(statuses, tt.wantStatuses) {
+				t.Errorf("statuses %v, want %v", statuses, tt.wantStatuses)
+			}
+			if result.Succeeded != tt.wantSucceeded || result.Failed != tt.wantFailed {
+				t.Errorf("%d succeeded and %d failed, want %d and %d", result.Succeeded, result.Failed, tt.wantSucceeded, tt.wantFailed)
+			}
+
+			for _, item := range result.Results {
+				if item.Status == "failed" && (tt.role == "cashier") != (item.Error == errRole.ErrPermissionDenied.Error()) {
+					t.Errorf("operation %d failed with %q", item.Index, item.Error)
+				}
+			}
+
+			var codes []string
+			db.Model(&models.Product{}).Pluck("code", &codes)
+			sort.Strings(codes)
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("stored products %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestBatchWithoutUser(t *testing.T) {
+	service, _ := newTestService(t)
+
+	_, err := service.Batch(context.Background(), &dto.ProductBatchRequest{
+		Operations: []dto.ProductBatchOperation{{Op: dto.ProductBatchDelete, UUID: unknownUUID}},
+	})
+	if !errors.Is(err, errConstant.ErrUnauthorized) {
+		t.Errorf("Batch without a logged in user = %v, want %v", err, errConstant.ErrUnauthorized)
+	}
+}
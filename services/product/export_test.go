@@ -0,0 +1,81 @@
+package services
+
+import (
+	"backend/common/util"
+	"backend/domain/dto"
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	name, asc := "name", "asc"
+	header := []string{"Code", "Name", "Unit", "Price Buy", "Price Sale", "Margin", "Stock", "Stock Value", "Created At", "Updated At"}
+
+	tests := []struct {
+		name  string
+		param dto.ProductExportParam
+		want  [][]string
+	}{
+		{
+			name:  "numbers sorted by name",
+			param: dto.ProductExportParam{ProductFilterParam: dto.ProductFilterParam{SortColumn: &name, SortOrder: &asc}},
+			want: [][]string{
+				{"KP-02", "Es Teh", "pcs", "10000", "15000", "5000", "10", "100000"},
+				{"KP-01", "Kopi", "pcs", "10000", "15000", "5000", "10", "100000"},
+			},
+		},
+		{
+			name: "rupiah",
+			param: dto.ProductExportParam{
+				Format:             util.FormatXLSX,
+				Rupiah:             true,
+				ProductFilterParam: dto.ProductFilterParam{SortColumn: &name, SortOrder: &asc},
+			},
+			want: [][]string{
+				{"KP-02", "Es Teh", "pcs", "Rp 10.000", "Rp 15.000", "Rp 5.000", "10", "Rp 100.000"},
+				{"KP-01", "Kopi", "pcs", "Rp 10.000", "Rp 15.000", "Rp 5.000", "10", "Rp 100.000"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			newTestProduct(t, db, "KP-01", "Kopi")
+			newTestProduct(t, db, "KP-02", "Es Teh")
+
+			var output bytes.Buffer
+			if err := service.Export(context.Background(), &output, &tt.param); err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+
+			format := tt.param.Format
+			if format == "" {
+				format = util.FormatCSV
+			}
+			rows, err := util.ReadSpreadsheet(&output, format)
+			if err != nil {
+				t.Fatalf("ReadSpreadsheet: %v", err)
+			}
+
+			if len(rows) != len(tt.want)+1 {
+				t.Fatalf("export has %d rows, want %d", len(rows), len(tt.want)+1)
+			}
+			if !reflect.DeepEqual(rows[0], header) {
+				t.Errorf("header = %q, want %q", rows[0], header)
+			}
+			for i, want := range tt.want {
+				// The times differ per run, only check they are filled in.
+				row := rows[i+1]
+				if len(row) != len(header) || row[8] == "" || row[9] == "" {
+					t.Fatalf("row %d = %q, want created and updated times", i+1, row)
+				}
+				if !reflect.DeepEqual(row[:8], want) {
+					t.Errorf("row %d = %q, want %q", i+1, row[:8], want)
+				}
+			}
+		})
+	}
+}
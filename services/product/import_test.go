@@ -0,0 +1,219 @@
+package services
+
+import (
+	errConstant "backend/constants/error"
+	errProduct "backend/constants/error/product"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		request     dto.ProductImportRequest
+		wantErr     error
+		wantActions []string
+		wantErrors  [][]string
+		wantColumns []string
+		wantCreated int
+		wantUpdated int
+		wantStored  int
+	}{
+		{
+			name: "creates and updates by code",
+			file: "code,name,price_buy,price_sale,stock,unit\n" +
+				"KP-01,Kopi Susu,12000,18000,20,cup\n" +
+				",Roti Bakar,8000,12000,5,pcs\n",
+			wantActions: []string{"update", "create"},
+			wantErrors:  [][]string{nil, nil},
+			wantCreated: 1,
+			wantUpdated: 1,
+			wantStored:  2,
+		},
+		{
+			name: "dry run writes nothing",
+			file: "code,name,price_buy,price_sale,stock,unit\n" +
+				"KP-01,Kopi Susu,12000,18000,20,cup\n" +
+				"KP-02,Es Teh,2000,5000,30,cup\n",
+			request:     dto.ProductImportRequest{DryRun: true},
+			wantActions: []string{"update", "create"},
+			wantErrors:  [][]string{nil, nil},
+			wantStored:  1,
+		},
+		{
+			name: "columns by mapping, header case and order",
+			file: "Satuan,Nama,Kode,Stok,Harga Jual,Harga Beli\n" +
+				"cup,Es Teh,KP-02,30,5000,2000\n",
+			request: dto.ProductImportRequest{Mapping: map[string]string{
+				"code":       "kode",
+				"name":       "nama",
+				"price_buy":  "harga beli",
+				"price_sale": "harga jual",
+				"stock":      "stok",
+				"unit":       "satuan",
+			}},
+			wantActions: []string{"create"},
+			wantErrors:  [][]string{nil},
+			wantCreated: 1,
+			wantStored:  2,
+		},
+		{
+			name:        "missing required columns",
+			file:        "code,name,price_sale\nKP-02,Es Teh,5000\n",
+			wantErr:     errProduct.ErrProductImportColumnNotFound,
+			wantColumns: []string{"price_buy", "stock", "unit"},
+			wantStored:  1,
+		},
+		{
+			name: "invalid rows are reported and nothing is written",
+			file: "code,name,price_buy,price_sale,stock,unit\n" +
+				"KP-02,Es Teh,2000,5000,30,cup\n" +
+				"KP-03,,2000,abc,30,cup\n" +
+				"KP-02,Es Teh Manis,2000,-5,30,cup\n",
+			wantErr:     errProduct.ErrProductImportInvalidRows,
+			wantActions: []string{"create", "", ""},
+			wantErrors:  [][]string{nil, {"PriceSale", "Name"}, {"PriceSale", "Code"}},
+			wantStored:  1,
+		},
+		{
+			name: "empty rows are skipped",
+			file: "code,name,price_buy,price_sale,stock,unit\n" +
+				",,,,,\n" +
+				"KP-02,Es Teh,2000,5000,30,cup\n",
+			wantActions: []string{"create"},
+			wantErrors:  [][]string{nil},
+			wantCreated: 1,
+			wantStored:  2,
+		},
+		{
+			name:       "header only",
+			file:       "code,name,price_buy,price_sale,stock,unit\n,,,,,\n",
+			wantErr:    errProduct.ErrProductImportEmpty,
+			wantStored: 1,
+		},
+		{
+			name:       "not a spreadsheet",
+			file:       "code,name\n\"KP-01,Kopi\n",
+			wantErr:    errConstant.ErrUnsupportedFileFormat,
+			wantStored: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			newTestProduct(t, db, "KP-01", "Kopi")
+
+			result, err := service.Import(context.Background(), strings.NewReader(tt.file), "products.csv", &tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Import error = %v, want %v", err, tt.wantErr)
+			}
+
+			var stored int64
+			db.Model(&models.Product{}).Count(&stored)
+			if int(stored) != tt.wantStored {
+				t.Errorf("%d products stored, want %d", stored, tt.wantStored)
+			}
+
+			if result == nil {
+				if tt.wantActions != nil || tt.wantColumns != nil {
+					t.Fatal("Import returned no result")
+				}
+				return
+			}
+
+			var columns []string
+			for _, column := range result.Columns {
+				columns = append(columns, column.Field)
+			}
+			if strings.Join(columns, ",") != strings.Join(tt.wantColumns, ",") {
+				t.Errorf("missing columns = %v, want %v", columns, tt.wantColumns)
+			}
+
+			if len(result.Rows) != len(tt.wantActions) {
+				t.Fatalf("%d rows reported, want %d", len(result.Rows), len(tt.wantActions))
+			}
+			for i, row := range result.Rows {
+				if row.Action != tt.wantActions[i] {
+					t.Errorf("row %d action = %q, want %q", row.Row, row.Action, tt.wantActions[i])
+				}
+
+				var fields []string
+				for _, rowError := range row.Errors {
+					fields = append(fields, rowError.Field)
+				}
+				if strings.Join(fields, ",") != strings.Join(tt.wantErrors[i], ",") {
+					t.Errorf("row %d errors = %v, want %v", row.Row, row.Errors, tt.wantErrors[i])
+				}
+			}
+
+			if result.Created != tt.wantCreated || result.Updated != tt.wantUpdated {
+				t.Errorf("created %d and updated %d, want %d and %d", result.Created, result.Updated, tt.wantCreated, tt.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestImportUpdatesByCode(t *testing.T) {
+	service, db := newTestService(t)
+	existing := newTestProduct(t, db, "KP-01", "Kopi")
+
+	file := "code,name,price_buy,price_sale,stock,unit\nKP-01,Kopi Susu,12000,18000,20,cup\n"
+	_, err := service.Import(context.Background(), strings.NewReader(file), "products.csv", &dto.ProductImportRequest{})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	var product models.Product
+	if err = db.First(&product, existing.ID).Error; err != nil {
+		t.Fatalf("load product: %v", err)
+	}
+	if product.UUID != existing.UUID || product.Name != "Kopi Susu" || product.PriceBuy != 12000 ||
+		product.PriceSale != 18000 || product.Stock != 20 || product.Unit != "cup" {
+		t.Errorf("product after import = %+v", product)
+	}
+}
+
+func TestImportRestoresArchivedByCode(t *testing.T) {
+	service, db := newTestService(t)
+	archived := newTestProduct(t, db, "KP-01", "Kopi")
+	newTestProduct(t, db, "KP-02", "Es Teh")
+	if err := db.Delete(archived).Error; err != nil {
+		t.Fatalf("archive product: %v", err)
+	}
+
+	file := "code,name,price_buy,price_sale,stock,unit\n" +
+		"KP-01,Kopi Susu,12000,18000,20,cup\n" +
+		"KP-02,Es Teh Manis,3000,5000,30,cup\n" +
+		"KP-03,Roti,8000,12000,5,pcs\n"
+	result, err := service.Import(context.Background(), strings.NewReader(file), "products.csv", &dto.ProductImportRequest{})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	var actions []string
+	for _, row := range result.Rows {
+		actions = append(actions, row.Action)
+	}
+	if want := []string{"restore", "update", "create"}; strings.Join(actions, ",") != strings.Join(want, ",") {
+		t.Errorf("row actions = %v, want %v", actions, want)
+	}
+	if result.Created != 1 || result.Updated != 1 || result.Restored != 1 {
+		t.Errorf("created %d, updated %d and restored %d, want 1 each", result.Created, result.Updated, result.Restored)
+	}
+
+	// The archived product comes back under its own UUID rather than as a
+	// second product with the same code.
+	var product models.Product
+	if err = db.Where("code = ?", "KP-01").First(&product).Error; err != nil {
+		t.Fatalf("load restored product: %v", err)
+	}
+	if product.UUID != archived.UUID || product.Name != "Kopi Susu" || product.DeletedAt.Valid {
+		t.Errorf("product after import = %+v", product)
+	}
+}
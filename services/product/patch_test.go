@@ -0,0 +1,85 @@
+package services
+
+import (
+	"backend/common/util"
+	errConstant "backend/constants/error"
+	errProduct "backend/constants/error/product"
+	"backend/domain/dto"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	stale := []uint{0}
+
+	tests := []struct {
+		name     string
+		body     string
+		versions []uint
+		want     dto.ProductResponse
+		wantErr  error
+	}{
+		{
+			name: "zero stock is applied",
+			body: `{"stock":0}`,
+			want: dto.ProductResponse{Code: "KP-01", Name: "Kopi", PriceBuy: 10000, PriceSale: 15000, Stock: 0, Unit: "pcs", Version: 2},
+		},
+		{
+			name: "null clears the code",
+			body: `{"code":null}`,
+			want: dto.ProductResponse{Code: "", Name: "Kopi", PriceBuy: 10000, PriceSale: 15000, Stock: 10, Unit: "pcs", Version: 2},
+		},
+		{
+			name: "members not sent are kept",
+			body: `{"name":"Kopi Susu","price_sale":18000,"unit":"cup"}`,
+			want: dto.ProductResponse{Code: "KP-01", Name: "Kopi Susu", PriceBuy: 10000, PriceSale: 18000, Stock: 10, Unit: "cup", Version: 2},
+		},
+		{
+			name:    "code of another product",
+			body:    `{"code":"TH-01"}`,
+			want:    dto.ProductResponse{Code: "KP-01", Name: "Kopi", PriceBuy: 10000, PriceSale: 15000, Stock: 10, Unit: "pcs", Version: 1},
+			wantErr: errProduct.ErrProductIsExist,
+		},
+		{
+			name:     "stale version",
+			body:     `{"stock":0}`,
+			versions: stale,
+			want:     dto.ProductResponse{Code: "KP-01", Name: "Kopi", PriceBuy: 10000, PriceSale: 15000, Stock: 10, Unit: "pcs", Version: 1},
+			wantErr:  errConstant.ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			ctx := context.Background()
+			product := newTestProduct(t, db, "KP-01", "Kopi")
+			newTestProduct(t, db, "TH-01", "Teh")
+			// Products without a code do not clash with each other.
+			newTestProduct(t, db, "", "Gula")
+
+			var request dto.PatchProductRequest
+			fields, err := util.ParseMergePatch([]byte(tt.body), &request)
+			if err != nil {
+				t.Fatalf("ParseMergePatch: %v", err)
+			}
+			request.Fields = fields
+
+			_, err = service.Patch(ctx, product.UUID.String(), &request, tt.versions)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Patch = %v, want %v", err, tt.wantErr)
+			}
+
+			got, err := service.GetByUUID(ctx, product.UUID.String())
+			if err != nil {
+				t.Fatalf("GetByUUID: %v", err)
+			}
+			if got.Code != tt.want.Code || got.Name != tt.want.Name || got.PriceBuy != tt.want.PriceBuy ||
+				got.PriceSale != tt.want.PriceSale || got.Stock != tt.want.Stock || got.Unit != tt.want.Unit ||
+				got.Version != tt.want.Version {
+				t.Errorf("product after patch = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,29 +1,78 @@
 package services
 
 import (
+	errValidation "backend/common/error"
+	"backend/common/storage"
 	"backend/common/util"
+	"backend/config"
+	"backend/constants"
+	errConstant "backend/constants/error"
+	errProduct "backend/constants/error/product"
+	errRole "backend/constants/error/role"
 	"backend/domain/dto"
+	"backend/domain/models"
 	"backend/repositories"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
 	uuid2 "github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io"
+	"math"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ProductService struct {
 	repository repositories.IRepositoryRegistry
+	storage    storage.IStorage
 }
 
 type IProductService interface {
 	GetAllWithPagination(context.Context, *dto.ProductRequestParam) (*util.PaginationResult, error)
-	GetAllWithoutPagination(context.Context) ([]dto.ProductResponse, error)
+	GetAllWithoutPagination(context.Context, *dto.ProductFilterParam) ([]dto.ProductResponse, error)
+	GetAllWithCursor(context.Context, *dto.ProductCursorParam) (*util.CursorResult, error)
 	GetByUUID(context.Context, string) (*dto.ProductResponse, error)
 	GetByCode(context.Context, string) (*dto.ProductResponse, error)
 	Create(context.Context, *dto.ProductRequest) (*dto.ProductResponse, error)
-	Update(context.Context, string, *dto.UpdateProductRequest) (*dto.ProductResponse, error)
-	Delete(context.Context, string) error
+	Update(context.Context, string, *dto.UpdateProductRequest, []uint) (*dto.ProductResponse, error)
+	Patch(context.Context, string, *dto.PatchProductRequest, []uint) (*dto.ProductResponse, error)
+	Delete(context.Context, string, []uint) error
+	Restore(context.Context, string) (*dto.ProductResponse, error)
+	Purge(context.Context, string) error
+	UploadImage(context.Context, string, *multipart.FileHeader) (*dto.ProductResponse, error)
+	DeleteImage(context.Context, string) (*dto.ProductResponse, error)
+	Import(context.Context, io.Reader, string, *dto.ProductImportRequest) (*dto.ProductImportResponse, error)
+	Export(context.Context, io.Writer, *dto.ProductExportParam) error
+	Batch(context.Context, *dto.ProductBatchRequest) (*dto.ProductBatchResponse, error)
+	AdjustPrice(context.Context, *dto.ProductFilterParam, *dto.ProductPriceAdjustmentRequest) (*dto.ProductPriceAdjustmentResponse, error)
 }
 
-func NewProductService(repository repositories.IRepositoryRegistry) IProductService {
-	return &ProductService{repository: repository}
+func NewProductService(repository repositories.IRepositoryRegistry, storage storage.IStorage) IProductService {
+	return &ProductService{repository: repository, storage: storage}
+}
+
+func (p *ProductService) fileURL(key *string) *string {
+	if key == nil {
+		return nil
+	}
+
+	url := p.storage.URL(*key)
+	return &url
+}
+
+func deletedAt(value gorm.DeletedAt) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
 }
 
 func (p *ProductService) GetAllWithPagination(ctx context.Context, param *dto.ProductRequestParam) (*util.PaginationResult, error) {
@@ -36,15 +85,19 @@ func (p *ProductService) GetAllWithPagination(ctx context.Context, param *dto.Pr
 	productResult := make([]*dto.ProductResponse, 0, len(products))
 	for _, product := range products {
 		productResult = append(productResult, &dto.ProductResponse{
-			UUID:      product.UUID,
-			Code:      product.Code,
-			Name:      product.Name,
-			PriceBuy:  product.PriceBuy,
-			PriceSale: product.PriceSale,
-			Stock:     product.Stock,
-			Unit:      product.Unit,
-			CreatedAt: product.CreatedAt,
-			UpdatedAt: product.UpdatedAt,
+			UUID:         product.UUID,
+			Code:         product.Code,
+			Name:         product.Name,
+			PriceBuy:     product.PriceBuy,
+			PriceSale:    product.PriceSale,
+			Stock:        product.Stock,
+			Unit:         product.Unit,
+			ImageURL:     p.fileURL(product.Image),
+			ThumbnailURL: p.fileURL(product.Thumbnail),
+			CreatedAt:    product.CreatedAt,
+			UpdatedAt:    product.UpdatedAt,
+			Version:      product.Version,
+			DeletedAt:    deletedAt(product.DeletedAt),
 		})
 	}
 
@@ -59,8 +112,37 @@ func (p *ProductService) GetAllWithPagination(ctx context.Context, param *dto.Pr
 	return &response, nil
 }
 
-func (p *ProductService) GetAllWithoutPagination(ctx context.Context) ([]dto.ProductResponse, error) {
-	products, err := p.repository.GetProduct().FindAllWithoutPagination(ctx)
+func (p *ProductService) GetAllWithCursor(ctx context.Context, param *dto.ProductCursorParam) (*util.CursorResult, error) {
+	products, page, err := p.repository.GetProduct().FindAllWithCursor(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	productResult := make([]*dto.ProductResponse, 0, len(products))
+	for _, product := range products {
+		productResult = append(productResult, &dto.ProductResponse{
+			UUID:         product.UUID,
+			Code:         product.Code,
+			Name:         product.Name,
+			PriceBuy:     product.PriceBuy,
+			PriceSale:    product.PriceSale,
+			Stock:        product.Stock,
+			Unit:         product.Unit,
+			ImageURL:     p.fileURL(product.Image),
+			ThumbnailURL: p.fileURL(product.Thumbnail),
+			CreatedAt:    product.CreatedAt,
+			UpdatedAt:    product.UpdatedAt,
+			Version:      product.Version,
+			DeletedAt:    deletedAt(product.DeletedAt),
+		})
+	}
+
+	response := util.GenerateCursorResult(page, param.Limit, productResult)
+	return &response, nil
+}
+
+func (p *ProductService) GetAllWithoutPagination(ctx context.Context, param *dto.ProductFilterParam) ([]dto.ProductResponse, error) {
+	products, err := p.repository.GetProduct().FindAllWithoutPagination(ctx, param)
 	if err != nil {
 		return nil, err
 	}
@@ -68,15 +150,19 @@ func (p *ProductService) GetAllWithoutPagination(ctx context.Context) ([]dto.Pro
 	productResult := make([]dto.ProductResponse, 0, len(products))
 	for _, product := range products {
 		productResult = append(productResult, dto.ProductResponse{
-			UUID:      product.UUID,
-			Code:      product.Code,
-			Name:      product.Name,
-			PriceBuy:  product.PriceBuy,
-			PriceSale: product.PriceSale,
-			Stock:     product.Stock,
-			Unit:      product.Unit,
-			CreatedAt: product.CreatedAt,
-			UpdatedAt: product.UpdatedAt,
+			UUID:         product.UUID,
+			Code:         product.Code,
+			Name:         product.Name,
+			PriceBuy:     product.PriceBuy,
+			PriceSale:    product.PriceSale,
+			Stock:        product.Stock,
+			Unit:         product.Unit,
+			ImageURL:     p.fileURL(product.Image),
+			ThumbnailURL: p.fileURL(product.Thumbnail),
+			CreatedAt:    product.CreatedAt,
+			UpdatedAt:    product.UpdatedAt,
+			Version:      product.Version,
+			DeletedAt:    deletedAt(product.DeletedAt),
 		})
 	}
 
@@ -90,15 +176,18 @@ func (p *ProductService) GetByUUID(ctx context.Context, uuid string) (*dto.Produ
 	}
 
 	productResult := &dto.ProductResponse{
-		UUID:      product.UUID,
-		Code:      product.Code,
-		Name:      product.Name,
-		PriceBuy:  product.PriceBuy,
-		PriceSale: product.PriceSale,
-		Stock:     product.Stock,
-		Unit:      product.Unit,
-		CreatedAt: product.CreatedAt,
-		UpdatedAt: product.UpdatedAt,
+		UUID:         product.UUID,
+		Code:         product.Code,
+		Name:         product.Name,
+		PriceBuy:     product.PriceBuy,
+		PriceSale:    product.PriceSale,
+		Stock:        product.Stock,
+		Unit:         product.Unit,
+		ImageURL:     p.fileURL(product.Image),
+		ThumbnailURL: p.fileURL(product.Thumbnail),
+		CreatedAt:    product.CreatedAt,
+		UpdatedAt:    product.UpdatedAt,
+		Version:      product.Version,
 	}
 
 	return productResult, nil
@@ -111,15 +200,18 @@ func (p *ProductService) GetByCode(ctx context.Context, code string) (*dto.Produ
 	}
 
 	productResult := &dto.ProductResponse{
-		UUID:      product.UUID,
-		Code:      product.Code,
-		Name:      product.Name,
-		PriceBuy:  product.PriceBuy,
-		PriceSale: product.PriceSale,
-		Stock:     product.Stock,
-		Unit:      product.Unit,
-		CreatedAt: product.CreatedAt,
-		UpdatedAt: product.UpdatedAt,
+		UUID:         product.UUID,
+		Code:         product.Code,
+		Name:         product.Name,
+		PriceBuy:     product.PriceBuy,
+		PriceSale:    product.PriceSale,
+		Stock:        product.Stock,
+		Unit:         product.Unit,
+		ImageURL:     p.fileURL(product.Image),
+		ThumbnailURL: p.fileURL(product.Thumbnail),
+		CreatedAt:    product.CreatedAt,
+		UpdatedAt:    product.UpdatedAt,
+		Version:      product.Version,
 	}
 
 	return productResult, nil
@@ -141,12 +233,13 @@ func (p *ProductService) Create(ctx context.Context, request *dto.ProductRequest
 		Unit:      newProduct.Unit,
 		CreatedAt: newProduct.CreatedAt,
 		UpdatedAt: newProduct.UpdatedAt,
+		Version:   newProduct.Version,
 	}
 
 	return productResult, nil
 }
 
-func (p *ProductService) Update(ctx context.Context, uuid string, request *dto.UpdateProductRequest) (*dto.ProductResponse, error) {
+func (p *ProductService) Update(ctx context.Context, uuid string, request *dto.UpdateProductRequest, versions []uint) (*dto.ProductResponse, error) {
 	updateProduct := &dto.UpdateProductRequest{
 		Code:      request.Code,
 		Name:      request.Name,
@@ -156,7 +249,7 @@ func (p *ProductService) Update(ctx context.Context, uuid string, request *dto.U
 		Unit:      request.Unit,
 	}
 
-	_, err := p.repository.GetProduct().Update(ctx, uuid, updateProduct)
+	_, err := p.repository.GetProduct().Update(ctx, uuid, updateProduct, versions)
 	if err != nil {
 		return nil, err
 	}
@@ -169,30 +262,869 @@ func (p *ProductService) Update(ctx context.Context, uuid string, request *dto.U
 	uuidParsed, _ := uuid2.Parse(uuid)
 
 	productResult := &dto.ProductResponse{
-		UUID:      uuidParsed,
-		Code:      updatedProduct.Code,
-		Name:      updatedProduct.Name,
-		PriceBuy:  updatedProduct.PriceBuy,
-		PriceSale: updatedProduct.PriceSale,
-		Stock:     updatedProduct.Stock,
-		Unit:      updatedProduct.Unit,
-		CreatedAt: updatedProduct.CreatedAt,
-		UpdatedAt: updatedProduct.UpdatedAt,
+		UUID:         uuidParsed,
+		Code:         updatedProduct.Code,
+		Name:         updatedProduct.Name,
+		PriceBuy:     updatedProduct.PriceBuy,
+		PriceSale:    updatedProduct.PriceSale,
+		Stock:        updatedProduct.Stock,
+		Unit:         updatedProduct.Unit,
+		ImageURL:     p.fileURL(updatedProduct.Image),
+		ThumbnailURL: p.fileURL(updatedProduct.Thumbnail),
+		CreatedAt:    updatedProduct.CreatedAt,
+		UpdatedAt:    updatedProduct.UpdatedAt,
+		Version:      updatedProduct.Version,
 	}
 
 	return productResult, nil
 }
 
-func (p *ProductService) Delete(ctx context.Context, uuid string) error {
+// Patch applies a merge patch. Every member the client sent is written, so
+// stock or price can be set to 0 and a null code clears it.
+func (p *ProductService) Patch(ctx context.Context, uuid string, request *dto.PatchProductRequest, versions []uint) (*dto.ProductResponse, error) {
+	values := patchValues(request)
+	err := p.repository.GetProduct().Patch(ctx, uuid, values, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.GetByUUID(ctx, uuid)
+}
+
+// patchValues maps the members of a product merge patch to columns.
+func patchValues(request *dto.PatchProductRequest) map[string]interface{} {
+	values := map[string]interface{}{}
+	if request.Fields.Has("code") {
+		values["code"] = ""
+		if request.Code != nil {
+			values["code"] = *request.Code
+		}
+	}
+	if request.Name != nil {
+		values["name"] = *request.Name
+	}
+	if request.PriceBuy != nil {
+		values["price_buy"] = *request.PriceBuy
+	}
+	if request.PriceSale != nil {
+		values["price_sale"] = *request.PriceSale
+	}
+	if request.Stock != nil {
+		values["stock"] = *request.Stock
+	}
+	if request.Unit != nil {
+		values["unit"] = *request.Unit
+	}
+
+	return values
+}
+
+func (p *ProductService) Delete(ctx context.Context, uuid string, versions []uint) error {
 	_, err := p.repository.GetProduct().FindByUUID(ctx, uuid)
 	if err != nil {
 		return err
 	}
 
-	err = p.repository.GetProduct().Delete(ctx, uuid)
+	err = p.repository.GetProduct().Delete(ctx, uuid, versions)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *ProductService) Restore(ctx context.Context, uuid string) (*dto.ProductResponse, error) {
+	product, err := p.repository.GetProduct().FindByUUIDWithArchived(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !product.DeletedAt.Valid {
+		return nil, errProduct.ErrProductNotArchived
+	}
+
+	err = p.repository.GetProduct().Restore(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.GetByUUID(ctx, uuid)
+}
+
+func (p *ProductService) Purge(ctx context.Context, uuid string) error {
+	product, err := p.repository.GetProduct().FindByUUIDWithArchived(ctx, uuid)
 	if err != nil {
 		return err
 	}
 
+	err = p.repository.GetProduct().Purge(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	p.removeFiles(ctx, product.Image, product.Thumbnail)
+
 	return nil
 }
+
+func (p *ProductService) UploadImage(ctx context.Context, uuid string, file *multipart.FileHeader) (*dto.ProductResponse, error) {
+	product, err := p.repository.GetProduct().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := int64(config.Config.Storage.MaxImageSize)
+	if file.Size > maxSize {
+		return nil, errProduct.ErrProductImageTooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(io.LimitReader(src, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(content)) > maxSize {
+		return nil, errProduct.ErrProductImageTooLarge
+	}
+
+	contentType, extension := util.DetectImageType(content)
+	if extension == "" {
+		return nil, errProduct.ErrProductImageInvalid
+	}
+
+	thumbnail, thumbnailType, err := util.GenerateThumbnail(
+		content,
+		config.Config.Storage.ThumbnailWidth,
+		config.Config.Storage.ThumbnailHeight,
+		config.Config.Storage.MaxImagePixels,
+	)
+	if err != nil {
+		if errors.Is(err, util.ErrImageTooManyPixels) {
+			return nil, errProduct.ErrProductImageTooLarge
+		}
+
+		return nil, errProduct.ErrProductImageInvalid
+	}
+
+	thumbnailExtension := ".jpg"
+	if thumbnailType == "image/png" {
+		thumbnailExtension = ".png"
+	}
+
+	name := uuid2.New().String()
+	imageKey := fmt.Sprintf("products/%s/%s%s", product.UUID, name, extension)
+	thumbnailKey := fmt.Sprintf("products/%s/%s_thumb%s", product.UUID, name, thumbnailExtension)
+
+	err = p.storage.Put(ctx, imageKey, bytes.NewReader(content), int64(len(content)), contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.storage.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), thumbnailType)
+	if err != nil {
+		p.removeFiles(ctx, &imageKey)
+		return nil, err
+	}
+
+	err = p.repository.GetProduct().UpdateImage(ctx, uuid, &imageKey, &thumbnailKey)
+	if err != nil {
+		p.removeFiles(ctx, &imageKey, &thumbnailKey)
+		return nil, err
+	}
+
+	p.removeFiles(ctx, product.Image, product.Thumbnail)
+
+	return p.GetByUUID(ctx, uuid)
+}
+
+func (p *ProductService) DeleteImage(ctx context.Context, uuid string) (*dto.ProductResponse, error) {
+	product, err := p.repository.GetProduct().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.repository.GetProduct().UpdateImage(ctx, uuid, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p.removeFiles(ctx, product.Image, product.Thumbnail)
+
+	return p.GetByUUID(ctx, uuid)
+}
+
+// removeFiles is best effort, an orphaned file is better than failing a
+// request whose database change already succeeded.
+func (p *ProductService) removeFiles(ctx context.Context, keys ...*string) {
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+
+		if err := p.storage.Delete(ctx, *key); err != nil {
+			logrus.Warnf("failed to delete file %s: %v", *key, err)
+		}
+	}
+}
+
+var productImportColumns = []struct {
+	column   string
+	field    string
+	required bool
+}{
+	{column: "code", field: "Code"},
+	{column: "name", field: "Name", required: true},
+	{column: "price_buy", field: "PriceBuy", required: true},
+	{column: "price_sale", field: "PriceSale", required: true},
+	{column: "stock", field: "Stock", required: true},
+	{column: "unit", field: "Unit", required: true},
+}
+
+func (p *ProductService) Import(ctx context.Context, reader io.Reader, filename string, request *dto.ProductImportRequest) (*dto.ProductImportResponse, error) {
+	format, err := util.SpreadsheetFormat(request.Format, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := util.ReadSpreadsheet(reader, format)
+	if err != nil {
+		if errors.Is(err, errConstant.ErrUnsupportedFileFormat) {
+			return nil, err
+		}
+
+		logrus.Errorf("failed to read import file: %v", err)
+		return nil, errConstant.ErrUnsupportedFileFormat
+	}
+
+	if len(rows) < 2 {
+		return nil, errProduct.ErrProductImportEmpty
+	}
+
+	result := &dto.ProductImportResponse{
+		DryRun: request.DryRun,
+		Rows:   make([]dto.ProductImportRowResult, 0, len(rows)-1),
+	}
+
+	header := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	indexes := make(map[string]int, len(productImportColumns))
+	for _, item := range productImportColumns {
+		name := item.column
+		if mapped, ok := request.Mapping[item.column]; ok && mapped != "" {
+			name = mapped
+		}
+
+		index, ok := header[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			if item.required {
+				result.Columns = append(result.Columns, errValidation.ValidationResponse{
+					Field:   item.column,
+					Message: fmt.Sprintf("column %s is not found", name),
+				})
+			}
+			continue
+		}
+		indexes[item.column] = index
+	}
+
+	if len(result.Columns) > 0 {
+		return result, errProduct.ErrProductImportColumnNotFound
+	}
+
+	var (
+		validate = validator.New()
+		requests = make([]dto.ProductRequest, 0, len(rows)-1)
+		valid    = make([]bool, 0, len(rows)-1)
+		codes    = make([]string, 0, len(rows)-1)
+		seen     = make(map[string]int)
+	)
+
+	for i, row := range rows[1:] {
+		if isEmptyRow(row) {
+			continue
+		}
+
+		var (
+			rowErrors []errValidation.ValidationResponse
+			req       dto.ProductRequest
+		)
+
+		cell := func(column string) string {
+			index, ok := indexes[column]
+			if !ok || index >= len(row) {
+				return ""
+			}
+			return util.UnescapeFormula(strings.TrimSpace(row[index]))
+		}
+
+		parseUint := func(column, field string) uint {
+			value := cell(column)
+			if value == "" {
+				return 0
+			}
+
+			number, err := strconv.ParseUint(value, 10, 0)
+			if err != nil {
+				rowErrors = append(rowErrors, errValidation.ValidationResponse{
+					Field:   field,
+					Message: fmt.Sprintf("%s must be uint type, got %s", field, value),
+				})
+			}
+			return uint(number)
+		}
+
+		req.Code = cell("code")
+		req.Name = cell("name")
+		req.PriceBuy = parseUint("price_buy", "PriceBuy")
+		req.PriceSale = parseUint("price_sale", "PriceSale")
+		req.Stock = parseUint("stock", "Stock")
+		req.Unit = cell("unit")
+
+		if err = validate.Struct(req); err != nil {
+			for _, fieldError := range errValidation.ErrValidationResponse(err) {
+				if !hasFieldError(rowErrors, fieldError.Field) {
+					rowErrors = append(rowErrors, fieldError)
+				}
+			}
+		}
+
+		rowNumber := i + 2
+		if req.Code != "" {
+			if firstRow, ok := seen[req.Code]; ok {
+				rowErrors = append(rowErrors, errValidation.ValidationResponse{
+					Field:   "Code",
+					Message: fmt.Sprintf("Code %s is duplicated with row %d", req.Code, firstRow),
+				})
+			} else {
+				seen[req.Code] = rowNumber
+				codes = append(codes, req.Code)
+			}
+		}
+
+		result.TotalRows++
+		result.Rows = append(result.Rows, dto.ProductImportRowResult{
+			Row:    rowNumber,
+			Code:   req.Code,
+			Name:   req.Name,
+			Errors: rowErrors,
+		})
+		requests = append(requests, req)
+		valid = append(valid, len(rowErrors) == 0)
+	}
+
+	if result.TotalRows == 0 {
+		return nil, errProduct.ErrProductImportEmpty
+	}
+
+	existing, err := p.repository.GetProduct().FindByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archived products keep their code, a row matching one restores it.
+	actions := make(map[string]string, len(existing))
+	for _, product := range existing {
+		actions[product.Code] = "update"
+		if product.DeletedAt.Valid {
+			actions[product.Code] = "restore"
+		}
+	}
+
+	validRequests := make([]dto.ProductRequest, 0, len(requests))
+	for i := range result.Rows {
+		if !valid[i] {
+			result.InvalidRows++
+			continue
+		}
+
+		result.ValidRows++
+		result.Rows[i].Action = "create"
+		if action, ok := actions[requests[i].Code]; ok {
+			result.Rows[i].Action = action
+		}
+		validRequests = append(validRequests, requests[i])
+	}
+
+	if request.DryRun {
+		return result, nil
+	}
+
+	if result.InvalidRows > 0 {
+		return result, errProduct.ErrProductImportInvalidRows
+	}
+
+	result.Created, result.Updated, result.Restored, err = p.repository.GetProduct().Import(ctx, validRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasFieldError(fieldErrors []errValidation.ValidationResponse, field string) bool {
+	for _, fieldError := range fieldErrors {
+		if fieldError.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *ProductService) Export(ctx context.Context, w io.Writer, param *dto.ProductExportParam) error {
+	format := param.Format
+	if format == "" {
+		format = util.FormatCSV
+	}
+
+	writer, err := util.NewSpreadsheetWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteRow(
+		"Code",
+		"Name",
+		"Unit",
+		"Price Buy",
+		"Price Sale",
+		"Margin",
+		"Stock",
+		"Stock Value",
+		"Created At",
+		"Updated At",
+	)
+	if err != nil {
+		return err
+	}
+
+	money := func(amount float64) interface{} {
+		if param.Rupiah {
+			return util.RupiahFormat(&amount)
+		}
+		return amount
+	}
+
+	datetime := func(value *time.Time) interface{} {
+		if value == nil {
+			return nil
+		}
+		return value.Format(time.DateTime)
+	}
+
+	err = p.repository.GetProduct().Stream(ctx, &param.ProductFilterParam, func(product *models.Product) error {
+		margin := float64(product.PriceSale) - float64(product.PriceBuy)
+		stockValue := float64(product.Stock) * float64(product.PriceBuy)
+
+		return writer.WriteRow(
+			product.Code,
+			product.Name,
+			product.Unit,
+			money(float64(product.PriceBuy)),
+			money(float64(product.PriceSale)),
+			money(margin),
+			product.Stock,
+			money(stockValue),
+			datetime(product.CreatedAt),
+			datetime(product.UpdatedAt),
+		)
+	})
+	if err != nil {
+		_ = writer.Close()
+		return err
+	}
+
+	return writer.Close()
+}
+
+// productBatchItem is a batch operation that passed validation.
+type productBatchItem struct {
+	create *dto.ProductRequest
+	patch  *dto.PatchProductRequest
+}
+
+// Batch runs create, update and delete operations. In atomic mode every
+// operation shares one transaction and the first failure rolls back the
+// whole batch. In best-effort mode each operation stands on its own and the
+// results say which ones went through. Every operation needs the permission
+// of its single product endpoint, one the role lacks rejects the whole batch
+// in atomic mode and only that operation in best-effort mode.
+func (p *ProductService) Batch(ctx context.Context, request *dto.ProductBatchRequest) (*dto.ProductBatchResponse, error) {
+	mode := request.Mode
+	if mode == "" {
+		mode = dto.ProductBatchAtomic
+	}
+
+	result := &dto.ProductBatchResponse{
+		Mode:    mode,
+		Results: make([]dto.ProductBatchItemResult, len(request.Operations)),
+	}
+
+	allowed, err := p.batchPermissions(ctx, request.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	denied := false
+	validate := validator.New()
+	items := make([]*productBatchItem, len(request.Operations))
+	for i, operation := range request.Operations {
+		result.Results[i] = dto.ProductBatchItemResult{
+			Index: i,
+			Op:    operation.Op,
+			UUID:  operation.UUID,
+		}
+
+		if granted, ok := allowed[operation.Op]; ok && !granted {
+			result.Results[i].Status = "failed"
+			result.Results[i].Error = errRole.ErrPermissionDenied.Error()
+			result.Failed++
+			denied = true
+			continue
+		}
+
+		item, fieldErrors := prepareBatchItem(validate, &operation)
+		if len(fieldErrors) > 0 {
+			result.Results[i].Status = "failed"
+			result.Results[i].Errors = fieldErrors
+			result.Failed++
+			continue
+		}
+		items[i] = item
+	}
+
+	if mode == dto.ProductBatchBestEffort {
+		for i, item := range items {
+			if item == nil {
+				continue
+			}
+
+			p.runBatchItem(ctx, p.repository, &request.Operations[i], item, &result.Results[i])
+			if result.Results[i].Status == "ok" {
+				result.Succeeded++
+			} else {
+				result.Failed++
+			}
+		}
+
+		return result, nil
+	}
+
+	if result.Failed > 0 {
+		for i := range result.Results {
+			if result.Results[i].Status == "" {
+				result.Results[i].Status = "skipped"
+			}
+		}
+
+		if denied {
+			return result, errRole.ErrPermissionDenied
+		}
+		return result, errProduct.ErrProductBatchInvalid
+	}
+
+	err = p.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		for i, item := range items {
+			p.runBatchItem(ctx, repository, &request.Operations[i], item, &result.Results[i])
+			if result.Results[i].Status != "ok" {
+				return errProduct.ErrProductBatchFailed
+			}
+			result.Succeeded++
+		}
+
+		return nil
+	})
+	if err != nil {
+		for i := range result.Results {
+			switch result.Results[i].Status {
+			case "ok":
+				result.Results[i].Status = "rolled_back"
+				result.Results[i].Data = nil
+				if result.Results[i].Op == dto.ProductBatchCreate {
+					result.Results[i].UUID = ""
+				}
+			case "failed":
+				result.Failed++
+			case "":
+				result.Results[i].Status = "skipped"
+			}
+		}
+		result.Succeeded = 0
+
+		if errors.Is(err, errProduct.ErrProductBatchFailed) {
+			return result, err
+		}
+
+		logrus.Errorf("failed to commit product batch: %v", err)
+		return result, errConstant.ErrSQLError
+	}
+
+	return result, nil
+}
+
+// batchPermissions tells for every op in the batch whether the role of the
+// logged in user may run it, the same as the single product endpoints.
+func (p *ProductService) batchPermissions(ctx context.Context, operations []dto.ProductBatchOperation) (map[string]bool, error) {
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if !ok || userLogin == nil {
+		return nil, errConstant.ErrUnauthorized
+	}
+
+	permissions := map[string]string{
+		dto.ProductBatchCreate: constants.PermissionProductCreate,
+		dto.ProductBatchUpdate: constants.PermissionProductUpdate,
+		dto.ProductBatchDelete: constants.PermissionProductDelete,
+	}
+
+	allowed := map[string]bool{}
+	for _, operation := range operations {
+		permission, ok := permissions[operation.Op]
+		if !ok {
+			continue
+		}
+		if _, ok = allowed[operation.Op]; ok {
+			continue
+		}
+
+		granted, err := p.repository.GetRole().HasPermission(ctx, userLogin.Role, permission)
+		if err != nil {
+			return nil, err
+		}
+		allowed[operation.Op] = granted
+	}
+
+	return allowed, nil
+}
+
+// prepareBatchItem decodes and validates the data of one operation with the
+// same rules as the single product endpoints.
+func prepareBatchItem(validate *validator.Validate, operation *dto.ProductBatchOperation) (*productBatchItem, []errValidation.ValidationResponse) {
+	if err := validate.Struct(operation); err != nil {
+		return nil, errValidation.ErrValidationResponse(err)
+	}
+
+	item := &productBatchItem{}
+	switch operation.Op {
+	case dto.ProductBatchCreate:
+		item.create = &dto.ProductRequest{}
+		if err := json.Unmarshal(operation.Data, item.create); err != nil {
+			return nil, errValidation.ErrValidationResponse(err)
+		}
+
+		if err := validate.Struct(item.create); err != nil {
+			return nil, errValidation.ErrValidationResponse(err)
+		}
+	case dto.ProductBatchUpdate:
+		item.patch = &dto.PatchProductRequest{}
+		fields, err := util.ParseMergePatch(operation.Data, item.patch)
+		if err != nil {
+			return nil, errValidation.ErrValidationResponse(err)
+		}
+		item.patch.Fields = fields
+
+		if err = validate.Struct(item.patch); err != nil {
+			return nil, errValidation.ErrValidationResponse(err)
+		}
+	}
+
+	return item, nil
+}
+
+// runBatchItem applies one operation through repository and records the
+// outcome. Only errors known to ErrMapping are shown to the client.
+func (p *ProductService) runBatchItem(
+	ctx context.Context,
+	repository repositories.IRepositoryRegistry,
+	operation *dto.ProductBatchOperation,
+	item *productBatchItem,
+	result *dto.ProductBatchItemResult,
+) {
+	var (
+		product *models.Product
+		err     error
+	)
+
+	switch operation.Op {
+	case dto.ProductBatchCreate:
+		product, err = repository.GetProduct().Create(ctx, item.create)
+	case dto.ProductBatchUpdate:
+		err = repository.GetProduct().Patch(ctx, operation.UUID, patchValues(item.patch), batchVersions(operation))
+		if err == nil {
+			product, err = repository.GetProduct().FindByUUID(ctx, operation.UUID)
+		}
+	case dto.ProductBatchDelete:
+		err = repository.GetProduct().Delete(ctx, operation.UUID, batchVersions(operation))
+	}
+
+	if err != nil {
+		result.Status = "failed"
+		result.Error = errConstant.ErrInternalServerError.Error()
+		if errConstant.ErrMapping(err) {
+			result.Error = err.Error()
+		}
+		return
+	}
+
+	result.Status = "ok"
+	if product != nil {
+		result.UUID = product.UUID.String()
+		result.Data = &dto.ProductResponse{
+			UUID:         product.UUID,
+			Code:         product.Code,
+			Name:         product.Name,
+			PriceBuy:     product.PriceBuy,
+			PriceSale:    product.PriceSale,
+			Stock:        product.Stock,
+			Unit:         product.Unit,
+			ImageURL:     p.fileURL(product.Image),
+			ThumbnailURL: p.fileURL(product.Thumbnail),
+			CreatedAt:    product.CreatedAt,
+			UpdatedAt:    product.UpdatedAt,
+			Version:      product.Version,
+		}
+	}
+}
+
+// batchVersions is the version an operation was sent with, in the form the
+// repository checks it, or nil when it was not sent.
+func batchVersions(operation *dto.ProductBatchOperation) []uint {
+	if operation.Version == nil {
+		return nil
+	}
+
+	return []uint{*operation.Version}
+}
+
+// AdjustPrice previews or applies a price rule to every product matched by
+// param. It refuses to run without uuids or a filter, so a forgotten query
+// string can not reprice the whole catalog. Each product is written with
+// the version it was previewed at, so a product edited in between makes
+// the whole adjustment fail instead of pricing it from stale numbers.
+func (p *ProductService) AdjustPrice(
+	ctx context.Context,
+	param *dto.ProductFilterParam,
+	request *dto.ProductPriceAdjustmentRequest,
+) (*dto.ProductPriceAdjustmentResponse, error) {
+	param.Include = ""
+	param.UUIDs = request.UUIDs
+	if !param.HasScope() {
+		return nil, errProduct.ErrProductAdjustmentNoScope
+	}
+
+	if request.Type == dto.ProductPriceAdjustmentPercent && (request.Value < -100 || request.Value > 1000) {
+		return nil, errProduct.ErrProductAdjustmentPercent
+	}
+
+	products, err := p.repository.GetProduct().FindAllWithoutPagination(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(products) == 0 {
+		return nil, errProduct.ErrProductAdjustmentEmpty
+	}
+
+	result := &dto.ProductPriceAdjustmentResponse{
+		DryRun: request.DryRun,
+		Total:  len(products),
+		Items:  make([]dto.ProductPriceAdjustmentItem, 0, len(products)),
+	}
+
+	for _, product := range products {
+		priceSale, err := adjustPrice(product.PriceSale, request)
+		if err != nil {
+			return nil, err
+		}
+
+		item := dto.ProductPriceAdjustmentItem{
+			UUID:            product.UUID,
+			Code:            product.Code,
+			Name:            product.Name,
+			PriceBuy:        product.PriceBuy,
+			PriceSaleBefore: product.PriceSale,
+			PriceSaleAfter:  priceSale,
+			BelowCost:       priceSale < product.PriceBuy,
+		}
+
+		if item.PriceSaleAfter != item.PriceSaleBefore {
+			result.Changed++
+		}
+		if item.BelowCost {
+			result.BelowCost++
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	if request.DryRun {
+		return result, nil
+	}
+
+	if result.BelowCost > 0 && !request.Confirm {
+		return result, errProduct.ErrProductAdjustmentBelowCost
+	}
+
+	err = p.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		for i, item := range result.Items {
+			if item.PriceSaleAfter == item.PriceSaleBefore {
+				continue
+			}
+
+			err := repository.GetProduct().Patch(ctx, item.UUID.String(), map[string]interface{}{
+				"price_sale": item.PriceSaleAfter,
+			}, []uint{products[i].Version})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+// adjustPrice applies the rule to one price and rounds it to the nearest
+// multiple of the requested rounding, a price never goes below zero. Prices
+// are stored as bigint, so one that does not fit is refused rather than
+// wrapped around.
+func adjustPrice(price uint, request *dto.ProductPriceAdjustmentRequest) (uint, error) {
+	adjusted := float64(price) + request.Value
+	if request.Type == dto.ProductPriceAdjustmentPercent {
+		adjusted = float64(price) * (1 + request.Value/100)
+	}
+
+	step := float64(max(request.Rounding, 1))
+	adjusted = math.Round(adjusted/step) * step
+	if adjusted < 0 {
+		return 0, nil
+	}
+
+	if adjusted >= math.MaxInt64 {
+		return 0, errProduct.ErrProductAdjustmentTooHigh
+	}
+
+	return uint(adjusted), nil
+}
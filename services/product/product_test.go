@@ -0,0 +1,83 @@
+package services
+
+import (
+	"backend/common/storage"
+	"backend/constants"
+	"backend/database/seeders"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"backend/repositories"
+	"context"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+// newTestDB opens a fresh SQLite database with the tables of the product
+// service and the seeded roles its permission checks read.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if err = db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.Product{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	seeders.RunRoleSeeder(db)
+	seeders.RunPermissionSeeder(db)
+
+	return db
+}
+
+func newTestService(t *testing.T) (*ProductService, *gorm.DB) {
+	t.Helper()
+
+	db := newTestDB(t)
+	files, err := storage.NewLocalStorage(t.TempDir(), "http://localhost/uploads")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	return NewProductService(repositories.NewRepositoryRegistry(db), files).(*ProductService), db
+}
+
+func newTestProduct(t *testing.T, db *gorm.DB, code, name string) *models.Product {
+	t.Helper()
+
+	product := &models.Product{
+		UUID:      uuid.New(),
+		Code:      code,
+		Name:      name,
+		PriceBuy:  10000,
+		PriceSale: 15000,
+		Stock:     10,
+		Unit:      "pcs",
+	}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	return product
+}
+
+// roleContext is the context of a request by a user with the role.
+func roleContext(role string) context.Context {
+	return context.WithValue(context.Background(), constants.UserLogin, &dto.UserResponse{Role: role})
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	errConstant "backend/constants/error"
+	errProduct "backend/constants/error/product"
+	"backend/domain/dto"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUpdateWithVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		versions    []uint
+		wantErr     error
+		wantVersion uint
+	}{
+		{name: "current version", versions: []uint{1}, wantVersion: 2},
+		{name: "stale version", versions: []uint{0}, wantErr: errConstant.ErrPreconditionFailed, wantVersion: 1},
+		{name: "future version", versions: []uint{2}, wantErr: errConstant.ErrPreconditionFailed, wantVersion: 1},
+		{name: "current version in a list", versions: []uint{0, 1}, wantVersion: 2},
+		{name: "list without the current version", versions: []uint{0, 2}, wantErr: errConstant.ErrPreconditionFailed, wantVersion: 1},
+		{name: "wildcard", wantVersion: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			ctx := context.Background()
+			product := newTestProduct(t, db, "KP-01", "Kopi")
+
+			_, err := service.Update(ctx, product.UUID.String(), &dto.UpdateProductRequest{Name: "Kopi Susu"}, tt.versions)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := service.GetByUUID(ctx, product.UUID.String())
+			if err != nil {
+				t.Fatalf("GetByUUID: %v", err)
+			}
+			if stored.Version != tt.wantVersion || (tt.wantErr == nil) != (stored.Name == "Kopi Susu") {
+				t.Errorf("stored name %q at version %d, want version %d", stored.Name, stored.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestDeleteWithVersion(t *testing.T) {
+	service, db := newTestService(t)
+	ctx := context.Background()
+	product := newTestProduct(t, db, "KP-01", "Kopi")
+
+	// Someone else saves the product after it was loaded at version 1.
+	if _, err := service.Update(ctx, product.UUID.String(), &dto.UpdateProductRequest{Stock: 5}, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	stale := []uint{1}
+	if err := service.Delete(ctx, product.UUID.String(), stale); !errors.Is(err, errConstant.ErrPreconditionFailed) {
+		t.Fatalf("Delete with a stale version = %v, want %v", err, errConstant.ErrPreconditionFailed)
+	}
+
+	current := []uint{2}
+	if err := service.Delete(ctx, product.UUID.String(), current); err != nil {
+		t.Fatalf("Delete with the current version: %v", err)
+	}
+
+	if err := service.Delete(ctx, product.UUID.String(), current); !errors.Is(err, errProduct.ErrProductNotFound) {
+		t.Errorf("Delete of a deleted product = %v, want %v", err, errProduct.ErrProductNotFound)
+	}
+	if _, err := service.Update(ctx, "6f1d6c0e-3c55-4a8b-9a3f-0d5a5f1c2b3d", &dto.UpdateProductRequest{Stock: 5}, current); !errors.Is(err, errProduct.ErrProductNotFound) {
+		t.Errorf("Update of an unknown product = %v, want %v", err, errProduct.ErrProductNotFound)
+	}
+}
@@ -1,30 +1,68 @@
 package services
 
 import (
+	"backend/common/keys"
+	"backend/common/mailer"
+	"backend/common/storage"
 	"backend/repositories"
+	apiClientService "backend/services/apiclient"
+	auditService "backend/services/audit"
 	productService "backend/services/product"
+	roleService "backend/services/role"
+	terminalService "backend/services/terminal"
 	userService "backend/services/user"
 )
 
 type Registry struct {
 	repository repositories.IRepositoryRegistry
+	storage    storage.IStorage
+	keys       keys.IKeySet
+	mailer     mailer.IMailer
 }
 
 type IServiceRegistry interface {
 	GetUser() userService.IUserService
 	GetProduct() productService.IProductService
+	GetRole() roleService.IRoleService
+	GetTerminal() terminalService.ITerminalService
+	GetApiClient() apiClientService.IApiClientService
+	GetAuditLog() auditService.IAuditLogService
 }
 
-func NewServiceRegistry(repository repositories.IRepositoryRegistry) IServiceRegistry {
+func NewServiceRegistry(
+	repository repositories.IRepositoryRegistry,
+	storage storage.IStorage,
+	keys keys.IKeySet,
+	mailer mailer.IMailer,
+) IServiceRegistry {
 	return &Registry{
 		repository: repository,
+		storage:    storage,
+		keys:       keys,
+		mailer:     mailer,
 	}
 }
 
 func (r *Registry) GetUser() userService.IUserService {
-	return userService.NewUserService(r.repository)
+	return userService.NewUserService(r.repository, r.keys, r.mailer)
 }
 
 func (r *Registry) GetProduct() productService.IProductService {
-	return productService.NewProductService(r.repository)
+	return productService.NewProductService(r.repository, r.storage)
+}
+
+func (r *Registry) GetRole() roleService.IRoleService {
+	return roleService.NewRoleService(r.repository)
+}
+
+func (r *Registry) GetTerminal() terminalService.ITerminalService {
+	return terminalService.NewTerminalService(r.repository)
+}
+
+func (r *Registry) GetApiClient() apiClientService.IApiClientService {
+	return apiClientService.NewApiClientService(r.repository)
+}
+
+func (r *Registry) GetAuditLog() auditService.IAuditLogService {
+	return auditService.NewAuditLogService(r.repository)
 }
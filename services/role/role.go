@@ -0,0 +1,254 @@
+package services
+
+import (
+	"backend/constants"
+	errRole "backend/constants/error/role"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"backend/repositories"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Built-in roles. The owner always has every permission and can not be
+// edited, the admin can be edited but not deleted because new users are
+// registered with it.
+const (
+	ownerRoleCode = "OWNER"
+	adminRoleCode = "ADMIN"
+)
+
+type RoleService struct {
+	repository repositories.IRepositoryRegistry
+}
+
+type IRoleService interface {
+	GetAll(context.Context) ([]dto.RoleResponse, error)
+	GetPermissions(context.Context) ([]dto.PermissionResponse, error)
+	Create(context.Context, *dto.RoleRequest) (*dto.RoleResponse, error)
+	Update(context.Context, string, *dto.UpdateRoleRequest) (*dto.RoleResponse, error)
+	SetTwoFactor(context.Context, string, *dto.RoleTwoFactorRequest) (*dto.RoleResponse, error)
+	Delete(context.Context, string) error
+	HasPermission(context.Context, string, string) (bool, error)
+}
+
+func NewRoleService(repository repositories.IRepositoryRegistry) IRoleService {
+	return &RoleService{repository: repository}
+}
+
+func roleResponse(role *models.Role) dto.RoleResponse {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, permission.Code)
+	}
+	slices.Sort(permissions)
+
+	return dto.RoleResponse{
+		Code:             strings.ToLower(role.Code),
+		Name:             role.Name,
+		RequireTwoFactor: role.RequireTwoFactor,
+		Permissions:      permissions,
+	}
+}
+
+func (r *RoleService) GetAll(ctx context.Context) ([]dto.RoleResponse, error) {
+	roles, err := r.repository.GetRole().FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, roleResponse(&role))
+	}
+
+	return result, nil
+}
+
+func (r *RoleService) GetPermissions(ctx context.Context) ([]dto.PermissionResponse, error) {
+	permissions, err := r.repository.GetRole().FindPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.PermissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		result = append(result, dto.PermissionResponse{
+			Code:        permission.Code,
+			Description: permission.Description,
+		})
+	}
+
+	return result, nil
+}
+
+// Create adds a role with permissions the role of the user already has, so
+// nobody can hand out more than they can do themselves.
+func (r *RoleService) Create(ctx context.Context, request *dto.RoleRequest) (*dto.RoleResponse, error) {
+	_, err := r.repository.GetRole().FindByCode(ctx, request.Code)
+	if err == nil {
+		return nil, errRole.ErrRoleExist
+	}
+
+	if !errors.Is(err, errRole.ErrRoleNotFound) {
+		return nil, err
+	}
+
+	permissions, err := r.findPermissions(ctx, request.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.checkPermissionsHeld(ctx, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Code:        request.Code,
+		Name:        request.Name,
+		Permissions: permissions,
+	}
+
+	err = r.repository.GetRole().Create(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	result := roleResponse(role)
+	return &result, nil
+}
+
+// Update replaces the name and permissions of a role. Users can not change
+// their own role, nor grant or take away a permission their role lacks.
+func (r *RoleService) Update(ctx context.Context, code string, request *dto.UpdateRoleRequest) (*dto.RoleResponse, error) {
+	role, err := r.repository.GetRole().FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.Code == ownerRoleCode {
+		return nil, errRole.ErrRoleProtected
+	}
+
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if ok && userLogin != nil && strings.EqualFold(userLogin.Role, role.Code) {
+		return nil, errRole.ErrOwnRole
+	}
+
+	permissions, err := r.findPermissions(ctx, request.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Taking away a permission is as much a change to it as granting one.
+	err = r.checkPermissionsHeld(ctx, slices.Concat(permissions, role.Permissions))
+	if err != nil {
+		return nil, err
+	}
+
+	role.Name = request.Name
+	err = r.repository.GetRole().Update(ctx, role, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := roleResponse(role)
+	return &result, nil
+}
+
+// SetTwoFactor makes two-factor authentication mandatory for the users of
+// the role, the owner role included. Members without it can only set it up
+// until they did.
+func (r *RoleService) SetTwoFactor(ctx context.Context, code string, request *dto.RoleTwoFactorRequest) (*dto.RoleResponse, error) {
+	role, err := r.repository.GetRole().FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.repository.GetRole().SetRequireTwoFactor(ctx, role, *request.Required)
+	if err != nil {
+		return nil, err
+	}
+
+	role.RequireTwoFactor = *request.Required
+	result := roleResponse(role)
+	return &result, nil
+}
+
+func (r *RoleService) Delete(ctx context.Context, code string) error {
+	role, err := r.repository.GetRole().FindByCode(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	if role.Code == ownerRoleCode || role.Code == adminRoleCode {
+		return errRole.ErrRoleProtected
+	}
+
+	users, err := r.repository.GetRole().CountUsers(ctx, role.ID)
+	if err != nil {
+		return err
+	}
+
+	if users > 0 {
+		return errRole.ErrRoleInUse
+	}
+
+	return r.repository.GetRole().Delete(ctx, role)
+}
+
+// HasPermission is checked on every request guarded by RequirePermission,
+// so a changed role takes effect without waiting for tokens to expire.
+func (r *RoleService) HasPermission(ctx context.Context, roleCode, permission string) (bool, error) {
+	return r.repository.GetRole().HasPermission(ctx, roleCode, permission)
+}
+
+// findPermissions resolves permission codes and fails on any code that is
+// not in the catalog.
+func (r *RoleService) findPermissions(ctx context.Context, codes []string) ([]models.Permission, error) {
+	codes = slices.Compact(slices.Sorted(slices.Values(codes)))
+	if len(codes) == 0 {
+		return []models.Permission{}, nil
+	}
+
+	permissions, err := r.repository.GetRole().FindPermissionsByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(permissions) != len(codes) {
+		return nil, errRole.ErrPermissionNotFound
+	}
+
+	return permissions, nil
+}
+
+// checkPermissionsHeld fails when the role of the user lacks any of the
+// permissions. Requests without a user, such as seeders, are not limited.
+func (r *RoleService) checkPermissionsHeld(ctx context.Context, permissions []models.Permission) error {
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if !ok || userLogin == nil {
+		return nil
+	}
+
+	role, err := r.repository.GetRole().FindByCode(ctx, userLogin.Role)
+	if err != nil {
+		return err
+	}
+
+	held := make(map[string]bool, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		held[permission.Code] = true
+	}
+
+	for _, permission := range permissions {
+		if !held[permission.Code] {
+			return errRole.ErrPermissionNotHeld
+		}
+	}
+
+	return nil
+}
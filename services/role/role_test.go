@@ -0,0 +1,325 @@
+package services
+
+import (
+	"backend/constants"
+	errRole "backend/constants/error/role"
+	"backend/database/seeders"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"backend/repositories"
+	"context"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestService returns a role service over a fresh SQLite database seeded
+// with the built-in roles and the permission catalog.
+func newTestService(t *testing.T) (*RoleService, *gorm.DB) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.User{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	seeders.RunRoleSeeder(db)
+	seeders.RunPermissionSeeder(db)
+
+	service := NewRoleService(repositories.NewRepositoryRegistry(db)).(*RoleService)
+	return service, db
+}
+
+// actorContext is the context of a request by a user with the role, or of
+// one without a user when role is empty.
+func actorContext(role string) context.Context {
+	if role == "" {
+		return context.Background()
+	}
+
+	return context.WithValue(context.Background(), constants.UserLogin, &dto.UserResponse{UUID: uuid.New(), Role: role})
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   string
+		request dto.RoleRequest
+		wantErr error
+		want    *dto.RoleResponse
+	}{
+		{
+			name: "created",
+			request: dto.RoleRequest{
+				Code:        "cashier",
+				Name:        "Cashier",
+				Permissions: []string{constants.PermissionProductRead, constants.PermissionProductExport, constants.PermissionProductRead},
+			},
+			want: &dto.RoleResponse{
+				Code:        "cashier",
+				Name:        "Cashier",
+				Permissions: []string{constants.PermissionProductExport, constants.PermissionProductRead},
+			},
+		},
+		{
+			name:    "code taken in another case",
+			request: dto.RoleRequest{Code: "admin", Name: "Admin", Permissions: []string{constants.PermissionProductRead}},
+			wantErr: errRole.ErrRoleExist,
+		},
+		{
+			name:    "unknown permission",
+			request: dto.RoleRequest{Code: "cashier", Name: "Cashier", Permissions: []string{constants.PermissionProductRead, "product:steal"}},
+			wantErr: errRole.ErrPermissionNotFound,
+		},
+		{
+			name:    "permissions the admin has",
+			actor:   "admin",
+			request: dto.RoleRequest{Code: "cashier", Name: "Cashier", Permissions: []string{constants.PermissionProductRead}},
+			want:    &dto.RoleResponse{Code: "cashier", Name: "Cashier", Permissions: []string{constants.PermissionProductRead}},
+		},
+		{
+			name:    "permission the admin lacks",
+			actor:   "admin",
+			request: dto.RoleRequest{Code: "cashier", Name: "Cashier", Permissions: []string{constants.PermissionProductRead, constants.PermissionUserManage}},
+			wantErr: errRole.ErrPermissionNotHeld,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+
+			got, err := service.Create(actorContext(tt.actor), &tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Create = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        string
+		permissions []string
+		wantErr     error
+	}{
+		{name: "admin", code: "admin", permissions: []string{constants.PermissionProductRead}},
+		{name: "permissions cleared", code: "admin", permissions: []string{}},
+		{name: "owner is protected", code: "owner", permissions: []string{constants.PermissionProductRead}, wantErr: errRole.ErrRoleProtected},
+		{name: "unknown role", code: "cashier", permissions: []string{}, wantErr: errRole.ErrRoleNotFound},
+		{name: "unknown permission", code: "admin", permissions: []string{"product:steal"}, wantErr: errRole.ErrPermissionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			ctx := context.Background()
+
+			_, err := service.Update(ctx, tt.code, &dto.UpdateRoleRequest{Name: "Renamed", Permissions: tt.permissions})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			// The stored role is what later requests are checked against.
+			for _, permission := range constants.AdminPermissions {
+				allowed, err := service.HasPermission(ctx, tt.code, permission)
+				if err != nil {
+					t.Fatalf("HasPermission: %v", err)
+				}
+				want := len(tt.permissions) > 0 && permission == constants.PermissionProductRead
+				if allowed != want {
+					t.Errorf("HasPermission(%s) = %v, want %v", permission, allowed, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateByAnotherRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		actor       string
+		code        string
+		permissions []string
+		wantErr     error
+	}{
+		{name: "permissions the admin has", actor: "admin", code: "cashier", permissions: []string{constants.PermissionProductRead, constants.PermissionUserRead}},
+		{name: "grant a permission the admin lacks", actor: "admin", code: "cashier", permissions: []string{constants.PermissionUserManage}, wantErr: errRole.ErrPermissionNotHeld},
+		{name: "take away a permission the admin lacks", actor: "admin", code: "auditor", permissions: []string{}, wantErr: errRole.ErrPermissionNotHeld},
+		{name: "own role", actor: "admin", code: "admin", permissions: []string{constants.PermissionProductRead}, wantErr: errRole.ErrOwnRole},
+		{name: "by the owner", actor: "owner", code: "auditor", permissions: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+
+			for code, permission := range map[string]string{"cashier": constants.PermissionProductRead, "auditor": constants.PermissionUserManage} {
+				_, err := service.Create(context.Background(), &dto.RoleRequest{Code: code, Name: code, Permissions: []string{permission}})
+				if err != nil {
+					t.Fatalf("Create %s: %v", code, err)
+				}
+			}
+
+			_, err := service.Update(actorContext(tt.actor), tt.code, &dto.UpdateRoleRequest{Name: "Renamed", Permissions: tt.permissions})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		users   int
+		wantErr error
+	}{
+		{name: "unused role", code: "cashier"},
+		{name: "role in use", code: "cashier", users: 1, wantErr: errRole.ErrRoleInUse},
+		{name: "owner is protected", code: "owner", wantErr: errRole.ErrRoleProtected},
+		{name: "admin is protected", code: "admin", wantErr: errRole.ErrRoleProtected},
+		{name: "unknown role", code: "waiter", wantErr: errRole.ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			ctx := context.Background()
+
+			role, err := service.Create(ctx, &dto.RoleRequest{
+				Code:        "cashier",
+				Name:        "Cashier",
+				Permissions: []string{constants.PermissionProductRead},
+			})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			for i := 0; i < tt.users; i++ {
+				var stored models.Role
+				db.Where("code = ?", "CASHIER").First(&stored)
+				db.Create(&models.User{
+					UUID:        uuid.New(),
+					Name:        "Sari",
+					Username:    "sari",
+					Password:    "-",
+					PhoneNumber: "0812345678",
+					Email:       "sari@example.com",
+					RoleID:      stored.ID,
+				})
+			}
+
+			if err = service.Delete(ctx, tt.code); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Delete = %v, want %v", err, tt.wantErr)
+			}
+
+			allowed, err := service.HasPermission(ctx, role.Code, constants.PermissionProductRead)
+			if err != nil {
+				t.Fatalf("HasPermission: %v", err)
+			}
+			if deleted := tt.wantErr == nil; allowed == deleted {
+				t.Errorf("HasPermission of the cashier after Delete = %v", allowed)
+			}
+		})
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	service, _ := newTestService(t)
+
+	tests := []struct {
+		name       string
+		role       string
+		permission string
+		want       bool
+	}{
+		{name: "owner has everything", role: "owner", permission: constants.PermissionRoleManage, want: true},
+		{name: "role code in lower case", role: "admin", permission: constants.PermissionProductRead, want: true},
+		{name: "admin can not manage roles", role: "ADMIN", permission: constants.PermissionRoleManage},
+		{name: "unknown permission", role: "owner", permission: "product:steal"},
+		{name: "unknown role", role: "cashier", permission: constants.PermissionProductRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.HasPermission(context.Background(), tt.role, tt.permission)
+			if err != nil {
+				t.Fatalf("HasPermission: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HasPermission(%s, %s) = %v, want %v", tt.role, tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTwoFactor(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := context.Background()
+	required, optional := true, false
+
+	tests := []struct {
+		name     string
+		code     string
+		required *bool
+		wantErr  error
+	}{
+		{name: "owner can be required", code: "owner", required: &required},
+		{name: "admin required", code: "admin", required: &required},
+		{name: "admin optional again", code: "admin", required: &optional},
+		{name: "unknown role", code: "cashier", required: &required, wantErr: errRole.ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.SetTwoFactor(ctx, tt.code, &dto.RoleTwoFactorRequest{Required: tt.required})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetTwoFactor = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.RequireTwoFactor != *tt.required {
+				t.Errorf("SetTwoFactor returned require_two_factor %v, want %v", got.RequireTwoFactor, *tt.required)
+			}
+
+			roles, err := service.GetAll(ctx)
+			if err != nil {
+				t.Fatalf("GetAll: %v", err)
+			}
+			for _, role := range roles {
+				if role.Code == tt.code && role.RequireTwoFactor != *tt.required {
+					t.Errorf("stored require_two_factor of %s = %v, want %v", role.Code, role.RequireTwoFactor, *tt.required)
+				}
+			}
+		})
+	}
+}
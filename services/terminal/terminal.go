@@ -0,0 +1,135 @@
+package services
+
+import (
+	"backend/common/util"
+	"backend/constants"
+	errTerminal "backend/constants/error/terminal"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"backend/repositories"
+	"context"
+	"github.com/google/uuid"
+)
+
+type TerminalService struct {
+	repository repositories.IRepositoryRegistry
+}
+
+type ITerminalService interface {
+	Create(context.Context, *dto.TerminalRequest) (*dto.TerminalCreatedResponse, error)
+	GetAll(context.Context) ([]dto.TerminalResponse, error)
+	Revoke(context.Context, string) error
+	Authenticate(context.Context, string) (*models.Terminal, error)
+	GetUsers(context.Context) ([]dto.TerminalUserResponse, error)
+}
+
+func NewTerminalService(repository repositories.IRepositoryRegistry) ITerminalService {
+	return &TerminalService{repository: repository}
+}
+
+func terminalResponse(terminal *models.Terminal) dto.TerminalResponse {
+	return dto.TerminalResponse{
+		UUID:       terminal.UUID,
+		Name:       terminal.Name,
+		LastSeenAt: terminal.LastSeenAt,
+		RevokedAt:  terminal.RevokedAt,
+		CreatedAt:  terminal.CreatedAt,
+	}
+}
+
+// Create registers a terminal and returns its key. The key is not stored,
+// a lost key means registering the terminal again.
+func (t *TerminalService) Create(ctx context.Context, request *dto.TerminalRequest) (*dto.TerminalCreatedResponse, error) {
+	key, keyHash, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	terminal := &models.Terminal{
+		UUID:    uuid.New(),
+		Name:    request.Name,
+		KeyHash: keyHash,
+	}
+	if err = t.repository.GetTerminal().Create(ctx, terminal); err != nil {
+		return nil, err
+	}
+
+	return &dto.TerminalCreatedResponse{
+		Terminal: terminalResponse(terminal),
+		Key:      key,
+	}, nil
+}
+
+func (t *TerminalService) GetAll(ctx context.Context) ([]dto.TerminalResponse, error) {
+	terminals, err := t.repository.GetTerminal().FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.TerminalResponse, 0, len(terminals))
+	for i := range terminals {
+		result = append(result, terminalResponse(&terminals[i]))
+	}
+
+	return result, nil
+}
+
+// Revoke retires the terminal, drops its PINs and logs out whoever is
+// logged in at it.
+func (t *TerminalService) Revoke(ctx context.Context, uuid string) error {
+	terminal, err := t.repository.GetTerminal().FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	return t.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		if err := repository.GetTerminal().Revoke(ctx, terminal.ID); err != nil {
+			return err
+		}
+
+		return repository.GetSession().RevokeByTerminal(ctx, terminal.ID)
+	})
+}
+
+// Authenticate resolves the terminal behind a key and records that it was
+// seen.
+func (t *TerminalService) Authenticate(ctx context.Context, key string) (*models.Terminal, error) {
+	if key == "" {
+		return nil, errTerminal.ErrTerminalUnauthorized
+	}
+
+	terminal, err := t.repository.GetTerminal().FindByKeyHash(ctx, util.HashToken(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.repository.GetTerminal().Touch(ctx, terminal.ID); err != nil {
+		return nil, err
+	}
+
+	return terminal, nil
+}
+
+// GetUsers lists who can log in by PIN at the terminal of the request.
+func (t *TerminalService) GetUsers(ctx context.Context) ([]dto.TerminalUserResponse, error) {
+	terminal, ok := ctx.Value(constants.Terminal).(*models.Terminal)
+	if !ok || terminal == nil {
+		return nil, errTerminal.ErrTerminalUnauthorized
+	}
+
+	users, err := t.repository.GetTerminal().FindUsers(ctx, terminal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.TerminalUserResponse, 0, len(users))
+	for _, user := range users {
+		result = append(result, dto.TerminalUserResponse{
+			UUID:     user.UUID,
+			Name:     user.Name,
+			Username: user.Username,
+		})
+	}
+
+	return result, nil
+}
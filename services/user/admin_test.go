@@ -0,0 +1,305 @@
+package services
+
+import (
+	"backend/constants"
+	errRole "backend/constants/error/role"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  dto.RegisterRequest
+		wantErr  error
+		wantRole string
+	}{
+		{
+			name:     "with a role",
+			request:  dto.RegisterRequest{Name: "Sari Dewi", Username: "sari", Password: "Password1", ConfirmPassword: "Password1", Role: "admin"},
+			wantRole: "admin",
+		},
+		{
+			name:    "passwords differ",
+			request: dto.RegisterRequest{Name: "Sari Dewi", Username: "sari", Password: "Password1", ConfirmPassword: "Password2", Role: "admin"},
+			wantErr: errUser.ErrPasswordDoesNotMatch,
+		},
+		{
+			name:    "unknown role",
+			request: dto.RegisterRequest{Name: "Sari Dewi", Username: "sari", Password: "Password1", ConfirmPassword: "Password1", Role: "cashier"},
+			wantErr: errRole.ErrRoleNotFound,
+		},
+		{
+			name:    "username taken",
+			request: dto.RegisterRequest{Name: "Budi", Username: "budi", Password: "Password1", ConfirmPassword: "Password1", Role: "admin"},
+			wantErr: errUser.ErrUsernameExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			newTestUser(t, db, "budi", "Password1")
+			tt.request.Email = tt.request.Username + "@example.org"
+			tt.request.PhoneNumber = "0812345678"
+
+			response, err := service.Register(context.Background(), &tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Register = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			user := response.User
+			if user.Name != tt.request.Name || user.Role != tt.wantRole || !user.IsActive {
+				t.Errorf("Register = %+v, want an active %s named %s", user, tt.wantRole, tt.request.Name)
+			}
+			login(t, service, tt.request.Username, tt.request.Password)
+		})
+	}
+}
+
+func TestSetActive(t *testing.T) {
+	service, db := newTestService(t)
+	owner := newTestUser(t, db, "owner", "Password1")
+	user := newTestUser(t, db, "budi", "Password1")
+	_, ownerSession := login(t, service, "owner", "Password1")
+	response, sessionID := login(t, service, "budi", "Password1")
+	ctx := userContext(owner, ownerSession)
+
+	data, err := service.SetActive(ctx, user.UUID.String(), false)
+	if err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+	if data.IsActive {
+		t.Error("SetActive(false) returned an active user")
+	}
+
+	// The open session ends now, not when its access token expires.
+	if err = service.ValidateSession(ctx, sessionID); !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("ValidateSession of the deactivated user = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+	if _, err = refresh(service, response.RefreshToken); err == nil {
+		t.Error("Refresh of the deactivated user succeeded")
+	}
+
+	// A deactivated account looks like a wrong password to the client.
+	_, err = service.Login(ctx, &dto.LoginRequest{Username: "budi", Password: "Password1"})
+	if !errors.Is(err, errUser.ErrInvalidCredentials) {
+		t.Errorf("Login of the deactivated user = %v, want %v", err, errUser.ErrInvalidCredentials)
+	}
+
+	if _, err = service.SetActive(ctx, user.UUID.String(), true); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+	login(t, service, "budi", "Password1")
+
+	if _, err = service.SetActive(ctx, owner.UUID.String(), false); !errors.Is(err, errUser.ErrCannotManageSelf) {
+		t.Errorf("SetActive of the own account = %v, want %v", err, errUser.ErrCannotManageSelf)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	service, db := newTestService(t)
+	owner := newTestUser(t, db, "owner", "Password1")
+	user := newTestUser(t, db, "budi", "Password1")
+	_, ownerSession := login(t, service, "owner", "Password1")
+	_, oldSession := login(t, service, "budi", "Password1")
+	ctx := userContext(owner, ownerSession)
+
+	response, err := service.ResetPassword(ctx, user.UUID.String())
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if len(response.TemporaryPassword) != 12 || !response.User.MustChangePassword {
+		t.Errorf("ResetPassword = %+v, want a 12 character password to be changed", response)
+	}
+
+	if err = service.ValidateSession(ctx, oldSession); !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("ValidateSession of a session from before the reset = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+	if _, err = service.Login(ctx, &dto.LoginRequest{Username: "budi", Password: "Password1"}); err == nil {
+		t.Error("Login with the old password succeeded")
+	}
+
+	// The temporary password only lets the user change it.
+	_, sessionID := login(t, service, "budi", response.TemporaryPassword)
+	userCtx := userContext(user, sessionID)
+	if err = service.ValidateSession(userCtx, sessionID); !errors.Is(err, errUser.ErrPasswordChangeRequired) {
+		t.Errorf("ValidateSession with the temporary password = %v, want %v", err, errUser.ErrPasswordChangeRequired)
+	}
+
+	err = service.ChangePassword(userCtx, &dto.ChangePasswordRequest{
+		CurrentPassword: response.TemporaryPassword,
+		Password:        "Password2",
+		ConfirmPassword: "Password2",
+	})
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if err = service.ValidateSession(userCtx, sessionID); err != nil {
+		t.Errorf("ValidateSession after changing the password: %v", err)
+	}
+	login(t, service, "budi", "Password2")
+
+	if _, err = service.ResetPassword(ctx, owner.UUID.String()); !errors.Is(err, errUser.ErrCannotManageSelf) {
+		t.Errorf("ResetPassword of the own account = %v, want %v", err, errUser.ErrCannotManageSelf)
+	}
+}
+
+func TestAssignRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		role        string
+		self        bool
+		admin       bool
+		wantErr     error
+		wantRole    string
+		wantRevoked bool
+	}{
+		{name: "other role", role: "admin", wantRole: "admin", wantRevoked: true},
+		{name: "same role", role: "OWNER", wantRole: "owner"},
+		{name: "unknown role", role: "cashier", wantErr: errRole.ErrRoleNotFound},
+		{name: "own account", role: "admin", self: true, wantErr: errUser.ErrCannotManageSelf},
+		{name: "role above the own", role: "owner", admin: true, wantErr: errUser.ErrRoleOutranksYours},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			owner := newTestUser(t, db, "owner", "Password1")
+			user := newTestUser(t, db, "budi", "Password1")
+			if tt.admin {
+				db.Model(owner).Update("role_id", constants.Admin)
+				db.Model(user).Update("role_id", constants.Admin)
+			}
+			_, ownerSession := login(t, service, "owner", "Password1")
+			_, sessionID := login(t, service, "budi", "Password1")
+			ctx := userContext(owner, ownerSession)
+
+			target := user
+			if tt.self {
+				target = owner
+			}
+
+			data, err := service.AssignRole(ctx, target.UUID.String(), &dto.AssignRoleRequest{Role: tt.role})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AssignRole = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if data.Role != tt.wantRole {
+				t.Errorf("role = %q, want %q", data.Role, tt.wantRole)
+			}
+
+			err = service.ValidateSession(ctx, sessionID)
+			if revoked := errors.Is(err, errUser.ErrSessionRevoked); revoked != tt.wantRevoked {
+				t.Errorf("ValidateSession after AssignRole = %v, want revoked %v", err, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		request dto.ChangePasswordRequest
+		wantErr error
+	}{
+		{name: "changed", request: dto.ChangePasswordRequest{CurrentPassword: "Password1", Password: "Password2", ConfirmPassword: "Password2"}},
+		{name: "wrong current password", request: dto.ChangePasswordRequest{CurrentPassword: "Password9", Password: "Password2", ConfirmPassword: "Password2"}, wantErr: errUser.ErrPasswordIncorrect},
+		{name: "passwords differ", request: dto.ChangePasswordRequest{CurrentPassword: "Password1", Password: "Password2", ConfirmPassword: "Password3"}, wantErr: errUser.ErrPasswordDoesNotMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			user := newTestUser(t, db, "budi", "Password1")
+
+			err := service.ChangePassword(userContext(user, ""), &tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangePassword = %v, want %v", err, tt.wantErr)
+			}
+
+			password := "Password1"
+			if err == nil {
+				password = tt.request.Password
+			}
+			login(t, service, "budi", password)
+
+			var stored models.User
+			db.First(&stored, user.ID)
+			if stored.MustChangePassword {
+				t.Error("ChangePassword left a forced change pending")
+			}
+		})
+	}
+
+	service, _ := newTestService(t)
+	err := service.ChangePassword(context.Background(), &dto.ChangePasswordRequest{})
+	if err == nil {
+		t.Error("ChangePassword without a logged in user succeeded")
+	}
+}
+
+func TestGetUserByUsername(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{name: "known user", username: "budi"},
+		{name: "unknown user", username: "sari", wantErr: errUser.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.GetUserByUsername(context.Background(), tt.username)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetUserByUsername = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (got.UUID != user.UUID || got.Role != "owner") {
+				t.Errorf("GetUserByUsername = %+v, want the owner %s", got, user.UUID)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	service, db := newTestService(t)
+	owner := newTestUser(t, db, "owner", "Password1")
+	user := newTestUser(t, db, "budi", "Password1")
+	_, ownerSession := login(t, service, "owner", "Password1")
+	_, sessionID := login(t, service, "budi", "Password1")
+	ctx := userContext(owner, ownerSession)
+
+	if err := service.Delete(ctx, user.UUID.String()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if err := service.ValidateSession(ctx, sessionID); !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("ValidateSession of the deleted user = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+	if _, err := service.GetUserByUUID(ctx, user.UUID.String()); !errors.Is(err, errUser.ErrUserNotFound) {
+		t.Errorf("GetUserByUUID of the deleted user = %v, want %v", err, errUser.ErrUserNotFound)
+	}
+	if _, err := service.Login(ctx, &dto.LoginRequest{Username: "budi", Password: "Password1"}); !errors.Is(err, errUser.ErrInvalidCredentials) {
+		t.Errorf("Login of the deleted user = %v, want %v", err, errUser.ErrInvalidCredentials)
+	}
+
+	if err := service.Delete(ctx, user.UUID.String()); !errors.Is(err, errUser.ErrUserNotFound) {
+		t.Errorf("Delete again = %v, want %v", err, errUser.ErrUserNotFound)
+	}
+	if err := service.Delete(ctx, owner.UUID.String()); !errors.Is(err, errUser.ErrCannotManageSelf) {
+		t.Errorf("Delete of the own account = %v, want %v", err, errUser.ErrCannotManageSelf)
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"backend/config"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func loginFrom(service *UserService, username, password, clientIP string) error {
+	_, err := service.Login(context.Background(), &dto.LoginRequest{
+		Username: username,
+		Password: password,
+		ClientIP: clientIP,
+	})
+	return err
+}
+
+// failLogins makes count logins with a wrong password and expects each to be
+// refused as invalid credentials.
+func failLogins(t *testing.T, service *UserService, username, clientIP string, count int) {
+	t.Helper()
+
+	for i := 0; i < count; i++ {
+		err := loginFrom(service, username, "wrong password", clientIP)
+		if !errors.Is(err, errUser.ErrInvalidCredentials) {
+			t.Fatalf("failed login %d = %v, want %v", i+1, err, errUser.ErrInvalidCredentials)
+		}
+	}
+}
+
+// wantLocked checks err is a lock that lasts about retryAfter.
+func wantLocked(t *testing.T, err error, retryAfter time.Duration) {
+	t.Helper()
+
+	var locked *errUser.LoginLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("login = %v, want %v", err, errUser.ErrLoginLocked)
+	}
+	if locked.RetryAfter <= 0 || locked.RetryAfter > retryAfter || locked.RetryAfter < retryAfter-5*time.Second {
+		t.Errorf("retry after %v, want about %v", locked.RetryAfter, retryAfter)
+	}
+}
+
+func TestLoginLocksUsernameAfterMaxAttempts(t *testing.T) {
+	service, db := newTestService(t)
+	newTestUser(t, db, "budi", "Password1")
+
+	failLogins(t, service, "budi", "10.0.0.1", config.Config.LoginMaxAttempts)
+
+	// The right password does not get through the lock, from any address.
+	lockout := time.Duration(config.Config.LoginLockoutMinutes) * time.Minute
+	wantLocked(t, loginFrom(service, "budi", "Password1", "10.0.0.1"), lockout)
+	wantLocked(t, loginFrom(service, "budi", "Password1", "10.0.0.2"), lockout)
+
+	// Refused attempts are not counted as failures.
+	var throttle models.LoginThrottle
+	db.Where("scope = ? AND subject = ?", loginScopeUsername, "budi").First(&throttle)
+	if throttle.Failures != config.Config.LoginMaxAttempts {
+		t.Errorf("failures = %d, want %d", throttle.Failures, config.Config.LoginMaxAttempts)
+	}
+}
+
+func TestLoginLockExpires(t *testing.T) {
+	service, db := newTestService(t)
+	newTestUser(t, db, "budi", "Password1")
+
+	failLogins(t, service, "budi", "10.0.0.1", config.Config.LoginMaxAttempts)
+
+	err := db.Model(&models.LoginThrottle{}).
+		Where("scope = ?", loginScopeUsername).
+		Update("locked_until", time.Now().Add(-time.Second)).
+		Error
+	if err != nil {
+		t.Fatalf("expire lock: %v", err)
+	}
+
+	if err = loginFrom(service, "budi", "Password1", "10.0.0.1"); err != nil {
+		t.Errorf("login after the lock expired: %v", err)
+	}
+}
+
+func TestLoginSuccessResetsFailures(t *testing.T) {
+	service, db := newTestService(t)
+	newTestUser(t, db, "budi", "Password1")
+	attempts := config.Config.LoginMaxAttempts
+
+	failLogins(t, service, "budi", "10.0.0.1", attempts-1)
+	if err := loginFrom(service, "budi", "Password1", "10.0.0.1"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	failLogins(t, service, "budi", "10.0.0.1", attempts-1)
+	if err := loginFrom(service, "budi", "Password1", "10.0.0.1"); err != nil {
+		t.Errorf("login after %d failures since the last success: %v", attempts-1, err)
+	}
+}
+
+func TestLoginUnknownUsernameIsThrottled(t *testing.T) {
+	service, _ := newTestService(t)
+
+	failLogins(t, service, "nobody", "10.0.0.1", config.Config.LoginMaxAttempts)
+
+	err := loginFrom(service, "nobody", "wrong password", "10.0.0.1")
+	if !errors.Is(err, errUser.ErrLoginLocked) {
+		t.Errorf("login of a locked unknown username = %v, want %v", err, errUser.ErrLoginLocked)
+	}
+}
+
+func TestLoginLocksClientIP(t *testing.T) {
+	service, db := newTestService(t)
+	config.Config.LoginIPMaxAttempts = 3
+	newTestUser(t, db, "budi", "Password1")
+
+	// Spraying one password over many usernames stays below every username
+	// limit but not below the one of the address.
+	for _, username := range []string{"andi", "citra", "dewi"} {
+		failLogins(t, service, username, "10.0.0.1", 1)
+	}
+
+	lockout := time.Duration(config.Config.LoginLockoutMinutes) * time.Minute
+	wantLocked(t, loginFrom(service, "budi", "Password1", "10.0.0.1"), lockout)
+
+	if err := loginFrom(service, "budi", "Password1", "10.0.0.2"); err != nil {
+		t.Errorf("login from another address: %v", err)
+	}
+}
+
+func TestLoginDelayGrowsWithFailures(t *testing.T) {
+	service, db := newTestService(t)
+	config.Config.LoginDelaySeconds = 2
+	newTestUser(t, db, "budi", "Password1")
+
+	failLogins(t, service, "budi", "10.0.0.1", 1)
+	wantLocked(t, loginFrom(service, "budi", "Password1", "10.0.0.1"), 2*time.Second)
+
+	// Wait out the delay of the username and the address.
+	err := db.Model(&models.LoginThrottle{}).
+		Where("locked_until IS NOT NULL").
+		Update("locked_until", time.Now().Add(-time.Second)).
+		Error
+	if err != nil {
+		t.Fatalf("expire delay: %v", err)
+	}
+
+	failLogins(t, service, "budi", "10.0.0.1", 1)
+	wantLocked(t, loginFrom(service, "budi", "Password1", "10.0.0.1"), 4*time.Second)
+}
+
+func TestLoginConcurrentFailuresAreAllCounted(t *testing.T) {
+	service, db := newTestService(t)
+	config.Config.LoginMaxAttempts = 100
+	newTestUser(t, db, "budi", "Password1")
+
+	const logins = 8
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < logins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_ = loginFrom(service, "budi", "wrong password", "10.0.0.1")
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var throttle models.LoginThrottle
+	db.Where("scope = ? AND subject = ?", loginScopeUsername, "budi").First(&throttle)
+	if throttle.Failures != logins {
+		t.Errorf("failures = %d, want %d", throttle.Failures, logins)
+	}
+}
+
+func TestUnlockClearsUsernameLock(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+
+	failLogins(t, service, "budi", "10.0.0.1", config.Config.LoginMaxAttempts)
+
+	if _, err := service.Unlock(context.Background(), user.UUID.String()); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+
+	if err := loginFrom(service, "budi", "Password1", "10.0.0.2"); err != nil {
+		t.Errorf("login after Unlock: %v", err)
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	previous := config.Config
+	t.Cleanup(func() { config.Config = previous })
+	config.Config.LoginDelaySeconds = 1
+
+	lockout := 15 * time.Minute
+	tests := []struct {
+		name        string
+		failures    int
+		maxAttempts int
+		lockout     time.Duration
+		want        time.Duration
+	}{
+		{name: "first failure", failures: 1, maxAttempts: 5, lockout: lockout, want: time.Second},
+		{name: "doubles", failures: 3, maxAttempts: 5, lockout: lockout, want: 4 * time.Second},
+		{name: "capped", failures: 20, maxAttempts: 50, lockout: lockout, want: maxLoginDelay},
+		{name: "never above lockout", failures: 4, maxAttempts: 5, lockout: 5 * time.Second, want: 5 * time.Second},
+		{name: "max attempts locks out", failures: 5, maxAttempts: 5, lockout: lockout, want: lockout},
+		{name: "no max attempts", failures: 50, maxAttempts: 0, lockout: lockout, want: maxLoginDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loginDelay(tt.failures, tt.maxAttempts, tt.lockout); got != tt.want {
+				t.Errorf("loginDelay(%d, %d, %v) = %v, want %v", tt.failures, tt.maxAttempts, tt.lockout, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// requestPasswordReset runs ForgotPassword for the user and returns the token
+// from the link in the mail.
+func requestPasswordReset(t *testing.T, service *UserService, mail *captureMailer, user *models.User) string {
+	t.Helper()
+
+	err := service.ForgotPassword(context.Background(), &dto.ForgotPasswordRequest{Email: user.Email})
+	if err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+
+	sent := mail.sent()
+	if len(sent) == 0 {
+		t.Fatal("no password reset mail was sent")
+	}
+
+	message := sent[len(sent)-1]
+	if message.To != user.Email {
+		t.Errorf("mail sent to %q, want %q", message.To, user.Email)
+	}
+
+	_, link, found := strings.Cut(message.Body, "token=")
+	if !found {
+		t.Fatalf("no token in the mail body: %q", message.Body)
+	}
+	link, _, _ = strings.Cut(link, "\n")
+
+	token, err := url.QueryUnescape(link)
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+
+	return token
+}
+
+func resetRequest(token, password string) *dto.PasswordResetRequest {
+	return &dto.PasswordResetRequest{Token: token, Password: password, ConfirmPassword: password}
+}
+
+func TestResetForgottenPasswordWorksOnce(t *testing.T) {
+	service, db := newTestService(t)
+	mail := service.mailer.(*captureMailer)
+	user := newTestUser(t, db, "budi", "OldPassword1")
+	ctx := context.Background()
+
+	token := requestPasswordReset(t, service, mail, user)
+
+	err := service.ResetForgottenPassword(ctx, resetRequest(token, "NewPassword1"))
+	if err != nil {
+		t.Fatalf("ResetForgottenPassword: %v", err)
+	}
+
+	var updated models.User
+	if err = db.First(&updated, user.ID).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("NewPassword1")) != nil {
+		t.Error("password was not changed")
+	}
+
+	err = service.ResetForgottenPassword(ctx, resetRequest(token, "OtherPassword1"))
+	if !errors.Is(err, errUser.ErrPasswordResetTokenInvalid) {
+		t.Errorf("second reset with the same token = %v, want %v", err, errUser.ErrPasswordResetTokenInvalid)
+	}
+}
+
+func TestResetForgottenPasswordRejectsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, db *gorm.DB, user *models.User, token string) string
+	}{
+		{
+			name: "unknown token",
+			tamper: func(t *testing.T, db *gorm.DB, user *models.User, token string) string {
+				return token + "x"
+			},
+		},
+		{
+			name: "expired token",
+			tamper: func(t *testing.T, db *gorm.DB, user *models.User, token string) string {
+				err := db.Model(&models.PasswordResetToken{}).
+					Where("user_id = ?", user.ID).
+					Update("expires_at", time.Now().Add(-time.Second)).
+					Error
+				if err != nil {
+					t.Fatalf("expire token: %v", err)
+				}
+				return token
+			},
+		},
+		{
+			name: "deactivated user",
+			tamper: func(t *testing.T, db *gorm.DB, user *models.User, token string) string {
+				if err := db.Model(user).Update("is_active", false).Error; err != nil {
+					t.Fatalf("deactivate user: %v", err)
+				}
+				return token
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			mail := service.mailer.(*captureMailer)
+			user := newTestUser(t, db, "budi", "OldPassword1")
+
+			token := tt.tamper(t, db, user, requestPasswordReset(t, service, mail, user))
+
+			err := service.ResetForgottenPassword(context.Background(), resetRequest(token, "NewPassword1"))
+			if !errors.Is(err, errUser.ErrPasswordResetTokenInvalid) {
+				t.Errorf("ResetForgottenPassword = %v, want %v", err, errUser.ErrPasswordResetTokenInvalid)
+			}
+		})
+	}
+}
+
+func TestResetForgottenPasswordOnlyLastTokenWorks(t *testing.T) {
+	service, db := newTestService(t)
+	mail := service.mailer.(*captureMailer)
+	user := newTestUser(t, db, "budi", "OldPassword1")
+	ctx := context.Background()
+
+	first := requestPasswordReset(t, service, mail, user)
+
+	// A second mail is only sent once the first one is a minute old.
+	err := db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ?", user.ID).
+		Update("created_at", time.Now().Add(-2*passwordResetInterval)).
+		Error
+	if err != nil {
+		t.Fatalf("age token: %v", err)
+	}
+	second := requestPasswordReset(t, service, mail, user)
+
+	err = service.ResetForgottenPassword(ctx, resetRequest(first, "NewPassword1"))
+	if !errors.Is(err, errUser.ErrPasswordResetTokenInvalid) {
+		t.Errorf("reset with the older token = %v, want %v", err, errUser.ErrPasswordResetTokenInvalid)
+	}
+
+	err = service.ResetForgottenPassword(ctx, resetRequest(second, "NewPassword1"))
+	if err != nil {
+		t.Errorf("reset with the latest token: %v", err)
+	}
+}
+
+func TestForgotPasswordSendsOneMailPerInterval(t *testing.T) {
+	service, db := newTestService(t)
+	mail := service.mailer.(*captureMailer)
+	user := newTestUser(t, db, "budi", "OldPassword1")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		err := service.ForgotPassword(ctx, &dto.ForgotPasswordRequest{Email: user.Email})
+		if err != nil {
+			t.Fatalf("ForgotPassword: %v", err)
+		}
+	}
+
+	err := service.ForgotPassword(ctx, &dto.ForgotPasswordRequest{Email: "nobody@example.com"})
+	if err != nil {
+		t.Errorf("ForgotPassword for an unknown email: %v", err)
+	}
+
+	if sent := mail.sent(); len(sent) != 1 {
+		t.Errorf("sent %d mails, want 1", len(sent))
+	}
+}
+
+func TestResetForgottenPasswordRace(t *testing.T) {
+	service, db := newTestService(t)
+	mail := service.mailer.(*captureMailer)
+	user := newTestUser(t, db, "budi", "OldPassword1")
+	token := requestPasswordReset(t, service, mail, user)
+
+	const resets = 4
+	errs := make([]error, resets)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < resets; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = service.ResetForgottenPassword(context.Background(), resetRequest(token, fmt.Sprintf("NewPassword%d", i)))
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := -1
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if succeeded >= 0 {
+				t.Errorf("resets %d and %d both succeeded", succeeded, i)
+			}
+			succeeded = i
+		case !errors.Is(err, errUser.ErrPasswordResetTokenInvalid):
+			t.Errorf("reset %d = %v, want %v", i, err, errUser.ErrPasswordResetTokenInvalid)
+		}
+	}
+	if succeeded < 0 {
+		t.Fatal("no reset succeeded")
+	}
+
+	var updated models.User
+	if err := db.First(&updated, user.ID).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	password := fmt.Sprintf("NewPassword%d", succeeded)
+	if bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte(password)) != nil {
+		t.Errorf("password is not the one of the reset that succeeded")
+	}
+}
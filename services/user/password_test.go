@@ -0,0 +1,180 @@
+package services
+
+import (
+	"backend/config"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	setTestConfig(t)
+
+	tests := []struct {
+		name       string
+		password   string
+		minLength  int
+		minClasses int
+		wantErrors []string
+	}{
+		{name: "letters and digits", password: "password1"},
+		{name: "upper and lower case", password: "Password"},
+		{name: "letters and symbols", password: "pass word"},
+		{name: "length counts characters, not bytes", password: "pässwörd1", minLength: 9},
+		{name: "too short", password: "Pass1", wantErrors: []string{"password must be at least 8 characters"}},
+		{
+			name:       "one class only",
+			password:   "password",
+			wantErrors: []string{"password must mix at least 2 of lower case letters, upper case letters, digits and symbols"},
+		},
+		{
+			name:     "every rule broken",
+			password: "1234",
+			wantErrors: []string{
+				"password must be at least 8 characters",
+				"password must mix at least 2 of lower case letters, upper case letters, digits and symbols",
+			},
+		},
+		{name: "all four classes required", password: "Password1!", minClasses: 4},
+		{
+			name:       "three of four classes",
+			password:   "Password1",
+			minClasses: 4,
+			wantErrors: []string{"password must mix at least 4 of lower case letters, upper case letters, digits and symbols"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.PasswordMinLength = 8
+			config.Config.PasswordMinClasses = 2
+			if tt.minLength > 0 {
+				config.Config.PasswordMinLength = tt.minLength
+			}
+			if tt.minClasses > 0 {
+				config.Config.PasswordMinClasses = tt.minClasses
+			}
+
+			var messages []string
+			if err := checkPasswordPolicy(tt.password); err != nil {
+				for _, item := range validationErrors(t, err) {
+					messages = append(messages, item.Message)
+				}
+			}
+			if !reflect.DeepEqual(messages, tt.wantErrors) {
+				t.Errorf("checkPasswordPolicy(%q) = %q, want %q", tt.password, messages, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestChangePasswordHistory(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	ctx := userContext(user, "")
+
+	change := func(current, password string) error {
+		return service.ChangePassword(ctx, &dto.ChangePasswordRequest{
+			CurrentPassword: current,
+			Password:        password,
+			ConfirmPassword: password,
+		})
+	}
+
+	// With a history of 3 the current and the two before it are refused.
+	steps := []struct {
+		current  string
+		password string
+		wantErr  error
+	}{
+		{current: "Password1", password: "Password1", wantErr: errUser.ErrPasswordReused},
+		{current: "Password1", password: "Password2"},
+		{current: "Password2", password: "Password1", wantErr: errUser.ErrPasswordReused},
+		{current: "Password2", password: "Password3"},
+		{current: "Password3", password: "Password1", wantErr: errUser.ErrPasswordReused},
+		{current: "Password3", password: "Password4"},
+		{current: "Password4", password: "Password2", wantErr: errUser.ErrPasswordReused},
+		{current: "Password4", password: "Password1"},
+	}
+
+	for i, step := range steps {
+		if err := change(step.current, step.password); !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d: ChangePassword to %s = %v, want %v", i, step.password, err, step.wantErr)
+		}
+	}
+
+	login(t, service, "budi", "Password1")
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	_, current := login(t, service, "budi", "Password1")
+	other, otherID := login(t, service, "budi", "Password1")
+
+	err := service.ChangePassword(userContext(user, current), &dto.ChangePasswordRequest{
+		CurrentPassword: "Password1",
+		Password:        "Password2",
+		ConfirmPassword: "Password2",
+	})
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	ctx := context.Background()
+	if err = service.ValidateSession(ctx, current); err != nil {
+		t.Errorf("ValidateSession of the session that changed the password: %v", err)
+	}
+	if err = service.ValidateSession(ctx, otherID); !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("ValidateSession of another session = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+	if _, err = refresh(service, other.RefreshToken); !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("Refresh of another session = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+}
+
+func TestChangePasswordThrottle(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	ctx := userContext(user, "")
+
+	change := func(current string) error {
+		return service.ChangePassword(ctx, &dto.ChangePasswordRequest{
+			CurrentPassword: current,
+			Password:        "Password2",
+			ConfirmPassword: "Password2",
+		})
+	}
+
+	for i := 0; i < config.Config.LoginMaxAttempts; i++ {
+		if err := change("wrong password"); !errors.Is(err, errUser.ErrPasswordIncorrect) {
+			t.Fatalf("wrong current password %d = %v, want %v", i+1, err, errUser.ErrPasswordIncorrect)
+		}
+	}
+
+	// The guesses lock the username, for the change and for the login.
+	lockout := time.Duration(config.Config.LoginLockoutMinutes) * time.Minute
+	wantLocked(t, change("Password1"), lockout)
+	wantLocked(t, loginFrom(service, "budi", "Password1", ""), lockout)
+}
+
+func TestRegisterChecksPasswordPolicy(t *testing.T) {
+	service, _ := newTestService(t)
+
+	_, err := service.Register(context.Background(), &dto.RegisterRequest{
+		Name:            "Sari Dewi",
+		Username:        "sari",
+		Password:        "short",
+		ConfirmPassword: "short",
+		Email:           "sari@example.org",
+		PhoneNumber:     "0812345678",
+		Role:            "admin",
+	})
+	if items := validationErrors(t, err); len(items) != 2 || items[0].Field != "password" {
+		t.Errorf("Register with a weak password = %v, want the password rules", err)
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"backend/common/util"
+	errConstant "backend/constants/error"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	stale := []uint{0}
+
+	tests := []struct {
+		name      string
+		body      string
+		versions  []uint
+		want      dto.UserResponse
+		wantErr   error
+		wantParse bool
+	}{
+		{
+			name: "members not sent are kept",
+			body: `{"name":"Budi Santoso","phone_number":"0899999999"}`,
+			want: dto.UserResponse{Name: "Budi Santoso", Username: "budi", Email: "budi@example.com", PhoneNumber: "0899999999"},
+		},
+		{
+			name: "own username again",
+			body: `{"username":"budi"}`,
+			want: dto.UserResponse{Name: "budi", Username: "budi", Email: "budi@example.com", PhoneNumber: "0812345678"},
+		},
+		{
+			name:    "username taken",
+			body:    `{"username":"sari"}`,
+			want:    dto.UserResponse{Name: "budi", Username: "budi", Email: "budi@example.com", PhoneNumber: "0812345678"},
+			wantErr: errUser.ErrUsernameExist,
+		},
+		{
+			name: "new email",
+			body: `{"email":"budi.s@example.com"}`,
+			want: dto.UserResponse{Name: "budi", Username: "budi", Email: "budi.s@example.com", PhoneNumber: "0812345678"},
+		},
+		{
+			name:    "email taken",
+			body:    `{"email":"sari@example.com"}`,
+			want:    dto.UserResponse{Name: "budi", Username: "budi", Email: "budi@example.com", PhoneNumber: "0812345678"},
+			wantErr: errUser.ErrEmailExist,
+		},
+		{
+			name:      "password is not a member",
+			body:      `{"password":"Password2","confirm_password":"Password2"}`,
+			want:      dto.UserResponse{Name: "budi", Username: "budi", Email: "budi@example.com", PhoneNumber: "0812345678"},
+			wantParse: true,
+		},
+		{
+			name:     "stale version",
+			body:     `{"name":"Budi Santoso"}`,
+			versions: stale,
+			want:     dto.UserResponse{Name: "budi", Username: "budi", Email: "budi@example.com", PhoneNumber: "0812345678"},
+			wantErr:  errConstant.ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			ctx := context.Background()
+			user := newTestUser(t, db, "budi", "Password1")
+			newTestUser(t, db, "sari", "Password1")
+
+			var request dto.PatchUserRequest
+			fields, err := util.ParseMergePatch([]byte(tt.body), &request)
+			if (err != nil) != tt.wantParse {
+				t.Fatalf("ParseMergePatch = %v, want an error %v", err, tt.wantParse)
+			}
+			if err == nil {
+				request.Fields = fields
+				_, err = service.Patch(ctx, &request, user.UUID.String(), tt.versions)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Patch = %v, want %v", err, tt.wantErr)
+				}
+			}
+
+			got, err := service.GetUserByUUID(ctx, user.UUID.String())
+			if err != nil {
+				t.Fatalf("GetUserByUUID: %v", err)
+			}
+			if got.Name != tt.want.Name || got.Username != tt.want.Username || got.Email != tt.want.Email || got.PhoneNumber != tt.want.PhoneNumber {
+				t.Errorf("user after patch = %+v, want %+v", got, tt.want)
+			}
+
+			// Only a changed address is told about it.
+			sent := service.mailer.(*captureMailer).sent()
+			if notified := len(sent) == 1 && sent[0].To == "budi@example.com"; notified != (got.Email != "budi@example.com") || len(sent) > 1 {
+				t.Errorf("sent %+v after the email became %q", sent, got.Email)
+			}
+
+			// The password is never changed by a patch.
+			login(t, service, "budi", "Password1")
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"backend/config"
+	"backend/constants"
+	errTerminal "backend/constants/error/terminal"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+// newTestTerminal registers a terminal with a throwaway key hash.
+func newTestTerminal(t *testing.T, service *UserService, name string) *models.Terminal {
+	t.Helper()
+
+	terminal := &models.Terminal{UUID: uuid.New(), Name: name, KeyHash: uuid.NewString()}
+	if err := service.repository.GetTerminal().Create(context.Background(), terminal); err != nil {
+		t.Fatalf("create terminal: %v", err)
+	}
+
+	return terminal
+}
+
+// terminalContext is the context of a request from the terminal, by the
+// user on the session when user is not nil.
+func terminalContext(terminal *models.Terminal, user *models.User, sessionID string) context.Context {
+	ctx := context.WithValue(context.Background(), constants.Terminal, terminal)
+	if user != nil {
+		ctx = context.WithValue(ctx, constants.UserLogin, &dto.UserResponse{UUID: user.UUID})
+		ctx = context.WithValue(ctx, constants.SessionID, sessionID)
+	}
+
+	return ctx
+}
+
+func enrollPin(t *testing.T, service *UserService, terminal *models.Terminal, user *models.User, pin string) {
+	t.Helper()
+
+	err := service.EnrollPin(terminalContext(terminal, user, ""), &dto.EnrollPinRequest{
+		Password:   "Password1",
+		Pin:        pin,
+		ConfirmPin: pin,
+	})
+	if err != nil {
+		t.Fatalf("EnrollPin: %v", err)
+	}
+}
+
+func pinLogin(service *UserService, terminal *models.Terminal, user *models.User, pin string) (*dto.LoginResponse, error) {
+	return service.PinLogin(terminalContext(terminal, nil, ""), &dto.PinLoginRequest{
+		UserUUID: user.UUID.String(),
+		Pin:      pin,
+		ClientIP: "10.0.0.1",
+	})
+}
+
+func TestPinLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		login   func(service *UserService, till, other *models.Terminal, budi, sari *models.User) (*dto.LoginResponse, error)
+		wantErr error
+	}{
+		{
+			name: "enrolled pin",
+			login: func(service *UserService, till, other *models.Terminal, budi, sari *models.User) (*dto.LoginResponse, error) {
+				return pinLogin(service, till, budi, "1234")
+			},
+		},
+		{
+			name: "wrong pin",
+			login: func(service *UserService, till, other *models.Terminal, budi, sari *models.User) (*dto.LoginResponse, error) {
+				return pinLogin(service, till, budi, "4321")
+			},
+			wantErr: errTerminal.ErrPinIncorrect,
+		},
+		{
+			name: "pin of another user",
+			login: func(service *UserService, till, other *models.Terminal, budi, sari *models.User) (*dto.LoginResponse, error) {
+				return pinLogin(service, till, sari, "1234")
+			},
+			wantErr: errTerminal.ErrPinIncorrect,
+		},
+		{
+			name: "other terminal",
+			login: func(service *UserService, till, other *models.Terminal, budi, sari *models.User) (*dto.LoginResponse, error) {
+				return pinLogin(service, other, budi, "1234")
+			},
+			wantErr: errTerminal.ErrPinIncorrect,
+		},
+		{
+			name: "unknown user",
+			login: func(service *UserService, till, other *models.Terminal, budi, sari *models.User) (*dto.LoginResponse, error) {
+				return pinLogin(service, till, &models.User{UUID: uuid.New()}, "1234")
+			},
+			wantErr: errTerminal.ErrPinIncorrect,
+		},
+		{
+			name: "no terminal",
+			login: func(service *UserService, till, other *models.Terminal, budi, sari *models.User) (*dto.LoginResponse, error) {
+				return service.PinLogin(context.Background(), &dto.PinLoginRequest{UserUUID: budi.UUID.String(), Pin: "1234"})
+			},
+			wantErr: errTerminal.ErrTerminalUnauthorized,
+		},
+		{
+			name: "deactivated user",
+			login: func(service *UserService, till, other *models.Terminal, budi, sari *models.User) (*dto.LoginResponse, error) {
+				if _, err := service.SetActive(context.Background(), budi.UUID.String(), false); err != nil {
+					return nil, err
+				}
+				return pinLogin(service, till, budi, "1234")
+			},
+			wantErr: errTerminal.ErrPinIncorrect,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			till := newTestTerminal(t, service, "Kasir 1")
+			other := newTestTerminal(t, service, "Kasir 2")
+			budi := newTestUser(t, db, "budi", "Password1")
+			sari := newTestUser(t, db, "sari", "Password1")
+			enrollPin(t, service, till, budi, "1234")
+			enrollPin(t, service, till, sari, "5678")
+
+			response, err := tt.login(service, till, other, budi, sari)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PinLogin = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && response.User.Username != "budi" {
+				t.Errorf("PinLogin logged in %q, want budi", response.User.Username)
+			}
+		})
+	}
+}
+
+func TestPinLoginSwitchesUser(t *testing.T) {
+	service, db := newTestService(t)
+	till := newTestTerminal(t, service, "Kasir 1")
+	budi := newTestUser(t, db, "budi", "Password1")
+	sari := newTestUser(t, db, "sari", "Password1")
+	enrollPin(t, service, till, budi, "1234")
+	enrollPin(t, service, till, sari, "5678")
+	_, passwordSession := login(t, service, "budi", "Password1")
+
+	first, err := pinLogin(service, till, budi, "1234")
+	if err != nil {
+		t.Fatalf("PinLogin: %v", err)
+	}
+	if _, err = pinLogin(service, till, sari, "5678"); err != nil {
+		t.Fatalf("PinLogin: %v", err)
+	}
+
+	// Only one user is logged in at a terminal at a time.
+	ctx := context.Background()
+	if err = service.ValidateSession(ctx, sessionOf(t, service, first.Token)); !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("ValidateSession of the previous user at the terminal = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+	if err = service.ValidateSession(ctx, passwordSession); err != nil {
+		t.Errorf("ValidateSession of a session outside the terminal: %v", err)
+	}
+}
+
+func TestPinLoginIsThrottled(t *testing.T) {
+	service, db := newTestService(t)
+	config.Config.PinMaxAttempts = 3
+	till := newTestTerminal(t, service, "Kasir 1")
+	other := newTestTerminal(t, service, "Kasir 2")
+	budi := newTestUser(t, db, "budi", "Password1")
+	enrollPin(t, service, till, budi, "1234")
+	enrollPin(t, service, other, budi, "1234")
+
+	for i := 0; i < config.Config.PinMaxAttempts; i++ {
+		if _, err := pinLogin(service, till, budi, "0000"); !errors.Is(err, errTerminal.ErrPinIncorrect) {
+			t.Fatalf("failed PIN login %d = %v, want %v", i+1, err, errTerminal.ErrPinIncorrect)
+		}
+	}
+
+	// The right PIN does not get past the lock.
+	_, err := pinLogin(service, till, budi, "1234")
+	wantLocked(t, err, time.Duration(config.Config.LoginLockoutMinutes)*time.Minute)
+
+	// Guessing at one terminal does not lock the user out of the others,
+	// and the password login of the user has its own count.
+	if _, err = pinLogin(service, other, budi, "1234"); err != nil {
+		t.Errorf("PinLogin at another terminal: %v", err)
+	}
+	login(t, service, "budi", "Password1")
+
+	if _, err = service.Unlock(context.Background(), budi.UUID.String()); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err = pinLogin(service, till, budi, "1234"); err != nil {
+		t.Errorf("PinLogin after Unlock: %v", err)
+	}
+}
+
+func TestEnrollPin(t *testing.T) {
+	service, db := newTestService(t)
+	till := newTestTerminal(t, service, "Kasir 1")
+	budi := newTestUser(t, db, "budi", "Password1")
+	ctx := terminalContext(till, budi, "")
+
+	err := service.EnrollPin(ctx, &dto.EnrollPinRequest{Password: "Password9", Pin: "1234", ConfirmPin: "1234"})
+	if !errors.Is(err, errUser.ErrPasswordIncorrect) {
+		t.Fatalf("EnrollPin with a wrong password = %v, want %v", err, errUser.ErrPasswordIncorrect)
+	}
+
+	enrollPin(t, service, till, budi, "1234")
+	enrollPin(t, service, till, budi, "9876")
+	if _, err = pinLogin(service, till, budi, "1234"); !errors.Is(err, errTerminal.ErrPinIncorrect) {
+		t.Errorf("PinLogin with the replaced PIN = %v, want %v", err, errTerminal.ErrPinIncorrect)
+	}
+	if _, err = pinLogin(service, till, budi, "9876"); err != nil {
+		t.Errorf("PinLogin with the new PIN: %v", err)
+	}
+
+	if err = service.RemovePin(ctx); err != nil {
+		t.Fatalf("RemovePin: %v", err)
+	}
+	if _, err = pinLogin(service, till, budi, "9876"); !errors.Is(err, errTerminal.ErrPinIncorrect) {
+		t.Errorf("PinLogin after RemovePin = %v, want %v", err, errTerminal.ErrPinIncorrect)
+	}
+}
+
+func TestPinLoginCapsFailuresPerTerminal(t *testing.T) {
+	service, db := newTestService(t)
+	config.Config.TerminalMaxAttempts = 4
+	till := newTestTerminal(t, service, "Kasir 1")
+	other := newTestTerminal(t, service, "Kasir 2")
+
+	// Two guesses at each user stay below the lock of every one of them,
+	// together they reach the cap of the terminal.
+	var users []*models.User
+	for _, username := range []string{"budi", "sari", "dewi"} {
+		user := newTestUser(t, db, username, "Password1")
+		enrollPin(t, service, till, user, "1234")
+		enrollPin(t, service, other, user, "1234")
+		users = append(users, user)
+	}
+	for _, user := range users[:2] {
+		for i := 0; i < 2; i++ {
+			if _, err := pinLogin(service, till, user, "0000"); !errors.Is(err, errTerminal.ErrPinIncorrect) {
+				t.Fatalf("failed PIN login of %s = %v, want %v", user.Username, err, errTerminal.ErrPinIncorrect)
+			}
+		}
+	}
+
+	_, err := pinLogin(service, till, users[2], "1234")
+	wantLocked(t, err, time.Duration(config.Config.LoginLockoutMinutes)*time.Minute)
+
+	if _, err = pinLogin(service, other, users[2], "1234"); err != nil {
+		t.Errorf("PinLogin at another terminal: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"backend/common/util"
+	"backend/constants"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+	"time"
+)
+
+// login logs the user in with the password and returns the response with
+// the session id from its access token.
+func login(t *testing.T, service *UserService, username, password string) (*dto.LoginResponse, string) {
+	t.Helper()
+
+	response, err := service.Login(context.Background(), &dto.LoginRequest{
+		Username:   username,
+		Password:   password,
+		DeviceName: "Kasir 1",
+		ClientIP:   "10.0.0.1",
+	})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	return response, sessionOf(t, service, response.Token)
+}
+
+// sessionOf returns the session id in the access token.
+func sessionOf(t *testing.T, service *UserService, token string) string {
+	t.Helper()
+
+	claims, err := service.ParseToken(token)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+
+	return claims.SessionID
+}
+
+func refresh(service *UserService, refreshToken string) (*dto.LoginResponse, error) {
+	return service.Refresh(context.Background(), &dto.RefreshTokenRequest{RefreshToken: refreshToken})
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	service, db := newTestService(t)
+	newTestUser(t, db, "budi", "Password1")
+
+	first, sessionID := login(t, service, "budi", "Password1")
+
+	second, err := refresh(service, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Refresh handed back the same refresh token")
+	}
+
+	if got := sessionOf(t, service, second.Token); got != sessionID {
+		t.Errorf("session id = %q, want the session of the login %q", got, sessionID)
+	}
+
+	_, err = refresh(service, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh with the rotated token: %v", err)
+	}
+
+	if err = service.ValidateSession(context.Background(), sessionID); err != nil {
+		t.Errorf("ValidateSession after rotation: %v", err)
+	}
+
+	var sessions int64
+	db.Model(&models.Session{}).Count(&sessions)
+	if sessions != 1 {
+		t.Errorf("%d sessions, want rotation to stay in one", sessions)
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	service, db := newTestService(t)
+	newTestUser(t, db, "budi", "Password1")
+	ctx := context.Background()
+
+	first, sessionID := login(t, service, "budi", "Password1")
+	second, err := refresh(service, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// Someone replays the token that was already rotated.
+	_, err = refresh(service, first.RefreshToken)
+	if !errors.Is(err, errUser.ErrRefreshTokenReused) {
+		t.Fatalf("Refresh with a used token = %v, want %v", err, errUser.ErrRefreshTokenReused)
+	}
+
+	err = service.ValidateSession(ctx, sessionID)
+	if !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("ValidateSession after reuse = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+
+	// The legitimate holder of the newest token is logged out as well.
+	_, err = refresh(service, second.RefreshToken)
+	if !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("Refresh with the newest token = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+
+	// Other sessions of the user are left alone.
+	other, otherID := login(t, service, "budi", "Password1")
+	if _, err = refresh(service, other.RefreshToken); err != nil {
+		t.Errorf("Refresh of another session: %v", err)
+	}
+	if err = service.ValidateSession(ctx, otherID); err != nil {
+		t.Errorf("ValidateSession of another session: %v", err)
+	}
+}
+
+func TestRefreshConcurrentUseOfOneToken(t *testing.T) {
+	service, db := newTestService(t)
+	newTestUser(t, db, "budi", "Password1")
+
+	first, sessionID := login(t, service, "budi", "Password1")
+
+	const refreshes = 4
+	errs := make([]error, refreshes)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < refreshes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = refresh(service, first.RefreshToken)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, errUser.ErrRefreshTokenReused) && !errors.Is(err, errUser.ErrSessionRevoked):
+			t.Errorf("refresh %d = %v, want reuse or revoked", i, err)
+		}
+	}
+	if succeeded > 1 {
+		t.Errorf("%d refreshes with one token succeeded, want at most 1", succeeded)
+	}
+
+	err := service.ValidateSession(context.Background(), sessionID)
+	if !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("ValidateSession after the race = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+}
+
+// TestRotateLosesRaceToEarlierRotation lines up the race deterministically:
+// both requests loaded the token before either of them rotated it.
+func TestRotateLosesRaceToEarlierRotation(t *testing.T) {
+	service, db := newTestService(t)
+	newTestUser(t, db, "budi", "Password1")
+	ctx := context.Background()
+
+	first, _ := login(t, service, "budi", "Password1")
+	sessions := service.repository.GetSession()
+
+	winner, err := sessions.FindRefreshToken(ctx, util.HashToken(first.RefreshToken))
+	if err != nil {
+		t.Fatalf("FindRefreshToken: %v", err)
+	}
+	loser, err := sessions.FindRefreshToken(ctx, util.HashToken(first.RefreshToken))
+	if err != nil {
+		t.Fatalf("FindRefreshToken: %v", err)
+	}
+
+	err = sessions.Rotate(ctx, winner, util.HashToken("winner"), refreshTokenExpiry())
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	err = sessions.Rotate(ctx, loser, util.HashToken("loser"), refreshTokenExpiry())
+	if !errors.Is(err, errUser.ErrRefreshTokenReused) {
+		t.Errorf("second Rotate = %v, want %v", err, errUser.ErrRefreshTokenReused)
+	}
+
+	if _, err = sessions.FindRefreshToken(ctx, util.HashToken("loser")); !errors.Is(err, errUser.ErrRefreshTokenInvalid) {
+		t.Errorf("token of the losing rotation was stored: %v", err)
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, service *UserService, db *gorm.DB, user *models.User, sessionID string)
+		token   func(token string) string
+		wantErr error
+	}{
+		{
+			name:    "unknown token",
+			token:   func(token string) string { return token + "x" },
+			wantErr: errUser.ErrRefreshTokenInvalid,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, service *UserService, db *gorm.DB, user *models.User, sessionID string) {
+				err := db.Model(&models.RefreshToken{}).
+					Where("used_at IS NULL").
+					Update("expires_at", time.Now().Add(-time.Second)).
+					Error
+				if err != nil {
+					t.Fatalf("expire token: %v", err)
+				}
+			},
+			wantErr: errUser.ErrRefreshTokenInvalid,
+		},
+		{
+			name: "logged out",
+			prepare: func(t *testing.T, service *UserService, db *gorm.DB, user *models.User, sessionID string) {
+				ctx := context.WithValue(context.Background(), constants.SessionID, sessionID)
+				if err := service.Logout(ctx); err != nil {
+					t.Fatalf("Logout: %v", err)
+				}
+			},
+			wantErr: errUser.ErrSessionRevoked,
+		},
+		{
+			name: "deactivated user",
+			prepare: func(t *testing.T, service *UserService, db *gorm.DB, user *models.User, sessionID string) {
+				if err := db.Model(user).Update("is_active", false).Error; err != nil {
+					t.Fatalf("deactivate user: %v", err)
+				}
+			},
+			wantErr: errUser.ErrUserInactive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			user := newTestUser(t, db, "budi", "Password1")
+			response, sessionID := login(t, service, "budi", "Password1")
+
+			if tt.prepare != nil {
+				tt.prepare(t, service, db, user, sessionID)
+			}
+
+			token := response.RefreshToken
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+
+			_, err := refresh(service, token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Refresh = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
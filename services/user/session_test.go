@@ -0,0 +1,250 @@
+package services
+
+import (
+	"backend/common/util"
+	errConstant "backend/constants/error"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const unknownSession = "6f1d6c0e-3c55-4a8b-9a3f-0d5a5f1c2b3d"
+
+func TestGetSessions(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	_, current := login(t, service, "budi", "Password1")
+
+	_, err := service.Login(context.Background(), &dto.LoginRequest{
+		Username:   "budi",
+		Password:   "Password1",
+		DeviceName: "Laptop kantor",
+		ClientIP:   "10.0.0.2",
+		UserAgent:  "Mozilla/5.0",
+	})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, revoked := login(t, service, "budi", "Password1")
+	if err = service.RevokeSession(userContext(user, current), revoked); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+
+	sessions, err := service.GetSessions(userContext(user, current))
+	if err != nil {
+		t.Fatalf("GetSessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("GetSessions listed %d sessions, want the 2 open ones", len(sessions))
+	}
+
+	devices := map[string]dto.SessionResponse{}
+	for _, session := range sessions {
+		devices[session.DeviceName] = session
+		if session.Current != (session.UUID.String() == current) {
+			t.Errorf("session %s current = %v", session.UUID, session.Current)
+		}
+	}
+	if laptop := devices["Laptop kantor"]; laptop.IPAddress != "10.0.0.2" || laptop.UserAgent != "Mozilla/5.0" || laptop.Terminal {
+		t.Errorf("laptop session = %+v", laptop)
+	}
+	if till := devices["Kasir 1"]; !till.Current || till.IPAddress != "10.0.0.1" {
+		t.Errorf("current session = %+v", till)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		session func(own, other, stranger string) string
+		wantErr error
+	}{
+		{name: "other device", session: func(own, other, stranger string) string { return other }},
+		{name: "current device", session: func(own, other, stranger string) string { return own }},
+		{name: "session of another user", session: func(own, other, stranger string) string { return stranger }, wantErr: errUser.ErrSessionNotFound},
+		{name: "unknown session", session: func(own, other, stranger string) string { return unknownSession }, wantErr: errUser.ErrSessionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			user := newTestUser(t, db, "budi", "Password1")
+			newTestUser(t, db, "sari", "Password1")
+			_, own := login(t, service, "budi", "Password1")
+			_, other := login(t, service, "budi", "Password1")
+			_, stranger := login(t, service, "sari", "Password1")
+
+			target := tt.session(own, other, stranger)
+			err := service.RevokeSession(userContext(user, own), target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RevokeSession = %v, want %v", err, tt.wantErr)
+			}
+
+			ctx := context.Background()
+			for _, session := range []string{own, other, stranger} {
+				err = service.ValidateSession(ctx, session)
+				if revoked := errors.Is(err, errUser.ErrSessionRevoked); revoked != (tt.wantErr == nil && session == target) {
+					t.Errorf("ValidateSession(%s) = %v", session, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRevokeUserSession(t *testing.T) {
+	service, db := newTestService(t)
+	budi := newTestUser(t, db, "budi", "Password1")
+	sari := newTestUser(t, db, "sari", "Password1")
+	_, budiSession := login(t, service, "budi", "Password1")
+	_, sariSession := login(t, service, "sari", "Password1")
+	ctx := context.Background()
+
+	err := service.RevokeUserSession(ctx, budi.UUID.String(), sariSession)
+	if !errors.Is(err, errUser.ErrSessionNotFound) {
+		t.Errorf("RevokeUserSession with a session of another user = %v, want %v", err, errUser.ErrSessionNotFound)
+	}
+
+	if err = service.RevokeUserSession(ctx, budi.UUID.String(), budiSession); err != nil {
+		t.Fatalf("RevokeUserSession: %v", err)
+	}
+
+	sessions, err := service.GetUserSessions(ctx, budi.UUID.String())
+	if err != nil {
+		t.Fatalf("GetUserSessions: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("GetUserSessions after revoking = %+v, want none", sessions)
+	}
+
+	sessions, err = service.GetUserSessions(ctx, sari.UUID.String())
+	if err != nil {
+		t.Fatalf("GetUserSessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].UUID.String() != sariSession || sessions[0].Current {
+		t.Errorf("GetUserSessions of sari = %+v", sessions)
+	}
+}
+
+// loginHistoryItems returns the history entries of a page without their
+// user and time, which differ per run.
+func loginHistoryItems(t *testing.T, data interface{}) []dto.LoginHistoryResponse {
+	t.Helper()
+
+	items, ok := data.([]dto.LoginHistoryResponse)
+	if !ok {
+		t.Fatalf("login history data is %T", data)
+	}
+
+	for i := range items {
+		if items[i].CreatedAt.IsZero() {
+			t.Errorf("entry %d has no time", i)
+		}
+		items[i].UserUUID, items[i].CreatedAt = nil, time.Time{}
+	}
+
+	return items
+}
+
+func TestLoginHistory(t *testing.T) {
+	service, db := newTestService(t)
+	budi := newTestUser(t, db, "budi", "Password1")
+	newTestUser(t, db, "sari", "Password1")
+
+	login(t, service, "budi", "Password1")
+	login(t, service, "sari", "Password1")
+	_ = loginFrom(service, "budi", "wrong password", "10.0.0.9")
+	_ = loginFrom(service, "nobody", "Password1", "10.0.0.9")
+
+	budiLogin := dto.LoginHistoryResponse{Username: "budi", Method: "password", Success: true, DeviceName: "Kasir 1", IPAddress: "10.0.0.1"}
+	sariLogin := dto.LoginHistoryResponse{Username: "sari", Method: "password", Success: true, DeviceName: "Kasir 1", IPAddress: "10.0.0.1"}
+	budiFailure := dto.LoginHistoryResponse{Username: "budi", Method: "password", Reason: errUser.ErrInvalidCredentials.Error(), IPAddress: "10.0.0.9"}
+	unknownFailure := dto.LoginHistoryResponse{Username: "nobody", Method: "password", Reason: errUser.ErrInvalidCredentials.Error(), IPAddress: "10.0.0.9"}
+
+	// Filtering by another username does not reach that user's history.
+	sari := "sari"
+	own, err := service.GetLoginHistory(userContext(budi, ""), &dto.LoginHistoryRequestParam{Page: 1, Limit: 10, LoginHistoryFilterParam: dto.LoginHistoryFilterParam{Username: &sari}})
+	if err != nil {
+		t.Fatalf("GetLoginHistory: %v", err)
+	}
+	if got, want := loginHistoryItems(t, own.Data), []dto.LoginHistoryResponse{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("own history filtered by another username = %+v, want %+v", got, want)
+	}
+
+	own, err = service.GetLoginHistory(userContext(budi, ""), &dto.LoginHistoryRequestParam{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetLoginHistory: %v", err)
+	}
+	if got, want := loginHistoryItems(t, own.Data), []dto.LoginHistoryResponse{budiFailure, budiLogin}; !reflect.DeepEqual(got, want) {
+		t.Errorf("own history = %+v, want %+v", got, want)
+	}
+
+	ownPage, err := service.GetLoginHistoryWithCursor(userContext(budi, ""), &dto.LoginHistoryCursorParam{CursorParam: util.CursorParam{Limit: 10}})
+	if err != nil {
+		t.Fatalf("GetLoginHistoryWithCursor: %v", err)
+	}
+	if got, want := loginHistoryItems(t, ownPage.Data), []dto.LoginHistoryResponse{budiFailure, budiLogin}; !reflect.DeepEqual(got, want) {
+		t.Errorf("own history by cursor = %+v, want %+v", got, want)
+	}
+
+	yes, no := true, false
+	text := func(value string) *string { return &value }
+	user := budi.UUID.String()
+
+	tests := []struct {
+		name  string
+		param dto.LoginHistoryFilterParam
+		want  []dto.LoginHistoryResponse
+	}{
+		{name: "everything, newest first", want: []dto.LoginHistoryResponse{unknownFailure, budiFailure, sariLogin, budiLogin}},
+		{name: "one user", param: dto.LoginHistoryFilterParam{User: &user}, want: []dto.LoginHistoryResponse{budiFailure, budiLogin}},
+		{name: "failed attempts", param: dto.LoginHistoryFilterParam{Success: &no}, want: []dto.LoginHistoryResponse{unknownFailure, budiFailure}},
+		{name: "successful logins by username", param: dto.LoginHistoryFilterParam{Success: &yes, Username: text(" sari ")}, want: []dto.LoginHistoryResponse{sariLogin}},
+		{name: "by address", param: dto.LoginHistoryFilterParam{IP: text("10.0.0.1")}, want: []dto.LoginHistoryResponse{sariLogin, budiLogin}},
+		{name: "by method", param: dto.LoginHistoryFilterParam{Method: text("pin")}, want: []dto.LoginHistoryResponse{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.GetAllLoginHistory(context.Background(), &dto.LoginHistoryRequestParam{Page: 1, Limit: 10, LoginHistoryFilterParam: tt.param})
+			if err != nil {
+				t.Fatalf("GetAllLoginHistory: %v", err)
+			}
+			if got := loginHistoryItems(t, result.Data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAllLoginHistory = %+v, want %+v", got, tt.want)
+			}
+
+			// One entry per page through the cursor gives the same list.
+			got := []dto.LoginHistoryResponse{}
+			param := &dto.LoginHistoryCursorParam{CursorParam: util.CursorParam{Limit: 1}, LoginHistoryFilterParam: tt.param}
+			for page := 0; page <= len(tt.want); page++ {
+				result, err := service.GetAllLoginHistoryWithCursor(context.Background(), param)
+				if err != nil {
+					t.Fatalf("GetAllLoginHistoryWithCursor: %v", err)
+				}
+				got = append(got, loginHistoryItems(t, result.Data)...)
+				if result.NextCursor == nil {
+					break
+				}
+				param.Cursor = *result.NextCursor
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAllLoginHistoryWithCursor = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	unknown := unknownSession
+	_, err = service.GetAllLoginHistory(context.Background(), &dto.LoginHistoryRequestParam{Page: 1, Limit: 10, LoginHistoryFilterParam: dto.LoginHistoryFilterParam{User: &unknown}})
+	if !errors.Is(err, errUser.ErrUserNotFound) {
+		t.Errorf("GetAllLoginHistory of an unknown user = %v, want %v", err, errUser.ErrUserNotFound)
+	}
+
+	_, err = service.GetAllLoginHistoryWithCursor(context.Background(), &dto.LoginHistoryCursorParam{CursorParam: util.CursorParam{Cursor: "not a cursor", Limit: 10}})
+	if !errors.Is(err, errConstant.ErrInvalidCursor) {
+		t.Errorf("GetAllLoginHistoryWithCursor with a bad cursor = %v, want %v", err, errConstant.ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"backend/common/keys"
+	"backend/domain/dto"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTokenKeys gives the service a key set in its own directory holding an
+// older RSA key and a newer Ed25519 key that signs. It returns the directory
+// and the Ed25519 and RSA key.
+func newTokenKeys(t *testing.T, service *UserService) (string, *keys.Key, *keys.Key) {
+	t.Helper()
+
+	dir := t.TempDir()
+	now := time.Now().UTC()
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+
+	rsaKID := now.Add(-time.Hour).Format("20060102T150405Z")
+	edKID := now.Format("20060102T150405Z")
+	writeTokenKey(t, dir, rsaKID, rsaPrivate)
+	writeTokenKey(t, dir, edKID, edPrivate)
+
+	set, err := keys.LoadKeySet(dir, keys.AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	service.keys = set
+
+	edKey, err := set.Find(edKID)
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	rsaKey, err := set.Find(rsaKID)
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+
+	return dir, edKey, rsaKey
+}
+
+func writeTokenKey(t *testing.T, dir, kid string, signer crypto.Signer) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+}
+
+// signToken signs claims for budi with method and key, naming kid in the
+// header when it is not empty.
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, expiresIn time.Duration) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, &Claims{
+		User:      &dto.UserResponse{Username: "budi"},
+		SessionID: "session",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		},
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func TestParseToken(t *testing.T) {
+	service, _ := newTestService(t)
+	_, edKey, rsaKey := newTokenKeys(t, service)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"signing key", signToken(t, jwt.SigningMethodEdDSA, edKey.Signer, edKey.ID, time.Minute)},
+		{"older rsa key", signToken(t, jwt.SigningMethodRS256, rsaKey.Signer, rsaKey.ID, time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := service.ParseToken(tt.token)
+			if err != nil {
+				t.Fatalf("ParseToken: %v", err)
+			}
+			if claims.User == nil || claims.User.Username != "budi" || claims.SessionID != "session" {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestParseTokenRejects(t *testing.T) {
+	service, _ := newTestService(t)
+	_, edKey, rsaKey := newTokenKeys(t, service)
+
+	_, stranger, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	rsaPublic, err := x509.MarshalPKIXPublicKey(rsaKey.Signer.Public())
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	rsaPublicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublic})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", signToken(t, jwt.SigningMethodEdDSA, edKey.Signer, edKey.ID, -time.Minute)},
+		{"no kid", signToken(t, jwt.SigningMethodEdDSA, edKey.Signer, "", time.Minute)},
+		{"unknown kid", signToken(t, jwt.SigningMethodEdDSA, edKey.Signer, "20000101T000000Z", time.Minute)},
+		{"key outside the set", signToken(t, jwt.SigningMethodEdDSA, stranger, edKey.ID, time.Minute)},
+		// The kid picks the key and the key fixes the algorithm, a token can
+		// not claim another one.
+		{"kid of the rsa key with eddsa", signToken(t, jwt.SigningMethodEdDSA, edKey.Signer, rsaKey.ID, time.Minute)},
+		{"kid of the eddsa key with rs256", signToken(t, jwt.SigningMethodRS256, rsaKey.Signer, edKey.ID, time.Minute)},
+		{"rs512 with the rsa key", signToken(t, jwt.SigningMethodRS512, rsaKey.Signer, rsaKey.ID, time.Minute)},
+		// The public key is public, an HMAC keyed with it proves nothing.
+		{"hs256 keyed with the public key", signToken(t, jwt.SigningMethodHS256, rsaPublicPEM, rsaKey.ID, time.Minute)},
+		{"none", signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, edKey.ID, time.Minute)},
+		{"garbage", "not.a.token"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if claims, err := service.ParseToken(tt.token); err == nil {
+				t.Errorf("ParseToken accepted the token: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestParseTokenAfterRotation(t *testing.T) {
+	service, db := newTestService(t)
+	newTestUser(t, db, "budi", "Password1")
+	dir, edKey, _ := newTokenKeys(t, service)
+
+	response, _ := login(t, service, "budi", "Password1")
+	token, _, err := jwt.NewParser().ParseUnverified(response.Token, &Claims{})
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	if token.Header["kid"] != edKey.ID || token.Method.Alg() != keys.AlgorithmEdDSA {
+		t.Errorf("token signed with kid %v and %s, want the signing key %s", token.Header["kid"], token.Method.Alg(), edKey.ID)
+	}
+
+	// A newer key takes over signing, tokens of the old one stay valid.
+	_, next, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	writeTokenKey(t, dir, time.Now().UTC().Add(time.Hour).Format("20060102T150405Z"), next)
+	if err = service.keys.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if _, err = service.ParseToken(response.Token); err != nil {
+		t.Errorf("token of the previous key after rotation: %v", err)
+	}
+
+	jwks := service.GetJwks()
+	if len(jwks.Keys) != 3 {
+		t.Errorf("JWKS holds %d keys, want 3", len(jwks.Keys))
+	}
+
+	// Once the key is gone its tokens are refused.
+	if err = os.Remove(filepath.Join(dir, edKey.ID+".pem")); err != nil {
+		t.Fatalf("remove key: %v", err)
+	}
+	if err = service.keys.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if _, err = service.ParseToken(response.Token); err == nil {
+		t.Error("ParseToken accepted a token of a removed key")
+	}
+}
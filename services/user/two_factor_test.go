@@ -0,0 +1,285 @@
+package services
+
+import (
+	"backend/common/util"
+	"backend/config"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// totpCode returns the code of secret offset time steps from now.
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := util.TotpCode(secret, time.Now().Unix()/30+offset)
+	if err != nil {
+		t.Fatalf("TotpCode: %v", err)
+	}
+	return code
+}
+
+// enableTwoFactor sets up two-factor authentication for the user with the
+// code of the current time step and returns the secret and recovery codes.
+func enableTwoFactor(t *testing.T, service *UserService, ctx context.Context, password string) (string, []string) {
+	t.Helper()
+
+	setup, err := service.SetupTwoFactor(ctx, &dto.TwoFactorSetupRequest{Password: password})
+	if err != nil {
+		t.Fatalf("SetupTwoFactor: %v", err)
+	}
+
+	recovery, err := service.EnableTwoFactor(ctx, &dto.TwoFactorCodeRequest{Code: totpCode(t, setup.Secret, 0)})
+	if err != nil {
+		t.Fatalf("EnableTwoFactor: %v", err)
+	}
+
+	return setup.Secret, recovery.RecoveryCodes
+}
+
+// challenge logs in with the password and returns the challenge token.
+func challenge(t *testing.T, service *UserService, username, password string) string {
+	t.Helper()
+
+	response, err := service.Login(context.Background(), &dto.LoginRequest{Username: username, Password: password})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if response.Challenge == nil || response.Token != "" {
+		t.Fatalf("Login of a user with two-factor authentication did not answer with only a challenge: %+v", response)
+	}
+
+	return response.Challenge.ChallengeToken
+}
+
+func verify(service *UserService, challengeToken, code string) (*dto.LoginResponse, error) {
+	return service.VerifyTwoFactor(context.Background(), &dto.TwoFactorVerifyRequest{
+		ChallengeToken: challengeToken,
+		Code:           code,
+	})
+}
+
+func TestEnableTwoFactor(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+
+	current, currentID := login(t, service, "budi", "Password1")
+	other, otherID := login(t, service, "budi", "Password1")
+	ctx := userContext(user, currentID)
+
+	_, codes := enableTwoFactor(t, service, ctx, "Password1")
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("%d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	status, err := service.GetTwoFactor(ctx)
+	if err != nil {
+		t.Fatalf("GetTwoFactor: %v", err)
+	}
+	if !status.Enabled || status.RecoveryCodesLeft != recoveryCodeCount {
+		t.Errorf("status = %+v, want enabled with %d recovery codes", status, recoveryCodeCount)
+	}
+
+	// The session that enabled it stays, the others only passed the password.
+	if err = service.ValidateSession(context.Background(), currentID); err != nil {
+		t.Errorf("ValidateSession of the enabling session: %v", err)
+	}
+	if _, err = refresh(service, current.RefreshToken); err != nil {
+		t.Errorf("Refresh of the enabling session: %v", err)
+	}
+	if err = service.ValidateSession(context.Background(), otherID); !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("ValidateSession of another session = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+	if _, err = refresh(service, other.RefreshToken); !errors.Is(err, errUser.ErrSessionRevoked) {
+		t.Errorf("Refresh of another session = %v, want %v", err, errUser.ErrSessionRevoked)
+	}
+
+	_, err = service.SetupTwoFactor(ctx, &dto.TwoFactorSetupRequest{Password: "Password1"})
+	if !errors.Is(err, errUser.ErrTwoFactorAlreadyEnabled) {
+		t.Errorf("SetupTwoFactor when enabled = %v, want %v", err, errUser.ErrTwoFactorAlreadyEnabled)
+	}
+}
+
+func TestEnableTwoFactorRejectsWrongCodes(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	_, sessionID := login(t, service, "budi", "Password1")
+	ctx := userContext(user, sessionID)
+
+	_, err := service.EnableTwoFactor(ctx, &dto.TwoFactorCodeRequest{Code: "123456"})
+	if !errors.Is(err, errUser.ErrTwoFactorNotSetUp) {
+		t.Errorf("EnableTwoFactor before setup = %v, want %v", err, errUser.ErrTwoFactorNotSetUp)
+	}
+
+	_, err = service.SetupTwoFactor(ctx, &dto.TwoFactorSetupRequest{Password: "wrong password"})
+	if !errors.Is(err, errUser.ErrPasswordIncorrect) {
+		t.Errorf("SetupTwoFactor with a wrong password = %v, want %v", err, errUser.ErrPasswordIncorrect)
+	}
+
+	setup, err := service.SetupTwoFactor(ctx, &dto.TwoFactorSetupRequest{Password: "Password1"})
+	if err != nil {
+		t.Fatalf("SetupTwoFactor: %v", err)
+	}
+
+	for i := 0; i < config.Config.LoginMaxAttempts; i++ {
+		_, err = service.EnableTwoFactor(ctx, &dto.TwoFactorCodeRequest{Code: totpCode(t, setup.Secret, 5)})
+		if !errors.Is(err, errUser.ErrTwoFactorCodeIncorrect) {
+			t.Fatalf("EnableTwoFactor with a wrong code = %v, want %v", err, errUser.ErrTwoFactorCodeIncorrect)
+		}
+	}
+
+	// Guessing stops at the lockout, even for the right code.
+	_, err = service.EnableTwoFactor(ctx, &dto.TwoFactorCodeRequest{Code: totpCode(t, setup.Secret, 0)})
+	if !errors.Is(err, errUser.ErrLoginLocked) {
+		t.Errorf("EnableTwoFactor after too many wrong codes = %v, want %v", err, errUser.ErrLoginLocked)
+	}
+}
+
+func TestVerifyTwoFactor(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	_, sessionID := login(t, service, "budi", "Password1")
+	secret, _ := enableTwoFactor(t, service, userContext(user, sessionID), "Password1")
+
+	var enabled models.User
+	if err := db.First(&enabled, user.ID).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+
+	// The code spent on enabling can not log in, the next one can.
+	used, err := util.TotpCode(secret, enabled.TotpLastStep)
+	if err != nil {
+		t.Fatalf("TotpCode: %v", err)
+	}
+	next, err := util.TotpCode(secret, enabled.TotpLastStep+1)
+	if err != nil {
+		t.Fatalf("TotpCode: %v", err)
+	}
+
+	token := challenge(t, service, "budi", "Password1")
+	_, err = verify(service, token, used)
+	if !errors.Is(err, errUser.ErrTwoFactorCodeIncorrect) {
+		t.Errorf("VerifyTwoFactor with a used code = %v, want %v", err, errUser.ErrTwoFactorCodeIncorrect)
+	}
+
+	response, err := verify(service, token, next)
+	if err != nil {
+		t.Fatalf("VerifyTwoFactor: %v", err)
+	}
+	if response.Token == "" || response.RefreshToken == "" {
+		t.Errorf("VerifyTwoFactor did not log in: %+v", response)
+	}
+
+	// The challenge is used up with the login.
+	_, err = verify(service, token, next)
+	if !errors.Is(err, errUser.ErrChallengeInvalid) {
+		t.Errorf("VerifyTwoFactor with a used challenge = %v, want %v", err, errUser.ErrChallengeInvalid)
+	}
+
+	// A code is accepted only once, also on a new challenge.
+	_, err = verify(service, challenge(t, service, "budi", "Password1"), next)
+	if !errors.Is(err, errUser.ErrTwoFactorCodeIncorrect) {
+		t.Errorf("VerifyTwoFactor with a replayed code = %v, want %v", err, errUser.ErrTwoFactorCodeIncorrect)
+	}
+}
+
+func TestVerifyTwoFactorRecoveryCode(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	_, sessionID := login(t, service, "budi", "Password1")
+	_, codes := enableTwoFactor(t, service, userContext(user, sessionID), "Password1")
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{name: "as shown", code: codes[0]},
+		{name: "without dash", code: strings.ReplaceAll(codes[1], "-", "")},
+		{name: "upper case", code: strings.ToUpper(codes[2])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := challenge(t, service, "budi", "Password1")
+			if _, err := verify(service, token, tt.code); err != nil {
+				t.Fatalf("VerifyTwoFactor: %v", err)
+			}
+
+			_, err := verify(service, challenge(t, service, "budi", "Password1"), tt.code)
+			if !errors.Is(err, errUser.ErrTwoFactorCodeIncorrect) {
+				t.Errorf("second use of a recovery code = %v, want %v", err, errUser.ErrTwoFactorCodeIncorrect)
+			}
+		})
+	}
+
+	status, err := service.GetTwoFactor(userContext(user, sessionID))
+	if err != nil {
+		t.Fatalf("GetTwoFactor: %v", err)
+	}
+	if want := int64(recoveryCodeCount - len(tests)); status.RecoveryCodesLeft != want {
+		t.Errorf("%d recovery codes left, want %d", status.RecoveryCodesLeft, want)
+	}
+}
+
+func TestVerifyTwoFactorIsThrottled(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	_, sessionID := login(t, service, "budi", "Password1")
+	secret, _ := enableTwoFactor(t, service, userContext(user, sessionID), "Password1")
+
+	token := challenge(t, service, "budi", "Password1")
+	for i := 0; i < config.Config.LoginMaxAttempts; i++ {
+		_, err := verify(service, token, "000000")
+		if !errors.Is(err, errUser.ErrTwoFactorCodeIncorrect) {
+			t.Fatalf("VerifyTwoFactor with a wrong code = %v, want %v", err, errUser.ErrTwoFactorCodeIncorrect)
+		}
+	}
+
+	// A fresh challenge does not reset the count, it is kept per user.
+	_, err := verify(service, challenge(t, service, "budi", "Password1"), totpCode(t, secret, 1))
+	if !errors.Is(err, errUser.ErrLoginLocked) {
+		t.Errorf("VerifyTwoFactor after too many wrong codes = %v, want %v", err, errUser.ErrLoginLocked)
+	}
+
+	// Account changes that ask for a code share the lock.
+	_, err = service.RegenerateRecoveryCodes(userContext(user, sessionID), &dto.TwoFactorCodeRequest{Code: totpCode(t, secret, 1)})
+	if !errors.Is(err, errUser.ErrLoginLocked) {
+		t.Errorf("RegenerateRecoveryCodes while locked = %v, want %v", err, errUser.ErrLoginLocked)
+	}
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	_, sessionID := login(t, service, "budi", "Password1")
+	ctx := userContext(user, sessionID)
+	secret, _ := enableTwoFactor(t, service, ctx, "Password1")
+
+	err := service.DisableTwoFactor(ctx, &dto.TwoFactorDisableRequest{Password: "wrong password", Code: totpCode(t, secret, 1)})
+	if !errors.Is(err, errUser.ErrPasswordIncorrect) {
+		t.Errorf("DisableTwoFactor with a wrong password = %v, want %v", err, errUser.ErrPasswordIncorrect)
+	}
+
+	err = service.DisableTwoFactor(ctx, &dto.TwoFactorDisableRequest{Password: "Password1", Code: "000000"})
+	if !errors.Is(err, errUser.ErrTwoFactorCodeIncorrect) {
+		t.Errorf("DisableTwoFactor with a wrong code = %v, want %v", err, errUser.ErrTwoFactorCodeIncorrect)
+	}
+
+	err = service.DisableTwoFactor(ctx, &dto.TwoFactorDisableRequest{Password: "Password1", Code: totpCode(t, secret, 1)})
+	if err != nil {
+		t.Fatalf("DisableTwoFactor: %v", err)
+	}
+
+	response, err := service.Login(context.Background(), &dto.LoginRequest{Username: "budi", Password: "Password1"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if response.Challenge != nil || response.Token == "" {
+		t.Errorf("Login after disabling still asks for a code: %+v", response)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"backend/constants"
+	errConstant "backend/constants/error"
+	errRole "backend/constants/error/role"
+	errUser "backend/constants/error/user"
+	"backend/domain/dto"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	stale := []uint{0}
+	unchanged := dto.UpdateRequest{Name: "budi", Username: "budi", Email: "budi@example.com", PhoneNumber: "0812345678"}
+
+	tests := []struct {
+		name     string
+		request  dto.UpdateRequest
+		versions []uint
+		wantErr  error
+	}{
+		{
+			name:    "updated",
+			request: dto.UpdateRequest{Name: "Budi Santoso", Username: "budi.s", Email: "budi.s@example.com", PhoneNumber: "0899999999"},
+		},
+		{
+			name:    "username taken",
+			request: dto.UpdateRequest{Name: "budi", Username: "sari", Email: "budi@example.com", PhoneNumber: "0812345678"},
+			wantErr: errUser.ErrUsernameExist,
+		},
+		{
+			name:    "email taken",
+			request: dto.UpdateRequest{Name: "budi", Username: "budi", Email: "sari@example.com", PhoneNumber: "0812345678"},
+			wantErr: errUser.ErrEmailExist,
+		},
+		{
+			name:     "stale version",
+			request:  dto.UpdateRequest{Name: "Budi Santoso", Username: "budi", Email: "budi@example.com", PhoneNumber: "0812345678"},
+			versions: stale,
+			wantErr:  errConstant.ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			ctx := context.Background()
+			user := newTestUser(t, db, "budi", "Password1")
+			newTestUser(t, db, "sari", "Password1")
+
+			_, err := service.Update(ctx, &tt.request, user.UUID.String(), tt.versions)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update = %v, want %v", err, tt.wantErr)
+			}
+
+			want := tt.request
+			if err != nil {
+				want = unchanged
+			}
+
+			got, err := service.GetUserByUUID(ctx, user.UUID.String())
+			if err != nil {
+				t.Fatalf("GetUserByUUID: %v", err)
+			}
+			if got.Name != want.Name || got.Username != want.Username || got.Email != want.Email || got.PhoneNumber != want.PhoneNumber {
+				t.Errorf("user after update = %+v, want %+v", got, want)
+			}
+
+			// The password is never changed by an update.
+			login(t, service, got.Username, "Password1")
+		})
+	}
+}
+
+func TestUpdateByAnotherUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      uint
+		target     uint
+		email      string
+		wantErr    error
+		wantNotice bool
+	}{
+		{name: "profile of the same role", actor: constants.Admin, target: constants.Admin, email: "sari@example.com"},
+		{name: "profile of a higher role", actor: constants.Admin, target: constants.Owner, email: "sari@example.com", wantErr: errUser.ErrRoleOutranksYours},
+		{name: "email without user:manage", actor: constants.Admin, target: constants.Admin, email: "sari.d@example.com", wantErr: errRole.ErrPermissionDenied},
+		{name: "email by a user manager", actor: constants.Owner, target: constants.Admin, email: "sari.d@example.com", wantNotice: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestService(t)
+			actor := newTestUser(t, db, "budi", "Password1")
+			target := newTestUser(t, db, "sari", "Password1")
+			db.Model(actor).Update("role_id", tt.actor)
+			db.Model(target).Update("role_id", tt.target)
+			_, sessionID := login(t, service, "budi", "Password1")
+			ctx := userContext(actor, sessionID)
+
+			request := dto.UpdateRequest{Name: "Sari Dewi", Username: "sari", Email: tt.email, PhoneNumber: "0812345678"}
+			_, err := service.Update(ctx, &request, target.UUID.String(), nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update = %v, want %v", err, tt.wantErr)
+			}
+
+			sent := service.mailer.(*captureMailer).sent()
+			if notified := len(sent) == 1 && sent[0].To == "sari@example.com"; notified != tt.wantNotice || len(sent) > 1 {
+				t.Errorf("sent %+v, want a notice to the old address %v", sent, tt.wantNotice)
+			}
+		})
+	}
+}
+
+func TestUpdateOwnEmail(t *testing.T) {
+	service, db := newTestService(t)
+	user := newTestUser(t, db, "budi", "Password1")
+	db.Model(user).Update("role_id", constants.Admin)
+	_, sessionID := login(t, service, "budi", "Password1")
+
+	// The account holder needs no user:manage for their own address.
+	request := dto.UpdateRequest{Name: "budi", Username: "budi", Email: "budi.s@example.com", PhoneNumber: "0812345678"}
+	if _, err := service.Update(userContext(user, sessionID), &request, user.UUID.String(), nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	sent := service.mailer.(*captureMailer).sent()
+	if len(sent) != 1 || sent[0].To != "budi@example.com" || !strings.Contains(sent[0].Body, "budi.s@example.com") {
+		t.Errorf("sent %+v, want a notice of the new address to the old one", sent)
+	}
+}
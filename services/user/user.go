@@ -1,82 +1,502 @@
 package services
 
 import (
+	errValidation "backend/common/error"
+	"backend/common/keys"
+	"backend/common/mailer"
+	"backend/common/util"
 	"backend/config"
 	"backend/constants"
+	errConstant "backend/constants/error"
+	errRole "backend/constants/error/role"
+	errTerminal "backend/constants/error/terminal"
 	errUser "backend/constants/error/user"
 	"backend/domain/dto"
 	"backend/domain/models"
 	"backend/repositories"
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
+	"math/big"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type UserService struct {
 	repository repositories.IRepositoryRegistry
+	keys       keys.IKeySet
+	mailer     mailer.IMailer
 }
 
 type IUserService interface {
 	Login(context.Context, *dto.LoginRequest) (*dto.LoginResponse, error)
+	Refresh(context.Context, *dto.RefreshTokenRequest) (*dto.LoginResponse, error)
+	Logout(context.Context) error
+	ValidateSession(context.Context, string) error
 	Register(context.Context, *dto.RegisterRequest) (*dto.RegisterResponse, error)
-	Update(context.Context, *dto.UpdateRequest, string) (*dto.UserResponse, error)
+	Update(context.Context, *dto.UpdateRequest, string, []uint) (*dto.UserResponse, error)
+	Patch(context.Context, *dto.PatchUserRequest, string, []uint) (*dto.UserResponse, error)
 	GetUserLogin(context.Context) (*dto.UserResponse, error)
 	GetUserByUUID(context.Context, string) (*dto.UserResponse, error)
+	GetUserByUsername(context.Context, string) (*dto.UserResponse, error)
+	GetAllWithPagination(context.Context, *dto.UserRequestParam) (*util.PaginationResult, error)
+	SetActive(context.Context, string, bool) (*dto.UserResponse, error)
+	ResetPassword(context.Context, string) (*dto.ResetPasswordResponse, error)
+	AssignRole(context.Context, string, *dto.AssignRoleRequest) (*dto.UserResponse, error)
+	Delete(context.Context, string) error
+	ChangePassword(context.Context, *dto.ChangePasswordRequest) error
+	ForgotPassword(context.Context, *dto.ForgotPasswordRequest) error
+	ResetForgottenPassword(context.Context, *dto.PasswordResetRequest) error
+	Unlock(context.Context, string) (*dto.UserResponse, error)
+	EnrollPin(context.Context, *dto.EnrollPinRequest) error
+	RemovePin(context.Context) error
+	PinLogin(context.Context, *dto.PinLoginRequest) (*dto.LoginResponse, error)
+	VerifyTwoFactor(context.Context, *dto.TwoFactorVerifyRequest) (*dto.LoginResponse, error)
+	GetTwoFactor(context.Context) (*dto.TwoFactorStatusResponse, error)
+	SetupTwoFactor(context.Context, *dto.TwoFactorSetupRequest) (*dto.TwoFactorSetupResponse, error)
+	EnableTwoFactor(context.Context, *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error)
+	DisableTwoFactor(context.Context, *dto.TwoFactorDisableRequest) error
+	RegenerateRecoveryCodes(context.Context, *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error)
+	GetSessions(context.Context) ([]dto.SessionResponse, error)
+	RevokeSession(context.Context, string) error
+	GetLoginHistory(context.Context, *dto.LoginHistoryRequestParam) (*util.PaginationResult, error)
+	GetUserSessions(context.Context, string) ([]dto.SessionResponse, error)
+	RevokeUserSession(context.Context, string, string) error
+	GetAllLoginHistory(context.Context, *dto.LoginHistoryRequestParam) (*util.PaginationResult, error)
+	GetLoginHistoryWithCursor(context.Context, *dto.LoginHistoryCursorParam) (*util.CursorResult, error)
+	GetAllLoginHistoryWithCursor(context.Context, *dto.LoginHistoryCursorParam) (*util.CursorResult, error)
+	ParseToken(string) (*Claims, error)
+	GetJwks() keys.JWKS
 }
 
+const (
+	loginScopeUsername  = "username"
+	loginScopePin       = "pin"
+	loginScopeTerminal  = "terminal"
+	loginScopeTwoFactor = "2fa"
+	loginScopeIP        = "ip"
+	maxLoginDelay       = 30 * time.Second
+
+	loginMethodPassword  = "password"
+	loginMethodPin       = "pin"
+	loginMethodTwoFactor = "2fa"
+
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	passwordResetInterval = time.Minute
+)
+
+// dummyPasswordHash is compared against when the username does not exist.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	return hash
+})
+
 type Claims struct {
-	User *dto.UserResponse
+	User      *dto.UserResponse
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewUserService(repository repositories.IRepositoryRegistry) IUserService {
-	return &UserService{repository: repository}
+func NewUserService(repository repositories.IRepositoryRegistry, keys keys.IKeySet, mailer mailer.IMailer) IUserService {
+	return &UserService{repository: repository, keys: keys, mailer: mailer}
 }
 
+// Login does not tell an unknown username from a wrong password, both count
+// as a failed attempt against the username and the client IP. Users with
+// two-factor authentication only get a challenge for VerifyTwoFactor.
 func (u *UserService) Login(ctx context.Context, request *dto.LoginRequest) (*dto.LoginResponse, error) {
+	attempt := loginAttempt{
+		method:   loginMethodPassword,
+		username: request.Username,
+		device:   sessionDevice(request.DeviceName, request.ClientIP, request.UserAgent),
+	}
+	subjects := loginSubjects(loginSubject{
+		scope:       loginScopeUsername,
+		subject:     request.Username,
+		maxAttempts: config.Config.LoginMaxAttempts,
+	}, request.ClientIP)
+
+	err := u.checkLoginThrottle(ctx, subjects)
+	if err != nil {
+		return nil, u.logLogin(ctx, attempt, nil, err)
+	}
+
 	user, err := u.repository.GetUser().FindByUsername(ctx, request.Username)
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, errUser.ErrUserNotFound) {
+			return nil, err
+		}
+
+		// Spend the same bcrypt time as for a known user.
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(request.Password))
+		return nil, u.logLogin(ctx, attempt, nil, u.recordLoginFailure(ctx, subjects, errUser.ErrInvalidCredentials))
 	}
 
+	attempt.user = user
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password))
+	if err != nil {
+		return nil, u.logLogin(ctx, attempt, nil, u.recordLoginFailure(ctx, subjects, errUser.ErrInvalidCredentials))
+	}
+
+	err = u.repository.GetLoginThrottle().Reset(ctx, loginScopeUsername, request.Username)
 	if err != nil {
 		return nil, err
 	}
 
-	expirationTime := time.Now().Add(time.Duration(config.Config.JwtExpirationTime) * time.Minute).Unix()
-	data := &dto.UserResponse{
-		UUID:        user.UUID,
-		Name:        user.Name,
-		Username:    user.Username,
-		PhoneNumber: user.PhoneNumber,
-		Email:       user.Email,
-		Role:        strings.ToLower(user.Role.Code),
+	// The history tells admins the account is deactivated, the client gets
+	// the same answer as for a wrong password so it can not find out.
+	if !user.IsActive {
+		_ = u.logLogin(ctx, attempt, nil, errUser.ErrUserInactive)
+		return nil, errUser.ErrInvalidCredentials
+	}
+
+	if user.TotpEnabledAt != nil {
+		return u.loginChallenge(ctx, user)
+	}
+
+	refreshToken, refreshTokenHash, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := u.repository.GetSession().Create(ctx, user.ID, nil, attempt.device, refreshTokenHash, refreshTokenExpiry())
+	if err != nil {
+		return nil, err
+	}
+
+	_ = u.logLogin(ctx, attempt, session, nil)
+	return u.loginResponse(user, session.UUID.String(), refreshToken)
+}
+
+type loginAttempt struct {
+	method   string
+	username string
+	user     *models.User
+	device   dto.SessionDevice
+}
+
+// logLogin writes the attempt to the login history and hands loginErr back.
+// Failing to write it is only logged, it does not decide the login.
+func (u *UserService) logLogin(ctx context.Context, attempt loginAttempt, session *models.Session, loginErr error) error {
+	history := &models.LoginHistory{
+		Username:   truncate(attempt.username, 50),
+		Method:     attempt.method,
+		Success:    loginErr == nil,
+		DeviceName: attempt.device.DeviceName,
+		IPAddress:  attempt.device.IPAddress,
+		UserAgent:  attempt.device.UserAgent,
+	}
+
+	if attempt.user != nil {
+		history.UserID = &attempt.user.ID
+		history.Username = attempt.user.Username
+	}
+
+	if session != nil {
+		history.SessionID = &session.ID
+	}
+
+	if loginErr != nil {
+		history.Reason = truncate(loginErr.Error(), 100)
+	}
+
+	err := u.repository.GetLoginHistory().Create(ctx, history)
+	if err != nil {
+		logrus.Errorf("failed to record login of %s: %v", history.Username, err)
+	}
+
+	return loginErr
+}
+
+// sessionDevice cuts the client supplied values down to what the session
+// columns hold.
+func sessionDevice(deviceName, ipAddress, userAgent string) dto.SessionDevice {
+	return dto.SessionDevice{
+		DeviceName: truncate(strings.TrimSpace(deviceName), 100),
+		IPAddress:  truncate(ipAddress, 45),
+		UserAgent:  truncate(userAgent, 255),
+	}
+}
+
+func truncate(value string, length int) string {
+	if utf8.RuneCountInString(value) <= length {
+		return value
+	}
+
+	return string([]rune(value)[:length])
+}
+
+// checkLoginThrottle refuses the attempt while the username or the client IP
+// is still waiting out a delay or lockout from earlier failures.
+func (u *UserService) checkLoginThrottle(ctx context.Context, subjects []loginSubject) error {
+	var retryAfter time.Duration
+	for _, subject := range subjects {
+		throttle, err := u.repository.GetLoginThrottle().Find(ctx, subject.scope, subject.subject)
+		if err != nil {
+			return err
+		}
+
+		if throttle.LockedUntil != nil {
+			retryAfter = max(retryAfter, time.Until(*throttle.LockedUntil))
+		}
+	}
+
+	if retryAfter > 0 {
+		return &errUser.LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// recordLoginFailure counts the failure and locks each subject for a delay
+// that doubles with every failure, or for the whole lockout once it reached
+// its maximum attempts. It returns loginErr for the caller to hand back.
+func (u *UserService) recordLoginFailure(ctx context.Context, subjects []loginSubject, loginErr error) error {
+	lockout := time.Duration(config.Config.LoginLockoutMinutes) * time.Minute
+	for _, subject := range subjects {
+		throttle, err := u.repository.GetLoginThrottle().RecordFailure(ctx, subject.scope, subject.subject, lockout)
+		if err != nil {
+			return err
+		}
+
+		delay := loginDelay(throttle.Failures, subject.maxAttempts, lockout)
+		if delay > 0 {
+			err = u.repository.GetLoginThrottle().Lock(ctx, throttle.ID, time.Now().Add(delay))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return loginErr
+}
+
+type loginSubject struct {
+	scope       string
+	subject     string
+	maxAttempts int
+}
+
+// loginSubjects throttles a login by its account and by the client IP.
+func loginSubjects(account loginSubject, clientIP string) []loginSubject {
+	subjects := []loginSubject{account}
+	if clientIP != "" {
+		subjects = append(subjects, loginSubject{
+			scope:       loginScopeIP,
+			subject:     clientIP,
+			maxAttempts: config.Config.LoginIPMaxAttempts,
+		})
+	}
+
+	return subjects
+}
+
+// pinSubject throttles the PIN of one user on one terminal.
+func pinSubject(userUUID, terminalUUID string) string {
+	return pinSubjectPrefix(userUUID) + terminalUUID
+}
+
+func pinSubjectPrefix(userUUID string) string {
+	return userUUID + ":"
+}
+
+func loginDelay(failures, maxAttempts int, lockout time.Duration) time.Duration {
+	if maxAttempts > 0 && failures >= maxAttempts {
+		return lockout
+	}
+
+	delay := time.Duration(config.Config.LoginDelaySeconds) * time.Second << min(failures-1, 10)
+	return min(delay, maxLoginDelay, lockout)
+}
+
+// Refresh trades a refresh token for a new access and refresh token pair.
+// A token that was already rotated means it leaked, so the whole session is
+// revoked and the user has to log in again.
+func (u *UserService) Refresh(ctx context.Context, request *dto.RefreshTokenRequest) (*dto.LoginResponse, error) {
+	token, err := u.repository.GetSession().FindRefreshToken(ctx, util.HashToken(request.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := token.Session.UUID.String()
+	if token.Session.RevokedAt != nil {
+		return nil, errUser.ErrSessionRevoked
+	}
+
+	if !token.Session.User.IsActive {
+		return nil, errUser.ErrUserInactive
+	}
+
+	if token.UsedAt != nil {
+		return nil, u.revokeReusedSession(ctx, sessionID)
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, errUser.ErrRefreshTokenInvalid
+	}
+
+	refreshToken, refreshTokenHash, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.repository.GetSession().Rotate(ctx, token, refreshTokenHash, refreshTokenExpiry())
+	if err != nil {
+		if errors.Is(err, errUser.ErrRefreshTokenReused) {
+			return nil, u.revokeReusedSession(ctx, sessionID)
+		}
+
+		return nil, err
+	}
+
+	return u.loginResponse(&token.Session.User, sessionID, refreshToken)
+}
+
+// Logout revokes the session of the access token used for the request.
+func (u *UserService) Logout(ctx context.Context) error {
+	sessionID, _ := ctx.Value(constants.SessionID).(string)
+	if sessionID == "" {
+		return errConstant.ErrUnauthorized
+	}
+
+	return u.repository.GetSession().Revoke(ctx, sessionID)
+}
+
+// ValidateSession is called for every authenticated request so a revoked
+// session or a deactivated user stops working before the access token
+// expires. ErrPasswordChangeRequired and ErrTwoFactorSetupRequired mean the
+// session is fine but the user has to finish that first.
+func (u *UserService) ValidateSession(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return errUser.ErrSessionRevoked
+	}
+
+	session, err := u.repository.GetSession().FindActive(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if !session.User.IsActive {
+		return errUser.ErrUserInactive
+	}
+
+	err = u.repository.GetSession().Touch(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+
+	if session.User.MustChangePassword {
+		return errUser.ErrPasswordChangeRequired
+	}
+
+	if session.User.Role.RequireTwoFactor && session.User.TotpEnabledAt == nil {
+		return errUser.ErrTwoFactorSetupRequired
+	}
+
+	return nil
+}
+
+func (u *UserService) revokeReusedSession(ctx context.Context, sessionID string) error {
+	logrus.Warnf("refresh token reused, revoking session %s", sessionID)
+	if err := u.repository.GetSession().Revoke(ctx, sessionID); err != nil {
+		return err
 	}
 
+	return errUser.ErrRefreshTokenReused
+}
+
+func (u *UserService) loginResponse(user *models.User, sessionID, refreshToken string) (*dto.LoginResponse, error) {
+	expirationTime := time.Now().Add(time.Duration(config.Config.JwtExpirationTime) * time.Minute).Unix()
+	data := userResponse(user)
+
 	claims := &Claims{
-		User: data,
+		User:      &data,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Unix(expirationTime, 0)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.Config.JwtSecretKey))
+	key := u.keys.SigningKey()
+	if key == nil {
+		return nil, keys.ErrKeyNotFound
+	}
+
+	token := jwt.NewWithClaims(key.SigningMethod(), claims)
+	token.Header["kid"] = key.ID
+	tokenString, err := token.SignedString(key.Signer)
 	if err != nil {
 		return nil, err
 	}
 
 	response := &dto.LoginResponse{
-		User:  *data,
-		Token: tokenString,
+		User:         data,
+		Token:        tokenString,
+		RefreshToken: refreshToken,
 	}
 
 	return response, nil
 }
 
+// ParseToken verifies an access token against the key named by its kid
+// header. The algorithm has to match the key, so a token cannot pick a
+// weaker one.
+func (u *UserService) ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := u.keys.Find(kid)
+		if err != nil {
+			return nil, errConstant.ErrInvalidToken
+		}
+
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errConstant.ErrInvalidToken
+		}
+
+		return key.Signer.Public(), nil
+	}, jwt.WithValidMethods([]string{keys.AlgorithmRS256, keys.AlgorithmEdDSA}))
+	if err != nil || !token.Valid {
+		return nil, errConstant.ErrUnauthorized
+	}
+
+	return claims, nil
+}
+
+func (u *UserService) GetJwks() keys.JWKS {
+	return u.keys.JWKS()
+}
+
+func refreshTokenExpiry() time.Time {
+	return time.Now().Add(time.Duration(config.Config.RefreshTokenExpiry) * time.Minute)
+}
+
+func userResponse(user *models.User) dto.UserResponse {
+	return dto.UserResponse{
+		UUID:               user.UUID,
+		Name:               user.Name,
+		Username:           user.Username,
+		PhoneNumber:        user.PhoneNumber,
+		Email:              user.Email,
+		Role:               strings.ToLower(user.Role.Code),
+		IsActive:           user.IsActive,
+		MustChangePassword: user.MustChangePassword,
+		TwoFactorEnabled:   user.TotpEnabledAt != nil,
+		Version:            user.Version,
+	}
+}
+
 func (u *UserService) isUsernameExist(ctx context.Context, username string) bool {
 	user, err := u.repository.GetUser().FindByUsername(ctx, username)
 	if err != nil {
@@ -103,7 +523,21 @@ func (u *UserService) isEmailExist(ctx context.Context, email string) bool {
 	return false
 }
 
+// Register creates an active user with the given role.
 func (u *UserService) Register(ctx context.Context, request *dto.RegisterRequest) (*dto.RegisterResponse, error) {
+	if request.Password != request.ConfirmPassword {
+		return nil, errUser.ErrPasswordDoesNotMatch
+	}
+
+	if err := checkPasswordPolicy(request.Password); err != nil {
+		return nil, err
+	}
+
+	role, err := u.repository.GetRole().FindByCode(ctx, request.Role)
+	if err != nil {
+		return nil, err
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
@@ -118,12 +552,12 @@ func (u *UserService) Register(ctx context.Context, request *dto.RegisterRequest
 	}
 
 	newUser := &dto.RegisterRequest{
-		Name:        request.Username,
+		Name:        request.Name,
 		Username:    request.Username,
 		Password:    string(hashedPassword),
 		Email:       request.Email,
 		PhoneNumber: request.PhoneNumber,
-		RoleID:      constants.Admin,
+		RoleID:      role.ID,
 	}
 
 	user, err := u.repository.GetUser().Register(ctx, newUser)
@@ -131,30 +565,25 @@ func (u *UserService) Register(ctx context.Context, request *dto.RegisterRequest
 		return nil, err
 	}
 
+	user.Role = *role
 	response := &dto.RegisterResponse{
-		User: dto.UserResponse{
-			UUID:        user.UUID,
-			Name:        user.Name,
-			Username:    user.Username,
-			PhoneNumber: user.PhoneNumber,
-			Email:       user.Email,
-		},
+		User: userResponse(user),
 	}
 
 	return response, nil
 }
 
-func (u *UserService) Update(ctx context.Context, request *dto.UpdateRequest, uuid string) (*dto.UserResponse, error) {
+// Update replaces the profile of a user. A changed email address is
+// announced to the previous one.
+func (u *UserService) Update(ctx context.Context, request *dto.UpdateRequest, uuid string, versions []uint) (*dto.UserResponse, error) {
 	var (
-		password                  string
 		checkUsername, checkEmail *models.User
-		hashedPassword            []byte
 		user, userResult          *models.User
 		err                       error
 		data                      dto.UserResponse
 	)
 
-	user, err = u.repository.GetUser().FindByUUID(ctx, uuid)
+	user, err = u.findEditableUser(ctx, uuid, &request.Email)
 	if err != nil {
 		return nil, err
 	}
@@ -183,72 +612,1377 @@ func (u *UserService) Update(ctx context.Context, request *dto.UpdateRequest, uu
 		}
 	}
 
-	if request.Password != nil {
-		if *request.Password != *request.ConfirmPassword {
-			return nil, errUser.ErrPasswordDoesNotMatch
-		}
-
-		hashedPassword, err = bcrypt.GenerateFromPassword([]byte(*request.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, err
-		}
-
-		password = string(hashedPassword)
-	}
-
-	userResult, err = u.repository.GetUser().Update(ctx, &dto.UpdateRequest{
+	_, err = u.repository.GetUser().Update(ctx, &dto.UpdateRequest{
 		Name:        request.Name,
 		Username:    request.Username,
-		Password:    &password,
 		Email:       request.Email,
 		PhoneNumber: request.PhoneNumber,
-	}, uuid)
+	}, uuid, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	userResult, err = u.repository.GetUser().FindByUUID(ctx, uuid)
 	if err != nil {
 		return nil, err
 	}
 
-	data = dto.UserResponse{
-		UUID:        userResult.UUID,
-		Name:        userResult.Name,
-		Username:    userResult.Username,
-		PhoneNumber: userResult.PhoneNumber,
-		Email:       userResult.Email,
+	if userResult.Email != user.Email {
+		u.notifyEmailChanged(ctx, userResult, user.Email)
 	}
 
+	data = userResponse(userResult)
+
 	return &data, nil
 }
 
-func (u *UserService) GetUserLogin(ctx context.Context) (*dto.UserResponse, error) {
-	var (
-		userLogin = ctx.Value(constants.UserLogin).(*dto.UserResponse)
-		data      dto.UserResponse
-	)
+// Patch applies a merge patch to a user. Username and email stay unique, the
+// password is only changed through ChangePassword or ResetPassword. A
+// changed email address is announced to the previous one.
+func (u *UserService) Patch(ctx context.Context, request *dto.PatchUserRequest, uuid string, versions []uint) (*dto.UserResponse, error) {
+	user, err := u.findEditableUser(ctx, uuid, request.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if request.Name != nil {
+		values["name"] = *request.Name
+	}
+
+	if request.Username != nil {
+		if *request.Username != user.Username && u.isUsernameExist(ctx, *request.Username) {
+			return nil, errUser.ErrUsernameExist
+		}
+
+		values["username"] = *request.Username
+	}
+
+	if request.Email != nil {
+		if *request.Email != user.Email && u.isEmailExist(ctx, *request.Email) {
+			return nil, errUser.ErrEmailExist
+		}
+
+		values["email"] = *request.Email
+	}
+
+	if request.PhoneNumber != nil {
+		values["phone_number"] = *request.PhoneNumber
+	}
+
+	err = u.repository.GetUser().Patch(ctx, uuid, values, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := u.repository.GetUser().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
 
-	data = dto.UserResponse{
-		UUID:        userLogin.UUID,
-		Name:        userLogin.Name,
-		Username:    userLogin.Username,
-		PhoneNumber: userLogin.PhoneNumber,
-		Email:       userLogin.Email,
-		Role:        userLogin.Role,
+	if patched.Email != user.Email {
+		u.notifyEmailChanged(ctx, patched, user.Email)
 	}
 
+	data := userResponse(patched)
+
 	return &data, nil
 }
 
+// GetUserLogin reads the user behind the access token from the database so
+// status flags changed after login are reported as they are now.
+func (u *UserService) GetUserLogin(ctx context.Context) (*dto.UserResponse, error) {
+	userLogin := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+
+	return u.GetUserByUUID(ctx, userLogin.UUID.String())
+}
+
 func (u *UserService) GetUserByUUID(ctx context.Context, uuid string) (*dto.UserResponse, error) {
 	user, err := u.repository.GetUser().FindByUUID(ctx, uuid)
 	if err != nil {
 		return nil, err
 	}
 
-	data := dto.UserResponse{
-		UUID:        user.UUID,
-		Name:        user.Name,
-		Username:    user.Username,
-		PhoneNumber: user.PhoneNumber,
-		Email:       user.Email,
+	data := userResponse(user)
+
+	return &data, nil
+}
+
+func (u *UserService) GetUserByUsername(ctx context.Context, username string) (*dto.UserResponse, error) {
+	user, err := u.repository.GetUser().FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
 	}
 
+	data := userResponse(user)
+
 	return &data, nil
 }
+
+func (u *UserService) GetAllWithPagination(ctx context.Context, param *dto.UserRequestParam) (*util.PaginationResult, error) {
+	users, total, err := u.repository.GetUser().FindAllWithPagination(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	userResult := make([]dto.UserResponse, 0, len(users))
+	for i := range users {
+		userResult = append(userResult, userResponse(&users[i]))
+	}
+
+	pagination := &util.PaginationParam{
+		Count: total,
+		Page:  param.Page,
+		Limit: param.Limit,
+		Data:  userResult,
+	}
+
+	response := util.GeneratePagination(*pagination)
+	return &response, nil
+}
+
+// SetActive activates or deactivates a user. Deactivating revokes their
+// sessions so it takes effect on the next request, not the next login.
+func (u *UserService) SetActive(ctx context.Context, uuid string, active bool) (*dto.UserResponse, error) {
+	user, err := u.findManagedUser(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.repository.GetUser().Patch(ctx, uuid, map[string]interface{}{"is_active": active}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !active {
+		if err = u.repository.GetSession().RevokeByUser(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return u.GetUserByUUID(ctx, uuid)
+}
+
+// ResetPassword replaces the password with a random temporary one and makes
+// the user change it after logging in with it.
+func (u *UserService) ResetPassword(ctx context.Context, uuid string) (*dto.ResetPasswordResponse, error) {
+	user, err := u.findManagedUser(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := generateTemporaryPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.repository.GetUser().Patch(ctx, uuid, map[string]interface{}{
+		"password":             string(hashedPassword),
+		"must_change_password": true,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.rememberPassword(ctx, u.repository, user); err != nil {
+		return nil, err
+	}
+
+	if err = u.repository.GetSession().RevokeByUser(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	data, err := u.GetUserByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ResetPasswordResponse{
+		User:              *data,
+		TemporaryPassword: password,
+	}, nil
+}
+
+// AssignRole moves a user to another role. Their sessions are revoked since
+// the role travels in the access token.
+func (u *UserService) AssignRole(ctx context.Context, uuid string, request *dto.AssignRoleRequest) (*dto.UserResponse, error) {
+	user, err := u.findManagedUser(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := u.repository.GetRole().FindByCode(ctx, request.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if ok && userLogin != nil {
+		err = u.checkRoleWithin(ctx, userLogin.Role, role.Code)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if role.ID == user.RoleID {
+		return u.GetUserByUUID(ctx, uuid)
+	}
+
+	err = u.repository.GetUser().Patch(ctx, uuid, map[string]interface{}{"role_id": role.ID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.repository.GetSession().RevokeByUser(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return u.GetUserByUUID(ctx, uuid)
+}
+
+// ChangePassword lets the logged in user replace their password, which also
+// clears a pending forced change. Every other session of the user is
+// revoked, the one the request came from stays logged in. A wrong current
+// password counts as a failed login of the username, so a stolen session
+// can not be used to guess it faster than the login form allows.
+func (u *UserService) ChangePassword(ctx context.Context, request *dto.ChangePasswordRequest) error {
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if !ok || userLogin == nil {
+		return errConstant.ErrUnauthorized
+	}
+
+	user, err := u.repository.GetUser().FindByUUID(ctx, userLogin.UUID.String())
+	if err != nil {
+		return err
+	}
+
+	subjects := []loginSubject{{
+		scope:       loginScopeUsername,
+		subject:     user.Username,
+		maxAttempts: config.Config.LoginMaxAttempts,
+	}}
+	if err = u.checkLoginThrottle(ctx, subjects); err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.CurrentPassword))
+	if err != nil {
+		return u.recordLoginFailure(ctx, subjects, errUser.ErrPasswordIncorrect)
+	}
+
+	err = u.repository.GetLoginThrottle().Reset(ctx, loginScopeUsername, user.Username)
+	if err != nil {
+		return err
+	}
+
+	if request.Password != request.ConfirmPassword {
+		return errUser.ErrPasswordDoesNotMatch
+	}
+
+	password, err := u.newPasswordHash(ctx, user, request.Password)
+	if err != nil {
+		return err
+	}
+
+	sessionID, _ := ctx.Value(constants.SessionID).(string)
+	return u.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		err := repository.GetUser().Patch(ctx, user.UUID.String(), map[string]interface{}{
+			"password":             password,
+			"must_change_password": false,
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		if err = u.rememberPassword(ctx, repository, user); err != nil {
+			return err
+		}
+
+		return repository.GetSession().RevokeOthers(ctx, user.ID, sessionID)
+	})
+}
+
+// ForgotPassword mails a password reset link to the active user with the
+// email. It succeeds whether or not there is such a user, so the answer
+// does not tell which emails are registered, and sends at most one mail a
+// minute per user. A mail that can not be sent is only logged for the same
+// reason.
+func (u *UserService) ForgotPassword(ctx context.Context, request *dto.ForgotPasswordRequest) error {
+	user, err := u.repository.GetUser().FindByEmail(ctx, strings.TrimSpace(request.Email))
+	if err != nil {
+		if errors.Is(err, errUser.ErrUserNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	sent, err := u.repository.GetPasswordReset().CountSince(ctx, user.ID, time.Now().Add(-passwordResetInterval))
+	if err != nil {
+		return err
+	}
+
+	if sent > 0 {
+		return nil
+	}
+
+	token, tokenHash, err := util.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	validFor := time.Duration(config.Config.PasswordResetMinutes) * time.Minute
+	err = u.repository.GetPasswordReset().Create(ctx, &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(validFor),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = u.mailer.Send(ctx, passwordResetMail(user, token, validFor))
+	if err != nil {
+		logrus.Errorf("Failed to send password reset mail to user %s: %v", user.UUID, err)
+	}
+
+	return nil
+}
+
+// ResetForgottenPassword sets the password with a token from ForgotPassword.
+// The token is used up, every session of the user is revoked and their
+// failed logins are cleared so they can log in right away.
+func (u *UserService) ResetForgottenPassword(ctx context.Context, request *dto.PasswordResetRequest) error {
+	if request.Password != request.ConfirmPassword {
+		return errUser.ErrPasswordDoesNotMatch
+	}
+
+	token, err := u.repository.GetPasswordReset().FindValid(ctx, util.HashToken(request.Token))
+	if err != nil {
+		return err
+	}
+
+	user := &token.User
+	if !user.IsActive {
+		return errUser.ErrPasswordResetTokenInvalid
+	}
+
+	password, err := u.newPasswordHash(ctx, user, request.Password)
+	if err != nil {
+		return err
+	}
+
+	err = u.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		if err := repository.GetPasswordReset().Use(ctx, token.ID); err != nil {
+			return err
+		}
+
+		err := repository.GetUser().Patch(ctx, user.UUID.String(), map[string]interface{}{
+			"password":             password,
+			"must_change_password": false,
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		if err = u.rememberPassword(ctx, repository, user); err != nil {
+			return err
+		}
+
+		return repository.GetSession().RevokeByUser(ctx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = u.repository.GetLoginThrottle().Reset(ctx, loginScopeUsername, user.Username)
+	if err != nil {
+		return err
+	}
+
+	return u.repository.GetLoginThrottle().ResetPrefix(ctx, loginScopePin, pinSubjectPrefix(user.UUID.String()))
+}
+
+func passwordResetMail(user *models.User, token string, validFor time.Duration) mailer.Message {
+	link := token
+	if config.Config.PasswordResetURL != "" {
+		link = config.Config.PasswordResetURL + "?token=" + url.QueryEscape(token)
+	}
+
+	return mailer.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Reset your %s password", config.Config.AppName),
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Someone asked to reset the password of your account %s. Use this to choose a new one:\n\n"+
+				"%s\n\n"+
+				"It works once within the next %d minutes. If you did not ask for it you can ignore this mail, "+
+				"your password stays the same.\n",
+			user.Name, user.Username, link, int(validFor.Minutes()),
+		),
+	}
+}
+
+// newPasswordHash checks a password the user picked against the policy and
+// their recent passwords and returns its hash.
+func (u *UserService) newPasswordHash(ctx context.Context, user *models.User, password string) (string, error) {
+	if err := checkPasswordPolicy(password); err != nil {
+		return "", err
+	}
+
+	if config.Config.PasswordHistory > 0 {
+		hashes := []string{user.Password}
+		if config.Config.PasswordHistory > 1 {
+			previous, err := u.repository.GetUser().FindPasswordHistory(ctx, user.ID, config.Config.PasswordHistory-1)
+			if err != nil {
+				return "", err
+			}
+
+			hashes = append(hashes, previous...)
+		}
+
+		for _, hash := range hashes {
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+				return "", errUser.ErrPasswordReused
+			}
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hashedPassword), nil
+}
+
+// rememberPassword keeps the password user had before a change. Together with
+// the current one it makes up the last PasswordHistory passwords.
+func (u *UserService) rememberPassword(ctx context.Context, repository repositories.IRepositoryRegistry, user *models.User) error {
+	if config.Config.PasswordHistory <= 1 {
+		return nil
+	}
+
+	return repository.GetUser().AddPasswordHistory(ctx, user.ID, user.Password, config.Config.PasswordHistory-1)
+}
+
+// checkPasswordPolicy returns the rules password breaks as validation errors.
+func checkPasswordPolicy(password string) error {
+	var validationErrors errValidation.ValidationErrors
+
+	if utf8.RuneCountInString(password) < config.Config.PasswordMinLength {
+		validationErrors = append(validationErrors, errValidation.ValidationResponse{
+			Field:   "password",
+			Message: fmt.Sprintf("password must be at least %d characters", config.Config.PasswordMinLength),
+		})
+	}
+
+	if passwordClasses(password) < config.Config.PasswordMinClasses {
+		validationErrors = append(validationErrors, errValidation.ValidationResponse{
+			Field: "password",
+			Message: fmt.Sprintf(
+				"password must mix at least %d of lower case letters, upper case letters, digits and symbols",
+				config.Config.PasswordMinClasses,
+			),
+		})
+	}
+
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+
+	return nil
+}
+
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+
+	return classes
+}
+
+// Unlock clears the failed password and PIN logins of the user so they can
+// log in again right away. Locks on client IPs and terminals are left to
+// expire.
+func (u *UserService) Unlock(ctx context.Context, uuid string) (*dto.UserResponse, error) {
+	user, err := u.repository.GetUser().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.repository.GetLoginThrottle().Reset(ctx, loginScopeUsername, user.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.repository.GetLoginThrottle().ResetPrefix(ctx, loginScopePin, pinSubjectPrefix(user.UUID.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	data := userResponse(user)
+	return &data, nil
+}
+
+// EnrollPin sets the PIN of the logged in user on the terminal the request
+// came from, after checking their password.
+func (u *UserService) EnrollPin(ctx context.Context, request *dto.EnrollPinRequest) error {
+	terminal, user, err := u.terminalUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password))
+	if err != nil {
+		return errUser.ErrPasswordIncorrect
+	}
+
+	pinHash, err := bcrypt.GenerateFromPassword([]byte(request.Pin), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return u.repository.GetTerminal().SavePin(ctx, terminal.ID, user.ID, string(pinHash))
+}
+
+func (u *UserService) RemovePin(ctx context.Context) error {
+	terminal, user, err := u.terminalUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return u.repository.GetTerminal().DeletePin(ctx, terminal.ID, user.ID)
+}
+
+// PinLogin switches the active user of a terminal. The session of whoever
+// was logged in at the terminal before is revoked. Failures are throttled
+// like password logins, per user on the terminal, per terminal and per
+// client IP, so guessing at one till neither locks the user out of the
+// others nor can spread over many users. No two-factor code is asked, the
+// terminal key already is the second factor.
+func (u *UserService) PinLogin(ctx context.Context, request *dto.PinLoginRequest) (*dto.LoginResponse, error) {
+	terminal, ok := ctx.Value(constants.Terminal).(*models.Terminal)
+	if !ok || terminal == nil {
+		return nil, errTerminal.ErrTerminalUnauthorized
+	}
+
+	attempt := loginAttempt{
+		method:   loginMethodPin,
+		username: request.UserUUID,
+		device:   sessionDevice(terminal.Name, request.ClientIP, request.UserAgent),
+	}
+	subjects := append(loginSubjects(loginSubject{
+		scope:       loginScopePin,
+		subject:     pinSubject(request.UserUUID, terminal.UUID.String()),
+		maxAttempts: config.Config.PinMaxAttempts,
+	}, request.ClientIP), loginSubject{
+		scope:       loginScopeTerminal,
+		subject:     terminal.UUID.String(),
+		maxAttempts: config.Config.TerminalMaxAttempts,
+	})
+
+	err := u.checkLoginThrottle(ctx, subjects)
+	if err != nil {
+		return nil, u.logLogin(ctx, attempt, nil, err)
+	}
+
+	pinHash := dummyPasswordHash()
+	user, err := u.repository.GetUser().FindByUUID(ctx, request.UserUUID)
+	if err != nil && !errors.Is(err, errUser.ErrUserNotFound) {
+		return nil, err
+	}
+
+	if user != nil {
+		attempt.user = user
+		pin, err := u.repository.GetTerminal().FindPin(ctx, terminal.ID, user.ID)
+		if err != nil && !errors.Is(err, errTerminal.ErrPinNotEnrolled) {
+			return nil, err
+		}
+
+		if pin != nil {
+			pinHash = []byte(pin.PinHash)
+		} else {
+			user = nil
+		}
+	}
+
+	err = bcrypt.CompareHashAndPassword(pinHash, []byte(request.Pin))
+	if err != nil || user == nil {
+		return nil, u.logLogin(ctx, attempt, nil, u.recordLoginFailure(ctx, subjects, errTerminal.ErrPinIncorrect))
+	}
+
+	err = u.repository.GetLoginThrottle().Reset(ctx, loginScopePin, pinSubject(request.UserUUID, terminal.UUID.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		_ = u.logLogin(ctx, attempt, nil, errUser.ErrUserInactive)
+		return nil, errTerminal.ErrPinIncorrect
+	}
+
+	refreshToken, refreshTokenHash, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var session *models.Session
+	err = u.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		if err := repository.GetSession().RevokeByTerminal(ctx, terminal.ID); err != nil {
+			return err
+		}
+
+		created, err := repository.GetSession().Create(ctx, user.ID, &terminal.ID, attempt.device, refreshTokenHash, refreshTokenExpiry())
+		session = created
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_ = u.logLogin(ctx, attempt, session, nil)
+	return u.loginResponse(user, session.UUID.String(), refreshToken)
+}
+
+// loginChallenge holds back the session of a user with two-factor
+// authentication until VerifyTwoFactor got the code.
+func (u *UserService) loginChallenge(ctx context.Context, user *models.User) (*dto.LoginResponse, error) {
+	token, tokenHash, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &models.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Duration(config.Config.TotpChallengeMinutes) * time.Minute),
+	}
+
+	err = u.repository.GetTwoFactor().CreateChallenge(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{
+		User: userResponse(user),
+		Challenge: &dto.TwoFactorChallengeResponse{
+			ChallengeToken: token,
+			ExpiresAt:      challenge.ExpiresAt,
+		},
+	}, nil
+}
+
+// VerifyTwoFactor finishes a login that Login answered with a challenge.
+// Wrong codes are throttled like wrong passwords, per user and client IP,
+// and the challenge stays usable until it expires.
+func (u *UserService) VerifyTwoFactor(ctx context.Context, request *dto.TwoFactorVerifyRequest) (*dto.LoginResponse, error) {
+	challenge, err := u.repository.GetTwoFactor().FindChallenge(ctx, util.HashToken(request.ChallengeToken))
+	if err != nil {
+		return nil, err
+	}
+
+	user := &challenge.User
+	attempt := loginAttempt{
+		method: loginMethodTwoFactor,
+		user:   user,
+		device: sessionDevice(request.DeviceName, request.ClientIP, request.UserAgent),
+	}
+	subjects := loginSubjects(twoFactorSubject(user), request.ClientIP)
+
+	err = u.checkLoginThrottle(ctx, subjects)
+	if err != nil {
+		return nil, u.logLogin(ctx, attempt, nil, err)
+	}
+
+	err = u.verifyTwoFactorCode(ctx, user, request.Code)
+	if err != nil {
+		if errors.Is(err, errUser.ErrTwoFactorCodeIncorrect) {
+			return nil, u.logLogin(ctx, attempt, nil, u.recordLoginFailure(ctx, subjects, err))
+		}
+
+		return nil, err
+	}
+
+	err = u.repository.GetLoginThrottle().Reset(ctx, loginScopeTwoFactor, user.UUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		_ = u.logLogin(ctx, attempt, nil, errUser.ErrUserInactive)
+		return nil, errUser.ErrChallengeInvalid
+	}
+
+	refreshToken, refreshTokenHash, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var session *models.Session
+	err = u.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		if err := repository.GetTwoFactor().DeleteChallenge(ctx, challenge.ID); err != nil {
+			return err
+		}
+
+		created, err := repository.GetSession().Create(ctx, user.ID, nil, attempt.device, refreshTokenHash, refreshTokenExpiry())
+		session = created
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_ = u.logLogin(ctx, attempt, session, nil)
+	return u.loginResponse(user, session.UUID.String(), refreshToken)
+}
+
+func (u *UserService) GetTwoFactor(ctx context.Context) (*dto.TwoFactorStatusResponse, error) {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := u.repository.GetTwoFactor().CountRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorStatusResponse{
+		Enabled:           user.TotpEnabledAt != nil,
+		Required:          user.Role.RequireTwoFactor,
+		RecoveryCodesLeft: count,
+	}, nil
+}
+
+// SetupTwoFactor generates a new TOTP secret for the logged in user. It only
+// takes effect once EnableTwoFactor saw a code from it, until then running
+// the setup again replaces the secret.
+func (u *UserService) SetupTwoFactor(ctx context.Context, request *dto.TwoFactorSetupRequest) (*dto.TwoFactorSetupResponse, error) {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TotpEnabledAt != nil {
+		return nil, errUser.ErrTwoFactorAlreadyEnabled
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password))
+	if err != nil {
+		return nil, errUser.ErrPasswordIncorrect
+	}
+
+	secret, err := util.GenerateTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.repository.GetUser().Patch(ctx, user.UUID.String(), map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: util.TotpProvisioningURI(config.Config.TotpIssuer, user.Username, secret),
+	}, nil
+}
+
+// EnableTwoFactor turns two-factor authentication on once the user proved
+// their authenticator works, and hands out the recovery codes. Other
+// sessions of the user only passed the password, so they are revoked. The
+// code shares the throttle of the login challenge.
+func (u *UserService) EnableTwoFactor(ctx context.Context, request *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error) {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TotpEnabledAt != nil {
+		return nil, errUser.ErrTwoFactorAlreadyEnabled
+	}
+
+	if user.TotpSecret == nil {
+		return nil, errUser.ErrTwoFactorNotSetUp
+	}
+
+	err = u.throttleTwoFactor(ctx, user, func() error {
+		return u.verifyTotp(ctx, user, request.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, _ := ctx.Value(constants.SessionID).(string)
+	err = u.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		err := repository.GetUser().Patch(ctx, user.UUID.String(), map[string]interface{}{
+			"totp_enabled_at": time.Now(),
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		if err = repository.GetTwoFactor().ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+			return err
+		}
+
+		return repository.GetSession().RevokeOthers(ctx, user.ID, sessionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor needs both the password and a code. Users of a role that
+// requires two-factor authentication can not turn it off.
+func (u *UserService) DisableTwoFactor(ctx context.Context, request *dto.TwoFactorDisableRequest) error {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if user.TotpEnabledAt == nil {
+		return errUser.ErrTwoFactorNotEnabled
+	}
+
+	if user.Role.RequireTwoFactor {
+		return errUser.ErrTwoFactorRequired
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password))
+	if err != nil {
+		return errUser.ErrPasswordIncorrect
+	}
+
+	err = u.checkTwoFactorCode(ctx, user, request.Code)
+	if err != nil {
+		return err
+	}
+
+	return u.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		err := repository.GetUser().Patch(ctx, user.UUID.String(), map[string]interface{}{
+			"totp_secret":     nil,
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		return repository.GetTwoFactor().DeleteRecoveryCodes(ctx, user.ID)
+	})
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user, the used
+// and unused ones alike.
+func (u *UserService) RegenerateRecoveryCodes(ctx context.Context, request *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error) {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TotpEnabledAt == nil {
+		return nil, errUser.ErrTwoFactorNotEnabled
+	}
+
+	err = u.checkTwoFactorCode(ctx, user, request.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.repository.GetTwoFactor().ReplaceRecoveryCodes(ctx, user.ID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// checkTwoFactorCode verifies a code for an account change, with the same
+// throttle as VerifyTwoFactor so a stolen session can not guess it.
+func (u *UserService) checkTwoFactorCode(ctx context.Context, user *models.User, code string) error {
+	return u.throttleTwoFactor(ctx, user, func() error {
+		return u.verifyTwoFactorCode(ctx, user, code)
+	})
+}
+
+// throttleTwoFactor runs verify under the two-factor throttle of the user,
+// counting an incorrect code as a failure and clearing them on success.
+func (u *UserService) throttleTwoFactor(ctx context.Context, user *models.User, verify func() error) error {
+	subjects := []loginSubject{twoFactorSubject(user)}
+
+	err := u.checkLoginThrottle(ctx, subjects)
+	if err != nil {
+		return err
+	}
+
+	err = verify()
+	if err != nil {
+		if errors.Is(err, errUser.ErrTwoFactorCodeIncorrect) {
+			return u.recordLoginFailure(ctx, subjects, err)
+		}
+
+		return err
+	}
+
+	return u.repository.GetLoginThrottle().Reset(ctx, loginScopeTwoFactor, user.UUID.String())
+}
+
+// verifyTwoFactorCode accepts the current TOTP code or an unused recovery
+// code, which is used up by it.
+func (u *UserService) verifyTwoFactorCode(ctx context.Context, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == recoveryCodeLength || len(code) == recoveryCodeLength+1 {
+		return u.repository.GetTwoFactor().UseRecoveryCode(ctx, user.ID, util.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	return u.verifyTotp(ctx, user, code)
+}
+
+// verifyTotp accepts each TOTP code only once, a code seen before is
+// treated as a wrong one.
+func (u *UserService) verifyTotp(ctx context.Context, user *models.User, code string) error {
+	if user.TotpSecret == nil {
+		return errUser.ErrTwoFactorNotSetUp
+	}
+
+	step, ok := util.ValidateTotp(*user.TotpSecret, strings.TrimSpace(code), time.Now())
+	if !ok || step <= user.TotpLastStep {
+		return errUser.ErrTwoFactorCodeIncorrect
+	}
+
+	return u.repository.GetUser().UseTotpStep(ctx, user.ID, step)
+}
+
+func twoFactorSubject(user *models.User) loginSubject {
+	return loginSubject{
+		scope:       loginScopeTwoFactor,
+		subject:     user.UUID.String(),
+		maxAttempts: config.Config.LoginMaxAttempts,
+	}
+}
+
+// generateRecoveryCodes returns the codes to show the user, formatted as
+// xxxxx-xxxxx, and the hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := randomString(recoveryCodeAlphabet, recoveryCodeLength)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		half := recoveryCodeLength / 2
+		codes = append(codes, code[:half]+"-"+code[half:])
+		hashes = append(hashes, util.HashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts a recovery code with or without its dash and
+// in any case.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
+
+// GetSessions lists where the logged in user is signed in.
+func (u *UserService) GetSessions(ctx context.Context) ([]dto.SessionResponse, error) {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.sessions(ctx, user)
+}
+
+// RevokeSession signs the logged in user out on one device, revoking the
+// current session is the same as a logout.
+func (u *UserService) RevokeSession(ctx context.Context, sessionUUID string) error {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return u.repository.GetSession().RevokeOwned(ctx, user.ID, sessionUUID)
+}
+
+func (u *UserService) GetLoginHistory(ctx context.Context, param *dto.LoginHistoryRequestParam) (*util.PaginationResult, error) {
+	err := u.ownLoginHistory(ctx, &param.LoginHistoryFilterParam)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.loginHistory(ctx, param)
+}
+
+func (u *UserService) GetLoginHistoryWithCursor(ctx context.Context, param *dto.LoginHistoryCursorParam) (*util.CursorResult, error) {
+	err := u.ownLoginHistory(ctx, &param.LoginHistoryFilterParam)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.loginHistoryWithCursor(ctx, param)
+}
+
+func (u *UserService) GetUserSessions(ctx context.Context, uuid string) ([]dto.SessionResponse, error) {
+	user, err := u.repository.GetUser().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.sessions(ctx, user)
+}
+
+// Delete removes a user for good. Their sessions are revoked first so
+// access tokens already handed out stop working with the account.
+func (u *UserService) Delete(ctx context.Context, uuid string) error {
+	user, err := u.findManagedUser(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	return u.repository.Transaction(ctx, func(repository repositories.IRepositoryRegistry) error {
+		if err := repository.GetSession().RevokeByUser(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return repository.GetUser().Delete(ctx, user)
+	})
+}
+
+func (u *UserService) RevokeUserSession(ctx context.Context, uuid, sessionUUID string) error {
+	user, err := u.repository.GetUser().FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	return u.repository.GetSession().RevokeOwned(ctx, user.ID, sessionUUID)
+}
+
+// GetAllLoginHistory is the login history of every account, optionally
+// narrowed to one user by UUID.
+func (u *UserService) GetAllLoginHistory(ctx context.Context, param *dto.LoginHistoryRequestParam) (*util.PaginationResult, error) {
+	err := u.anyLoginHistory(ctx, &param.LoginHistoryFilterParam)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.loginHistory(ctx, param)
+}
+
+func (u *UserService) GetAllLoginHistoryWithCursor(ctx context.Context, param *dto.LoginHistoryCursorParam) (*util.CursorResult, error) {
+	err := u.anyLoginHistory(ctx, &param.LoginHistoryFilterParam)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.loginHistoryWithCursor(ctx, param)
+}
+
+// ownLoginHistory narrows the filter to the logged in user.
+func (u *UserService) ownLoginHistory(ctx context.Context, filter *dto.LoginHistoryFilterParam) error {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter.UserID = &user.ID
+	return nil
+}
+
+// anyLoginHistory narrows the filter to the user its UUID names, if any.
+func (u *UserService) anyLoginHistory(ctx context.Context, filter *dto.LoginHistoryFilterParam) error {
+	filter.UserID = nil
+	if filter.User != nil {
+		user, err := u.repository.GetUser().FindByUUID(ctx, *filter.User)
+		if err != nil {
+			return err
+		}
+
+		filter.UserID = &user.ID
+	}
+
+	return nil
+}
+
+func (u *UserService) sessions(ctx context.Context, user *models.User) ([]dto.SessionResponse, error) {
+	sessions, err := u.repository.GetSession().FindActiveByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentID, _ := ctx.Value(constants.SessionID).(string)
+	result := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, dto.SessionResponse{
+			UUID:       session.UUID,
+			DeviceName: session.DeviceName,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			Terminal:   session.TerminalID != nil,
+			Current:    session.UUID.String() == currentID,
+			LoginAt:    session.CreatedAt,
+			LastSeenAt: session.LastUsedAt,
+		})
+	}
+
+	return result, nil
+}
+
+func (u *UserService) loginHistory(ctx context.Context, param *dto.LoginHistoryRequestParam) (*util.PaginationResult, error) {
+	histories, total, err := u.repository.GetLoginHistory().FindAllWithPagination(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination := &util.PaginationParam{
+		Count: total,
+		Page:  param.Page,
+		Limit: param.Limit,
+		Data:  loginHistoryResponses(histories),
+	}
+
+	response := util.GeneratePagination(*pagination)
+	return &response, nil
+}
+
+func (u *UserService) loginHistoryWithCursor(ctx context.Context, param *dto.LoginHistoryCursorParam) (*util.CursorResult, error) {
+	histories, page, err := u.repository.GetLoginHistory().FindAllWithCursor(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	response := util.GenerateCursorResult(page, param.Limit, loginHistoryResponses(histories))
+	return &response, nil
+}
+
+func loginHistoryResponses(histories []models.LoginHistory) []dto.LoginHistoryResponse {
+	result := make([]dto.LoginHistoryResponse, 0, len(histories))
+	for _, history := range histories {
+		item := dto.LoginHistoryResponse{
+			Username:   history.Username,
+			Method:     history.Method,
+			Success:    history.Success,
+			Reason:     history.Reason,
+			DeviceName: history.DeviceName,
+			IPAddress:  history.IPAddress,
+			UserAgent:  history.UserAgent,
+			CreatedAt:  history.CreatedAt,
+		}
+
+		if history.User != nil {
+			item.UserUUID = &history.User.UUID
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// currentUser loads the logged in user fresh from the database.
+func (u *UserService) currentUser(ctx context.Context) (*models.User, error) {
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if !ok || userLogin == nil {
+		return nil, errConstant.ErrUnauthorized
+	}
+
+	return u.repository.GetUser().FindByUUID(ctx, userLogin.UUID.String())
+}
+
+// terminalUser returns the terminal and the logged in user of a request
+// that went through both RequireTerminal and Authenticate.
+func (u *UserService) terminalUser(ctx context.Context) (*models.Terminal, *models.User, error) {
+	terminal, ok := ctx.Value(constants.Terminal).(*models.Terminal)
+	if !ok || terminal == nil {
+		return nil, nil, errTerminal.ErrTerminalUnauthorized
+	}
+
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if !ok || userLogin == nil {
+		return nil, nil, errConstant.ErrUnauthorized
+	}
+
+	user, err := u.repository.GetUser().FindByUUID(ctx, userLogin.UUID.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return terminal, user, nil
+}
+
+// findManagedUser loads the user an admin action targets. Admins can not
+// deactivate, demote or reset themselves so the last owner can not lock
+// everyone out, nor anyone whose role can do more than their own.
+func (u *UserService) findManagedUser(ctx context.Context, uuid string) (*models.User, error) {
+	user, err := u.repository.GetUser().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if !ok || userLogin == nil {
+		return user, nil
+	}
+
+	if userLogin.UUID == user.UUID {
+		return nil, errUser.ErrCannotManageSelf
+	}
+
+	err = u.checkRoleWithin(ctx, userLogin.Role, user.Role.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// findEditableUser loads the user an update or merge patch targets. Anyone
+// allowed to update users may edit the profile of a user whose role can do
+// no more than their own. The email address is where password reset links
+// go, so only the account holder or a user manager may change it.
+func (u *UserService) findEditableUser(ctx context.Context, uuid string, email *string) (*models.User, error) {
+	user, err := u.repository.GetUser().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if !ok || userLogin == nil || userLogin.UUID == user.UUID {
+		return user, nil
+	}
+
+	if email == nil || *email == user.Email {
+		err = u.checkRoleWithin(ctx, userLogin.Role, user.Role.Code)
+		if err != nil {
+			return nil, err
+		}
+
+		return user, nil
+	}
+
+	allowed, err := u.repository.GetRole().HasPermission(ctx, userLogin.Role, constants.PermissionUserManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, errRole.ErrPermissionDenied
+	}
+
+	return u.findManagedUser(ctx, uuid)
+}
+
+// checkRoleWithin refuses when the role target has a permission the role
+// actor lacks.
+func (u *UserService) checkRoleWithin(ctx context.Context, actor, target string) error {
+	if strings.EqualFold(actor, target) {
+		return nil
+	}
+
+	actorRole, err := u.repository.GetRole().FindByCode(ctx, actor)
+	if err != nil {
+		return err
+	}
+
+	targetRole, err := u.repository.GetRole().FindByCode(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	granted := make(map[string]bool, len(actorRole.Permissions))
+	for _, permission := range actorRole.Permissions {
+		granted[permission.Code] = true
+	}
+
+	for _, permission := range targetRole.Permissions {
+		if !granted[permission.Code] {
+			return errUser.ErrRoleOutranksYours
+		}
+	}
+
+	return nil
+}
+
+// notifyEmailChanged tells the previous address of a user that their email
+// was changed, so a change the holder did not make does not go unnoticed.
+func (u *UserService) notifyEmailChanged(ctx context.Context, user *models.User, previous string) {
+	err := u.mailer.Send(ctx, mailer.Message{
+		To:      previous,
+		Subject: fmt.Sprintf("Your %s email address was changed", config.Config.AppName),
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"The email address of your account %s was changed to %s. Password reset links "+
+				"now go to the new address.\n\n"+
+				"If you did not make this change, contact your administrator right away.\n",
+			user.Name, user.Username, user.Email,
+		),
+	})
+	if err != nil {
+		logrus.Errorf("Failed to send email change notice to user %s: %v", user.UUID, err)
+	}
+}
+
+// generateTemporaryPassword returns a 12 character password without
+// look-alike characters so it can be read out to the user.
+func generateTemporaryPassword() (string, error) {
+	return randomString("abcdefghjkmnpqrstuvwxyzABCDEFGHJKMNPQRSTUVWXYZ23456789", 12)
+}
+
+func randomString(alphabet string, length int) (string, error) {
+	buffer := make([]byte, length)
+	for i := range buffer {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+
+		buffer[i] = alphabet[n.Int64()]
+	}
+
+	return string(buffer), nil
+}
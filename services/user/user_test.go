@@ -0,0 +1,189 @@
+package services
+
+import (
+	errValidation "backend/common/error"
+	"backend/common/keys"
+	"backend/common/mailer"
+	"backend/config"
+	"backend/constants"
+	"backend/database/seeders"
+	"backend/domain/dto"
+	"backend/domain/models"
+	"backend/repositories"
+	"context"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// captureMailer keeps the messages it is asked to send.
+type captureMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (c *captureMailer) Send(_ context.Context, message mailer.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func (c *captureMailer) sent() []mailer.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]mailer.Message(nil), c.messages...)
+}
+
+// newTestDB opens a fresh SQLite database with the tables of the user
+// service. It is a file rather than :memory: so concurrent requests share it,
+// the busy timeout makes their writes wait for each other.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	err = db.AutoMigrate(
+		&models.Role{},
+		&models.Permission{},
+		&models.User{},
+		&models.PasswordHistory{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.LoginThrottle{},
+		&models.LoginHistory{},
+		&models.Terminal{},
+		&models.TerminalPin{},
+		&models.RecoveryCode{},
+		&models.LoginChallenge{},
+		&models.PasswordResetToken{},
+	)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	err = db.Create([]models.Role{
+		{ID: constants.Owner, Code: "OWNER", Name: "Owner"},
+		{ID: constants.Admin, Code: "ADMIN", Name: "Administrator"},
+	}).Error
+	if err != nil {
+		t.Fatalf("create roles: %v", err)
+	}
+	seeders.RunPermissionSeeder(db)
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	return db
+}
+
+// setTestConfig sets the config the user service reads and restores the
+// previous one when the test ends.
+func setTestConfig(t *testing.T) {
+	t.Helper()
+
+	previous := config.Config
+	t.Cleanup(func() { config.Config = previous })
+
+	config.Config.AppName = "backend-pos"
+	config.Config.JwtExpirationTime = 15
+	config.Config.RefreshTokenExpiry = 60
+	config.Config.LoginMaxAttempts = 5
+	config.Config.LoginIPMaxAttempts = 100
+	config.Config.LoginLockoutMinutes = 15
+	config.Config.LoginDelaySeconds = 0
+	config.Config.PinMaxAttempts = 5
+	config.Config.TerminalMaxAttempts = 20
+	config.Config.PasswordMinLength = 8
+	config.Config.PasswordMinClasses = 2
+	config.Config.PasswordHistory = 3
+	config.Config.TotpIssuer = "backend-pos"
+	config.Config.TotpChallengeMinutes = 5
+	config.Config.PasswordResetMinutes = 30
+	config.Config.PasswordResetURL = "https://app.example.com/reset-password"
+}
+
+// newTestService returns a user service whose mail is kept by a
+// captureMailer.
+func newTestService(t *testing.T) (*UserService, *gorm.DB) {
+	t.Helper()
+
+	setTestConfig(t)
+	db := newTestDB(t)
+
+	keySet, err := keys.NewKeySet(t.TempDir(), keys.AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	service := NewUserService(repositories.NewRepositoryRegistry(db), keySet, &captureMailer{}).(*UserService)
+	return service, db
+}
+
+// newTestUser creates an active owner with the password, hashed at the lowest cost
+// to keep the tests fast.
+func newTestUser(t *testing.T, db *gorm.DB, username, password string) *models.User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	user := &models.User{
+		UUID:        uuid.New(),
+		Name:        username,
+		Username:    username,
+		Password:    string(hash),
+		PhoneNumber: "0812345678",
+		Email:       username + "@example.com",
+		RoleID:      constants.Owner,
+		IsActive:    true,
+	}
+	if err = db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	return user
+}
+
+// userContext is the context of a request by the user on the session.
+func userContext(user *models.User, sessionID string) context.Context {
+	role := "owner"
+	if user.RoleID == constants.Admin {
+		role = "admin"
+	}
+
+	ctx := context.WithValue(context.Background(), constants.UserLogin, &dto.UserResponse{UUID: user.UUID, Role: role})
+	return context.WithValue(ctx, constants.SessionID, sessionID)
+}
+
+// validationErrors returns the validation errors err must be.
+func validationErrors(t *testing.T, err error) errValidation.ValidationErrors {
+	t.Helper()
+
+	var items errValidation.ValidationErrors
+	if !errors.As(err, &items) {
+		t.Fatalf("error %v is not a validation error", err)
+	}
+
+	return items
+}